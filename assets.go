@@ -0,0 +1,66 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/caiyeon/goldfish/handlers"
+	"github.com/labstack/echo"
+)
+
+// the frontend's build output. build.sh runs the frontend build first, which fills public/
+//
+//go:embed all:public
+var embeddedAssets embed.FS
+
+// serves the ui's files from dir if it has them, otherwise from those built into goldfish
+func assetFileSystem(dir string) http.FileSystem {
+	public, err := fs.Sub(embeddedAssets, "public")
+	if err != nil {
+		panic(err)
+	}
+	assets := http.FS(public)
+	if _, err := fs.Stat(public, "index.html"); err != nil && dir == "" {
+		log.Println("[WARN ]: This binary was built without the ui, only the api will be served")
+	}
+	if dir == "" {
+		return assets
+	}
+
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		log.Fatalf("assets_dir %q is not a directory", dir)
+	}
+	return overlayFileSystem{http.Dir(dir), assets}
+}
+
+// the index page is branded as it's served, so branding changes apply on a config reload
+func indexHandler(assets http.FileSystem) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		f, err := assets.Open("/index.html")
+		if err != nil {
+			return echo.ErrNotFound
+		}
+		defer f.Close()
+		index, err := ioutil.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		return c.HTMLBlob(http.StatusOK, handlers.BrandIndex(index))
+	}
+}
+
+// files in the first file system take precedence, so a single file can be replaced
+type overlayFileSystem struct {
+	top, bottom http.FileSystem
+}
+
+func (o overlayFileSystem) Open(name string) (http.File, error) {
+	if f, err := o.top.Open(name); err == nil {
+		return f, nil
+	}
+	return o.bottom.Open(name)
+}
@@ -0,0 +1,361 @@
+// reads vault's audit log, counting who reads and writes each secret path over time
+package auditlog
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/config"
+)
+
+// paths counted at once. Entries for further paths are dropped until old ones age out
+const maxPaths = 10000
+
+// paths that hold no secrets, or only the caller's own
+var ignoredPrefixes = []string{"sys/", "auth/", "cubbyhole/"}
+
+// the parts of an audit entry that are counted. Vault hmacs tokens and accessors, but not display names
+type entry struct {
+	Time string `json:"time"`
+	Type string `json:"type"`
+	Auth struct {
+		DisplayName string `json:"display_name"`
+	} `json:"auth"`
+	Request struct {
+		Operation string `json:"operation"`
+		Path      string `json:"path"`
+	} `json:"request"`
+	Error string `json:"error"`
+}
+
+type counts struct {
+	Reads  int `json:"reads"`
+	Writes int `json:"writes"`
+	Denied int `json:"denied"`
+}
+
+func (c *counts) add(write, denied bool) {
+	switch {
+	case denied:
+		c.Denied++
+	case write:
+		c.Writes++
+	default:
+		c.Reads++
+	}
+}
+
+func (c *counts) merge(o counts) {
+	c.Reads += o.Reads
+	c.Writes += o.Writes
+	c.Denied += o.Denied
+}
+
+type actor struct {
+	counts
+	last time.Time
+}
+
+type pathStats struct {
+	// by the unix time each bucket starts at
+	buckets map[int64]*counts
+	actors  map[string]*actor
+	last    time.Time
+	// the last read that wasn't denied
+	lastRead time.Time
+}
+
+// the access to one path, in buckets oldest first, and by who accessed it, most active first
+type PathHeat struct {
+	Path string `json:"path"`
+	counts
+	Buckets []Bucket `json:"buckets"`
+	Actors  []Actor  `json:"actors"`
+}
+
+type Bucket struct {
+	Start time.Time `json:"start"`
+	counts
+}
+
+type Actor struct {
+	Name string `json:"name"`
+	counts
+	LastSeen time.Time `json:"last_seen"`
+}
+
+type Heatmap struct {
+	Since  time.Time  `json:"since"`
+	Bucket float64    `json:"bucket_seconds"`
+	Paths  []PathHeat `json:"paths"`
+}
+
+type Status struct {
+	Configured bool      `json:"configured"`
+	Type       string    `json:"type,omitempty"`
+	Entries    int       `json:"entries"`
+	Paths      int       `json:"paths"`
+	Dropped    int       `json:"dropped"`
+	LastEntry  time.Time `json:"last_entry"`
+	Error      string    `json:"error,omitempty"`
+}
+
+var (
+	lock    = new(sync.Mutex)
+	current *config.AuditSourceConfig
+	stop    chan struct{}
+	paths   = make(map[string]*pathStats)
+	status  Status
+	pruned  time.Time
+	// when the counts kept began
+	started time.Time
+)
+
+// starts reading the configured audit log, replacing any source read before. A nil config stops reading
+// counts are kept if only the retention changes, and dropped otherwise
+// may be called again at runtime, e.g. when the config file is reloaded
+func Configure(c *config.AuditSourceConfig) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if stop != nil {
+		close(stop)
+		stop = nil
+	}
+	if current == nil || c == nil || current.Type != c.Type || current.Path != c.Path ||
+		current.Address != c.Address || current.Bucket != c.Bucket {
+		paths = make(map[string]*pathStats)
+		status = Status{}
+		started = time.Now()
+	}
+	current = c
+	status.Configured = c != nil
+	status.Error = ""
+	if c == nil {
+		return
+	}
+	status.Type = c.Type
+
+	stop = make(chan struct{})
+	go run(c, stop)
+	if c.Type == "file" {
+		log.Printf("[INFO ]: Tailing vault's audit log at %s\n", c.Path)
+	}
+}
+
+func setError(err error) {
+	lock.Lock()
+	defer lock.Unlock()
+	if err == nil {
+		status.Error = ""
+	} else {
+		status.Error = err.Error()
+	}
+}
+
+// counts one line of the audit log. Only responses are counted, since each request is logged twice
+func record(line []byte) {
+	var e entry
+	if err := json.Unmarshal(line, &e); err != nil || e.Type != "response" {
+		return
+	}
+	path := strings.TrimPrefix(e.Request.Path, "/")
+	if path == "" {
+		return
+	}
+	for _, prefix := range ignoredPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return
+		}
+	}
+
+	var write bool
+	switch e.Request.Operation {
+	case "read", "list":
+	case "create", "update", "delete":
+		write = true
+	default:
+		return
+	}
+	denied := strings.Contains(e.Error, "permission denied")
+	at, err := time.Parse(time.RFC3339Nano, e.Time)
+	if err != nil {
+		at = time.Now()
+	}
+	name := e.Auth.DisplayName
+	if name == "" {
+		name = "unauthenticated"
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	c := current
+	if c == nil {
+		return
+	}
+	prune(c)
+	if at.Before(time.Now().Add(-c.Retention)) {
+		return
+	}
+
+	p, ok := paths[path]
+	if !ok {
+		if len(paths) >= maxPaths {
+			status.Dropped++
+			return
+		}
+		p = &pathStats{
+			buckets: make(map[int64]*counts),
+			actors:  make(map[string]*actor),
+		}
+		paths[path] = p
+	}
+
+	start := at.Truncate(c.Bucket).Unix()
+	if p.buckets[start] == nil {
+		p.buckets[start] = &counts{}
+	}
+	p.buckets[start].add(write, denied)
+	if p.actors[name] == nil {
+		p.actors[name] = &actor{}
+	}
+	a := p.actors[name]
+	a.add(write, denied)
+	if at.After(a.last) {
+		a.last = at
+	}
+	if at.After(p.last) {
+		p.last = at
+	}
+	if !write && !denied && at.After(p.lastRead) {
+		p.lastRead = at
+	}
+	status.Entries++
+	if at.After(status.LastEntry) {
+		status.LastEntry = at
+	}
+}
+
+// drops buckets older than retention, and paths and actors not seen since
+// at most once a minute, as every path is visited. The lock must be held
+func prune(c *config.AuditSourceConfig) {
+	if time.Since(pruned) < time.Minute {
+		return
+	}
+	pruned = time.Now()
+	cutoff := pruned.Add(-c.Retention)
+	for path, p := range paths {
+		if p.last.Before(cutoff) {
+			delete(paths, path)
+			continue
+		}
+		for start := range p.buckets {
+			if time.Unix(start, 0).Add(c.Bucket).Before(cutoff) {
+				delete(p.buckets, start)
+			}
+		}
+		for name, a := range p.actors {
+			if a.last.Before(cutoff) {
+				delete(p.actors, name)
+			}
+		}
+	}
+}
+
+// whether path is prefix itself or below it, on a path segment boundary, so secret/app doesn't take in secret/apple
+func underPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// the access to paths under prefix within the last window, busiest path first
+// actors' counts are of the whole retention, since they aren't kept per bucket
+func Query(prefix string, window time.Duration) Heatmap {
+	lock.Lock()
+	defer lock.Unlock()
+
+	h := Heatmap{Paths: make([]PathHeat, 0)}
+	c := current
+	if c == nil {
+		return h
+	}
+	prune(c)
+	if window <= 0 || window > c.Retention {
+		window = c.Retention
+	}
+	h.Since = time.Now().Add(-window).Truncate(c.Bucket)
+	h.Bucket = c.Bucket.Seconds()
+
+	for path, p := range paths {
+		if !underPrefix(path, prefix) || p.last.Before(h.Since) {
+			continue
+		}
+		heat := PathHeat{
+			Path:    path,
+			Buckets: make([]Bucket, 0),
+			Actors:  make([]Actor, 0),
+		}
+		for start, counts := range p.buckets {
+			if t := time.Unix(start, 0); !t.Before(h.Since) {
+				heat.Buckets = append(heat.Buckets, Bucket{Start: t.UTC(), counts: *counts})
+				heat.merge(*counts)
+			}
+		}
+		sort.Slice(heat.Buckets, func(i, j int) bool { return heat.Buckets[i].Start.Before(heat.Buckets[j].Start) })
+		for name, a := range p.actors {
+			if !a.last.Before(h.Since) {
+				heat.Actors = append(heat.Actors, Actor{Name: name, counts: a.counts, LastSeen: a.last.UTC()})
+			}
+		}
+		sort.Slice(heat.Actors, func(i, j int) bool {
+			return total(heat.Actors[i].counts) > total(heat.Actors[j].counts) ||
+				(total(heat.Actors[i].counts) == total(heat.Actors[j].counts) && heat.Actors[i].Name < heat.Actors[j].Name)
+		})
+		h.Paths = append(h.Paths, heat)
+	}
+	sort.Slice(h.Paths, func(i, j int) bool {
+		return total(h.Paths[i].counts) > total(h.Paths[j].counts) ||
+			(total(h.Paths[i].counts) == total(h.Paths[j].counts) && h.Paths[i].Path < h.Paths[j].Path)
+	})
+	return h
+}
+
+// how far back reads are known: the retention, or less if goldfish began reading the audit log since
+// zero if no audit source is configured
+func Coverage() time.Duration {
+	lock.Lock()
+	defer lock.Unlock()
+	if current == nil {
+		return 0
+	}
+	if since := time.Since(started); since < current.Retention {
+		return since
+	}
+	return current.Retention
+}
+
+// when path was last read, or the zero time if it wasn't within coverage
+func LastRead(path string) time.Time {
+	lock.Lock()
+	defer lock.Unlock()
+	if p, ok := paths[path]; ok {
+		return p.lastRead
+	}
+	return time.Time{}
+}
+
+func total(c counts) int {
+	return c.Reads + c.Writes + c.Denied
+}
+
+func GetStatus() Status {
+	lock.Lock()
+	defer lock.Unlock()
+	s := status
+	s.Paths = len(paths)
+	return s
+}
@@ -0,0 +1,179 @@
+package auditlog
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/caiyeon/goldfish/config"
+)
+
+// how often a tailed file is checked for new entries, or for being rotated
+const pollInterval = time.Second
+
+// reads entries from the configured source until stop is closed
+func run(c *config.AuditSourceConfig, stop chan struct{}) {
+	switch c.Type {
+	case "file":
+		tail(c.Path, stop)
+	case "socket":
+		listen(c.Address, c.Allowed_cidrs, stop)
+	}
+}
+
+// follows a file audit device's log from its current end, as tail -F does
+// a log that shrinks, or is replaced by a new file, is read again from its start
+func tail(path string, stop chan struct{}) {
+	var f *os.File
+	var r *bufio.Reader
+	var offset int64
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for first := true; ; first = false {
+		if f == nil {
+			var err error
+			if f, err = os.Open(path); err != nil {
+				setError(err)
+			} else {
+				// entries already in the log when goldfish starts are skipped, as their time has mostly passed
+				if first {
+					offset, _ = f.Seek(0, io.SeekEnd)
+				} else {
+					offset = 0
+				}
+				r = bufio.NewReader(f)
+				setError(nil)
+			}
+		}
+
+		if f != nil {
+			for {
+				line, err := r.ReadBytes('\n')
+				if err != nil {
+					// a partial line is read again once the rest of it is written
+					if len(line) > 0 {
+						f.Seek(offset, io.SeekStart)
+						r.Reset(f)
+					}
+					break
+				}
+				offset += int64(len(line))
+				record(line)
+			}
+			if rotated(f, path, offset) {
+				f.Close()
+				f = nil
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// whether the log at path is no longer the open file, or was truncated
+func rotated(f *os.File, path string, offset int64) bool {
+	open, err := f.Stat()
+	if err != nil {
+		return true
+	}
+	current, err := os.Stat(path)
+	if err != nil {
+		// the new log may not have been created yet
+		return false
+	}
+	return !os.SameFile(open, current) || open.Size() < offset
+}
+
+// accepts connections from socket audit devices, which write an entry per line
+// the devices can't authenticate, so connections from outside allowed (if any are given) are closed unread
+func listen(address string, allowed []string, stop chan struct{}) {
+	var networks []*net.IPNet
+	for _, cidr := range allowed {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			networks = append(networks, network)
+		}
+	}
+
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Printf("[ERROR]: Audit log socket could not listen on %s: %s\n", address, err.Error())
+		setError(err)
+		return
+	}
+	log.Printf("[INFO ]: Receiving vault's audit log on %s\n", address)
+	setError(nil)
+	go func() {
+		<-stop
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			setError(err)
+			time.Sleep(pollInterval)
+			continue
+		}
+		if !peerAllowed(conn, networks) {
+			log.Printf("[WARN ]: Refused audit log connection from %s\n", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+		go receive(conn, stop)
+	}
+}
+
+func peerAllowed(conn net.Conn, networks []*net.IPNet) bool {
+	if len(networks) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	for _, n := range networks {
+		if ip != nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func receive(conn net.Conn, stop chan struct{}) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			conn.Close()
+		case <-done:
+		}
+	}()
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	// entries carrying large secrets can be long
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		record(scanner.Bytes())
+	}
+}
@@ -1,19 +1,21 @@
-# You need go (v1.8), npm (v3), nodejs (v7)
+# You need go (v1.18), npm (v3), nodejs (v7)
+
+# the ui is embedded with go:embed's all: prefix, which needs go 1.18
+GO_MINOR=$(go version | sed -E 's/.*go1\.([0-9]+).*/\1/')
+if [ -z "$GO_MINOR" ] || [ "$GO_MINOR" -lt 18 ]; then
+	echo "Go 1.18 or later is required, found: $(go version)"
+	exit 1
+fi
 
 # generate frontend static assets
 cd frontend
 npm run build || exit 1
 cd ..
 
-# code-ify static assets
-go get github.com/GeertJohan/go.rice || exit 1
-go get github.com/GeertJohan/go.rice/rice || exit 1
-rm -f rice-box.go || exit 1
-rice embed-go || exit 1
-
-# compile goldfish binary
-env GOOS=linux GOARCH=amd64 go build -o goldfish-linux-amd64 -v github.com/caiyeon/goldfish || exit 1
-env GOOS=windows GOARCH=amd64 go build -o goldfish-windows-amd64.exe -v github.com/caiyeon/goldfish || exit 1
+# compile goldfish binary, with the commit and date it was built from
+LDFLAGS="-X main.commit=$(git rev-parse --short HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+env GOOS=linux GOARCH=amd64 go build -ldflags "$LDFLAGS" -o goldfish-linux-amd64 -v github.com/caiyeon/goldfish || exit 1
+env GOOS=windows GOARCH=amd64 go build -ldflags "$LDFLAGS" -o goldfish-windows-amd64.exe -v github.com/caiyeon/goldfish || exit 1
 
 # report build
 echo 'Successfully built ' $(git describe --always --tags)
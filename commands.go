@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/caiyeon/goldfish/config"
+)
+
+func main() {
+	// no subcommand (or a leading flag) means the old flag-only cli, which is 'serve'
+	if len(os.Args) < 2 || strings.HasPrefix(os.Args[1], "-") {
+		serve(os.Args[1:])
+		return
+	}
+
+	switch os.Args[1] {
+	case "serve":
+		serve(os.Args[2:])
+	case "validate":
+		os.Exit(validate(os.Args[2:]))
+	case "bootstrap":
+		os.Exit(bootstrap(os.Args[2:]))
+	case "service":
+		os.Exit(service(os.Args[2:]))
+	case "version":
+		fmt.Println(versionString)
+	case "help":
+		fmt.Fprintf(os.Stderr, helpMessage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", os.Args[1])
+		fmt.Fprintf(os.Stderr, helpMessage)
+		os.Exit(1)
+	}
+}
+
+// parses a deployment config file without starting anything
+func validate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	path := flags.String("config", "", "The path of the deployment config file")
+	flags.Parse(args)
+
+	// allow 'goldfish validate config.hcl' as well
+	if *path == "" && flags.NArg() > 0 {
+		*path = flags.Arg(0)
+	}
+
+	c, err := config.LoadConfigFile(*path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid config:", err.Error())
+		return 1
+	}
+	if c.Vault.Address == "" {
+		fmt.Fprintln(os.Stderr, "Invalid config: vault address is missing")
+		return 1
+	}
+
+	fmt.Println("Config is valid")
+	return 0
+}
+
+// hands a wrapping token to an already running goldfish instance
+// with -rebootstrap, replaces the credentials of an instance that is already bootstrapped
+func bootstrap(args []string) int {
+	flags := flag.NewFlagSet("bootstrap", flag.ExitOnError)
+	address := flags.String("address", "http://127.0.0.1:8000", "The address of the running goldfish instance")
+	token := flags.String("token", "", "Token generated from approle (must be wrapped!)")
+	insecure := flags.Bool("tls-skip-verify", false, "Skip verification of goldfish's certificate")
+	rebootstrap := flags.Bool("rebootstrap", false, "Replace the credentials of an instance that is already bootstrapped")
+	vaultToken := flags.String("vault-token", os.Getenv("VAULT_TOKEN"), "A vault token that can write goldfish's runtime config (for -rebootstrap)")
+	flags.Parse(args)
+
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "A wrapping token must be provided with -token")
+		return 1
+	}
+	if *rebootstrap && *vaultToken == "" {
+		fmt.Fprintln(os.Stderr, "A vault token must be provided with -vault-token or VAULT_TOKEN to re-bootstrap")
+		return 1
+	}
+
+	body, _ := json.Marshal(map[string]string{"wrapping_token": *token})
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: *insecure},
+		},
+	}
+
+	endpoint := "/v1/bootstrap"
+	if *rebootstrap {
+		endpoint = "/v1/rebootstrap"
+	}
+	req, err := http.NewRequest("POST", strings.TrimRight(*address, "/")+endpoint, bytes.NewReader(body))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Bootstrap failed:", err.Error())
+		return 1
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if *rebootstrap {
+		req.Header.Set("X-Vault-Token", *vaultToken)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Bootstrap failed:", err.Error())
+		return 1
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Result string
+		Error  string
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode != http.StatusOK {
+		if result.Error == "" {
+			result.Error = resp.Status
+		}
+		fmt.Fprintln(os.Stderr, "Bootstrap failed:", result.Error)
+		return 1
+	}
+
+	fmt.Println("Goldfish successfully bootstrapped")
+	return 0
+}
@@ -1,24 +1,91 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"net"
 	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/caiyeon/goldfish/schedule"
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
 	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/hashicorp/vault/helper/tlsutil"
+	"github.com/labstack/gommon/bytes"
 )
 
 var ch = make(chan error)
 
 type Config struct {
-	Listener        *ListenerConfig `hcl:"-"`
-	Vault           *VaultConfig    `hcl:"-"`
-	DisableMlock    bool            `hcl:"-"`
-	DisableMlockRaw interface{}     `hcl:"disable_mlock"`
+	Listener        *ListenerConfig           `hcl:"-"`
+	ExtraListeners  []*ListenerConfig         `hcl:"-"`
+	Vault           *VaultConfig              `hcl:"-"`
+	Telemetry       *TelemetryConfig          `hcl:"-"`
+	Session         *SessionConfig            `hcl:"-"`
+	RateLimit       *RateLimitConfig          `hcl:"-"`
+	Clusters        map[string]*ClusterConfig `hcl:"-"`
+	Logins          map[string]*LoginConfig   `hcl:"-"`
+	Roles           map[string]*RoleConfig    `hcl:"-"`
+	DisableMlock    bool                      `hcl:"-"`
+	DisableMlockRaw interface{}               `hcl:"disable_mlock"`
+
+	// shown by the ui, so deployments can be told apart. Nil unless configured
+	Branding *BrandingConfig `hcl:"-"`
+
+	// limits on tokens created through goldfish, on top of vault's own. Nil unless configured
+	TokenCreation *TokenCreationConfig `hcl:"-"`
+
+	// logging in to goldfish through the organization's identity provider. Nil unless configured
+	OIDC *OIDCConfig `hcl:"-"`
+
+	// lets change requests be acted on from slack's interactive messages. Nil unless configured
+	ChatOps *ChatOpsConfig `hcl:"-"`
+
+	// periodic snapshots of vault's integrated (raft) storage. Nil unless configured
+	RaftSnapshot *RaftSnapshotConfig `hcl:"-"`
+
+	// vault's audit log, read for the access heatmap. Nil unless configured
+	AuditSource *AuditSourceConfig `hcl:"-"`
+
+	// scheduled reports, and where they are sent
+	Notifiers map[string]*NotifierConfig `hcl:"-"`
+	Reports   map[string]*ReportConfig   `hcl:"-"`
+
+	// rules policy requests are checked against when submitted
+	PolicyLints map[string]*PolicyLintConfig `hcl:"-"`
+
+	// every state-changing endpoint is refused, for a browse-only instance
+	ReadOnly    bool        `hcl:"-"`
+	ReadOnlyRaw interface{} `hcl:"read_only"`
+
+	// deletes must be repeated with the confirmation token the first attempt returns
+	RequireConfirmation    bool        `hcl:"-"`
+	RequireConfirmationRaw interface{} `hcl:"require_confirmation"`
+
+	// rolling a policy back to before an approved change makes a change request, instead of using the user's token
+	RollbackApproval    bool        `hcl:"-"`
+	RollbackApprovalRaw interface{} `hcl:"rollback_requires_approval"`
+
+	// /v1/version asks github whether there's a newer release
+	UpdateCheck    bool        `hcl:"-"`
+	UpdateCheckRaw interface{} `hcl:"update_check"`
+
+	// /v1/status shows whether goldfish and vault are up, without a token
+	StatusPage    bool        `hcl:"-"`
+	StatusPageRaw interface{} `hcl:"status_page"`
+
+	// refuses every feature that needs the internet, for networks without egress
+	AirGapped    bool        `hcl:"-"`
+	AirGappedRaw interface{} `hcl:"air_gapped"`
 }
 
 type ListenerConfig struct {
@@ -28,28 +95,376 @@ type ListenerConfig struct {
 	Tls_cert_file    string
 	Tls_key_file     string
 	Tls_autoredirect bool
+
+	// failed logins per ip and per username back off exponentially, then lock out. 0 attempts disables it
+	Login_max_attempts int
+	Login_backoff      time.Duration
+	Login_lockout      time.Duration
+
+	// on SIGINT or SIGTERM, in-flight requests get this long to finish
+	Shutdown_timeout time.Duration
+
+	// an idle timeout of 0 means the read timeout is used. A gzip level of 0 disables compression
+	Body_limit    string
+	Gzip_level    int
+	Read_timeout  time.Duration
+	Write_timeout time.Duration
+	Idle_timeout  time.Duration
+
+	// requests must come from an allowed CIDR (if any are set) and not from a denied one
+	// admin endpoints must also come from an admin allowed CIDR, if any are set
+	Allowed_cidrs       []string
+	Denied_cidrs        []string
+	Admin_allowed_cidrs []string
+
+	// reverse proxies whose X-Forwarded-For and X-Real-IP headers are believed. Anyone else's are ignored
+	Trusted_proxies []string
+
+	// security headers sent over tls. The preset fills in whichever of these weren't given
+	Security_preset         string
+	Csp                     string
+	Frame_options           string
+	Referrer_policy         string
+	Hsts_max_age            time.Duration
+	Hsts_include_subdomains bool
+	Hsts_preload            bool
+
+	// mutual tls: clients must present a certificate signed by this CA, optionally with an allowed CN or OU
+	Tls_require_client_cert bool
+	Tls_client_ca_file      string
+	Tls_client_allowed_cns  []string
+	Tls_client_allowed_ous  []string
+
+	// names as in vault's tls_cipher_suites. No suites means go's defaults
+	Tls_min_version   string
+	Tls_cipher_suites []string
+	Tls_cipher_preset string
+
+	// serves swagger ui for the api at /docs, with its assets built into goldfish at a pinned version
+	Swagger_ui bool
+
+	// the ui's files are served from here, falling back to those built into the binary
+	Assets_dir string
+
+	// the plain http listener that tls_autoredirect redirects from
+	Tls_redirect_address string
+
+	// let's encrypt, used when tls is enabled without cert files
+	Autocert_cache_dir string
+	Autocert_email     string
+	Autocert_hosts     []string
+
+	// serves the ui and api under a path, e.g. "/goldfish" behind a path based ingress
+	Base_path string
+
+	// only for unix listeners, where address is the socket's path
+	Socket_mode  os.FileMode
+	Socket_user  string
+	Socket_group string
 }
 
 type VaultConfig struct {
 	Type            string
 	Address         string
+	Addresses       []string
+	Address_srv     string
+	Address_consul  string
+	Consul_tag      string
+	Consul_address  string
 	Tls_skip_verify bool
+	Ca_cert         string
+	Ca_path         string
+	Client_cert     string
+	Client_key      string
+	Tls_server_name string
+	Proxy_address   string
+	No_proxy        string
 	Runtime_config  string
 	Approle_login   string
 	Approle_id      string
+
+	// admin-tunable settings, empty for "<runtime_config>/settings"
+	Settings_path string
+	// goldfish's own records and job leases, empty for "<runtime_config>/state"
+	State_path string
+
+	Approle_secret_id      string
+	Approle_secret_id_file string
+
+	Token_file string
+
+	Kubernetes_role     string
+	Kubernetes_login    string
+	Kubernetes_jwt_file string
+
+	Timeout        time.Duration
+	List_timeout   time.Duration
+	Max_retries    int
+	Retry_wait_min time.Duration
+	Retry_wait_max time.Duration
+
+	// token accessor, policy, and mount lists are cached per session this long. 0 disables it
+	List_cache_ttl time.Duration
+
+	// secret, token accessor and policy lists stop at this many keys, unless 0. Streamed lists too
+	List_max_items int
+
+	// keeps /v1/health/ready passing while vault is sealed or down, e.g. during maintenance
+	Ready_while_sealed bool
+
+	// how often vault's health is polled, and how long the history of those checks is kept
+	Health_check_interval time.Duration
+	Health_history        time.Duration
+}
+
+// additional vault clusters users may log in to
+// goldfish's own token, runtime config and transit key always live in the 'vault' block's cluster
+type ClusterConfig struct {
+	Name            string
+	Address         string
+	Tls_skip_verify bool
+	Ca_cert         string
+}
+
+// a login option at an auth mount, offered by its name. The type defaults to the name, the path to auth/<name>
+// so "ldap" may be moved to another mount, and e.g. "corp-ldap" and "partner-ldap" offered side by side
+type LoginConfig struct {
+	Name  string
+	Type  string
+	Path  string
+	Label string
+}
+
+// login types goldfish can log in with, besides tokens, which need no mount
+var LoginTypes = []string{"userpass", "ldap", "github", "okta"}
+
+// a goldfish role, held by tokens with any of its vault policies or identity groups
+// endpoints are api tags such as "tokens", single endpoints such as "POST /v1/token/revoke-accessor", or "*"
+type RoleConfig struct {
+	Name      string   `json:"-"`
+	Policies  []string `json:"policies"`
+	Groups    []string `json:"groups"`
+	Endpoints []string `json:"endpoints"`
+}
+
+// somewhere goldfish sends notifications, such as scheduled reports
+// slack notifiers without a url use the runtime config's slack webhook
+type NotifierConfig struct {
+	Name               string
+	Type               string
+	Url                string
+	Channel            string
+	Smtp_address       string
+	Smtp_username      string
+	Smtp_password_file string
+	From               string
+	To                 []string
+	// pagerduty's routing key, or opsgenie's api key
+	Key_file string
+}
+
+// vault's audit log, tailed from a file device's file, or received from a socket device
+// entries are counted in buckets per secret path, and dropped once older than retention
+type AuditSourceConfig struct {
+	Type    string
+	Path    string
+	Address string
+
+	// vault's socket audit device can't authenticate, so a socket that isn't on loopback only accepts these
+	Allowed_cidrs []string
+
+	Retention time.Duration
+	Bucket    time.Duration
+}
+
+// a rule proposed policies are checked against before a policy request is made
+// forbid_capabilities refuses capabilities on paths, require_deny refuses policies that could reach paths
+// without denying them, and policy_name refuses names that don't match pattern
+type PolicyLintConfig struct {
+	Name         string
+	Type         string
+	Paths        []string
+	Capabilities []string
+	Pattern      string
+	// block refuses the request, warn only reports the violation to the requester
+	Action  string
+	Message string
+}
+
+// a report goldfish runs on a schedule, sending the results to notifiers
+type ReportConfig struct {
+	Name     string
+	Type     string
+	Schedule string
+	Notify   []string
+	// how far ahead expiring tokens and certificates are reported, or how old requests are escalated at
+	Window     time.Duration
+	Pki_mounts []string
+	// folders whose untagged secrets are reported, e.g. "secret/"
+	Paths []string
+}
+
+type SessionConfig struct {
+	Store          string
+	File_path      string
+	Redis_address  string
+	Redis_password string
+	Redis_db       int
+
+	// a dedicated transit key for session tokens, instead of the runtime config's server transit key
+	Transit_key     string
+	Transit_backend string
+
+	// zero means no limit, besides the lifetime of the vault token itself
+	Ttl              time.Duration
+	Idle_timeout     time.Duration
+	Absolute_timeout time.Duration
+
+	// destructive endpoints need credentials re-entered within this long. Zero disables it
+	Step_up_window time.Duration
+
+	// users with update capability here may revoke every session. Empty means the vault runtime config path
+	Admin_path string
+}
+
+// limits are per caller, i.e. per session or per ip. A rate of 0 means no limit
+type RateLimitConfig struct {
+	Requests_per_second float64
+	Burst               int
+	Key                 string
+
+	// routes listed here are limited separately, instead of sharing the limit above
+	Routes map[string]RouteLimitConfig
+}
+
+type BrandingConfig struct {
+	Organization string
+	Logo_file    string
+	Login_banner string
+	Accent_color string
+}
+
+type TokenCreationConfig struct {
+	// applied to requests without a ttl
+	Default_ttl time.Duration
+
+	// caps ttl, explicit_max_ttl and period. Tokens requested without an explicit_max_ttl are given this one
+	Max_ttl time.Duration
+
+	// if set, every token must be limited to between 1 and this many uses
+	Max_num_uses int
+
+	// tokens are only handed out response-wrapped, so the token itself is never shown to whoever created it
+	Require_wrapping bool
+	Max_wrap_ttl     time.Duration
+}
+
+type RaftSnapshotConfig struct {
+	// when snapshots are taken, as for reports, e.g. "@every 6h" or "0 3 * * *"
+	Schedule string
+
+	// the most recent snapshots kept at the destination, older ones are deleted. 0 keeps them all
+	Retain int
+
+	// snapshots are written to a local directory, or uploaded to an s3 compatible bucket
+	Local_dir string
+	S3_bucket string
+	S3_prefix string
+	S3_region string
+
+	// for s3 compatible storage other than aws, e.g. "https://minio.internal:9000"
+	S3_endpoint string
+
+	// without key files, credentials are found as aws's tools find them, e.g. from the environment
+	S3_access_key_file string
+	S3_secret_key_file string
+}
+
+// goldfish as an openid connect relying party. The identity provider's id token is exchanged for a
+// vault token with a role of a jwt auth method, which must trust the same provider
+type OIDCConfig struct {
+	// the provider's issuer, where /.well-known/openid-configuration is found
+	Discovery_url      string
+	Client_id          string
+	Client_secret_file string
+
+	// must be goldfish's /v1/login/oidc/callback, as registered with the provider
+	Redirect_url string
+	Scopes       []string
+
+	Vault_mount string
+	Vault_role  string
+}
+
+type ChatOpsConfig struct {
+	// slack's signing secret, which callbacks must be signed with. Read each time a callback arrives
+	Signing_secret_file string
+
+	// chat user ids, e.g. slack's "U012AB3CD", mapped to the display names they act as
+	Approvers map[string]string
+
+	// where users reach goldfish, for links back to the ui
+	Ui_address string
+}
+
+type RouteLimitConfig struct {
+	Requests_per_second float64
+	Burst               int
+}
+
+type TelemetryConfig struct {
+	Prometheus_disable bool
+	Prometheus_address string
+
+	// a file holding the bearer token scrapers send. /metrics is only served on the listeners with one
+	Prometheus_token_file string
+	Otlp_endpoint         string
+	Otlp_headers          map[string]string
+	Trace_sample_ratio    float64
+	Statsd_address        string
+	Statsd_prefix         string
+	Statsd_tags           []string
 }
 
 func LoadConfigFile(path string) (*Config, error) {
 	if path == "" {
+		// without a config file, every required value must come from the environment
+		if hasEnvOverrides() {
+			return ParseConfig(envOnlyConfigString)
+		}
 		return nil, errors.New("[ERROR]: Config file not specified")
 	}
 	d, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	if isJSON(path, d) {
+		return ParseConfigJSON(string(d))
+	}
 	return ParseConfig(string(d))
 }
 
+// the format is picked by the extension, or for any other, e.g. goldfish.conf or none at all, by the content
+// a json config is an object, while hcl never starts with a brace
+func isJSON(path string, d []byte) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return true
+	case ".hcl":
+		return false
+	}
+	return strings.HasPrefix(strings.TrimSpace(string(d)), "{")
+}
+
+// hcl can parse json by itself, but encoding/json gives clearer errors on malformed files
+func ParseConfigJSON(d string) (*Config, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal([]byte(d), &raw); err != nil {
+		return nil, fmt.Errorf("Config file is not valid JSON: %s", err.Error())
+	}
+	return ParseConfig(d)
+}
+
 func LoadConfigDev() (*Config, chan struct{}, []string, string, error) {
 	// start a vault dev instance
 	unsealToken, shutdownCh := initDevVaultCore()
@@ -71,9 +486,23 @@ func LoadConfigDev() (*Config, chan struct{}, []string, string, error) {
 
 	result := Config{
 		Listener: &ListenerConfig{
-			Type:        "tcp",
-			Address:     "127.0.0.1:8000",
-			Tls_disable: true,
+			Type:                 "tcp",
+			Address:              "127.0.0.1:8000",
+			Tls_disable:          true,
+			Login_max_attempts:   defaultLoginMaxAttempts,
+			Login_backoff:        defaultLoginBackoff,
+			Login_lockout:        defaultLoginLockout,
+			Shutdown_timeout:     defaultShutdownTimeout,
+			Body_limit:           defaultBodyLimit,
+			Gzip_level:           defaultGzipLevel,
+			Read_timeout:         defaultReadTimeout,
+			Write_timeout:        defaultWriteTimeout,
+			Security_preset:      "default",
+			Csp:                  securityPresets["default"].Csp,
+			Frame_options:        securityPresets["default"].Frame_options,
+			Tls_min_version:      "tls12",
+			Tls_redirect_address: ":80",
+			Autocert_cache_dir:   "/var/www/.cache",
 		},
 		Vault: &VaultConfig{
 			Type:           "vault",
@@ -81,7 +510,15 @@ func LoadConfigDev() (*Config, chan struct{}, []string, string, error) {
 			Runtime_config: "secret/goldfish",
 			Approle_login:  "auth/approle/login",
 			Approle_id:     "goldfish",
+			Timeout:        defaultVaultTimeout,
+			List_timeout:   defaultVaultListTimeout,
+			Max_retries:    defaultMaxRetries,
+			Retry_wait_min: defaultRetryWaitMin,
+			Retry_wait_max: defaultRetryWaitMax,
 		},
+		Telemetry:    &TelemetryConfig{},
+		Session:      &SessionConfig{Store: "memory"},
+		RateLimit:    &RateLimitConfig{},
 		DisableMlock: true,
 	}
 
@@ -103,8 +540,11 @@ func ParseConfig(d string) (*Config, error) {
 
 	// make a new config and decode hcl
 	result := Config{
-		Listener: &ListenerConfig{},
-		Vault:    &VaultConfig{},
+		Listener:  &ListenerConfig{},
+		Vault:     &VaultConfig{},
+		Telemetry: &TelemetryConfig{},
+		Session:   &SessionConfig{Store: "memory"},
+		RateLimit: &RateLimitConfig{},
 	}
 	if err := hcl.DecodeObject(&result, obj); err != nil {
 		return nil, err
@@ -117,28 +557,113 @@ func ParseConfig(d string) (*Config, error) {
 	}
 
 	// perform checks on root config keys
+	if v := os.Getenv("GOLDFISH_DISABLE_MLOCK"); v != "" {
+		result.DisableMlockRaw = v
+	}
 	if result.DisableMlockRaw != nil {
 		if result.DisableMlock, err = parseutil.ParseBool(result.DisableMlockRaw); err != nil {
 			return nil, err
 		}
 	}
+	if v := os.Getenv("GOLDFISH_READ_ONLY"); v != "" {
+		result.ReadOnlyRaw = v
+	}
+	if result.ReadOnlyRaw != nil {
+		if result.ReadOnly, err = parseutil.ParseBool(result.ReadOnlyRaw); err != nil {
+			return nil, err
+		}
+	}
+	if v := os.Getenv("GOLDFISH_REQUIRE_CONFIRMATION"); v != "" {
+		result.RequireConfirmationRaw = v
+	}
+	if result.RequireConfirmationRaw != nil {
+		if result.RequireConfirmation, err = parseutil.ParseBool(result.RequireConfirmationRaw); err != nil {
+			return nil, err
+		}
+	}
+	if v := os.Getenv("GOLDFISH_ROLLBACK_REQUIRES_APPROVAL"); v != "" {
+		result.RollbackApprovalRaw = v
+	}
+	if result.RollbackApprovalRaw != nil {
+		if result.RollbackApproval, err = parseutil.ParseBool(result.RollbackApprovalRaw); err != nil {
+			return nil, err
+		}
+	}
+	if v := os.Getenv("GOLDFISH_AIR_GAPPED"); v != "" {
+		result.AirGappedRaw = v
+	}
+	if result.AirGappedRaw != nil {
+		if result.AirGapped, err = parseutil.ParseBool(result.AirGappedRaw); err != nil {
+			return nil, err
+		}
+	}
+	if v := os.Getenv("GOLDFISH_UPDATE_CHECK"); v != "" {
+		result.UpdateCheckRaw = v
+	}
+	if result.UpdateCheckRaw != nil {
+		if result.UpdateCheck, err = parseutil.ParseBool(result.UpdateCheckRaw); err != nil {
+			return nil, err
+		}
+	}
+	if v := os.Getenv("GOLDFISH_STATUS_PAGE"); v != "" {
+		result.StatusPageRaw = v
+	}
+	if result.StatusPageRaw != nil {
+		if result.StatusPage, err = parseutil.ParseBool(result.StatusPageRaw); err != nil {
+			return nil, err
+		}
+	}
 
 	// config root object should contain only this set of keys
 	valid := []string{
 		"listener",
 		"vault",
+		"telemetry",
+		"session",
+		"rate_limit",
+		"cluster",
+		"login",
+		"role",
+		"notifier",
+		"report",
+		"policy_lint",
+		"branding",
+		"token_creation",
+		"chatops",
+		"oidc",
+		"raft_snapshot",
+		"audit_source",
 		"disable_mlock",
+		"read_only",
+		"require_confirmation",
+		"rollback_requires_approval",
+		"update_check",
+		"status_page",
+		"air_gapped",
 	}
 	if err := checkHCLKeys(list, valid); err != nil {
 		return nil, err
 	}
+	if err := addEnvBlocks(list); err != nil {
+		return nil, err
+	}
 
 	// build each specific config component
-	if object := list.Filter("listener"); len(object.Items) != 1 {
-		return nil, fmt.Errorf("Config requires exactly one 'listener' object")
+	if object := list.Filter("listener"); len(object.Items) == 0 {
+		return nil, fmt.Errorf("Config requires at least one 'listener' object")
 	} else {
-		// there should be only one listener to parse
-		if err := parseListener(&result, object.Items[0]); err != nil {
+		// the first listener is the primary one, any others are served alongside it
+		for i, item := range object.Items {
+			l, block := result.Listener, "listener"
+			if i > 0 {
+				l, block = &ListenerConfig{}, fmt.Sprintf("listener_%d", i+1)
+				result.ExtraListeners = append(result.ExtraListeners, l)
+			}
+			if err := parseListener(l, block, item); err != nil {
+				return nil, fmt.Errorf("Error parsing 'listener': %s", err.Error())
+			}
+		}
+		if err := checkListeners(&result); err != nil {
 			return nil, fmt.Errorf("Error parsing 'listener': %s", err.Error())
 		}
 	}
@@ -152,9 +677,184 @@ func ParseConfig(d string) (*Config, error) {
 		}
 	}
 
+	// telemetry is optional
+	if object := list.Filter("telemetry"); len(object.Items) > 1 {
+		return nil, fmt.Errorf("Config allows at most one 'telemetry' object")
+	} else if len(object.Items) == 1 {
+		if err := parseTelemetry(&result, object.Items[0]); err != nil {
+			return nil, fmt.Errorf("Error parsing 'telemetry': %s", err.Error())
+		}
+	}
+
+	// session is optional, sessions are kept in memory by default
+	if object := list.Filter("session"); len(object.Items) > 1 {
+		return nil, fmt.Errorf("Config allows at most one 'session' object")
+	} else if len(object.Items) == 1 {
+		if err := parseSession(&result, object.Items[0]); err != nil {
+			return nil, fmt.Errorf("Error parsing 'session': %s", err.Error())
+		}
+	}
+
+	// rate limiting is optional, and disabled by default
+	if object := list.Filter("rate_limit"); len(object.Items) > 1 {
+		return nil, fmt.Errorf("Config allows at most one 'rate_limit' object")
+	} else if len(object.Items) == 1 {
+		if err := parseRateLimit(&result, object.Items[0]); err != nil {
+			return nil, fmt.Errorf("Error parsing 'rate_limit': %s", err.Error())
+		}
+	}
+
+	// branding is optional, the ui looks the same everywhere by default
+	if object := list.Filter("branding"); len(object.Items) > 1 {
+		return nil, fmt.Errorf("Config allows at most one 'branding' object")
+	} else if len(object.Items) == 1 {
+		if err := parseBranding(&result, object.Items[0]); err != nil {
+			return nil, fmt.Errorf("Error parsing 'branding': %s", err.Error())
+		}
+	}
+
+	// token creation is only limited by vault's policies and roles by default
+	if object := list.Filter("token_creation"); len(object.Items) > 1 {
+		return nil, fmt.Errorf("Config allows at most one 'token_creation' object")
+	} else if len(object.Items) == 1 {
+		if err := parseTokenCreation(&result, object.Items[0]); err != nil {
+			return nil, fmt.Errorf("Error parsing 'token_creation': %s", err.Error())
+		}
+	}
+
+	// vault's storage is only snapshotted by goldfish if configured
+	if object := list.Filter("raft_snapshot"); len(object.Items) > 1 {
+		return nil, fmt.Errorf("Config allows at most one 'raft_snapshot' object")
+	} else if len(object.Items) == 1 {
+		if err := parseRaftSnapshot(&result, object.Items[0]); err != nil {
+			return nil, fmt.Errorf("Error parsing 'raft_snapshot': %s", err.Error())
+		}
+	}
+
+	// vault's audit log is only read by goldfish if configured
+	if object := list.Filter("audit_source"); len(object.Items) > 1 {
+		return nil, fmt.Errorf("Config allows at most one 'audit_source' object")
+	} else if len(object.Items) == 1 {
+		if err := parseAuditSource(&result, object.Items[0]); err != nil {
+			return nil, fmt.Errorf("Error parsing 'audit_source': %s", err.Error())
+		}
+	}
+
+	// change requests are only acted on in goldfish's ui by default
+	if object := list.Filter("chatops"); len(object.Items) > 1 {
+		return nil, fmt.Errorf("Config allows at most one 'chatops' object")
+	} else if len(object.Items) == 1 {
+		if err := parseChatOps(&result, object.Items[0]); err != nil {
+			return nil, fmt.Errorf("Error parsing 'chatops': %s", err.Error())
+		}
+	}
+
+	// users only log in with their vault credentials by default
+	if object := list.Filter("oidc"); len(object.Items) > 1 {
+		return nil, fmt.Errorf("Config allows at most one 'oidc' object")
+	} else if len(object.Items) == 1 {
+		if err := parseOIDC(&result, object.Items[0]); err != nil {
+			return nil, fmt.Errorf("Error parsing 'oidc': %s", err.Error())
+		}
+	}
+
+	// clusters are optional, and each must be named
+	for _, item := range list.Filter("cluster").Items {
+		if err := parseCluster(&result, item); err != nil {
+			return nil, fmt.Errorf("Error parsing 'cluster': %s", err.Error())
+		}
+	}
+
+	// logins are optional. Without any, each login type uses vault's default mount, e.g. auth/ldap
+	for _, item := range list.Filter("login").Items {
+		if err := parseLogin(&result, item); err != nil {
+			return nil, fmt.Errorf("Error parsing 'login': %s", err.Error())
+		}
+	}
+
+	// roles are optional. Without any, goldfish leaves authorization to vault alone
+	for _, item := range list.Filter("role").Items {
+		if err := parseRole(&result, item); err != nil {
+			return nil, fmt.Errorf("Error parsing 'role': %s", err.Error())
+		}
+	}
+
+	// notifiers and reports are optional. Reports may only notify notifiers defined here
+	for _, item := range list.Filter("notifier").Items {
+		if err := parseNotifier(&result, item); err != nil {
+			return nil, fmt.Errorf("Error parsing 'notifier': %s", err.Error())
+		}
+	}
+	for _, item := range list.Filter("report").Items {
+		if err := parseReport(&result, item); err != nil {
+			return nil, fmt.Errorf("Error parsing 'report': %s", err.Error())
+		}
+	}
+
+	// policy lint rules are optional
+	for _, item := range list.Filter("policy_lint").Items {
+		if err := parsePolicyLint(&result, item); err != nil {
+			return nil, fmt.Errorf("Error parsing 'policy_lint': %s", err.Error())
+		}
+	}
+
+	if result.AirGapped {
+		if err := checkAirGapped(&result); err != nil {
+			return nil, fmt.Errorf("air_gapped: %s", err.Error())
+		}
+	}
+
 	return &result, nil
 }
 
+// refuses anything that would reach the internet, and drops external origins from the content security policy
+// vault, telemetry, and notifier addresses are assumed to be inside the network
+func checkAirGapped(result *Config) error {
+	if result.UpdateCheck {
+		return errors.New("update_check needs access to api.github.com")
+	}
+	for i, l := range result.Listeners() {
+		if !l.Tls_disable && l.Tls_cert_file == "" && l.Tls_key_file == "" {
+			return fmt.Errorf("listener %d uses let's encrypt, set tls_cert_file and tls_key_file instead", i+1)
+		}
+		l.Csp = localCSP(l.Csp)
+	}
+	for name, n := range result.Notifiers {
+		if n.Type == "slack" && n.Url == "" {
+			return fmt.Errorf("notifier.%s posts to slack's webhook from the runtime config, set url to an internal webhook instead", name)
+		}
+		if (n.Type == "pagerduty" || n.Type == "opsgenie") && n.Url == "" {
+			return fmt.Errorf("notifier.%s posts to %s's api, set url to an internal proxy instead", name, n.Type)
+		}
+	}
+	if r := result.RaftSnapshot; r != nil && r.S3_bucket != "" && r.S3_endpoint == "" {
+		return errors.New("raft_snapshot uploads to aws s3, set s3_endpoint to internal s3 compatible storage instead")
+	}
+	return nil
+}
+
+// removes hosts and scheme sources such as "https:", keeping keywords like 'self' and local schemes like data:
+func localCSP(csp string) string {
+	var directives []string
+	for _, directive := range strings.Split(csp, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+		// directives such as sandbox take values rather than sources
+		name := fields[0]
+		takesSources := strings.HasSuffix(name, "-src") || name == "frame-ancestors" || name == "form-action" || name == "base-uri"
+		kept := []string{name}
+		for _, value := range fields[1:] {
+			if !takesSources || strings.HasPrefix(value, "'") || value == "data:" || value == "blob:" {
+				kept = append(kept, value)
+			}
+		}
+		directives = append(directives, strings.Join(kept, " "))
+	}
+	return strings.Join(directives, "; ")
+}
+
 func checkHCLKeys(node ast.Node, valid []string) error {
 	var list *ast.ObjectList
 	switch n := node.(type) {
@@ -182,47 +882,134 @@ func checkHCLKeys(node ast.Node, valid []string) error {
 	return err
 }
 
-func parseListener(result *Config, listener *ast.ObjectItem) error {
+// decodes a block's values as strings, with env overrides applied
+// booleans (from json or env) are normalized to the 0 or 1 that hcl files use
+func decodeBlock(block string, valid []string, node ast.Node) (map[string]string, error) {
+	var raw map[string]interface{}
+	if err := hcl.DecodeObject(&raw, node); err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch t := v.(type) {
+		case bool:
+			if t {
+				m[k] = "1"
+			} else {
+				m[k] = "0"
+			}
+		case string, int, int64, float64:
+			m[k] = fmt.Sprint(t)
+		default:
+			return nil, fmt.Errorf("%s: unsupported value type %T", k, v)
+		}
+	}
+
+	m = applyEnvOverrides(block, valid, m)
+	for k, v := range m {
+		if v == "true" {
+			m[k] = "1"
+		} else if v == "false" {
+			m[k] = "0"
+		}
+	}
+	return m, nil
+}
+
+var listenerKeys = []string{
+	"address",
+	"tls_disable",
+	"tls_cert_file",
+	"tls_key_file",
+	"tls_autoredirect",
+	"login_max_attempts",
+	"login_backoff",
+	"login_lockout",
+	"shutdown_timeout",
+	"body_limit",
+	"gzip_level",
+	"read_timeout",
+	"write_timeout",
+	"idle_timeout",
+	"allowed_cidrs",
+	"denied_cidrs",
+	"admin_allowed_cidrs",
+	"trusted_proxies",
+	"security_preset",
+	"csp",
+	"frame_options",
+	"referrer_policy",
+	"hsts_max_age",
+	"hsts_include_subdomains",
+	"hsts_preload",
+	"tls_require_client_cert",
+	"tls_client_ca_file",
+	"tls_client_allowed_cns",
+	"tls_client_allowed_ous",
+	"tls_min_version",
+	"tls_cipher_suites",
+	"tls_cipher_preset",
+	"tls_redirect_address",
+	"autocert_cache_dir",
+	"autocert_email",
+	"autocert_hosts",
+	"base_path",
+	"swagger_ui",
+	"assets_dir",
+	"socket_mode",
+	"socket_user",
+	"socket_group",
+}
+
+// block names the env overrides, e.g. GOLDFISH_LISTENER_ADDRESS, or GOLDFISH_LISTENER_2_ADDRESS for the second
+func parseListener(l *ListenerConfig, block string, listener *ast.ObjectItem) error {
 	key := "listener"
 	if len(listener.Keys) > 0 {
 		key = listener.Keys[0].Token.Value().(string)
 	}
 
-	valid := []string{
-		"address",
-		"tls_disable",
-		"tls_cert_file",
-		"tls_key_file",
-		"tls_autoredirect",
-	}
+	valid := listenerKeys
 	if err := checkHCLKeys(listener.Val, valid); err != nil {
 		return fmt.Errorf("listener.%s: %s", key, err.Error())
 	}
 
-	var m map[string]string
-	if err := hcl.DecodeObject(&m, listener.Val); err != nil {
+	m, err := decodeBlock(block, valid, listener.Val)
+	if err != nil {
 		return fmt.Errorf("listener.%s: %s", key, err.Error())
 	}
 
+	// login throttling and shutdown are shared by all listeners, so their settings are only read from the first
+	if block != "listener" {
+		for _, k := range []string{"login_max_attempts", "login_backoff", "login_lockout", "shutdown_timeout"} {
+			if _, ok := m[k]; ok {
+				return fmt.Errorf("listener.%s: %s is only valid in the first listener", key, k)
+			}
+		}
+	}
+
 	// check and enforce field values
-	result.Listener.Type = strings.ToLower(key)
+	l.Type = strings.ToLower(key)
+	if l.Type != "tcp" && l.Type != "unix" {
+		return fmt.Errorf("listener.%s: listener type must be tcp or unix", key)
+	}
 
 	if address, ok := m["address"]; !ok || address == "" {
 		return fmt.Errorf("listener.%s: address is required", key)
 	} else {
-		result.Listener.Address = address
+		l.Address = address
 	}
 
 	if certFile, ok := m["tls_cert_file"]; ok {
-		result.Listener.Tls_cert_file = certFile
+		l.Tls_cert_file = certFile
 	}
 	if keyFile, ok := m["tls_key_file"]; ok {
-		result.Listener.Tls_key_file = keyFile
+		l.Tls_key_file = keyFile
 	}
 
 	if tlsDisable, ok := m["tls_disable"]; ok {
 		if tlsDisable == "1" {
-			result.Listener.Tls_disable = true
+			l.Tls_disable = true
 		} else if tlsDisable != "0" {
 			return fmt.Errorf("listener.%s: tls_disable can be 0 or 1", key)
 		}
@@ -230,74 +1017,574 @@ func parseListener(result *Config, listener *ast.ObjectItem) error {
 
 	if redirect, ok := m["tls_autoredirect"]; ok {
 		if redirect == "1" {
-			if result.Listener.Tls_disable {
+			if l.Tls_disable {
 				return fmt.Errorf("listener.%s: tls_autoredirect conflicts with tls_disable", key)
 			}
-			result.Listener.Tls_autoredirect = true
+			l.Tls_autoredirect = true
 		} else if redirect != "0" {
 			return fmt.Errorf("listener.%s: tls_autoredirect can be 0 or 1", key)
 		}
 	}
 
-	return nil
-}
-
-func parseVault(result *Config, vault *ast.ObjectItem) error {
-	key := "vault"
-	if len(vault.Keys) > 0 {
-		key = vault.Keys[0].Token.Value().(string)
+	l.Login_max_attempts = defaultLoginMaxAttempts
+	if v, ok := m["login_max_attempts"]; ok {
+		if l.Login_max_attempts, err = strconv.Atoi(v); err != nil || l.Login_max_attempts < 0 {
+			return fmt.Errorf("listener.%s: login_max_attempts must be a number", key)
+		}
 	}
-
-	valid := []string{
-		"address",
-		"tls_skip_verify",
-		"runtime_config",
-		"approle_login",
-		"approle_id",
+	durations := []struct {
+		key   string
+		field *time.Duration
+		def   time.Duration
+	}{
+		{"login_backoff", &l.Login_backoff, defaultLoginBackoff},
+		{"login_lockout", &l.Login_lockout, defaultLoginLockout},
+		{"shutdown_timeout", &l.Shutdown_timeout, defaultShutdownTimeout},
+		{"read_timeout", &l.Read_timeout, defaultReadTimeout},
+		{"write_timeout", &l.Write_timeout, defaultWriteTimeout},
+		{"idle_timeout", &l.Idle_timeout, 0},
 	}
-	if err := checkHCLKeys(vault.Val, valid); err != nil {
-		return fmt.Errorf("vault.%s: %s", key, err.Error())
+	for _, d := range durations {
+		*d.field = d.def
+		if v, ok := m[d.key]; ok {
+			duration, err := parseutil.ParseDurationSecond(v)
+			if err != nil || duration < 0 {
+				return fmt.Errorf("listener.%s: %s must be a duration, e.g. \"15m\"", key, d.key)
+			}
+			*d.field = duration
+		}
 	}
 
-	var m map[string]string
-	if err := hcl.DecodeObject(&m, vault.Val); err != nil {
-		return fmt.Errorf("vault.%s: %s", key, err.Error())
+	// in echo's format, e.g. "32M"
+	l.Body_limit = defaultBodyLimit
+	if v, ok := m["body_limit"]; ok {
+		if n, err := bytes.Parse(v); err != nil || n <= 0 {
+			return fmt.Errorf("listener.%s: body_limit must be a size, e.g. \"32M\"", key)
+		}
+		l.Body_limit = v
 	}
 
-	// check and enforce field values, possibly writing default values
-	result.Vault.Type = strings.ToLower(key)
-
-	if address, ok := m["address"]; !ok || address == "" {
-		return fmt.Errorf("vault.%s: address is required", key)
-	} else {
-		if url, err := url.Parse(address); err != nil {
-			return fmt.Errorf("failed to set address %v reason: %s", address, err.Error())
-		} else {
-			if !(url.Scheme == "http" || url.Scheme == "https") {
-				return fmt.Errorf("vault.%s: address must be prefixed with scheme i.e. http:// or https://", key)
-			}
-			result.Vault.Address = url.String()
+	l.Gzip_level = defaultGzipLevel
+	if v, ok := m["gzip_level"]; ok {
+		if l.Gzip_level, err = strconv.Atoi(v); err != nil || l.Gzip_level < 0 || l.Gzip_level > 9 {
+			return fmt.Errorf("listener.%s: gzip_level must be a number from 0 to 9", key)
 		}
 	}
 
-	if tlsSkip, ok := m["tls_skip_verify"]; ok {
-		if tlsSkip == "1" {
-			result.Vault.Tls_skip_verify = true
-		} else if tlsSkip != "0" {
-			return fmt.Errorf("listener.%s: tls_disable can be 0 or 1", key)
+	lists := []struct {
+		key   string
+		field *[]string
+	}{
+		{"allowed_cidrs", &l.Allowed_cidrs},
+		{"denied_cidrs", &l.Denied_cidrs},
+		{"admin_allowed_cidrs", &l.Admin_allowed_cidrs},
+		{"trusted_proxies", &l.Trusted_proxies},
+	}
+	for _, l := range lists {
+		if *l.field, err = parseCIDRs(m[l.key]); err != nil {
+			return fmt.Errorf("listener.%s: %s: %s", key, l.key, err.Error())
 		}
 	}
 
-	if runtimeConfig, ok := m["runtime_config"]; ok {
-		result.Vault.Runtime_config = runtimeConfig
-	} else {
-		result.Vault.Runtime_config = "secret/goldfish"
+	if err := parseClientAuth(l, key, m); err != nil {
+		return err
+	}
+	if err := parseTLSVersions(l, key, m); err != nil {
+		return err
 	}
 
-	if login, ok := m["approle_login"]; ok {
-		result.Vault.Approle_login = login
-	} else {
-		result.Vault.Approle_login = "auth/approle/login"
+	if l.Tls_redirect_address = m["tls_redirect_address"]; l.Tls_redirect_address == "" {
+		l.Tls_redirect_address = ":80"
+	}
+	if l.Autocert_cache_dir = m["autocert_cache_dir"]; l.Autocert_cache_dir == "" {
+		l.Autocert_cache_dir = "/var/www/.cache"
+	}
+	l.Autocert_email = m["autocert_email"]
+	l.Autocert_hosts = splitList(m["autocert_hosts"])
+
+	if l.Base_path, err = parseBasePath(m["base_path"]); err != nil {
+		return fmt.Errorf("listener.%s: %s", key, err.Error())
+	}
+	if swagger, ok := m["swagger_ui"]; ok {
+		if swagger == "1" {
+			l.Swagger_ui = true
+		} else if swagger != "0" {
+			return fmt.Errorf("listener.%s: swagger_ui can be 0 or 1", key)
+		}
+	}
+	l.Assets_dir = m["assets_dir"]
+	if err := parseSecurityHeaders(l, key, m); err != nil {
+		return err
+	}
+	return parseUnixSocket(l, key, m)
+}
+
+// a unix socket is only reachable locally, so settings about remote clients don't apply
+func parseUnixSocket(l *ListenerConfig, key string, m map[string]string) error {
+	if l.Type != "unix" {
+		for _, k := range []string{"socket_mode", "socket_user", "socket_group"} {
+			if _, ok := m[k]; ok {
+				return fmt.Errorf("listener.%s: %s is only valid for unix listeners", key, k)
+			}
+		}
+		return nil
+	}
+
+	if !l.Tls_disable && (l.Tls_cert_file == "" || l.Tls_key_file == "") {
+		return fmt.Errorf("listener.%s: unix listeners need tls_disable, or tls_cert_file and tls_key_file", key)
+	}
+	if l.Tls_autoredirect {
+		return fmt.Errorf("listener.%s: tls_autoredirect is not supported for unix listeners", key)
+	}
+	if len(l.Allowed_cidrs) > 0 || len(l.Denied_cidrs) > 0 || len(l.Admin_allowed_cidrs) > 0 || len(l.Trusted_proxies) > 0 {
+		return fmt.Errorf("listener.%s: cidr lists are not supported for unix listeners", key)
+	}
+
+	l.Socket_mode = 0660
+	if v, ok := m["socket_mode"]; ok {
+		mode, err := strconv.ParseUint(v, 8, 32)
+		if err != nil || mode > 0777 {
+			return fmt.Errorf("listener.%s: socket_mode must be an octal permission, e.g. \"0660\"", key)
+		}
+		l.Socket_mode = os.FileMode(mode)
+	}
+	l.Socket_user = m["socket_user"]
+	l.Socket_group = m["socket_group"]
+	return nil
+}
+
+// the primary listener first, followed by any extra listeners in config order
+func (c *Config) Listeners() []*ListenerConfig {
+	return append([]*ListenerConfig{c.Listener}, c.ExtraListeners...)
+}
+
+// listeners can't share an address, and only one can use let's encrypt, which always binds :443
+func checkListeners(result *Config) error {
+	addresses := make(map[string]bool)
+	autocert := 0
+	for _, l := range result.Listeners() {
+		bound := []string{l.Address}
+		if l.Tls_autoredirect {
+			bound = append(bound, l.Tls_redirect_address)
+		}
+		for _, address := range bound {
+			if addresses[address] {
+				return fmt.Errorf("address %q is used by more than one listener", address)
+			}
+			addresses[address] = true
+		}
+		if !l.Tls_disable && l.Tls_cert_file == "" && l.Tls_key_file == "" {
+			autocert++
+		}
+	}
+	if autocert > 1 {
+		return fmt.Errorf("only one listener can use let's encrypt")
+	}
+	return nil
+}
+
+// normalizes to a leading slash and no trailing slash, so "/" and "" both mean the root
+func parseBasePath(path string) (string, error) {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	if path == "" {
+		return "", nil
+	}
+	if strings.ContainsAny(path, "?#") || strings.Contains(path, "//") {
+		return "", fmt.Errorf("base_path must be a plain url path, got %q", path)
+	}
+	return "/" + path, nil
+}
+
+func parseClientAuth(l *ListenerConfig, key string, m map[string]string) error {
+	if v, ok := m["tls_require_client_cert"]; ok {
+		if v == "1" {
+			l.Tls_require_client_cert = true
+		} else if v != "0" {
+			return fmt.Errorf("listener.%s: tls_require_client_cert can be 0 or 1", key)
+		}
+	}
+	l.Tls_client_ca_file = m["tls_client_ca_file"]
+	l.Tls_client_allowed_cns = splitList(m["tls_client_allowed_cns"])
+	l.Tls_client_allowed_ous = splitList(m["tls_client_allowed_ous"])
+
+	if !l.Tls_require_client_cert {
+		if l.Tls_client_ca_file != "" || l.Tls_client_allowed_cns != nil || l.Tls_client_allowed_ous != nil {
+			return fmt.Errorf("listener.%s: client certificate options require tls_require_client_cert", key)
+		}
+		return nil
+	}
+
+	// let's encrypt certificates are managed by echo, which leaves no room for client auth
+	if l.Tls_disable || l.Tls_cert_file == "" || l.Tls_key_file == "" {
+		return fmt.Errorf("listener.%s: tls_require_client_cert requires tls_cert_file and tls_key_file", key)
+	}
+	if l.Tls_client_ca_file == "" {
+		return fmt.Errorf("listener.%s: tls_require_client_cert requires tls_client_ca_file", key)
+	}
+	return nil
+}
+
+// suites using only FIPS 140-2 approved algorithms: ECDHE key exchange and AES-GCM
+var fipsCipherSuites = []string{
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+}
+
+func parseTLSVersions(l *ListenerConfig, key string, m map[string]string) error {
+	if l.Tls_min_version = strings.ToLower(m["tls_min_version"]); l.Tls_min_version == "" {
+		l.Tls_min_version = "tls12"
+	}
+	if _, ok := tlsutil.TLSLookup[l.Tls_min_version]; !ok {
+		return fmt.Errorf("listener.%s: tls_min_version can be tls10, tls11, or tls12", key)
+	}
+
+	l.Tls_cipher_suites = splitList(m["tls_cipher_suites"])
+	if _, err := tlsutil.ParseCiphers(strings.Join(l.Tls_cipher_suites, ",")); err != nil {
+		return fmt.Errorf("listener.%s: tls_cipher_suites: %s", key, err.Error())
+	}
+
+	switch l.Tls_cipher_preset = strings.ToLower(m["tls_cipher_preset"]); l.Tls_cipher_preset {
+	case "":
+	case "fips":
+		if l.Tls_min_version != "tls12" {
+			return fmt.Errorf("listener.%s: the fips preset requires tls_min_version tls12", key)
+		}
+		if l.Tls_cipher_suites == nil {
+			l.Tls_cipher_suites = append([]string(nil), fipsCipherSuites...)
+		}
+		for _, suite := range l.Tls_cipher_suites {
+			if !containsString(fipsCipherSuites, suite) {
+				return fmt.Errorf("listener.%s: %s is not allowed by the fips preset", key, suite)
+			}
+		}
+	default:
+		return fmt.Errorf("listener.%s: tls_cipher_preset can only be fips", key)
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, entry := range list {
+		if entry == s {
+			return true
+		}
+	}
+	return false
+}
+
+// splits a comma separated list, dropping empty entries. Returns nil for an empty list
+func splitList(list string) []string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// the "strict" preset allows no external origins at all, for air-gapped deployments
+var securityPresets = map[string]ListenerConfig{
+	"default": {
+		Csp:           "default-src 'self' https://api.github.com",
+		Frame_options: "SAMEORIGIN",
+	},
+	"strict": {
+		Csp:             "default-src 'self'; frame-ancestors 'none'; base-uri 'self'; form-action 'self'",
+		Frame_options:   "DENY",
+		Referrer_policy: "no-referrer",
+	},
+}
+
+var validReferrerPolicies = []string{
+	"no-referrer",
+	"no-referrer-when-downgrade",
+	"origin",
+	"origin-when-cross-origin",
+	"same-origin",
+	"strict-origin",
+	"strict-origin-when-cross-origin",
+	"unsafe-url",
+}
+
+func parseSecurityHeaders(l *ListenerConfig, key string, m map[string]string) error {
+	l.Security_preset = "default"
+	if preset, ok := m["security_preset"]; ok && preset != "" {
+		l.Security_preset = strings.ToLower(preset)
+	}
+	preset, ok := securityPresets[l.Security_preset]
+	if !ok {
+		return fmt.Errorf("listener.%s: security_preset can be default or strict", key)
+	}
+
+	if l.Csp = m["csp"]; l.Csp == "" {
+		l.Csp = preset.Csp
+	}
+
+	if l.Frame_options = strings.ToUpper(m["frame_options"]); l.Frame_options == "" {
+		l.Frame_options = preset.Frame_options
+	} else if l.Frame_options != "SAMEORIGIN" && l.Frame_options != "DENY" {
+		return fmt.Errorf("listener.%s: frame_options can be SAMEORIGIN or DENY", key)
+	}
+
+	if l.Referrer_policy = strings.ToLower(m["referrer_policy"]); l.Referrer_policy == "" {
+		l.Referrer_policy = preset.Referrer_policy
+	} else {
+		valid := false
+		for _, policy := range validReferrerPolicies {
+			valid = valid || l.Referrer_policy == policy
+		}
+		if !valid {
+			return fmt.Errorf("listener.%s: referrer_policy must be one of %s", key, strings.Join(validReferrerPolicies, ", "))
+		}
+	}
+
+	if v, ok := m["hsts_max_age"]; ok {
+		duration, err := parseutil.ParseDurationSecond(v)
+		if err != nil || duration < 0 {
+			return fmt.Errorf("listener.%s: hsts_max_age must be a duration, e.g. \"8760h\"", key)
+		}
+		l.Hsts_max_age = duration
+	}
+	flags := []struct {
+		key   string
+		field *bool
+	}{
+		{"hsts_include_subdomains", &l.Hsts_include_subdomains},
+		{"hsts_preload", &l.Hsts_preload},
+	}
+	for _, f := range flags {
+		if v, ok := m[f.key]; ok {
+			if v == "1" {
+				*f.field = true
+			} else if v != "0" {
+				return fmt.Errorf("listener.%s: %s can be 0 or 1", key, f.key)
+			}
+		}
+	}
+
+	// browsers' preload lists only accept a year or more, covering subdomains
+	if l.Hsts_preload && (l.Hsts_max_age < 365*24*time.Hour || !l.Hsts_include_subdomains) {
+		return fmt.Errorf("listener.%s: hsts_preload requires hsts_max_age of at least 8760h and hsts_include_subdomains", key)
+	}
+	return nil
+}
+
+// parses a comma separated list of CIDRs, where a bare IP means just that address
+func parseCIDRs(list string) ([]string, error) {
+	var cidrs []string
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("%s is not an IP or CIDR", entry)
+			}
+			if ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not an IP or CIDR", entry)
+		}
+		cidrs = append(cidrs, network.String())
+	}
+	return cidrs, nil
+}
+
+// defaults for login throttling: waits of 1s, 2s, 4s, and 8s, then a lockout on the 5th failure
+const (
+	defaultLoginMaxAttempts = 5
+	defaultLoginBackoff     = time.Second
+	defaultLoginLockout     = 15 * time.Minute
+)
+
+// below kubernetes' default 30s grace period, so draining finishes before a SIGKILL
+const defaultShutdownTimeout = 20 * time.Second
+
+// the write timeout covers the whole response, so it allows for slow vault calls
+const (
+	defaultBodyLimit    = "32M"
+	defaultGzipLevel    = 5
+	defaultReadTimeout  = 10 * time.Second
+	defaultWriteTimeout = 2 * time.Minute
+)
+
+// reports of expiring tokens and certificates look this far ahead by default
+const defaultReportWindow = 7 * 24 * time.Hour
+
+// defaults for the vault client. Lists of large mounts can be slow, so they get more time
+const (
+	defaultVaultTimeout     = 60 * time.Second
+	defaultVaultListTimeout = 2 * time.Minute
+	defaultMaxRetries       = 2
+	defaultListMaxItems     = 100000
+	defaultRetryWaitMin     = 500 * time.Millisecond
+	defaultRetryWaitMax     = 5 * time.Second
+
+	defaultHealthCheckInterval = 5 * time.Second
+	defaultHealthHistory       = 24 * time.Hour
+)
+
+var vaultKeys = []string{
+	"address",
+	"address_srv",
+	"address_consul",
+	"consul_tag",
+	"consul_address",
+	"tls_skip_verify",
+	"ca_cert",
+	"ca_path",
+	"client_cert",
+	"client_key",
+	"tls_server_name",
+	"proxy_address",
+	"no_proxy",
+	"runtime_config",
+	"settings_path",
+	"state_path",
+	"approle_login",
+	"approle_id",
+	"approle_secret_id",
+	"approle_secret_id_file",
+	"token_file",
+	"kubernetes_role",
+	"kubernetes_login",
+	"kubernetes_jwt_file",
+	"timeout",
+	"list_timeout",
+	"list_cache_ttl",
+	"list_max_items",
+	"max_retries",
+	"retry_wait_min",
+	"retry_wait_max",
+	"ready_while_sealed",
+	"health_check_interval",
+	"health_history",
+}
+
+func parseVault(result *Config, vault *ast.ObjectItem) error {
+	key := "vault"
+	if len(vault.Keys) > 0 {
+		key = vault.Keys[0].Token.Value().(string)
+	}
+
+	valid := vaultKeys
+	if err := checkHCLKeys(vault.Val, valid); err != nil {
+		return fmt.Errorf("vault.%s: %s", key, err.Error())
+	}
+
+	m, err := decodeBlock("vault", valid, vault.Val)
+	if err != nil {
+		return fmt.Errorf("vault.%s: %s", key, err.Error())
+	}
+
+	// check and enforce field values, possibly writing default values
+	result.Vault.Type = strings.ToLower(key)
+
+	// address may be a comma separated list of nodes to fail over between
+	// address_srv is resolved at runtime, and its nodes are tried before any in address
+	srv, consul := m["address_srv"], m["address_consul"]
+	if address := m["address"]; address == "" && srv == "" && consul == "" {
+		return fmt.Errorf("vault.%s: address is required", key)
+	} else if address != "" {
+		for _, a := range strings.Split(address, ",") {
+			if url, err := url.Parse(strings.TrimSpace(a)); err != nil {
+				return fmt.Errorf("failed to set address %v reason: %s", a, err.Error())
+			} else {
+				if !(url.Scheme == "http" || url.Scheme == "https") {
+					return fmt.Errorf("vault.%s: address must be prefixed with scheme i.e. http:// or https://", key)
+				}
+				result.Vault.Addresses = append(result.Vault.Addresses, url.String())
+			}
+		}
+		result.Vault.Address = result.Vault.Addresses[0]
+	}
+
+	if srv != "" {
+		if url, err := url.Parse(srv); err != nil {
+			return fmt.Errorf("failed to set address_srv %v reason: %s", srv, err.Error())
+		} else if !(url.Scheme == "http" || url.Scheme == "https") || url.Host == "" {
+			return fmt.Errorf("vault.%s: address_srv must look like https://_vault._tcp.example.com", key)
+		} else {
+			result.Vault.Address_srv = url.String()
+		}
+		if result.Vault.Address == "" {
+			result.Vault.Address = result.Vault.Address_srv
+		}
+	}
+
+	// address_consul is a url such as https://vault, where the host is the consul service name
+	if consul != "" {
+		if url, err := url.Parse(consul); err != nil {
+			return fmt.Errorf("failed to set address_consul %v reason: %s", consul, err.Error())
+		} else if !(url.Scheme == "http" || url.Scheme == "https") || url.Host == "" {
+			return fmt.Errorf("vault.%s: address_consul must look like https://vault", key)
+		} else {
+			result.Vault.Address_consul = url.String()
+		}
+		if result.Vault.Address == "" {
+			result.Vault.Address = result.Vault.Address_consul
+		}
+
+		result.Vault.Consul_address = m["consul_address"]
+		if tag, ok := m["consul_tag"]; ok {
+			result.Vault.Consul_tag = tag
+		} else {
+			result.Vault.Consul_tag = "active"
+		}
+	}
+
+	// a single address needs no failover
+	if len(result.Vault.Addresses) == 1 {
+		result.Vault.Addresses = nil
+	}
+
+	if tlsSkip, ok := m["tls_skip_verify"]; ok {
+		if tlsSkip == "1" {
+			result.Vault.Tls_skip_verify = true
+		} else if tlsSkip != "0" {
+			return fmt.Errorf("listener.%s: tls_disable can be 0 or 1", key)
+		}
+	}
+
+	result.Vault.Ca_cert = m["ca_cert"]
+	result.Vault.Ca_path = m["ca_path"]
+	result.Vault.Client_cert = m["client_cert"]
+	result.Vault.Client_key = m["client_key"]
+	result.Vault.Tls_server_name = m["tls_server_name"]
+	if (result.Vault.Client_cert == "") != (result.Vault.Client_key == "") {
+		return fmt.Errorf("vault.%s: client_cert and client_key must be set together", key)
+	}
+
+	if proxy := m["proxy_address"]; proxy != "" {
+		if u, err := url.Parse(proxy); err != nil || !(u.Scheme == "http" || u.Scheme == "https") || u.Host == "" {
+			return fmt.Errorf("vault.%s: proxy_address must look like http://proxy:3128", key)
+		}
+		result.Vault.Proxy_address = proxy
+	}
+	result.Vault.No_proxy = m["no_proxy"]
+
+	if runtimeConfig, ok := m["runtime_config"]; ok {
+		result.Vault.Runtime_config = runtimeConfig
+	} else {
+		result.Vault.Runtime_config = "secret/goldfish"
+	}
+	result.Vault.Settings_path = m["settings_path"]
+	result.Vault.State_path = strings.TrimSuffix(m["state_path"], "/")
+
+	if login, ok := m["approle_login"]; ok {
+		result.Vault.Approle_login = login
+	} else {
+		result.Vault.Approle_login = "auth/approle/login"
 	}
 
 	if id, ok := m["approle_id"]; ok {
@@ -306,5 +1593,1187 @@ func parseVault(result *Config, vault *ast.ObjectItem) error {
 		result.Vault.Approle_id = "goldfish"
 	}
 
+	result.Vault.Approle_secret_id = m["approle_secret_id"]
+	result.Vault.Approle_secret_id_file = m["approle_secret_id_file"]
+	if result.Vault.Approle_secret_id != "" && result.Vault.Approle_secret_id_file != "" {
+		return fmt.Errorf("vault.%s: approle_secret_id and approle_secret_id_file are mutually exclusive", key)
+	}
+
+	result.Vault.Token_file = m["token_file"]
+
+	result.Vault.Kubernetes_role = m["kubernetes_role"]
+	if login, ok := m["kubernetes_login"]; ok {
+		result.Vault.Kubernetes_login = login
+	} else {
+		result.Vault.Kubernetes_login = "auth/kubernetes/login"
+	}
+	if jwtFile, ok := m["kubernetes_jwt_file"]; ok {
+		result.Vault.Kubernetes_jwt_file = jwtFile
+	} else {
+		result.Vault.Kubernetes_jwt_file = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	}
+	if result.Vault.Kubernetes_role != "" && result.Vault.Token_file != "" {
+		return fmt.Errorf("vault.%s: kubernetes_role and token_file are mutually exclusive", key)
+	}
+
+	// durations may be given as "30s", "2m", or a number of seconds
+	durations := []struct {
+		key   string
+		field *time.Duration
+		def   time.Duration
+	}{
+		{"timeout", &result.Vault.Timeout, defaultVaultTimeout},
+		{"list_timeout", &result.Vault.List_timeout, defaultVaultListTimeout},
+		{"list_cache_ttl", &result.Vault.List_cache_ttl, 0},
+		{"retry_wait_min", &result.Vault.Retry_wait_min, defaultRetryWaitMin},
+		{"retry_wait_max", &result.Vault.Retry_wait_max, defaultRetryWaitMax},
+		{"health_check_interval", &result.Vault.Health_check_interval, defaultHealthCheckInterval},
+		{"health_history", &result.Vault.Health_history, defaultHealthHistory},
+	}
+	for _, d := range durations {
+		*d.field = d.def
+		if v, ok := m[d.key]; ok {
+			duration, err := parseutil.ParseDurationSecond(v)
+			if err != nil || duration < 0 {
+				return fmt.Errorf("vault.%s: %s must be a duration, e.g. \"30s\"", key, d.key)
+			}
+			*d.field = duration
+		}
+	}
+	if result.Vault.Retry_wait_min > result.Vault.Retry_wait_max {
+		return fmt.Errorf("vault.%s: retry_wait_min can not be more than retry_wait_max", key)
+	}
+	if result.Vault.Health_check_interval < time.Second {
+		return fmt.Errorf("vault.%s: health_check_interval must be at least 1s", key)
+	}
+
+	result.Vault.Max_retries = defaultMaxRetries
+	if v, ok := m["max_retries"]; ok {
+		if result.Vault.Max_retries, err = strconv.Atoi(v); err != nil || result.Vault.Max_retries < 0 {
+			return fmt.Errorf("vault.%s: max_retries must be a number, 0 to disable retries", key)
+		}
+	}
+
+	result.Vault.List_max_items = defaultListMaxItems
+	if v, ok := m["list_max_items"]; ok {
+		if result.Vault.List_max_items, err = strconv.Atoi(v); err != nil || result.Vault.List_max_items < 0 {
+			return fmt.Errorf("vault.%s: list_max_items must be a number, 0 for no limit", key)
+		}
+	}
+
+	if v, ok := m["ready_while_sealed"]; ok {
+		if v == "1" {
+			result.Vault.Ready_while_sealed = true
+		} else if v != "0" {
+			return fmt.Errorf("vault.%s: ready_while_sealed can be 0 or 1", key)
+		}
+	}
+
+	return nil
+}
+
+var telemetryKeys = []string{
+	"prometheus_disable",
+	"prometheus_address",
+	"prometheus_token_file",
+	"otlp_endpoint",
+	"otlp_headers",
+	"trace_sample_ratio",
+	"statsd_address",
+	"statsd_prefix",
+	"statsd_tags",
+}
+
+func parseTelemetry(result *Config, telemetry *ast.ObjectItem) error {
+	valid := telemetryKeys
+	if err := checkHCLKeys(telemetry.Val, valid); err != nil {
+		return fmt.Errorf("telemetry: %s", err.Error())
+	}
+
+	m, err := decodeBlock("telemetry", valid, telemetry.Val)
+	if err != nil {
+		return fmt.Errorf("telemetry: %s", err.Error())
+	}
+
+	if disable, ok := m["prometheus_disable"]; ok {
+		if disable == "1" {
+			result.Telemetry.Prometheus_disable = true
+		} else if disable != "0" {
+			return fmt.Errorf("telemetry: prometheus_disable can be 0 or 1")
+		}
+	}
+
+	if address, ok := m["prometheus_address"]; ok {
+		if result.Telemetry.Prometheus_disable && address != "" {
+			return fmt.Errorf("telemetry: prometheus_address conflicts with prometheus_disable")
+		}
+		result.Telemetry.Prometheus_address = address
+	}
+
+	if file, ok := m["prometheus_token_file"]; ok {
+		if result.Telemetry.Prometheus_disable && file != "" {
+			return fmt.Errorf("telemetry: prometheus_token_file conflicts with prometheus_disable")
+		}
+		result.Telemetry.Prometheus_token_file = file
+	}
+
+	if err := parseStatsd(result.Telemetry, m); err != nil {
+		return err
+	}
+	return parseTracing(result.Telemetry, m)
+}
+
+// like tracing, prefix and tags are left unset unless a statsd address is given
+func parseStatsd(t *TelemetryConfig, m map[string]string) error {
+	address := m["statsd_address"]
+	if address != "" {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return fmt.Errorf("telemetry: statsd_address must be in the format host:port")
+		}
+	}
+
+	prefix := "goldfish."
+	if p, ok := m["statsd_prefix"]; ok {
+		prefix = p
+	}
+
+	// e.g. "env:prod,team:platform"
+	tags := splitList(m["statsd_tags"])
+	for _, tag := range tags {
+		if strings.ContainsAny(tag, "|#") {
+			return fmt.Errorf("telemetry: statsd_tags can't contain '|' or '#'")
+		}
+	}
+
+	if address != "" {
+		t.Statsd_address = address
+		t.Statsd_prefix = prefix
+		t.Statsd_tags = tags
+	}
+	return nil
+}
+
+// the other tracing keys are still checked, but left unset, unless an otlp endpoint is given
+func parseTracing(t *TelemetryConfig, m map[string]string) error {
+	endpoint := m["otlp_endpoint"]
+	if endpoint != "" {
+		if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("telemetry: otlp_endpoint must be an http or https url")
+		}
+	}
+
+	// e.g. "authorization=Bearer abc,x-scope-orgid=goldfish"
+	var headers map[string]string
+	for _, entry := range splitList(m["otlp_headers"]) {
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return fmt.Errorf("telemetry: otlp_headers must be a comma separated list of name=value")
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+
+	ratio := 1.0
+	if r := m["trace_sample_ratio"]; r != "" {
+		f, err := strconv.ParseFloat(r, 64)
+		if err != nil || f < 0 || f > 1 {
+			return fmt.Errorf("telemetry: trace_sample_ratio must be a number between 0 and 1")
+		}
+		ratio = f
+	}
+
+	if endpoint != "" {
+		t.Otlp_endpoint = endpoint
+		t.Otlp_headers = headers
+		t.Trace_sample_ratio = ratio
+	}
 	return nil
 }
+
+var rateLimitKeys = []string{
+	"requests_per_second",
+	"burst",
+	"key",
+	"routes",
+}
+
+func parseRateLimit(result *Config, rateLimit *ast.ObjectItem) error {
+	valid := rateLimitKeys
+	if err := checkHCLKeys(rateLimit.Val, valid); err != nil {
+		return fmt.Errorf("rate_limit: %s", err.Error())
+	}
+
+	m, err := decodeBlock("rate_limit", valid, rateLimit.Val)
+	if err != nil {
+		return fmt.Errorf("rate_limit: %s", err.Error())
+	}
+
+	r := result.RateLimit
+	if r.Requests_per_second, r.Burst, err = parseRate(m["requests_per_second"], m["burst"]); err != nil {
+		return fmt.Errorf("rate_limit: %s", err.Error())
+	}
+
+	// e.g. "/v1/login=0.2:5, /v1/bulletins=0", where a burst may be omitted
+	for _, entry := range splitList(m["routes"]) {
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(strings.TrimSpace(kv[0]), "/") || strings.TrimSpace(kv[1]) == "" {
+			return fmt.Errorf("rate_limit: routes must be a comma separated list of route=rate:burst")
+		}
+		route := strings.TrimSpace(kv[0])
+		rate := strings.SplitN(strings.TrimSpace(kv[1]), ":", 2)
+		if len(rate) == 1 {
+			rate = append(rate, "")
+		}
+		var limit RouteLimitConfig
+		if limit.Requests_per_second, limit.Burst, err = parseRate(rate[0], rate[1]); err != nil {
+			return fmt.Errorf("rate_limit: route %s: %s", route, err.Error())
+		}
+		if r.Routes == nil {
+			r.Routes = make(map[string]RouteLimitConfig)
+		}
+		r.Routes[route] = limit
+	}
+
+	key := m["key"]
+	switch key {
+	case "":
+		key = "session"
+	case "session", "ip":
+	default:
+		return fmt.Errorf("rate_limit: key can be \"session\" or \"ip\"")
+	}
+
+	// without any limits, the key is left unset like the rest
+	if r.Requests_per_second > 0 || len(r.Routes) > 0 {
+		r.Key = key
+	}
+	return nil
+}
+
+// the burst defaults to one second's worth of requests
+func parseRate(rate, burst string) (float64, int, error) {
+	perSecond := 0.0
+	if rate != "" {
+		var err error
+		if perSecond, err = strconv.ParseFloat(rate, 64); err != nil || perSecond < 0 {
+			return 0, 0, fmt.Errorf("requests_per_second must be a positive number")
+		}
+	}
+	if burst == "" || burst == "0" {
+		return perSecond, int(math.Ceil(perSecond)), nil
+	}
+	n, err := strconv.Atoi(burst)
+	if err != nil || n < 1 {
+		return 0, 0, fmt.Errorf("burst must be a positive number")
+	}
+	if perSecond == 0 {
+		return 0, 0, fmt.Errorf("burst requires requests_per_second")
+	}
+	return perSecond, n, nil
+}
+
+var validAccentColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+var brandingKeys = []string{
+	"organization",
+	"logo_file",
+	"login_banner",
+	"accent_color",
+}
+
+func parseBranding(result *Config, branding *ast.ObjectItem) error {
+	valid := brandingKeys
+	if err := checkHCLKeys(branding.Val, valid); err != nil {
+		return fmt.Errorf("branding: %s", err.Error())
+	}
+
+	m, err := decodeBlock("branding", valid, branding.Val)
+	if err != nil {
+		return fmt.Errorf("branding: %s", err.Error())
+	}
+
+	b := &BrandingConfig{
+		Organization: m["organization"],
+		Logo_file:    m["logo_file"],
+		Login_banner: m["login_banner"],
+		Accent_color: m["accent_color"],
+	}
+	if b.Accent_color != "" && !validAccentColor.MatchString(b.Accent_color) {
+		return fmt.Errorf("branding: accent_color must be a hex color, e.g. \"#d9534f\"")
+	}
+	// the logo is read when it's served, so it can be replaced without a reload
+	if b.Logo_file != "" {
+		if info, err := os.Stat(b.Logo_file); err != nil || info.IsDir() {
+			return fmt.Errorf("branding: logo_file %q is not a readable file", b.Logo_file)
+		}
+	}
+	result.Branding = b
+	return nil
+}
+
+var tokenCreationKeys = []string{
+	"default_ttl",
+	"max_ttl",
+	"max_num_uses",
+	"require_wrapping",
+	"max_wrap_ttl",
+}
+
+func parseTokenCreation(result *Config, tokenCreation *ast.ObjectItem) error {
+	valid := tokenCreationKeys
+	if err := checkHCLKeys(tokenCreation.Val, valid); err != nil {
+		return fmt.Errorf("token_creation: %s", err.Error())
+	}
+
+	m, err := decodeBlock("token_creation", valid, tokenCreation.Val)
+	if err != nil {
+		return fmt.Errorf("token_creation: %s", err.Error())
+	}
+
+	t := &TokenCreationConfig{}
+	durations := []struct {
+		key   string
+		field *time.Duration
+	}{
+		{"default_ttl", &t.Default_ttl},
+		{"max_ttl", &t.Max_ttl},
+		{"max_wrap_ttl", &t.Max_wrap_ttl},
+	}
+	for _, d := range durations {
+		if v, ok := m[d.key]; ok {
+			duration, err := parseutil.ParseDurationSecond(v)
+			if err != nil || duration < 0 {
+				return fmt.Errorf("token_creation: %s must be a duration, e.g. \"768h\"", d.key)
+			}
+			*d.field = duration
+		}
+	}
+	if t.Max_ttl != 0 && t.Default_ttl > t.Max_ttl {
+		return fmt.Errorf("token_creation: default_ttl can not be more than max_ttl")
+	}
+	if v, ok := m["max_num_uses"]; ok {
+		if t.Max_num_uses, err = strconv.Atoi(v); err != nil || t.Max_num_uses < 0 {
+			return fmt.Errorf("token_creation: max_num_uses must be a number")
+		}
+	}
+	if v, ok := m["require_wrapping"]; ok {
+		if v == "1" {
+			t.Require_wrapping = true
+		} else if v != "0" {
+			return fmt.Errorf("token_creation: require_wrapping can be 0 or 1")
+		}
+	}
+	result.TokenCreation = t
+	return nil
+}
+
+const oidcCallbackPath = "/v1/login/oidc/callback"
+
+var oidcKeys = []string{
+	"discovery_url",
+	"client_id",
+	"client_secret_file",
+	"redirect_url",
+	"scopes",
+	"vault_mount",
+	"vault_role",
+}
+
+func parseOIDC(result *Config, oidc *ast.ObjectItem) error {
+	valid := oidcKeys
+	if err := checkHCLKeys(oidc.Val, valid); err != nil {
+		return fmt.Errorf("oidc: %s", err.Error())
+	}
+
+	m, err := decodeBlock("oidc", valid, oidc.Val)
+	if err != nil {
+		return fmt.Errorf("oidc: %s", err.Error())
+	}
+
+	o := &OIDCConfig{
+		Discovery_url:      strings.TrimSuffix(m["discovery_url"], "/"),
+		Client_id:          m["client_id"],
+		Client_secret_file: m["client_secret_file"],
+		Redirect_url:       m["redirect_url"],
+		Scopes:             splitList(m["scopes"]),
+		Vault_mount:        strings.Trim(m["vault_mount"], "/"),
+		Vault_role:         m["vault_role"],
+	}
+	if u, err := url.Parse(o.Discovery_url); err != nil || !(u.Scheme == "http" || u.Scheme == "https") || u.Host == "" {
+		return fmt.Errorf("oidc: discovery_url must look like https://host/path")
+	}
+	if u, err := url.Parse(o.Redirect_url); err != nil || !(u.Scheme == "http" || u.Scheme == "https") || u.Host == "" ||
+		!strings.HasSuffix(u.Path, oidcCallbackPath) {
+		return fmt.Errorf("oidc: redirect_url must be goldfish's address followed by %s", oidcCallbackPath)
+	}
+	if o.Client_id == "" || o.Client_secret_file == "" {
+		return fmt.Errorf("oidc: client_id and client_secret_file are required")
+	}
+	if o.Vault_role == "" {
+		return fmt.Errorf("oidc: vault_role is required")
+	}
+	if o.Vault_mount == "" {
+		o.Vault_mount = "jwt"
+	}
+	// the openid scope is what makes the provider return an id token
+	if len(o.Scopes) == 0 {
+		o.Scopes = []string{"openid", "profile", "email"}
+	}
+	hasOpenID := false
+	for _, scope := range o.Scopes {
+		hasOpenID = hasOpenID || scope == "openid"
+	}
+	if !hasOpenID {
+		o.Scopes = append([]string{"openid"}, o.Scopes...)
+	}
+	result.OIDC = o
+	return nil
+}
+
+var chatOpsKeys = []string{
+	"signing_secret_file",
+	"approvers",
+	"ui_address",
+}
+
+func parseChatOps(result *Config, chatOps *ast.ObjectItem) error {
+	valid := chatOpsKeys
+	if err := checkHCLKeys(chatOps.Val, valid); err != nil {
+		return fmt.Errorf("chatops: %s", err.Error())
+	}
+
+	m, err := decodeBlock("chatops", valid, chatOps.Val)
+	if err != nil {
+		return fmt.Errorf("chatops: %s", err.Error())
+	}
+
+	t := &ChatOpsConfig{
+		Signing_secret_file: m["signing_secret_file"],
+		Approvers:           make(map[string]string),
+		Ui_address:          strings.TrimSuffix(m["ui_address"], "/"),
+	}
+	if t.Signing_secret_file == "" {
+		return fmt.Errorf("chatops: signing_secret_file is required")
+	}
+	// approvers are listed as "<chat user id>=<display name>"
+	for _, entry := range splitList(m["approvers"]) {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return fmt.Errorf("chatops: approvers must look like \"U012AB3CD=alice, U045EF6GH=bob\"")
+		}
+		t.Approvers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	if len(t.Approvers) == 0 {
+		return fmt.Errorf("chatops: approvers is required")
+	}
+	if t.Ui_address != "" {
+		if u, err := url.Parse(t.Ui_address); err != nil || !(u.Scheme == "http" || u.Scheme == "https") || u.Host == "" {
+			return fmt.Errorf("chatops: ui_address must look like https://host")
+		}
+	}
+	result.ChatOps = t
+	return nil
+}
+
+var raftSnapshotKeys = []string{
+	"schedule",
+	"retain",
+	"local_dir",
+	"s3_bucket",
+	"s3_prefix",
+	"s3_region",
+	"s3_endpoint",
+	"s3_access_key_file",
+	"s3_secret_key_file",
+}
+
+func parseRaftSnapshot(result *Config, raftSnapshot *ast.ObjectItem) error {
+	valid := raftSnapshotKeys
+	if err := checkHCLKeys(raftSnapshot.Val, valid); err != nil {
+		return fmt.Errorf("raft_snapshot: %s", err.Error())
+	}
+
+	m, err := decodeBlock("raft_snapshot", valid, raftSnapshot.Val)
+	if err != nil {
+		return fmt.Errorf("raft_snapshot: %s", err.Error())
+	}
+
+	r := &RaftSnapshotConfig{
+		Schedule:           m["schedule"],
+		Local_dir:          m["local_dir"],
+		S3_bucket:          m["s3_bucket"],
+		S3_prefix:          m["s3_prefix"],
+		S3_region:          m["s3_region"],
+		S3_endpoint:        strings.TrimSuffix(m["s3_endpoint"], "/"),
+		S3_access_key_file: m["s3_access_key_file"],
+		S3_secret_key_file: m["s3_secret_key_file"],
+	}
+	if r.Schedule == "" {
+		return fmt.Errorf("raft_snapshot: schedule is required")
+	}
+	if _, err := schedule.Parse(r.Schedule); err != nil {
+		return fmt.Errorf("raft_snapshot: invalid schedule: %s", err.Error())
+	}
+	if v, ok := m["retain"]; ok {
+		if r.Retain, err = strconv.Atoi(v); err != nil || r.Retain < 0 {
+			return fmt.Errorf("raft_snapshot: retain must be a number")
+		}
+	}
+	if (r.Local_dir == "") == (r.S3_bucket == "") {
+		return fmt.Errorf("raft_snapshot: exactly one of local_dir or s3_bucket is required")
+	}
+	if r.S3_bucket == "" && (r.S3_prefix != "" || r.S3_region != "" || r.S3_endpoint != "" ||
+		r.S3_access_key_file != "" || r.S3_secret_key_file != "") {
+		return fmt.Errorf("raft_snapshot: s3 settings need s3_bucket")
+	}
+	if (r.S3_access_key_file == "") != (r.S3_secret_key_file == "") {
+		return fmt.Errorf("raft_snapshot: s3_access_key_file and s3_secret_key_file must be set together")
+	}
+	if r.S3_endpoint != "" {
+		if u, err := url.Parse(r.S3_endpoint); err != nil || !(u.Scheme == "http" || u.Scheme == "https") || u.Host == "" {
+			return fmt.Errorf("raft_snapshot: s3_endpoint must look like https://host")
+		}
+	}
+	if r.S3_bucket != "" && r.S3_region == "" {
+		r.S3_region = "us-east-1"
+	}
+	result.RaftSnapshot = r
+	return nil
+}
+
+const (
+	defaultAuditRetention = 168 * time.Hour
+	defaultAuditBucket    = time.Hour
+
+	// buckets kept per path, which bounds the heatmap's memory along with its path limit
+	maxAuditBuckets = 1000
+)
+
+var auditSourceKeys = []string{
+	"type",
+	"path",
+	"address",
+	"allowed_cidrs",
+	"retention",
+	"bucket",
+}
+
+func parseAuditSource(result *Config, auditSource *ast.ObjectItem) error {
+	valid := auditSourceKeys
+	if err := checkHCLKeys(auditSource.Val, valid); err != nil {
+		return fmt.Errorf("audit_source: %s", err.Error())
+	}
+
+	m, err := decodeBlock("audit_source", valid, auditSource.Val)
+	if err != nil {
+		return fmt.Errorf("audit_source: %s", err.Error())
+	}
+
+	a := &AuditSourceConfig{
+		Type:      m["type"],
+		Path:      m["path"],
+		Address:   m["address"],
+		Retention: defaultAuditRetention,
+		Bucket:    defaultAuditBucket,
+	}
+	switch a.Type {
+	case "file":
+		if a.Path == "" {
+			return fmt.Errorf("audit_source: path is required")
+		}
+		if a.Address != "" || m["allowed_cidrs"] != "" {
+			return fmt.Errorf("audit_source: address and allowed_cidrs are only for the socket type")
+		}
+	case "socket":
+		host, port, err := net.SplitHostPort(a.Address)
+		if err != nil {
+			return fmt.Errorf("audit_source: address must look like host:port")
+		}
+		if a.Path != "" {
+			return fmt.Errorf("audit_source: path is only for the file type")
+		}
+		// anyone who can connect can write entries, so the socket is only on loopback unless asked otherwise
+		if host == "" {
+			a.Address = net.JoinHostPort("127.0.0.1", port)
+		}
+		if a.Allowed_cidrs, err = parseCIDRs(m["allowed_cidrs"]); err != nil {
+			return fmt.Errorf("audit_source: allowed_cidrs: %s", err.Error())
+		}
+		if ip := net.ParseIP(host); (ip == nil || !ip.IsLoopback()) && host != "" && host != "localhost" &&
+			len(a.Allowed_cidrs) == 0 {
+			return fmt.Errorf("audit_source: a socket not on loopback needs allowed_cidrs, the addresses of vault's nodes")
+		}
+	default:
+		return fmt.Errorf("audit_source: type must be file or socket")
+	}
+	if v, ok := m["retention"]; ok {
+		if a.Retention, err = parseutil.ParseDurationSecond(v); err != nil || a.Retention <= 0 {
+			return fmt.Errorf("audit_source: retention must be a duration, e.g. \"168h\"")
+		}
+	}
+	if v, ok := m["bucket"]; ok {
+		if a.Bucket, err = parseutil.ParseDurationSecond(v); err != nil || a.Bucket < time.Minute {
+			return fmt.Errorf("audit_source: bucket must be a duration of at least a minute, e.g. \"1h\"")
+		}
+	}
+	if a.Retention < a.Bucket || a.Retention/a.Bucket > maxAuditBuckets {
+		return fmt.Errorf("audit_source: retention must be between 1 and %d buckets long", maxAuditBuckets)
+	}
+	result.AuditSource = a
+	return nil
+}
+
+var sessionKeys = []string{
+	"store",
+	"file_path",
+	"redis_address",
+	"redis_password",
+	"redis_db",
+	"transit_key",
+	"transit_backend",
+	"ttl",
+	"idle_timeout",
+	"absolute_timeout",
+	"step_up_window",
+	"admin_path",
+}
+
+func parseSession(result *Config, session *ast.ObjectItem) error {
+	valid := sessionKeys
+	if err := checkHCLKeys(session.Val, valid); err != nil {
+		return fmt.Errorf("session: %s", err.Error())
+	}
+
+	m, err := decodeBlock("session", valid, session.Val)
+	if err != nil {
+		return fmt.Errorf("session: %s", err.Error())
+	}
+
+	if store, ok := m["store"]; ok && store != "" {
+		result.Session.Store = strings.ToLower(store)
+	}
+
+	switch result.Session.Store {
+	case "memory":
+	case "file":
+		if result.Session.File_path = m["file_path"]; result.Session.File_path == "" {
+			return fmt.Errorf("session: file_path is required for the file store")
+		}
+	case "redis":
+		if result.Session.Redis_address = m["redis_address"]; result.Session.Redis_address == "" {
+			return fmt.Errorf("session: redis_address is required for the redis store")
+		}
+		result.Session.Redis_password = m["redis_password"]
+		if db, ok := m["redis_db"]; ok {
+			if result.Session.Redis_db, err = strconv.Atoi(db); err != nil || result.Session.Redis_db < 0 {
+				return fmt.Errorf("session: redis_db must be a number")
+			}
+		}
+	default:
+		return fmt.Errorf("session: store can be memory, file, or redis")
+	}
+
+	if result.Session.Transit_key = m["transit_key"]; result.Session.Transit_key != "" {
+		if strings.Contains(result.Session.Transit_key, "/") {
+			return fmt.Errorf("session: transit_key must be a key name, not a path")
+		}
+		if result.Session.Transit_backend = strings.Trim(m["transit_backend"], "/"); result.Session.Transit_backend == "" {
+			result.Session.Transit_backend = "transit"
+		}
+	} else if m["transit_backend"] != "" {
+		return fmt.Errorf("session: transit_backend requires transit_key")
+	}
+
+	durations := []struct {
+		key   string
+		field *time.Duration
+	}{
+		{"ttl", &result.Session.Ttl},
+		{"idle_timeout", &result.Session.Idle_timeout},
+		{"absolute_timeout", &result.Session.Absolute_timeout},
+		{"step_up_window", &result.Session.Step_up_window},
+	}
+	for _, d := range durations {
+		if v, ok := m[d.key]; ok {
+			duration, err := parseutil.ParseDurationSecond(v)
+			if err != nil || duration < 0 {
+				return fmt.Errorf("session: %s must be a duration, e.g. \"8h\"", d.key)
+			}
+			*d.field = duration
+		}
+	}
+	// last seen times are only recorded once a minute
+	if s := result.Session; s.Idle_timeout != 0 && s.Idle_timeout < 2*time.Minute {
+		return fmt.Errorf("session: idle_timeout must be at least 2m")
+	}
+	if s := result.Session; s.Absolute_timeout != 0 && s.Ttl > s.Absolute_timeout {
+		return fmt.Errorf("session: ttl can not be more than absolute_timeout")
+	}
+
+	result.Session.Admin_path = strings.Trim(m["admin_path"], "/")
+
+	return nil
+}
+
+var validClusterName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func parseCluster(result *Config, cluster *ast.ObjectItem) error {
+	if len(cluster.Keys) == 0 {
+		return fmt.Errorf("cluster requires a name")
+	}
+	name := cluster.Keys[0].Token.Value().(string)
+	if !validClusterName.MatchString(name) {
+		return fmt.Errorf("cluster.%s: name may only contain letters, numbers, '-' and '_'", name)
+	}
+	if _, ok := result.Clusters[name]; ok {
+		return fmt.Errorf("cluster.%s: defined more than once", name)
+	}
+
+	valid := []string{
+		"address",
+		"tls_skip_verify",
+		"ca_cert",
+	}
+	if err := checkHCLKeys(cluster.Val, valid); err != nil {
+		return fmt.Errorf("cluster.%s: %s", name, err.Error())
+	}
+
+	m, err := decodeBlock("cluster_"+name, valid, cluster.Val)
+	if err != nil {
+		return fmt.Errorf("cluster.%s: %s", name, err.Error())
+	}
+
+	c := &ClusterConfig{
+		Name:    name,
+		Ca_cert: m["ca_cert"],
+	}
+
+	if u, err := url.Parse(m["address"]); err != nil || m["address"] == "" {
+		return fmt.Errorf("cluster.%s: address is required", name)
+	} else if !(u.Scheme == "http" || u.Scheme == "https") {
+		return fmt.Errorf("cluster.%s: address must be prefixed with scheme i.e. http:// or https://", name)
+	} else {
+		c.Address = u.String()
+	}
+
+	if tlsSkip, ok := m["tls_skip_verify"]; ok {
+		if tlsSkip == "1" {
+			c.Tls_skip_verify = true
+		} else if tlsSkip != "0" {
+			return fmt.Errorf("cluster.%s: tls_skip_verify can be 0 or 1", name)
+		}
+	}
+
+	if result.Clusters == nil {
+		result.Clusters = make(map[string]*ClusterConfig)
+	}
+	result.Clusters[name] = c
+	return nil
+}
+
+func parseLogin(result *Config, login *ast.ObjectItem) error {
+	if len(login.Keys) == 0 {
+		return fmt.Errorf("login requires a name")
+	}
+	name := login.Keys[0].Token.Value().(string)
+	if !validClusterName.MatchString(name) {
+		return fmt.Errorf("login.%s: name may only contain letters, numbers, '-' and '_'", name)
+	}
+	if name == "token" {
+		return fmt.Errorf("login.%s: token logins need no mount", name)
+	}
+	if _, ok := result.Logins[name]; ok {
+		return fmt.Errorf("login.%s: defined more than once", name)
+	}
+
+	valid := []string{
+		"type",
+		"path",
+		"label",
+	}
+	if err := checkHCLKeys(login.Val, valid); err != nil {
+		return fmt.Errorf("login.%s: %s", name, err.Error())
+	}
+
+	m, err := decodeBlock("login_"+name, valid, login.Val)
+	if err != nil {
+		return fmt.Errorf("login.%s: %s", name, err.Error())
+	}
+
+	l := &LoginConfig{
+		Name:  name,
+		Type:  strings.ToLower(m["type"]),
+		Path:  strings.Trim(m["path"], "/"),
+		Label: m["label"],
+	}
+	if l.Type == "" {
+		l.Type = name
+	}
+	if !containsString(LoginTypes, l.Type) {
+		return fmt.Errorf("login.%s: type must be one of %s", name, strings.Join(LoginTypes, ", "))
+	}
+	if l.Path == "" {
+		l.Path = name
+	}
+	if !strings.HasPrefix(l.Path, "auth/") {
+		l.Path = "auth/" + l.Path
+	}
+	if !validMountPath.MatchString(l.Path) {
+		return fmt.Errorf("login.%s: path must be a mount, e.g. \"auth/corp-ldap\"", name)
+	}
+	if l.Label == "" {
+		l.Label = name
+	}
+
+	if result.Logins == nil {
+		result.Logins = make(map[string]*LoginConfig)
+	}
+	result.Logins[name] = l
+	return nil
+}
+
+var validMountPath = regexp.MustCompile(`^auth/[A-Za-z0-9_][A-Za-z0-9_.-]*(/[A-Za-z0-9_][A-Za-z0-9_.-]*)*$`)
+
+func parseRole(result *Config, role *ast.ObjectItem) error {
+	if len(role.Keys) == 0 {
+		return fmt.Errorf("role requires a name")
+	}
+	name := role.Keys[0].Token.Value().(string)
+	if !validClusterName.MatchString(name) {
+		return fmt.Errorf("role.%s: name may only contain letters, numbers, '-' and '_'", name)
+	}
+	if _, ok := result.Roles[name]; ok {
+		return fmt.Errorf("role.%s: defined more than once", name)
+	}
+
+	valid := []string{
+		"policies",
+		"groups",
+		"endpoints",
+	}
+	if err := checkHCLKeys(role.Val, valid); err != nil {
+		return fmt.Errorf("role.%s: %s", name, err.Error())
+	}
+
+	m, err := decodeBlock("role_"+name, valid, role.Val)
+	if err != nil {
+		return fmt.Errorf("role.%s: %s", name, err.Error())
+	}
+
+	r := &RoleConfig{
+		Name:      name,
+		Policies:  splitList(m["policies"]),
+		Groups:    splitList(m["groups"]),
+		Endpoints: splitList(m["endpoints"]),
+	}
+	if err := validateRole(r); err != nil {
+		return fmt.Errorf("role.%s: %s", name, err.Error())
+	}
+
+	if result.Roles == nil {
+		result.Roles = make(map[string]*RoleConfig)
+	}
+	result.Roles[name] = r
+	return nil
+}
+
+func parseNotifier(result *Config, notifier *ast.ObjectItem) error {
+	if len(notifier.Keys) == 0 {
+		return fmt.Errorf("notifier requires a name")
+	}
+	name := notifier.Keys[0].Token.Value().(string)
+	if !validClusterName.MatchString(name) {
+		return fmt.Errorf("notifier.%s: name may only contain letters, numbers, '-' and '_'", name)
+	}
+	if _, ok := result.Notifiers[name]; ok {
+		return fmt.Errorf("notifier.%s: defined more than once", name)
+	}
+
+	valid := []string{
+		"type",
+		"url",
+		"channel",
+		"smtp_address",
+		"smtp_username",
+		"smtp_password_file",
+		"from",
+		"to",
+		"key_file",
+	}
+	if err := checkHCLKeys(notifier.Val, valid); err != nil {
+		return fmt.Errorf("notifier.%s: %s", name, err.Error())
+	}
+
+	m, err := decodeBlock("notifier_"+name, valid, notifier.Val)
+	if err != nil {
+		return fmt.Errorf("notifier.%s: %s", name, err.Error())
+	}
+
+	n := &NotifierConfig{
+		Name:               name,
+		Type:               m["type"],
+		Url:                m["url"],
+		Channel:            m["channel"],
+		Smtp_address:       m["smtp_address"],
+		Smtp_username:      m["smtp_username"],
+		Smtp_password_file: m["smtp_password_file"],
+		From:               m["from"],
+		To:                 splitList(m["to"]),
+		Key_file:           m["key_file"],
+	}
+	if n.Url != "" {
+		if u, err := url.Parse(n.Url); err != nil || !(u.Scheme == "http" || u.Scheme == "https") || u.Host == "" {
+			return fmt.Errorf("notifier.%s: url must look like https://host/path", name)
+		}
+	}
+	switch n.Type {
+	case "slack":
+	case "webhook":
+		if n.Url == "" {
+			return fmt.Errorf("notifier.%s: url is required", name)
+		}
+	case "email":
+		if _, _, err := net.SplitHostPort(n.Smtp_address); err != nil {
+			return fmt.Errorf("notifier.%s: smtp_address must look like host:port", name)
+		}
+		if n.From == "" || len(n.To) == 0 {
+			return fmt.Errorf("notifier.%s: from and to are required", name)
+		}
+	case "pagerduty", "opsgenie":
+		if n.Key_file == "" {
+			return fmt.Errorf("notifier.%s: key_file is required", name)
+		}
+	default:
+		return fmt.Errorf("notifier.%s: type must be one of slack, webhook, email, pagerduty, opsgenie", name)
+	}
+
+	if result.Notifiers == nil {
+		result.Notifiers = make(map[string]*NotifierConfig)
+	}
+	result.Notifiers[name] = n
+	return nil
+}
+
+var reportTypes = map[string]bool{
+	"expiring_tokens":   true,
+	"expiring_certs":    true,
+	"pending_requests":  true,
+	"unused_policies":   true,
+	"stale_requests":    true,
+	"untagged_secrets":  true,
+	"overdue_rotations": true,
+}
+
+func parseReport(result *Config, report *ast.ObjectItem) error {
+	if len(report.Keys) == 0 {
+		return fmt.Errorf("report requires a name")
+	}
+	name := report.Keys[0].Token.Value().(string)
+	if !validClusterName.MatchString(name) {
+		return fmt.Errorf("report.%s: name may only contain letters, numbers, '-' and '_'", name)
+	}
+	if _, ok := result.Reports[name]; ok {
+		return fmt.Errorf("report.%s: defined more than once", name)
+	}
+
+	valid := []string{
+		"type",
+		"schedule",
+		"notify",
+		"window",
+		"pki_mounts",
+		"paths",
+	}
+	if err := checkHCLKeys(report.Val, valid); err != nil {
+		return fmt.Errorf("report.%s: %s", name, err.Error())
+	}
+
+	m, err := decodeBlock("report_"+name, valid, report.Val)
+	if err != nil {
+		return fmt.Errorf("report.%s: %s", name, err.Error())
+	}
+
+	r := &ReportConfig{
+		Name:       name,
+		Type:       m["type"],
+		Schedule:   m["schedule"],
+		Notify:     splitList(m["notify"]),
+		Window:     defaultReportWindow,
+		Pki_mounts: splitList(m["pki_mounts"]),
+		Paths:      splitList(m["paths"]),
+	}
+	if !reportTypes[r.Type] {
+		return fmt.Errorf("report.%s: type must be one of expiring_tokens, expiring_certs, pending_requests, unused_policies, stale_requests, untagged_secrets, overdue_rotations", name)
+	}
+	if _, err := schedule.Parse(r.Schedule); err != nil {
+		return fmt.Errorf("report.%s: invalid schedule: %s", name, err.Error())
+	}
+	if len(r.Notify) == 0 {
+		return fmt.Errorf("report.%s: notify is required", name)
+	}
+	for _, n := range r.Notify {
+		if _, ok := result.Notifiers[n]; !ok {
+			return fmt.Errorf("report.%s: notifier %q is not defined", name, n)
+		}
+	}
+	if v, ok := m["window"]; ok {
+		if r.Window, err = parseutil.ParseDurationSecond(v); err != nil || r.Window <= 0 {
+			return fmt.Errorf("report.%s: window must be a duration, e.g. \"168h\"", name)
+		}
+	}
+	// a week is too long to leave a request unseen, so stale requests have no default
+	if _, ok := m["window"]; !ok && r.Type == "stale_requests" {
+		return fmt.Errorf("report.%s: window is required", name)
+	}
+	if r.Type == "expiring_certs" && len(r.Pki_mounts) == 0 {
+		return fmt.Errorf("report.%s: pki_mounts is required", name)
+	}
+	if r.Type == "untagged_secrets" {
+		if len(r.Paths) == 0 {
+			return fmt.Errorf("report.%s: paths is required", name)
+		}
+		for _, p := range r.Paths {
+			if !strings.HasSuffix(p, "/") {
+				return fmt.Errorf("report.%s: paths must be folders, ending in '/'", name)
+			}
+		}
+	}
+
+	if result.Reports == nil {
+		result.Reports = make(map[string]*ReportConfig)
+	}
+	result.Reports[name] = r
+	return nil
+}
+
+var policyLintTypes = map[string]bool{
+	"forbid_capabilities": true,
+	"require_deny":        true,
+	"policy_name":         true,
+}
+
+// the capabilities vault's policies may grant
+var policyCapabilities = map[string]bool{
+	"create": true,
+	"read":   true,
+	"update": true,
+	"delete": true,
+	"list":   true,
+	"sudo":   true,
+	"deny":   true,
+}
+
+func parsePolicyLint(result *Config, lint *ast.ObjectItem) error {
+	if len(lint.Keys) == 0 {
+		return fmt.Errorf("policy_lint requires a name")
+	}
+	name := lint.Keys[0].Token.Value().(string)
+	if !validClusterName.MatchString(name) {
+		return fmt.Errorf("policy_lint.%s: name may only contain letters, numbers, '-' and '_'", name)
+	}
+	if _, ok := result.PolicyLints[name]; ok {
+		return fmt.Errorf("policy_lint.%s: defined more than once", name)
+	}
+
+	valid := []string{
+		"type",
+		"paths",
+		"capabilities",
+		"pattern",
+		"action",
+		"message",
+	}
+	if err := checkHCLKeys(lint.Val, valid); err != nil {
+		return fmt.Errorf("policy_lint.%s: %s", name, err.Error())
+	}
+
+	m, err := decodeBlock("policy_lint_"+name, valid, lint.Val)
+	if err != nil {
+		return fmt.Errorf("policy_lint.%s: %s", name, err.Error())
+	}
+
+	l := &PolicyLintConfig{
+		Name:         name,
+		Type:         m["type"],
+		Paths:        splitList(m["paths"]),
+		Capabilities: splitList(m["capabilities"]),
+		Pattern:      m["pattern"],
+		Action:       m["action"],
+		Message:      m["message"],
+	}
+	if !policyLintTypes[l.Type] {
+		return fmt.Errorf("policy_lint.%s: type must be one of forbid_capabilities, require_deny, policy_name", name)
+	}
+	if l.Type == "policy_name" {
+		if l.Pattern == "" {
+			return fmt.Errorf("policy_lint.%s: pattern is required", name)
+		}
+		if _, err := regexp.Compile(l.Pattern); err != nil {
+			return fmt.Errorf("policy_lint.%s: invalid pattern: %s", name, err.Error())
+		}
+	} else if len(l.Paths) == 0 {
+		return fmt.Errorf("policy_lint.%s: paths is required", name)
+	}
+	for _, c := range l.Capabilities {
+		if !policyCapabilities[c] {
+			return fmt.Errorf("policy_lint.%s: unknown capability %q", name, c)
+		}
+	}
+	switch l.Action {
+	case "":
+		l.Action = "block"
+	case "block", "warn":
+	default:
+		return fmt.Errorf("policy_lint.%s: action must be block or warn", name)
+	}
+
+	if result.PolicyLints == nil {
+		result.PolicyLints = make(map[string]*PolicyLintConfig)
+	}
+	result.PolicyLints[name] = l
+	return nil
+}
+
+var (
+	validRoleTag      = regexp.MustCompile(`^[a-z_-]+$`)
+	validRoleEndpoint = regexp.MustCompile(`^(GET|POST|PUT|DELETE) /v1/\S*$`)
+)
+
+func validateRole(r *RoleConfig) error {
+	if len(r.Policies) == 0 && len(r.Groups) == 0 {
+		return errors.New("at least one of policies or groups is required")
+	}
+	if len(r.Endpoints) == 0 {
+		return errors.New("endpoints is required")
+	}
+	for _, e := range r.Endpoints {
+		if e != "*" && !validRoleTag.MatchString(e) && !validRoleEndpoint.MatchString(e) {
+			return fmt.Errorf("invalid endpoint %q, expected an api tag, \"<METHOD> /v1/<path>\", or \"*\"", e)
+		}
+	}
+	return nil
+}
+
+// parses roles kept in vault's runtime config, e.g.
+// {"helpdesk": {"policies": ["helpdesk"], "endpoints": ["tokens"]}}
+func ParseRolesJSON(doc string) (map[string]*RoleConfig, error) {
+	var roles map[string]*RoleConfig
+	if err := json.Unmarshal([]byte(doc), &roles); err != nil {
+		return nil, err
+	}
+	for name, r := range roles {
+		if !validClusterName.MatchString(name) {
+			return nil, fmt.Errorf("role.%s: name may only contain letters, numbers, '-' and '_'", name)
+		}
+		if r == nil {
+			return nil, fmt.Errorf("role.%s: must be an object", name)
+		}
+		r.Name = name
+		if err := validateRole(r); err != nil {
+			return nil, fmt.Errorf("role.%s: %s", name, err.Error())
+		}
+	}
+	return roles, nil
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"os"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/vault/api"
 
@@ -28,12 +30,26 @@ func TestConfigParser(t *testing.T) {
 		So(err, ShouldBeNil)
 		So(cfg, ShouldResemble, &Config {
 			Listener: &ListenerConfig {
-				Type:             "tcp",
-				Address:          "127.0.0.1:8000",
-				Tls_disable:      false,
-				Tls_cert_file:    "",
-				Tls_key_file:     "",
-				Tls_autoredirect: false,
+				Type:                 "tcp",
+				Address:              "127.0.0.1:8000",
+				Tls_disable:          false,
+				Tls_cert_file:        "",
+				Tls_key_file:         "",
+				Tls_autoredirect:     false,
+				Login_max_attempts:   5,
+				Login_backoff:        time.Second,
+				Login_lockout:        15 * time.Minute,
+				Shutdown_timeout:     20 * time.Second,
+				Body_limit:           "32M",
+				Gzip_level:           5,
+				Read_timeout:         10 * time.Second,
+				Write_timeout:        2 * time.Minute,
+				Security_preset:      "default",
+				Csp:                  "default-src 'self' https://api.github.com",
+				Frame_options:        "SAMEORIGIN",
+				Tls_min_version:      "tls12",
+				Tls_redirect_address: ":80",
+				Autocert_cache_dir:   "/var/www/.cache",
 			},
 			Vault: &VaultConfig {
 				Type:            "vault",
@@ -42,7 +58,20 @@ func TestConfigParser(t *testing.T) {
 				Runtime_config:  "secret/goldfish",
 				Approle_login:   "auth/approle/login",
 				Approle_id:      "goldfish",
+				Kubernetes_login:    "auth/kubernetes/login",
+				Kubernetes_jwt_file: "/var/run/secrets/kubernetes.io/serviceaccount/token",
+				Timeout:        60 * time.Second,
+				List_timeout:   2 * time.Minute,
+				Max_retries:    2,
+				List_max_items: 100000,
+				Retry_wait_min: 500 * time.Millisecond,
+				Retry_wait_max: 5 * time.Second,
+				Health_check_interval: 5 * time.Second,
+				Health_history: 24 * time.Hour,
 			},
+			Telemetry: &TelemetryConfig {},
+			Session:   &SessionConfig { Store: "memory" },
+			RateLimit: &RateLimitConfig {},
 		})
 	})
 
@@ -59,10 +88,24 @@ func TestConfigParser(t *testing.T) {
 		So(err, ShouldBeNil)
 		So(cfg, ShouldResemble, &Config {
 			Listener: &ListenerConfig {
-				Type:        "tcp",
-				Address:     "127.0.0.1:8000",
-				Tls_disable: false,
-				Tls_autoredirect: true,
+				Type:                 "tcp",
+				Address:              "127.0.0.1:8000",
+				Tls_disable:          false,
+				Tls_autoredirect:     true,
+				Login_max_attempts:   5,
+				Login_backoff:        time.Second,
+				Login_lockout:        15 * time.Minute,
+				Shutdown_timeout:     20 * time.Second,
+				Body_limit:           "32M",
+				Gzip_level:           5,
+				Read_timeout:         10 * time.Second,
+				Write_timeout:        2 * time.Minute,
+				Security_preset:      "default",
+				Csp:                  "default-src 'self' https://api.github.com",
+				Frame_options:        "SAMEORIGIN",
+				Tls_min_version:      "tls12",
+				Tls_redirect_address: ":80",
+				Autocert_cache_dir:   "/var/www/.cache",
 			},
 			Vault: &VaultConfig {
 				Type:           "vault",
@@ -70,7 +113,20 @@ func TestConfigParser(t *testing.T) {
 				Runtime_config: "secret/goldfish",
 				Approle_login:  "auth/approle/login",
 				Approle_id:     "goldfish",
+				Kubernetes_login:    "auth/kubernetes/login",
+				Kubernetes_jwt_file: "/var/run/secrets/kubernetes.io/serviceaccount/token",
+				Timeout:        60 * time.Second,
+				List_timeout:   2 * time.Minute,
+				Max_retries:    2,
+				List_max_items: 100000,
+				Retry_wait_min: 500 * time.Millisecond,
+				Retry_wait_max: 5 * time.Second,
+				Health_check_interval: 5 * time.Second,
+				Health_history: 24 * time.Hour,
 			},
+			Telemetry: &TelemetryConfig {},
+			Session:   &SessionConfig { Store: "memory" },
+			RateLimit: &RateLimitConfig {},
 		})
 	})
 
@@ -87,8 +143,22 @@ func TestConfigParser(t *testing.T) {
 		So(err, ShouldBeNil)
 		So(cfg, ShouldResemble, &Config {
 			Listener: &ListenerConfig {
-				Type:        "tcp",
-				Address:     "127.0.0.1:8000",
+				Type:                 "tcp",
+				Address:              "127.0.0.1:8000",
+				Login_max_attempts:   5,
+				Login_backoff:        time.Second,
+				Login_lockout:        15 * time.Minute,
+				Shutdown_timeout:     20 * time.Second,
+				Body_limit:           "32M",
+				Gzip_level:           5,
+				Read_timeout:         10 * time.Second,
+				Write_timeout:        2 * time.Minute,
+				Security_preset:      "default",
+				Csp:                  "default-src 'self' https://api.github.com",
+				Frame_options:        "SAMEORIGIN",
+				Tls_min_version:      "tls12",
+				Tls_redirect_address: ":80",
+				Autocert_cache_dir:   "/var/www/.cache",
 			},
 			Vault: &VaultConfig {
 				Type:            "vault",
@@ -97,7 +167,20 @@ func TestConfigParser(t *testing.T) {
 				Runtime_config:  "secret/goldfish",
 				Approle_login:   "auth/approle/login",
 				Approle_id:      "goldfish",
+				Kubernetes_login:    "auth/kubernetes/login",
+				Kubernetes_jwt_file: "/var/run/secrets/kubernetes.io/serviceaccount/token",
+				Timeout:        60 * time.Second,
+				List_timeout:   2 * time.Minute,
+				Max_retries:    2,
+				List_max_items: 100000,
+				Retry_wait_min: 500 * time.Millisecond,
+				Retry_wait_max: 5 * time.Second,
+				Health_check_interval: 5 * time.Second,
+				Health_history: 24 * time.Hour,
 			},
+			Telemetry: &TelemetryConfig {},
+			Session:   &SessionConfig { Store: "memory" },
+			RateLimit: &RateLimitConfig {},
 		})
 	})
 
@@ -112,7 +195,7 @@ func TestConfigParser(t *testing.T) {
 		So(cfg, ShouldBeNil)
 	})
 
-	Convey("Parser should reject invalid strings - multiple listener configs", t, func() {
+	Convey("Parser should reject invalid strings - multiple let's encrypt listeners", t, func() {
 		cfg, err := ParseConfig(`
 			listener "tcp" {
 				address          = "127.0.0.1:8000"
@@ -288,6 +371,1756 @@ func TestConfigParser(t *testing.T) {
 		So(cfg, ShouldBeNil)
 	})
 
+	Convey("Parser should reject invalid vault - secret_id and secret_id_file both set", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address                = "http://127.0.0.1:8200"
+				approle_secret_id      = "abc"
+				approle_secret_id_file = "/etc/goldfish/secret_id"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should reject invalid vault - kubernetes_role and token_file both set", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+				kubernetes_role = "goldfish"
+				token_file      = "/var/run/vault/token"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should accept valid string - multiple vault addresses", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "https://vault1:8200, https://vault2:8200"
+				address_srv     = "https://_vault._tcp.example.com"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Vault.Address, ShouldEqual, "https://vault1:8200")
+		So(cfg.Vault.Addresses, ShouldResemble, []string{"https://vault1:8200", "https://vault2:8200"})
+		So(cfg.Vault.Address_srv, ShouldEqual, "https://_vault._tcp.example.com")
+	})
+
+	Convey("Parser should accept valid string - consul discovery", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address_consul  = "https://vault"
+				consul_address  = "127.0.0.1:8500"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Vault.Address, ShouldEqual, "https://vault")
+		So(cfg.Vault.Address_consul, ShouldEqual, "https://vault")
+		So(cfg.Vault.Consul_tag, ShouldEqual, "active")
+		So(cfg.Vault.Consul_address, ShouldEqual, "127.0.0.1:8500")
+	})
+
+	Convey("Parser should reject invalid vault - client_cert without client_key", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "https://127.0.0.1:8200"
+				ca_cert         = "/etc/goldfish/vault-ca.pem"
+				client_cert     = "/etc/goldfish/client.pem"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should reject invalid vault - malformed proxy_address", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "https://127.0.0.1:8200"
+				proxy_address   = "proxy:3128"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should accept valid string - vault client tuning", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+				timeout         = "10s"
+				list_timeout    = 300
+				list_cache_ttl  = "30s"
+				max_retries     = 0
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Vault.Timeout, ShouldEqual, 10 * time.Second)
+		So(cfg.Vault.List_timeout, ShouldEqual, 5 * time.Minute)
+		So(cfg.Vault.List_cache_ttl, ShouldEqual, 30 * time.Second)
+		So(cfg.Vault.Max_retries, ShouldEqual, 0)
+		So(cfg.Vault.List_max_items, ShouldEqual, 100000)
+		So(cfg.Vault.Retry_wait_min, ShouldEqual, 500 * time.Millisecond)
+	})
+
+	Convey("Parser should reject invalid vault - negative list max items", t, func() {
+		_, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+				list_max_items  = -1
+			}
+			`)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Parser should reject invalid vault - invalid timeout", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+				timeout         = "soon"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should accept valid string - clusters", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			cluster "staging" {
+				address         = "https://vault.staging:8200"
+				ca_cert         = "/etc/goldfish/staging-ca.pem"
+			}
+			cluster "dev" {
+				address         = "http://vault.dev:8200"
+				tls_skip_verify = 1
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Clusters, ShouldResemble, map[string]*ClusterConfig {
+			"staging": &ClusterConfig {
+				Name:    "staging",
+				Address: "https://vault.staging:8200",
+				Ca_cert: "/etc/goldfish/staging-ca.pem",
+			},
+			"dev": &ClusterConfig {
+				Name:            "dev",
+				Address:         "http://vault.dev:8200",
+				Tls_skip_verify: true,
+			},
+		})
+	})
+
+	Convey("Parser should reject invalid clusters - duplicate name", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			cluster "dev" {
+				address         = "http://vault.dev:8200"
+			}
+			cluster "dev" {
+				address         = "http://vault.dev2:8200"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should accept valid string - logins", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			login "ldap" {
+				path            = "corp-ldap/"
+			}
+			login "partner-ldap" {
+				type            = "ldap"
+				label           = "Partner LDAP"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Logins, ShouldResemble, map[string]*LoginConfig {
+			"ldap": &LoginConfig {
+				Name:  "ldap",
+				Type:  "ldap",
+				Path:  "auth/corp-ldap",
+				Label: "ldap",
+			},
+			"partner-ldap": &LoginConfig {
+				Name:  "partner-ldap",
+				Type:  "ldap",
+				Path:  "auth/partner-ldap",
+				Label: "Partner LDAP",
+			},
+		})
+	})
+
+	Convey("Parser should reject invalid logins - unknown type", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			login "corp" {
+				type            = "kerberos"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should accept valid string - login throttling", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address            = "127.0.0.1:8000"
+				login_max_attempts = 10
+				login_backoff      = "500ms"
+				login_lockout      = "1h"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Listener.Login_max_attempts, ShouldEqual, 10)
+		So(cfg.Listener.Login_backoff, ShouldEqual, 500 * time.Millisecond)
+		So(cfg.Listener.Login_lockout, ShouldEqual, time.Hour)
+	})
+
+	Convey("Parser should reject invalid login throttling - bad lockout", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address       = "127.0.0.1:8000"
+				login_lockout = "forever"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should accept valid string - ip filters", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address             = "127.0.0.1:8000"
+				allowed_cidrs       = "10.0.0.0/8, 192.168.1.7"
+				denied_cidrs        = "10.1.0.0/16"
+				admin_allowed_cidrs = "10.0.5.1/24"
+				trusted_proxies     = "10.9.0.1"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Listener.Allowed_cidrs, ShouldResemble, []string{"10.0.0.0/8", "192.168.1.7/32"})
+		So(cfg.Listener.Denied_cidrs, ShouldResemble, []string{"10.1.0.0/16"})
+		So(cfg.Listener.Admin_allowed_cidrs, ShouldResemble, []string{"10.0.5.0/24"})
+		So(cfg.Listener.Trusted_proxies, ShouldResemble, []string{"10.9.0.1/32"})
+	})
+
+	Convey("Parser should reject invalid ip filters - malformed cidr", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address       = "127.0.0.1:8000"
+				allowed_cidrs = "10.0.0.0/33"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should accept valid string - strict security headers", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address                 = "127.0.0.1:8000"
+				security_preset         = "strict"
+				frame_options           = "sameorigin"
+				hsts_max_age            = "8760h"
+				hsts_include_subdomains = 1
+				hsts_preload            = 1
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Listener.Csp, ShouldEqual, "default-src 'self'; frame-ancestors 'none'; base-uri 'self'; form-action 'self'")
+		So(cfg.Listener.Frame_options, ShouldEqual, "SAMEORIGIN")
+		So(cfg.Listener.Referrer_policy, ShouldEqual, "no-referrer")
+		So(cfg.Listener.Hsts_max_age, ShouldEqual, 8760 * time.Hour)
+		So(cfg.Listener.Hsts_preload, ShouldBeTrue)
+	})
+
+	Convey("Parser should reject invalid security headers - preload without subdomains", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address      = "127.0.0.1:8000"
+				hsts_max_age = "8760h"
+				hsts_preload = 1
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should accept valid string - client certificates", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address                 = "127.0.0.1:8000"
+				tls_cert_file           = "/path/to/fullchain.pem"
+				tls_key_file            = "/path/to/privkey.pem"
+				tls_require_client_cert = 1
+				tls_client_ca_file      = "/path/to/clients.pem"
+				tls_client_allowed_ous  = "ops, security"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Listener.Tls_require_client_cert, ShouldBeTrue)
+		So(cfg.Listener.Tls_client_ca_file, ShouldEqual, "/path/to/clients.pem")
+		So(cfg.Listener.Tls_client_allowed_ous, ShouldResemble, []string{"ops", "security"})
+	})
+
+	Convey("Parser should reject invalid client certificates - missing ca", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address                 = "127.0.0.1:8000"
+				tls_cert_file           = "/path/to/fullchain.pem"
+				tls_key_file            = "/path/to/privkey.pem"
+				tls_require_client_cert = 1
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should accept valid string - fips cipher preset", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address           = "127.0.0.1:8000"
+				tls_cipher_preset = "fips"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Listener.Tls_min_version, ShouldEqual, "tls12")
+		So(cfg.Listener.Tls_cipher_suites, ShouldResemble, []string{
+			"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+			"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+			"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+			"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+		})
+	})
+
+	Convey("Parser should reject invalid tls settings - unknown cipher suite", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address           = "127.0.0.1:8000"
+				tls_cipher_suites = "TLS_RSA_WITH_AES_128_GCM_SHA256, TLS_MADE_UP"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should accept valid string - let's encrypt", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address              = "goldfish.example.com:443"
+				tls_autoredirect     = 1
+				tls_redirect_address = ":8080"
+				autocert_cache_dir   = "/var/lib/goldfish/autocert"
+				autocert_email       = "ops@example.com"
+				autocert_hosts       = "vault-ui.example.com"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Listener.Tls_redirect_address, ShouldEqual, ":8080")
+		So(cfg.Listener.Autocert_cache_dir, ShouldEqual, "/var/lib/goldfish/autocert")
+		So(cfg.Listener.Autocert_email, ShouldEqual, "ops@example.com")
+		So(cfg.Listener.Autocert_hosts, ShouldResemble, []string{"vault-ui.example.com"})
+	})
+
+	Convey("Parser should accept valid string - base path", t, func() {
+		for _, path := range []string{"goldfish", "/goldfish", "/goldfish/"} {
+			cfg, err := ParseConfig(`
+				listener "tcp" {
+					address   = "127.0.0.1:8000"
+					base_path = "` + path + `"
+				}
+				vault {
+					address         = "http://127.0.0.1:8200"
+				}
+				`)
+			So(err, ShouldBeNil)
+			So(cfg.Listener.Base_path, ShouldEqual, "/goldfish")
+		}
+	})
+
+	Convey("Parser should reject invalid base path", t, func() {
+		_, err := ParseConfig(`
+			listener "tcp" {
+				address   = "127.0.0.1:8000"
+				base_path = "/goldfish?x=1"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Parser should accept valid string - roles", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			role "helpdesk" {
+				policies  = "helpdesk, support"
+				groups    = "helpdesk-team"
+				endpoints = "tokens, GET /v1/sessions"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Roles["helpdesk"], ShouldResemble, &RoleConfig {
+			Name:      "helpdesk",
+			Policies:  []string{"helpdesk", "support"},
+			Groups:    []string{"helpdesk-team"},
+			Endpoints: []string{"tokens", "GET /v1/sessions"},
+		})
+	})
+
+	Convey("Parser should reject invalid roles", t, func() {
+		for _, role := range []string{
+			`role "helpdesk" { endpoints = "tokens" }`,
+			`role "helpdesk" { policies = "helpdesk" }`,
+			`role "helpdesk" { policies = "helpdesk", endpoints = "read tokens" }`,
+			`role "help desk" { policies = "helpdesk", endpoints = "tokens" }`,
+		} {
+			_, err := ParseConfig(`
+				listener "tcp" {
+					address = "127.0.0.1:8000"
+				}
+				vault {
+					address         = "http://127.0.0.1:8200"
+				}
+				` + role)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Roles kept in vault should be parsed and validated", t, func() {
+		roles, err := ParseRolesJSON(`{"helpdesk": {"policies": ["helpdesk"], "endpoints": ["tokens"]}}`)
+		So(err, ShouldBeNil)
+		So(roles["helpdesk"].Name, ShouldEqual, "helpdesk")
+		So(roles["helpdesk"].Endpoints, ShouldResemble, []string{"tokens"})
+
+		_, err = ParseRolesJSON(`{"helpdesk": {"endpoints": ["tokens"]}}`)
+		So(err, ShouldNotBeNil)
+		_, err = ParseRolesJSON(`{"helpdesk": null}`)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Parser should accept valid string - branding", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			branding {
+				organization = "Example Corp (staging)"
+				login_banner = "Staging - data is reset nightly"
+				accent_color = "#f0ad4e"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Branding, ShouldResemble, &BrandingConfig{
+			Organization: "Example Corp (staging)",
+			Login_banner: "Staging - data is reset nightly",
+			Accent_color: "#f0ad4e",
+		})
+
+		for _, branding := range []string{
+			`branding { accent_color = "red" }`,
+			`branding { accent_color = "#f0ad4e; }" }`,
+			`branding { logo_file = "/nonexistent/logo.png" }`,
+		} {
+			_, err := ParseConfig(`
+				listener "tcp" {
+					address = "127.0.0.1:8000"
+				}
+				vault {
+					address         = "http://127.0.0.1:8200"
+				}
+				` + branding)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Parser should accept valid string - token_creation", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			token_creation {
+				default_ttl      = "24h"
+				max_ttl          = "768h"
+				max_num_uses     = 100
+				require_wrapping = 1
+				max_wrap_ttl     = "1h"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.TokenCreation, ShouldResemble, &TokenCreationConfig{
+			Default_ttl:      24 * time.Hour,
+			Max_ttl:          768 * time.Hour,
+			Max_num_uses:     100,
+			Require_wrapping: true,
+			Max_wrap_ttl:     time.Hour,
+		})
+
+		for _, tokenCreation := range []string{
+			`token_creation { default_ttl = "forever" }`,
+			`token_creation {
+				default_ttl = "48h"
+				max_ttl     = "24h"
+			}`,
+			`token_creation { max_num_uses = -1 }`,
+			`token_creation { require_wrapping = "yes" }`,
+		} {
+			_, err := ParseConfig(`
+				listener "tcp" {
+					address = "127.0.0.1:8000"
+				}
+				vault {
+					address         = "http://127.0.0.1:8200"
+				}
+				` + tokenCreation)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Parser should accept valid string - raft_snapshot", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			raft_snapshot {
+				schedule    = "@every 6h"
+				retain      = 7
+				s3_bucket   = "backups"
+				s3_prefix   = "vault/"
+				s3_endpoint = "https://minio.internal:9000/"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.RaftSnapshot, ShouldResemble, &RaftSnapshotConfig{
+			Schedule:    "@every 6h",
+			Retain:      7,
+			S3_bucket:   "backups",
+			S3_prefix:   "vault/",
+			S3_region:   "us-east-1",
+			S3_endpoint: "https://minio.internal:9000",
+		})
+
+		for _, raftSnapshot := range []string{
+			`raft_snapshot { local_dir = "/var/backups/vault" }`,
+			`raft_snapshot {
+				schedule = "whenever"
+				local_dir = "/var/backups/vault"
+			}`,
+			`raft_snapshot { schedule = "@every 6h" }`,
+			`raft_snapshot {
+				schedule  = "@every 6h"
+				local_dir = "/var/backups/vault"
+				s3_bucket = "backups"
+			}`,
+			`raft_snapshot {
+				schedule  = "@every 6h"
+				local_dir = "/var/backups/vault"
+				retain    = -1
+			}`,
+			`raft_snapshot {
+				schedule           = "@every 6h"
+				s3_bucket          = "backups"
+				s3_access_key_file = "/etc/goldfish/s3_access_key"
+			}`,
+		} {
+			_, err := ParseConfig(`
+				listener "tcp" {
+					address = "127.0.0.1:8000"
+				}
+				vault {
+					address         = "http://127.0.0.1:8200"
+				}
+				` + raftSnapshot)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Parser should accept valid string - oidc", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			oidc {
+				discovery_url      = "https://login.example.com/"
+				client_id          = "goldfish"
+				client_secret_file = "/etc/goldfish/oidc_client_secret"
+				redirect_url       = "https://goldfish.example.com/goldfish/v1/login/oidc/callback"
+				scopes             = "email, groups"
+				vault_mount        = "/oidc/"
+				vault_role         = "goldfish"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.OIDC, ShouldResemble, &OIDCConfig{
+			Discovery_url:      "https://login.example.com",
+			Client_id:          "goldfish",
+			Client_secret_file: "/etc/goldfish/oidc_client_secret",
+			Redirect_url:       "https://goldfish.example.com/goldfish/v1/login/oidc/callback",
+			Scopes:             []string{"openid", "email", "groups"},
+			Vault_mount:        "oidc",
+			Vault_role:         "goldfish",
+		})
+
+		for _, oidc := range []string{
+			`oidc {
+				discovery_url      = "login.example.com"
+				client_id          = "goldfish"
+				client_secret_file = "/etc/goldfish/oidc_client_secret"
+				redirect_url       = "https://goldfish.example.com/v1/login/oidc/callback"
+				vault_role         = "goldfish"
+			}`,
+			`oidc {
+				discovery_url      = "https://login.example.com"
+				client_id          = "goldfish"
+				client_secret_file = "/etc/goldfish/oidc_client_secret"
+				redirect_url       = "https://goldfish.example.com/callback"
+				vault_role         = "goldfish"
+			}`,
+			`oidc {
+				discovery_url = "https://login.example.com"
+				client_id     = "goldfish"
+				redirect_url  = "https://goldfish.example.com/v1/login/oidc/callback"
+				vault_role    = "goldfish"
+			}`,
+			`oidc {
+				discovery_url      = "https://login.example.com"
+				client_id          = "goldfish"
+				client_secret_file = "/etc/goldfish/oidc_client_secret"
+				redirect_url       = "https://goldfish.example.com/v1/login/oidc/callback"
+			}`,
+		} {
+			_, err := ParseConfig(`
+				listener "tcp" {
+					address = "127.0.0.1:8000"
+				}
+				vault {
+					address         = "http://127.0.0.1:8200"
+				}
+				` + oidc)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Parser should accept valid string - chatops", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			chatops {
+				signing_secret_file = "/etc/goldfish/slack_signing_secret"
+				approvers           = "U012AB3CD=ldap-alice, U045EF6GH = ldap-bob"
+				ui_address          = "https://goldfish.example.com/"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.ChatOps, ShouldResemble, &ChatOpsConfig{
+			Signing_secret_file: "/etc/goldfish/slack_signing_secret",
+			Approvers: map[string]string{
+				"U012AB3CD": "ldap-alice",
+				"U045EF6GH": "ldap-bob",
+			},
+			Ui_address: "https://goldfish.example.com",
+		})
+
+		for _, chatOps := range []string{
+			`chatops { approvers = "U012AB3CD=ldap-alice" }`,
+			`chatops { signing_secret_file = "/etc/goldfish/slack_signing_secret" }`,
+			`chatops {
+				signing_secret_file = "/etc/goldfish/slack_signing_secret"
+				approvers           = "ldap-alice"
+			}`,
+			`chatops {
+				signing_secret_file = "/etc/goldfish/slack_signing_secret"
+				approvers           = "U012AB3CD=ldap-alice"
+				ui_address          = "goldfish.example.com"
+			}`,
+		} {
+			_, err := ParseConfig(`
+				listener "tcp" {
+					address = "127.0.0.1:8000"
+				}
+				vault {
+					address         = "http://127.0.0.1:8200"
+				}
+				` + chatOps)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Parser should accept valid string - notifiers and reports", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			report "certs" {
+				type       = "expiring_certs"
+				schedule   = "0 9 * * 1-5"
+				notify     = "ops, security"
+				window     = "720h"
+				pki_mounts = "pki, pki_int"
+			}
+			notifier "ops" {
+				type    = "slack"
+				channel = "#ops"
+			}
+			notifier "security" {
+				type         = "email"
+				smtp_address = "smtp.example.com:587"
+				from         = "goldfish@example.com"
+				to           = "security@example.com, ops@example.com"
+			}
+			notifier "oncall" {
+				type     = "pagerduty"
+				key_file = "/etc/goldfish/pagerduty_key"
+			}
+			report "stale" {
+				type     = "stale_requests"
+				schedule = "*/15 * * * *"
+				notify   = "oncall"
+				window   = "4h"
+			}
+			report "untagged" {
+				type     = "untagged_secrets"
+				schedule = "@weekly"
+				notify   = "security"
+				paths    = "secret/, kv/"
+			}
+			report "rotations" {
+				type     = "overdue_rotations"
+				schedule = "0 9 * * 1"
+				notify   = "security"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Notifiers["ops"], ShouldResemble, &NotifierConfig{
+			Name:    "ops",
+			Type:    "slack",
+			Channel: "#ops",
+		})
+		So(cfg.Notifiers["security"].To, ShouldResemble, []string{"security@example.com", "ops@example.com"})
+		So(cfg.Reports["certs"], ShouldResemble, &ReportConfig{
+			Name:       "certs",
+			Type:       "expiring_certs",
+			Schedule:   "0 9 * * 1-5",
+			Notify:     []string{"ops", "security"},
+			Window:     720 * time.Hour,
+			Pki_mounts: []string{"pki", "pki_int"},
+		})
+		So(cfg.Notifiers["oncall"].Key_file, ShouldEqual, "/etc/goldfish/pagerduty_key")
+		So(cfg.Reports["stale"].Window, ShouldEqual, 4*time.Hour)
+		So(cfg.Reports["untagged"].Paths, ShouldResemble, []string{"secret/", "kv/"})
+		So(cfg.Reports["rotations"].Type, ShouldEqual, "overdue_rotations")
+	})
+
+	Convey("Parser should reject invalid notifiers and reports", t, func() {
+		for _, block := range []string{
+			`notifier "ops" { type = "pager" }`,
+			`notifier "ops" { type = "webhook" }`,
+			`notifier "ops" { type = "webhook", url = "ftp://example.com" }`,
+			`notifier "ops" { type = "email", smtp_address = "smtp.example.com", from = "a@example.com", to = "b@example.com" }`,
+			`notifier "ops" { type = "email", smtp_address = "smtp.example.com:25" }`,
+			`notifier "ops" { type = "opsgenie" }`,
+			`notifier "ops" { type = "pagerduty", key_file = "/etc/goldfish/pagerduty_key" }
+			report "stale" { type = "stale_requests", schedule = "@hourly", notify = "ops" }`,
+			`report "tokens" { type = "expiring_tokens", schedule = "@daily", notify = "ops" }`,
+			`notifier "ops" { type = "slack" }
+			report "tokens" { type = "expired_tokens", schedule = "@daily", notify = "ops" }`,
+			`notifier "ops" { type = "slack" }
+			report "tokens" { type = "expiring_tokens", schedule = "0 25 * * *", notify = "ops" }`,
+			`notifier "ops" { type = "slack" }
+			report "tokens" { type = "expiring_tokens", schedule = "@daily" }`,
+			`notifier "ops" { type = "slack" }
+			report "certs" { type = "expiring_certs", schedule = "@daily", notify = "ops" }`,
+			`notifier "ops" { type = "slack" }
+			report "untagged" { type = "untagged_secrets", schedule = "@daily", notify = "ops" }`,
+			`notifier "ops" { type = "slack" }
+			report "untagged" { type = "untagged_secrets", schedule = "@daily", notify = "ops", paths = "secret" }`,
+		} {
+			_, err := ParseConfig(`
+				listener "tcp" {
+					address = "127.0.0.1:8000"
+				}
+				vault {
+					address         = "http://127.0.0.1:8200"
+				}
+				` + block)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Parser should accept valid string - audit_source", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			audit_source {
+				type          = "socket"
+				address       = "0.0.0.0:9090"
+				allowed_cidrs = "10.0.0.0/24, 10.0.1.5"
+				bucket        = "15m"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.AuditSource, ShouldResemble, &AuditSourceConfig{
+			Type:          "socket",
+			Address:       "0.0.0.0:9090",
+			Allowed_cidrs: []string{"10.0.0.0/24", "10.0.1.5/32"},
+			Retention:     168 * time.Hour,
+			Bucket:        15 * time.Minute,
+		})
+
+		// without a host, the socket is only on loopback
+		cfg, err = ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			audit_source {
+				type    = "socket"
+				address = ":9090"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.AuditSource.Address, ShouldEqual, "127.0.0.1:9090")
+
+		for _, auditSource := range []string{
+			`audit_source { type = "syslog" }`,
+			`audit_source { type = "file" }`,
+			`audit_source { type = "socket", address = "9090" }`,
+			`audit_source { type = "socket", address = "0.0.0.0:9090" }`,
+			`audit_source { type = "socket", address = "127.0.0.1:9090", allowed_cidrs = "vault" }`,
+			`audit_source {
+				type    = "file"
+				path    = "/var/log/vault/audit.log"
+				address = "0.0.0.0:9090"
+			}`,
+			`audit_source {
+				type   = "file"
+				path   = "/var/log/vault/audit.log"
+				bucket = "10s"
+			}`,
+			`audit_source {
+				type      = "file"
+				path      = "/var/log/vault/audit.log"
+				retention = "8760h"
+				bucket    = "1m"
+			}`,
+		} {
+			_, err := ParseConfig(`
+				listener "tcp" {
+					address = "127.0.0.1:8000"
+				}
+				vault {
+					address         = "http://127.0.0.1:8200"
+				}
+				` + auditSource)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Parser should accept valid string - policy lints", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			policy_lint "no-sys-sudo" {
+				type         = "forbid_capabilities"
+				paths        = "sys/*"
+				capabilities = "sudo"
+			}
+			policy_lint "admin" {
+				type    = "require_deny"
+				paths   = "secret/admin/*"
+				message = "secret/admin is for break glass only"
+			}
+			policy_lint "naming" {
+				type    = "policy_name"
+				pattern = "^team-[a-z]+-(ro|rw)$"
+				action  = "warn"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.PolicyLints["no-sys-sudo"], ShouldResemble, &PolicyLintConfig{
+			Name:         "no-sys-sudo",
+			Type:         "forbid_capabilities",
+			Paths:        []string{"sys/*"},
+			Capabilities: []string{"sudo"},
+			Action:       "block",
+		})
+		So(cfg.PolicyLints["admin"].Message, ShouldEqual, "secret/admin is for break glass only")
+		So(cfg.PolicyLints["naming"].Action, ShouldEqual, "warn")
+
+		for _, block := range []string{
+			`policy_lint "a" { type = "forbid_paths", paths = "sys/*" }`,
+			`policy_lint "a" { type = "forbid_capabilities" }`,
+			`policy_lint "a" { type = "forbid_capabilities", paths = "sys/*", capabilities = "write" }`,
+			`policy_lint "a" { type = "require_deny", paths = "secret/*", action = "ignore" }`,
+			`policy_lint "a" { type = "policy_name" }`,
+			`policy_lint "a" { type = "policy_name", pattern = "team-(" }`,
+		} {
+			_, err := ParseConfig(`
+				listener "tcp" {
+					address = "127.0.0.1:8000"
+				}
+				vault {
+					address         = "http://127.0.0.1:8200"
+				}
+				` + block)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Parser should accept valid string - read only", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			read_only = 1
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.ReadOnly, ShouldBeTrue)
+
+		_, err = ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			read_only = "maybe"
+			`)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Parser should accept valid string - require confirmation", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			require_confirmation = 1
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.RequireConfirmation, ShouldBeTrue)
+	})
+
+	Convey("Parser should accept valid string - rollback requires approval", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			rollback_requires_approval = 1
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.RollbackApproval, ShouldBeTrue)
+	})
+
+	Convey("Parser should accept valid string - update check", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			update_check = 1
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.UpdateCheck, ShouldBeTrue)
+	})
+
+	Convey("Parser should accept valid string - status page", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			status_page = 1
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.StatusPage, ShouldBeTrue)
+	})
+
+	Convey("Parser should accept valid string - settings path", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+				settings_path   = "secret/goldfish-settings"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Vault.Settings_path, ShouldEqual, "secret/goldfish-settings")
+	})
+
+	Convey("Parser should accept valid string - state path", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+				state_path      = "secret/goldfish-state/"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Vault.State_path, ShouldEqual, "secret/goldfish-state")
+	})
+
+	Convey("Parser should accept valid string - swagger ui", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address    = "127.0.0.1:8000"
+				swagger_ui = 1
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Listener.Swagger_ui, ShouldBeTrue)
+
+		_, err = ParseConfig(`
+			listener "tcp" {
+				address    = "127.0.0.1:8000"
+				swagger_ui = 2
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Parser should accept valid string - air gapped", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address     = "127.0.0.1:8000"
+				tls_disable = 1
+				csp         = "default-src 'self' https://api.github.com; img-src 'self' data: https:; sandbox allow-forms"
+				swagger_ui  = 1
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			air_gapped = 1
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.AirGapped, ShouldBeTrue)
+		So(cfg.Listener.Swagger_ui, ShouldBeTrue)
+		So(cfg.Listener.Csp, ShouldEqual, "default-src 'self'; img-src 'self' data:; sandbox allow-forms")
+	})
+
+	Convey("Parser should reject anything that needs the internet when air gapped", t, func() {
+		for _, body := range []string{
+			`listener "tcp" {
+				address = "127.0.0.1:8000"
+			}`,
+			`listener "tcp" {
+				address     = "127.0.0.1:8000"
+				tls_disable = 1
+			}
+			update_check = 1`,
+			`listener "tcp" {
+				address     = "127.0.0.1:8000"
+				tls_disable = 1
+			}
+			notifier "ops" { type = "slack" }`,
+		} {
+			_, err := ParseConfig(body + `
+				vault {
+					address         = "http://127.0.0.1:8200"
+				}
+				air_gapped = 1
+				`)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Parser should accept valid string - assets dir", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address    = "127.0.0.1:8000"
+				assets_dir = "/etc/goldfish/assets"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Listener.Assets_dir, ShouldEqual, "/etc/goldfish/assets")
+	})
+
+	Convey("Parser should accept valid string - unix listener", t, func() {
+		cfg, err := ParseConfig(`
+			listener "unix" {
+				address      = "/run/goldfish/goldfish.sock"
+				tls_disable  = 1
+				socket_mode  = "0600"
+				socket_group = "nginx"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Listener.Type, ShouldEqual, "unix")
+		So(cfg.Listener.Socket_mode, ShouldEqual, os.FileMode(0600))
+		So(cfg.Listener.Socket_user, ShouldEqual, "")
+		So(cfg.Listener.Socket_group, ShouldEqual, "nginx")
+	})
+
+	Convey("Parser should reject invalid unix listeners", t, func() {
+		listeners := []string{
+			// let's encrypt needs a public tcp listener
+			`listener "unix" {
+				address = "/run/goldfish/goldfish.sock"
+			}`,
+			`listener "unix" {
+				address       = "/run/goldfish/goldfish.sock"
+				tls_disable   = 1
+				allowed_cidrs = "10.0.0.0/8"
+			}`,
+			`listener "unix" {
+				address     = "/run/goldfish/goldfish.sock"
+				tls_disable = 1
+				socket_mode = "rw-rw----"
+			}`,
+			`listener "tcp" {
+				address     = "127.0.0.1:8000"
+				socket_mode = "0660"
+			}`,
+			`listener "udp" {
+				address     = "127.0.0.1:8000"
+			}`,
+		}
+		for _, listener := range listeners {
+			_, err := ParseConfig(listener + `
+				vault {
+					address         = "http://127.0.0.1:8200"
+				}
+				`)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Parser should accept valid string - multiple listeners", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address       = ":443"
+				tls_cert_file = "/tmp/cert.pem"
+				tls_key_file  = "/tmp/key.pem"
+			}
+			listener "tcp" {
+				address     = "10.0.0.1:8000"
+				tls_disable = 1
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Listener.Address, ShouldEqual, ":443")
+		So(cfg.ExtraListeners, ShouldHaveLength, 1)
+		So(cfg.ExtraListeners[0].Address, ShouldEqual, "10.0.0.1:8000")
+		So(cfg.ExtraListeners[0].Tls_disable, ShouldBeTrue)
+		So(cfg.Listeners(), ShouldResemble, []*ListenerConfig{cfg.Listener, cfg.ExtraListeners[0]})
+	})
+
+	Convey("Parser should reject conflicting listeners", t, func() {
+		listeners := []string{
+			`listener "tcp" {
+				address     = ":8000"
+				tls_disable = 1
+			}
+			listener "tcp" {
+				address     = ":8000"
+				tls_disable = 1
+			}`,
+			`listener "tcp" {
+				address     = ":8000"
+				tls_disable = 1
+			}
+			listener "tcp" {
+				address            = ":8001"
+				tls_disable        = 1
+				login_max_attempts = 3
+			}`,
+		}
+		for _, listener := range listeners {
+			_, err := ParseConfig(listener + `
+				vault {
+					address         = "http://127.0.0.1:8200"
+				}
+				`)
+			So(err, ShouldNotBeNil)
+		}
+	})
+
+	Convey("Parser should accept valid string - ready while sealed", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address            = "http://127.0.0.1:8200"
+				ready_while_sealed = 1
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Vault.Ready_while_sealed, ShouldBeTrue)
+	})
+
+	Convey("Parser should accept valid string - health history", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address               = "http://127.0.0.1:8200"
+				health_check_interval = "30s"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Vault.Health_check_interval, ShouldEqual, 30 * time.Second)
+		So(cfg.Vault.Health_history, ShouldEqual, 24 * time.Hour)
+	})
+
+	Convey("Parser should reject invalid vault - health check interval too short", t, func() {
+		_, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address               = "http://127.0.0.1:8200"
+				health_check_interval = "100ms"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Parser should accept valid string - shutdown timeout", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+				shutdown_timeout = "1m"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Listener.Shutdown_timeout, ShouldEqual, time.Minute)
+	})
+
+	Convey("Parser should accept valid string - server limits and timeouts", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+				body_limit       = "256M"
+				gzip_level       = 0
+				read_timeout     = "1m"
+				write_timeout    = "10m"
+				idle_timeout     = "2m"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Listener.Body_limit, ShouldEqual, "256M")
+		So(cfg.Listener.Gzip_level, ShouldEqual, 0)
+		So(cfg.Listener.Read_timeout, ShouldEqual, time.Minute)
+		So(cfg.Listener.Write_timeout, ShouldEqual, 10*time.Minute)
+		So(cfg.Listener.Idle_timeout, ShouldEqual, 2*time.Minute)
+	})
+
+	Convey("Parser should reject invalid strings - body limit", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+				body_limit       = "lots"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should accept valid string - telemetry", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			telemetry {
+				prometheus_address    = "127.0.0.1:9000"
+				prometheus_token_file = "/etc/goldfish/metrics_token"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Telemetry, ShouldResemble, &TelemetryConfig {
+			Prometheus_address:    "127.0.0.1:9000",
+			Prometheus_token_file: "/etc/goldfish/metrics_token",
+		})
+	})
+
+	Convey("Parser should reject invalid telemetry - address conflicts with disable", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			telemetry {
+				prometheus_disable = 1
+				prometheus_address = "127.0.0.1:9000"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should accept valid string - tracing", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			telemetry {
+				otlp_endpoint      = "http://127.0.0.1:4318"
+				otlp_headers       = "authorization=Bearer abc, x-scope-orgid=goldfish"
+				trace_sample_ratio = 0.25
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Telemetry, ShouldResemble, &TelemetryConfig {
+			Otlp_endpoint:      "http://127.0.0.1:4318",
+			Otlp_headers:       map[string]string{"authorization": "Bearer abc", "x-scope-orgid": "goldfish"},
+			Trace_sample_ratio: 0.25,
+		})
+	})
+
+	Convey("Parser should reject invalid telemetry - trace sample ratio out of range", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			telemetry {
+				otlp_endpoint      = "http://127.0.0.1:4318"
+				trace_sample_ratio = 2
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should accept valid string - statsd", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			telemetry {
+				prometheus_disable = 1
+				statsd_address     = "127.0.0.1:8125"
+				statsd_tags        = "env:prod, team:platform"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Telemetry, ShouldResemble, &TelemetryConfig {
+			Prometheus_disable: true,
+			Statsd_address:     "127.0.0.1:8125",
+			Statsd_prefix:      "goldfish.",
+			Statsd_tags:        []string{"env:prod", "team:platform"},
+		})
+	})
+
+	Convey("Parser should accept valid string - rate limit", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			rate_limit {
+				requests_per_second = 2.5
+				routes              = "/v1/login=0.2:5, /v1/bulletins=0"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.RateLimit, ShouldResemble, &RateLimitConfig {
+			Requests_per_second: 2.5,
+			Burst:               3,
+			Key:                 "session",
+			Routes: map[string]RouteLimitConfig {
+				"/v1/login":     { Requests_per_second: 0.2, Burst: 5 },
+				"/v1/bulletins": {},
+			},
+		})
+	})
+
+	Convey("Parser should reject invalid rate limit - burst without a rate", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			rate_limit {
+				burst = 10
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should accept valid string - session", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			session {
+				store          = "redis"
+				redis_address  = "127.0.0.1:6379"
+				redis_password = "hunter2"
+				redis_db       = 3
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Session, ShouldResemble, &SessionConfig {
+			Store:          "redis",
+			Redis_address:  "127.0.0.1:6379",
+			Redis_password: "hunter2",
+			Redis_db:       3,
+		})
+	})
+
+	Convey("Parser should accept valid string - session transit key", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			session {
+				transit_key = "goldfish-sessions"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Session, ShouldResemble, &SessionConfig {
+			Store:           "memory",
+			Transit_key:     "goldfish-sessions",
+			Transit_backend: "transit",
+		})
+	})
+
+	Convey("Parser should reject invalid session - transit backend without key", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			session {
+				transit_backend = "transit"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should accept valid string - session timeouts", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			session {
+				ttl              = "8h"
+				idle_timeout     = "30m"
+				absolute_timeout = 86400
+				step_up_window   = "5m"
+				admin_path       = "/secret/goldfish-admin"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Session, ShouldResemble, &SessionConfig {
+			Store:            "memory",
+			Ttl:              8 * time.Hour,
+			Idle_timeout:     30 * time.Minute,
+			Absolute_timeout: 24 * time.Hour,
+			Step_up_window:   5 * time.Minute,
+			Admin_path:       "secret/goldfish-admin",
+		})
+	})
+
+	Convey("Parser should reject invalid session - ttl beyond absolute timeout", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			session {
+				ttl              = "8h"
+				absolute_timeout = "1h"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should reject invalid session - unknown store", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			session {
+				store = "bolt"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Parser should reject invalid session - file store without path", t, func() {
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			session {
+				store = "file"
+			}
+			`)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Env variables should override config file values", t, func() {
+		os.Setenv("GOLDFISH_VAULT_ADDR", "https://vault.example.com:8200")
+		os.Setenv("GOLDFISH_LISTENER_TLS_DISABLE", "1")
+		defer os.Unsetenv("GOLDFISH_VAULT_ADDR")
+		defer os.Unsetenv("GOLDFISH_LISTENER_TLS_DISABLE")
+
+		cfg, err := ParseConfig(`
+			listener "tcp" {
+				address          = "127.0.0.1:8000"
+			}
+			vault {
+				address         = "http://127.0.0.1:8200"
+			}
+			`)
+		So(err, ShouldBeNil)
+		So(cfg.Vault.Address, ShouldEqual, "https://vault.example.com:8200")
+		So(cfg.Listener.Tls_disable, ShouldBeTrue)
+	})
+
+	Convey("Env variables should be enough without a config file", t, func() {
+		os.Setenv("GOLDFISH_LISTENER_ADDRESS", ":8000")
+		os.Setenv("GOLDFISH_VAULT_ADDRESS", "http://127.0.0.1:8200")
+		defer os.Unsetenv("GOLDFISH_LISTENER_ADDRESS")
+		defer os.Unsetenv("GOLDFISH_VAULT_ADDRESS")
+
+		cfg, err := LoadConfigFile("")
+		So(err, ShouldBeNil)
+		So(cfg.Listener.Address, ShouldEqual, ":8000")
+		So(cfg.Vault.Address, ShouldEqual, "http://127.0.0.1:8200")
+		So(cfg.Vault.Approle_id, ShouldEqual, "goldfish")
+	})
+
+	Convey("Env variables should create blocks the config file lacks", t, func() {
+		os.Setenv("GOLDFISH_RATE_LIMIT_REQUESTS_PER_SECOND", "5")
+		defer os.Unsetenv("GOLDFISH_RATE_LIMIT_REQUESTS_PER_SECOND")
+
+		cfg, err := ParseConfig(defaultConfigString)
+		So(err, ShouldBeNil)
+		So(cfg.RateLimit.Requests_per_second, ShouldEqual, 5)
+	})
+
+	Convey("Unknown GOLDFISH_ env variables should not replace a config file", t, func() {
+		os.Setenv("GOLDFISH_DEPLOY_ENV", "staging")
+		defer os.Unsetenv("GOLDFISH_DEPLOY_ENV")
+
+		cfg, err := LoadConfigFile("")
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
+	Convey("Config diff should list changed fields", t, func() {
+		a, err := ParseConfig(defaultConfigString)
+		So(err, ShouldBeNil)
+		b, err := ParseConfig(defaultConfigString)
+		So(err, ShouldBeNil)
+		So(Diff(a, b), ShouldBeEmpty)
+
+		b.Vault.Address = "http://127.0.0.1:8201"
+		b.DisableMlock = true
+		So(Diff(a, b), ShouldResemble, []string{
+			"vault.address: http://127.0.0.1:8200 -> http://127.0.0.1:8201",
+			"disable_mlock: false -> true",
+		})
+
+		b.ExtraListeners = []*ListenerConfig{{Type: "tcp", Address: ":8001"}}
+		So(Diff(a, b), ShouldContain, "listener.2: block added or removed")
+	})
+
 	Convey("Starting up a dev vault", t, func() {
 		cfg, shutdownCh, _, secretID, err := LoadConfigDev()
 		So(err, ShouldBeNil)
@@ -317,6 +2150,34 @@ func TestConfigParser(t *testing.T) {
 		So(cfg, ShouldResemble, sampleParsedConfig)
 	})
 
+	Convey("Loading valid custom config - json", t, func() {
+		cfg, err := LoadConfigFile("sample.json")
+		So(err, ShouldBeNil)
+		So(cfg, ShouldResemble, sampleParsedConfig)
+	})
+
+	Convey("Config format should be sniffed without a known extension", t, func() {
+		So(isJSON("goldfish.conf", []byte("\n  {\"vault\": {}}")), ShouldBeTrue)
+		So(isJSON("goldfish", []byte("listener \"tcp\" {}")), ShouldBeFalse)
+		So(isJSON("goldfish.hcl", []byte("{}")), ShouldBeFalse)
+		So(isJSON("goldfish.JSON", []byte("")), ShouldBeTrue)
+	})
+
+	Convey("Parser should accept valid json string - booleans", t, func() {
+		cfg, err := ParseConfigJSON(`{
+			"listener": { "tcp": { "address": "127.0.0.1:8000", "tls_disable": true } },
+			"vault": { "address": "http://127.0.0.1:8200" }
+		}`)
+		So(err, ShouldBeNil)
+		So(cfg.Listener.Tls_disable, ShouldBeTrue)
+	})
+
+	Convey("Parser should reject invalid json string", t, func() {
+		cfg, err := ParseConfigJSON(`{ "listener": { "tcp": { "address": "127.0.0.1:8000" } }, `)
+		So(err, ShouldNotBeNil)
+		So(cfg, ShouldBeNil)
+	})
+
 	Convey("Loading invalid custom config - no file specified", t, func() {
 		cfg, err := LoadConfigFile("")
 		So(err, ShouldNotBeNil)
@@ -349,9 +2210,23 @@ vault {
 
 var defaultParsedConfig = &Config {
 	Listener: &ListenerConfig {
-		Type:        "tcp",
-		Address:     "127.0.0.1:8000",
-		Tls_disable: true,
+		Type:                 "tcp",
+		Address:              "127.0.0.1:8000",
+		Tls_disable:          true,
+		Login_max_attempts:   5,
+		Login_backoff:        time.Second,
+		Login_lockout:        15 * time.Minute,
+		Shutdown_timeout:     20 * time.Second,
+		Body_limit:           "32M",
+		Gzip_level:           5,
+		Read_timeout:         10 * time.Second,
+		Write_timeout:        2 * time.Minute,
+		Security_preset:      "default",
+		Csp:                  "default-src 'self' https://api.github.com",
+		Frame_options:        "SAMEORIGIN",
+		Tls_min_version:      "tls12",
+		Tls_redirect_address: ":80",
+		Autocert_cache_dir:   "/var/www/.cache",
 	},
 	Vault: &VaultConfig {
 		Type:           "vault",
@@ -359,15 +2234,42 @@ var defaultParsedConfig = &Config {
 		Runtime_config: "secret/goldfish",
 		Approle_login:  "auth/approle/login",
 		Approle_id:     "goldfish",
+		Kubernetes_login:    "auth/kubernetes/login",
+		Kubernetes_jwt_file: "/var/run/secrets/kubernetes.io/serviceaccount/token",
+		Timeout:        60 * time.Second,
+		List_timeout:   2 * time.Minute,
+		Max_retries:    2,
+		List_max_items: 100000,
+		Retry_wait_min: 500 * time.Millisecond,
+		Retry_wait_max: 5 * time.Second,
+		Health_check_interval: 5 * time.Second,
+		Health_history: 24 * time.Hour,
 	},
+	Telemetry: &TelemetryConfig {},
+	Session:   &SessionConfig { Store: "memory" },
+	RateLimit: &RateLimitConfig {},
 	DisableMlock: false,
 }
 
 var devParsedConfig = &Config {
 	Listener: &ListenerConfig {
-		Type:        "tcp",
-		Address:     "127.0.0.1:8000",
-		Tls_disable: true,
+		Type:                 "tcp",
+		Address:              "127.0.0.1:8000",
+		Tls_disable:          true,
+		Login_max_attempts:   5,
+		Login_backoff:        time.Second,
+		Login_lockout:        15 * time.Minute,
+		Shutdown_timeout:     20 * time.Second,
+		Body_limit:           "32M",
+		Gzip_level:           5,
+		Read_timeout:         10 * time.Second,
+		Write_timeout:        2 * time.Minute,
+		Security_preset:      "default",
+		Csp:                  "default-src 'self' https://api.github.com",
+		Frame_options:        "SAMEORIGIN",
+		Tls_min_version:      "tls12",
+		Tls_redirect_address: ":80",
+		Autocert_cache_dir:   "/var/www/.cache",
 	},
 	Vault: &VaultConfig {
 		Type:           "vault",
@@ -375,15 +2277,40 @@ var devParsedConfig = &Config {
 		Runtime_config: "secret/goldfish",
 		Approle_login:  "auth/approle/login",
 		Approle_id:     "goldfish",
+		Timeout:        60 * time.Second,
+		List_timeout:   2 * time.Minute,
+		Max_retries:    2,
+		List_max_items: 100000,
+		Retry_wait_min: 500 * time.Millisecond,
+		Retry_wait_max: 5 * time.Second,
+		Health_check_interval: 5 * time.Second,
+		Health_history: 24 * time.Hour,
 	},
+	Telemetry: &TelemetryConfig {},
+	Session:   &SessionConfig { Store: "memory" },
+	RateLimit: &RateLimitConfig {},
 	DisableMlock: true,
 }
 
 var sampleParsedConfig = &Config {
 	Listener: &ListenerConfig {
-		Type:        "tcp",
-		Address:     "127.0.0.1:8000",
-		Tls_disable: true,
+		Type:                 "tcp",
+		Address:              "127.0.0.1:8000",
+		Tls_disable:          true,
+		Login_max_attempts:   5,
+		Login_backoff:        time.Second,
+		Login_lockout:        15 * time.Minute,
+		Shutdown_timeout:     20 * time.Second,
+		Body_limit:           "32M",
+		Gzip_level:           5,
+		Read_timeout:         10 * time.Second,
+		Write_timeout:        2 * time.Minute,
+		Security_preset:      "default",
+		Csp:                  "default-src 'self' https://api.github.com",
+		Frame_options:        "SAMEORIGIN",
+		Tls_min_version:      "tls12",
+		Tls_redirect_address: ":80",
+		Autocert_cache_dir:   "/var/www/.cache",
 	},
 	Vault: &VaultConfig {
 		Type:           "vault",
@@ -391,7 +2318,26 @@ var sampleParsedConfig = &Config {
 		Runtime_config: "secret/goldfish",
 		Approle_login:  "auth/approle/login",
 		Approle_id:     "goldfish",
+		Kubernetes_login:    "auth/kubernetes/login",
+		Kubernetes_jwt_file: "/var/run/secrets/kubernetes.io/serviceaccount/token",
+		Timeout:        60 * time.Second,
+		List_timeout:   2 * time.Minute,
+		Max_retries:    2,
+		List_max_items: 100000,
+		Retry_wait_min: 500 * time.Millisecond,
+		Retry_wait_max: 5 * time.Second,
+		Health_check_interval: 5 * time.Second,
+		Health_history: 24 * time.Hour,
 	},
+	Telemetry: &TelemetryConfig {},
+	Session:   &SessionConfig { Store: "memory" },
+	RateLimit: &RateLimitConfig {},
 	DisableMlock: false,
 	DisableMlockRaw: 0,
+	ReadOnlyRaw: 0,
+	RequireConfirmationRaw: 0,
+	RollbackApprovalRaw: 0,
+	UpdateCheckRaw: 0,
+	StatusPageRaw: 0,
+	AirGappedRaw: 0,
 }
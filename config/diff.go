@@ -0,0 +1,207 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// returns a human readable list of differences between two configs
+func Diff(old, new *Config) []string {
+	var changes []string
+	changes = append(changes, diffStruct("listener", old.Listener, new.Listener)...)
+	for i := 0; i < len(old.ExtraListeners) || i < len(new.ExtraListeners); i++ {
+		changes = append(changes, diffStruct(fmt.Sprintf("listener.%d", i+2), extraListener(old, i), extraListener(new, i))...)
+	}
+	changes = append(changes, diffStruct("vault", old.Vault, new.Vault)...)
+	changes = append(changes, diffStruct("telemetry", old.Telemetry, new.Telemetry)...)
+	changes = append(changes, diffStruct("session", old.Session, new.Session)...)
+	changes = append(changes, diffStruct("branding", old.Branding, new.Branding)...)
+	changes = append(changes, diffStruct("token_creation", old.TokenCreation, new.TokenCreation)...)
+	changes = append(changes, diffStruct("chatops", old.ChatOps, new.ChatOps)...)
+	changes = append(changes, diffStruct("oidc", old.OIDC, new.OIDC)...)
+	changes = append(changes, diffStruct("raft_snapshot", old.RaftSnapshot, new.RaftSnapshot)...)
+	changes = append(changes, diffStruct("audit_source", old.AuditSource, new.AuditSource)...)
+	for _, name := range clusterNames(old, new) {
+		changes = append(changes, diffStruct("cluster."+name, old.Clusters[name], new.Clusters[name])...)
+	}
+	for _, name := range loginNames(old, new) {
+		changes = append(changes, diffStruct("login."+name, old.Logins[name], new.Logins[name])...)
+	}
+	for _, name := range roleNames(old, new) {
+		changes = append(changes, diffStruct("role."+name, old.Roles[name], new.Roles[name])...)
+	}
+	for _, name := range notifierNames(old, new) {
+		changes = append(changes, diffStruct("notifier."+name, old.Notifiers[name], new.Notifiers[name])...)
+	}
+	for _, name := range reportNames(old, new) {
+		changes = append(changes, diffStruct("report."+name, old.Reports[name], new.Reports[name])...)
+	}
+	for _, name := range policyLintNames(old, new) {
+		changes = append(changes, diffStruct("policy_lint."+name, old.PolicyLints[name], new.PolicyLints[name])...)
+	}
+	if old.DisableMlock != new.DisableMlock {
+		changes = append(changes, fmt.Sprintf("disable_mlock: %v -> %v", old.DisableMlock, new.DisableMlock))
+	}
+	if old.ReadOnly != new.ReadOnly {
+		changes = append(changes, fmt.Sprintf("read_only: %v -> %v", old.ReadOnly, new.ReadOnly))
+	}
+	if old.RequireConfirmation != new.RequireConfirmation {
+		changes = append(changes, fmt.Sprintf("require_confirmation: %v -> %v", old.RequireConfirmation, new.RequireConfirmation))
+	}
+	if old.RollbackApproval != new.RollbackApproval {
+		changes = append(changes, fmt.Sprintf("rollback_requires_approval: %v -> %v", old.RollbackApproval, new.RollbackApproval))
+	}
+	if old.AirGapped != new.AirGapped {
+		changes = append(changes, fmt.Sprintf("air_gapped: %v -> %v", old.AirGapped, new.AirGapped))
+	}
+	if old.UpdateCheck != new.UpdateCheck {
+		changes = append(changes, fmt.Sprintf("update_check: %v -> %v", old.UpdateCheck, new.UpdateCheck))
+	}
+	if old.StatusPage != new.StatusPage {
+		changes = append(changes, fmt.Sprintf("status_page: %v -> %v", old.StatusPage, new.StatusPage))
+	}
+	return changes
+}
+
+func diffStruct(prefix string, a, b interface{}) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.IsNil() || vb.IsNil() {
+		if va.IsNil() != vb.IsNil() {
+			return []string{prefix + ": block added or removed"}
+		}
+		return nil
+	}
+	va, vb = va.Elem(), vb.Elem()
+
+	var changes []string
+	for i := 0; i < va.NumField(); i++ {
+		x, y := va.Field(i).Interface(), vb.Field(i).Interface()
+		if reflect.DeepEqual(x, y) {
+			continue
+		}
+		name := strings.ToLower(va.Type().Field(i).Name)
+		if isSensitive(name) {
+			changes = append(changes, prefix+"."+name+": (value changed)")
+		} else {
+			changes = append(changes, fmt.Sprintf("%s.%s: %v -> %v", prefix, name, x, y))
+		}
+	}
+	return changes
+}
+
+// nil if the config has fewer extra listeners
+func extraListener(c *Config, i int) *ListenerConfig {
+	if i < len(c.ExtraListeners) {
+		return c.ExtraListeners[i]
+	}
+	return nil
+}
+
+// sorted names of clusters in either config
+func clusterNames(old, new *Config) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, c := range []*Config{old, new} {
+		for name := range c.Clusters {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sorted names of logins in either config
+func loginNames(old, new *Config) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, c := range []*Config{old, new} {
+		for name := range c.Logins {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sorted names of roles in either config
+func roleNames(old, new *Config) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, c := range []*Config{old, new} {
+		for name := range c.Roles {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sorted names of notifiers in either config
+func notifierNames(old, new *Config) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, c := range []*Config{old, new} {
+		for name := range c.Notifiers {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sorted names of reports in either config
+func reportNames(old, new *Config) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, c := range []*Config{old, new} {
+		for name := range c.Reports {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sorted names of policy lint rules in either config
+func policyLintNames(old, new *Config) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, c := range []*Config{old, new} {
+		for name := range c.PolicyLints {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// values of these fields should never make it to the logs
+// webhook urls, such as slack's, are as good as a password
+func isSensitive(name string) bool {
+	for _, s := range []string{"secret", "password", "token", "headers", "url"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"os"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/ast"
+)
+
+// config used when no file is given, with the blocks a config requires. Env variables fill in the rest
+const envOnlyConfigString = `
+listener "tcp" {}
+vault {}
+`
+
+// shorthand names, for consistency with vault's own env variables
+var envAliases = map[string]string{
+	"GOLDFISH_VAULT_ADDR": "GOLDFISH_VAULT_ADDRESS",
+}
+
+// any config key can be overridden by GOLDFISH_<BLOCK>_<KEY>, e.g. GOLDFISH_LISTENER_TLS_DISABLE
+func envName(block, key string) string {
+	return "GOLDFISH_" + strings.ToUpper(block) + "_" + strings.ToUpper(key)
+}
+
+func lookupEnv(name string) (string, bool) {
+	if v, ok := os.LookupEnv(name); ok {
+		return v, true
+	}
+	for alias, target := range envAliases {
+		if target == name {
+			if v, ok := os.LookupEnv(alias); ok {
+				return v, true
+			}
+		}
+	}
+	return "", false
+}
+
+// overwrites values of a decoded block with any matching env variables
+func applyEnvOverrides(block string, valid []string, m map[string]string) map[string]string {
+	if m == nil {
+		m = make(map[string]string)
+	}
+	for _, key := range valid {
+		if v, ok := lookupEnv(envName(block, key)); ok {
+			m[key] = v
+		}
+	}
+	return m
+}
+
+// the blocks there is only one of, which env variables may set keys of even if the config file has no such block
+var envBlocks = map[string][]string{
+	"listener":       listenerKeys,
+	"vault":          vaultKeys,
+	"telemetry":      telemetryKeys,
+	"session":        sessionKeys,
+	"rate_limit":     rateLimitKeys,
+	"branding":       brandingKeys,
+	"token_creation": tokenCreationKeys,
+	"oidc":           oidcKeys,
+	"chatops":        chatOpsKeys,
+	"raft_snapshot":  raftSnapshotKeys,
+	"audit_source":   auditSourceKeys,
+}
+
+// root keys, overridden by GOLDFISH_<KEY>
+var envRootKeys = []string{
+	"disable_mlock",
+	"read_only",
+	"require_confirmation",
+	"rollback_requires_approval",
+	"update_check",
+	"status_page",
+	"air_gapped",
+}
+
+func blockHasEnv(block string, keys []string) bool {
+	for _, key := range keys {
+		if _, ok := lookupEnv(envName(block, key)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// adds an empty block for each one the config file lacks but env variables set keys of,
+// so that their overrides are applied like any other
+func addEnvBlocks(list *ast.ObjectList) error {
+	for block, keys := range envBlocks {
+		if len(list.Filter(block).Items) > 0 || !blockHasEnv(block, keys) {
+			continue
+		}
+		src := block + " {}"
+		if block == "listener" {
+			src = `listener "tcp" {}`
+		}
+		obj, err := hcl.Parse(src)
+		if err != nil {
+			return err
+		}
+		list.Items = append(list.Items, obj.Node.(*ast.ObjectList).Items...)
+	}
+	return nil
+}
+
+// true if any env variable sets a known key, so goldfish can run without a config file
+// other GOLDFISH_ variables, e.g. ones meant for scripts around goldfish, are not enough
+func hasEnvOverrides() bool {
+	for block, keys := range envBlocks {
+		if blockHasEnv(block, keys) {
+			return true
+		}
+	}
+	for _, key := range envRootKeys {
+		if os.Getenv("GOLDFISH_"+strings.ToUpper(key)) != "" {
+			return true
+		}
+	}
+	return false
+}
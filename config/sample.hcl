@@ -1,4 +1,14 @@
+# Every value in this file can be overridden with an env variable named
+# GOLDFISH_<BLOCK>_<KEY>, e.g. GOLDFISH_VAULT_ADDRESS or GOLDFISH_LISTENER_TLS_DISABLE
+# (GOLDFISH_VAULT_ADDR also works). Blocks there is only one of, e.g. telemetry, are created if the file has none.
+# If no config file is given, env variables alone are used.
+
 # [Required] listener defines how goldfish will listen to incoming connections
+# More listener blocks may follow, e.g. a tls_disable one on an internal address for health checks
+# and metrics. Each is served with its own settings, except login_* and shutdown_timeout, which are only read from the first
+# Under systemd, goldfish supports Type=notify (ready once bootstrapped), WatchdogSec, and socket
+# activation, where the n-th socket of the .socket unit is used instead of the n-th listener's address
+# The watchdog is only fed while vault's health checks pass, so WatchdogSec must exceed health_check_interval
 listener "tcp" {
 	# [Required] [Format: "address", "address:port", or ":port"]
 	# The address and port at which goldfish will listen from
@@ -6,6 +16,7 @@ listener "tcp" {
 	address       = "127.0.0.1:8000"
 
 	# [Required (unless tls_disable = 1)] the certificate file
+	# The certificate and key are reloaded on SIGHUP, and within seconds of changing on disk
 	tls_cert_file = ""
 
 	# [Required (unless tls_disable = 1)] the private key file
@@ -19,23 +30,215 @@ listener "tcp" {
 	# [Optional] [Default: 0] [Allowed values: 0, 1]
 	# If this is set to 1, goldfish will redirect port 80 to port 443
 	tls_autoredirect = 0
+
+	# [Optional] [Default: ":80"] The plain http address that tls_autoredirect redirects from
+	tls_redirect_address = ":80"
+
+	# If tls is enabled without tls_cert_file and tls_key_file, let's encrypt is used
+	# Certificates are only issued for the host in address, and any in autocert_hosts
+	# Let's encrypt must be able to reach this listener on port 443 for its tls-sni challenge
+
+	# [Optional] [Default: "/var/www/.cache"] Where certificates and the account key are kept
+	autocert_cache_dir   = "/var/www/.cache"
+
+	# [Optional] A contact email for the let's encrypt account, for expiry notices
+	autocert_email       = ""
+
+	# [Optional] A comma separated list of extra host names to request certificates for
+	autocert_hosts       = ""
+
+	# [Optional] Serve goldfish under a url path, e.g. "/goldfish" for https://tools.example.com/goldfish/
+	# The reverse proxy should forward the full path, without stripping the prefix
+	base_path = ""
+
+	# [Optional] [Default: 0] [Allowed values: 0, 1] Serve swagger ui for the api at /docs
+	# The openapi document at /v1/openapi.json is always served; swagger ui's files are built into goldfish
+	swagger_ui = 0
+
+	# [Optional] A directory to serve the ui's files from, e.g. for a custom logo or stylesheet
+	# Files it doesn't have, such as the rest of the ui, are served from those built into goldfish
+	assets_dir = ""
+
+	# To listen on a unix socket instead of a tcp port, e.g. behind a local nginx or envoy,
+	# use listener "unix" with address set to the socket's path, e.g. "/run/goldfish/goldfish.sock"
+	# A unix listener needs tls_disable = 1 (or cert files), and cannot use let's encrypt or cidr lists
+	# Only local processes can connect to the socket, so the X-Forwarded-For or X-Real-IP header the proxy
+	# sets is believed for login throttling, rate limits and session records
+
+	# [Optional] [Default: "0660"] The socket file's permissions, as a quoted octal string
+	# socket_mode  = "0660"
+
+	# [Optional] The user and group that own the socket file, e.g. the proxy's group
+	# socket_user  = "goldfish"
+	# socket_group = "nginx"
+
+	# [Optional] [Default: 5] Failed logins from one ip, or for one username, wait twice as long
+	# after each failure, and are locked out on this many failures. 0 disables login throttling
+	login_max_attempts = 5
+
+	# [Optional] [Default: "1s"] The wait after the first failed login
+	login_backoff      = "1s"
+
+	# [Optional] [Default: "15m"] How long a lockout lasts
+	login_lockout      = "15m"
+
+	# [Optional] [Default: "20s"] On SIGINT or SIGTERM, goldfish stops accepting connections,
+	# and waits this long for in-flight requests to finish. Keep it below the orchestrator's kill timeout
+	shutdown_timeout   = "20s"
+
+	# [Optional] [Default: "32M"] Larger request bodies are rejected with a 413, e.g. large transit payloads
+	body_limit         = "32M"
+
+	# [Optional] [Default: 5] [Allowed values: 0 to 9] Compression level of responses. 0 disables gzip
+	gzip_level         = 5
+
+	# [Optional] [Default: "10s"] How long a client may take to send a request
+	read_timeout       = "10s"
+
+	# [Optional] [Default: "2m"] How long a response may take, including any calls to vault
+	write_timeout      = "2m"
+
+	# [Optional] [Default: "0"] How long idle keep-alive connections are kept open. "0" uses read_timeout
+	idle_timeout       = "0"
+
+	# [Optional] [Format: "10.0.0.0/8, 192.168.1.1"] Comma separated CIDRs or IPs
+	# If set, only requests from these networks are served. The client's address is checked: the
+	# connecting peer's, or the one a trusted proxy (below) forwarded the request for
+	allowed_cidrs       = ""
+
+	# [Optional] Requests from these networks are always refused, even if they are allowed above
+	denied_cidrs        = ""
+
+	# [Optional] If set, admin endpoints (bootstrap, rebootstrap, revoking all sessions, and api tokens)
+	# are only served to these networks, in addition to the lists above
+	# Disaster recovery replication endpoints are refused on listeners without it
+	admin_allowed_cidrs = ""
+
+	# [Optional] [Format: "10.0.0.0/8, 192.168.1.1"] Reverse proxies in front of goldfish
+	# The client address a trusted proxy puts in X-Forwarded-For or X-Real-IP is used for ip filters,
+	# rate limits, login throttling and session records. Those headers are ignored from anyone else,
+	# since any client could forge them
+	trusted_proxies     = ""
+
+	# [Optional] [Default: "default"] [Allowed values: "default", "strict"]
+	# Security headers sent when tls is enabled. Any header set below overrides the preset's
+	# "default" allows api.github.com (for update checks), and framing by the same origin
+	# "strict" allows no external origins and no framing, and sends no referrer
+	security_preset         = "default"
+
+	# [Optional] The Content-Security-Policy header
+	csp                     = ""
+
+	# [Optional] [Allowed values: "SAMEORIGIN", "DENY"] The X-Frame-Options header
+	frame_options           = ""
+
+	# [Optional] The Referrer-Policy header, e.g. "same-origin"
+	referrer_policy         = ""
+
+	# [Optional] [Default: "0"] The max-age of the Strict-Transport-Security header. "0" disables it
+	hsts_max_age            = "0"
+
+	# [Optional] [Default: 0] [Allowed values: 0, 1]
+	hsts_include_subdomains = 0
+
+	# [Optional] [Default: 0] [Allowed values: 0, 1]
+	# Requires hsts_max_age of at least "8760h" and hsts_include_subdomains
+	hsts_preload            = 0
+
+	# [Optional] [Default: 0] [Allowed values: 0, 1]
+	# Set this to 1 to refuse connections without a client certificate signed by tls_client_ca_file
+	# Requires tls_cert_file and tls_key_file. Load balancer health checks will need a certificate too
+	tls_require_client_cert = 0
+
+	# [Required for client certificates] A PEM encoded CA certificate file to verify clients with
+	tls_client_ca_file      = ""
+
+	# [Optional] Comma separated lists of allowed subject CNs and OUs. If both are set, a client
+	# certificate must match both lists
+	tls_client_allowed_cns  = ""
+	tls_client_allowed_ous  = ""
+
+	# [Optional] [Default: "tls12"] [Allowed values: "tls10", "tls11", "tls12"]
+	tls_min_version         = "tls12"
+
+	# [Optional] A comma separated list of cipher suites, named as in vault's tls_cipher_suites
+	# If not set, go's defaults are used
+	tls_cipher_suites       = ""
+
+	# [Optional] [Allowed values: "fips"]
+	# "fips" only allows FIPS 140-2 approved algorithms: tls 1.2, ECDHE with P-256 or P-384, and AES-GCM
+	# Note that go's crypto is not itself a validated module
+	tls_cipher_preset       = ""
 }
 
 # [Required] vault defines how goldfish should bootstrap to vault
 vault {
 	# [Required] [Format: "protocol://address:port"]
 	# This is vault's address. Vault must be up before goldfish is deployed!
+	# For failover, list several nodes separated by commas. They are tried in order on
+	# connection errors or a 503 (e.g. a sealed node)
 	address         = "http://127.0.0.1:8200"
 
+	# [Optional] [Format: "protocol://srv-name"]
+	# A DNS SRV name to discover vault nodes with, e.g. "https://_vault._tcp.example.com"
+	# Resolved nodes are tried before those in address. If set, address may be omitted
+	address_srv     = ""
+
+	# [Optional] [Format: "protocol://consul-service-name"]
+	# Discover vault nodes with consul instead, e.g. "https://vault". Only healthy nodes are used,
+	# and goldfish follows changes (e.g. a new leader) as consul reports them
+	# Consul nodes are tried first. If set, address may be omitted
+	address_consul  = ""
+
+	# [Optional] [Default: "active"]
+	# The consul service tag to filter on. Vault tags its leader "active"
+	consul_tag      = "active"
+
+	# [Optional] [Default: CONSUL_HTTP_ADDR env variable, or "127.0.0.1:8500"]
+	# The address of the consul agent to query
+	consul_address  = ""
+
 	# [Optional] [Default: 0] [Allowed values: 0, 1]
 	# Set this to 1 to skip verifying the certificate of vault (e.g. self-signed certs)
 	tls_skip_verify = 0
 
+	# [Optional] A PEM encoded CA certificate file, or a directory of them, to verify vault with
+	# These are re-read when they change on disk, so a rotated CA needs no restart
+	ca_cert         = ""
+	ca_path         = ""
+
+	# [Optional] A client certificate and key to present to vault. Both must be set together
+	client_cert     = ""
+	client_key      = ""
+
+	# [Optional] The server name (SNI) to use when connecting to vault
+	tls_server_name = ""
+
+	# [Optional] [Format: "http://proxy:3128"]
+	# A proxy for calls to vault. If empty, the HTTP_PROXY and HTTPS_PROXY env variables are used
+	proxy_address   = ""
+
+	# [Optional] Comma separated hosts, domain suffixes (e.g. ".internal"), or CIDRs to never proxy
+	# The NO_PROXY env variable is also honored when proxy_address is empty
+	no_proxy        = ""
+
 	# [Required] [Default: "secret/goldfish"]
 	# This should be a generic secret endpoint where runtime settings are stored
 	# See wiki for what key values are required in this
 	runtime_config  = "secret/goldfish"
 
+	# [Optional] [Default: "<runtime_config>/settings"]
+	# Where admin-tunable settings (GET and PUT /v1/settings) are stored. Goldfish's token must be able to read it
+	# Users with 'update' capability on this path may change the settings, which apply without a restart
+	settings_path   = ""
+
+	# [Optional] [Default: "<runtime_config>/state"]
+	# Where goldfish keeps records that must outlive its token and be shared by every goldfish instance:
+	# secret tags, policy snapshots, bookmarks, approval delegations, change request approval stats,
+	# and the leases that run scheduled jobs on one instance only
+	# Goldfish's token needs create, read, update, delete and list on this path and below it
+	state_path      = ""
+
 	# [Optional] [Default: "auth/approle/login"]
 	# You can omit this, unless you mounted approle somewhere weird
 	approle_login   = "auth/approle/login"
@@ -43,8 +246,506 @@ vault {
 	# [Optional] [Default: "goldfish"]
 	# You can omit this if you already customized the approle ID to be 'goldfish'
 	approle_id      = "goldfish"
+
+	# [Optional] A secret_id for goldfish's approle, or a file containing one
+	# If either is set, goldfish will log in again by itself when its token can no longer be renewed
+	# These are mutually exclusive. The file is re-read each time, so it may be rotated in place
+	approle_secret_id      = ""
+	approle_secret_id_file = ""
+
+	# [Optional] A file containing goldfish's vault token, e.g. a vault agent auto-auth sink
+	# If set, no wrapping token is needed. The file is watched, and rotated tokens are picked up
+	# The token should not be response-wrapped
+	token_file = ""
+
+	# [Optional] If set, goldfish logs in with its kubernetes service account instead of a wrapping token
+	# This is the role in vault's kubernetes auth backend. Mutually exclusive with token_file
+	kubernetes_role     = ""
+
+	# [Optional] [Default: "auth/kubernetes/login"]
+	kubernetes_login    = "auth/kubernetes/login"
+
+	# [Optional] [Default: "/var/run/secrets/kubernetes.io/serviceaccount/token"]
+	kubernetes_jwt_file = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	# [Optional] [Default: "60s"] [Format: "30s", "2m", or a number of seconds]
+	# How long a single call to vault may take
+	timeout        = "60s"
+
+	# [Optional] [Default: "2m"]
+	# How long a single LIST call to vault may take. Listing large mounts can be slow
+	list_timeout   = "2m"
+
+	# [Optional] [Default: "0"]
+	# Token accessor, policy, and mount lists are cached per session for this long, e.g. "30s"
+	# Changes made through goldfish clear the cache. Changes made elsewhere show up once it expires
+	list_cache_ttl = "0"
+
+	# [Optional] [Default: 100000]
+	# Secret, token accessor and policy lists stop after this many keys, and say they were cut short
+	# Set to 0 for no limit. Clients sending "Accept: application/x-ndjson" get each key as its own line,
+	# as vault's response is read, instead of one response holding every key
+	list_max_items = 100000
+
+	# [Optional] [Default: 2]
+	# How many times a failed call is retried. Set to 0 to disable retries
+	# Connection failures are always retried, server errors only for reads and lists
+	max_retries    = 2
+
+	# [Optional] [Default: "500ms" and "5s"]
+	# The wait between retries doubles from retry_wait_min each time, up to retry_wait_max
+	retry_wait_min = "500ms"
+	retry_wait_max = "5s"
+
+	# [Optional] [Default: 0] Goldfish serves /v1/health/live, which always passes, and /v1/health/ready,
+	# which fails until goldfish is bootstrapped, and while vault is sealed or down or its token is invalid
+	# If 1, vault being sealed or down doesn't fail readiness, so users still reach goldfish during maintenance
+	ready_while_sealed = 0
+
+	# [Optional] [Default: "5s"] How often goldfish checks vault's health. At least 1s
+	# [Optional] [Default: "24h"] How long those checks are kept, for /v1/health/history:
+	# seal and unseal events, standby transitions and latency over time. Memory only; lost on restart
+	# The history needs a session, unless status_page is on, which serves it without node addresses or messages
+	health_check_interval = "5s"
+	health_history = "24h"
+}
+
+# [Optional] cluster defines another vault that users may pick at login. Repeat for each cluster
+# goldfish's own token, runtime config, and transit key always live in the 'vault' cluster above
+# Env overrides for clusters are named GOLDFISH_CLUSTER_<NAME>_<KEY>
+# cluster "staging" {
+# 	# [Required] [Format: "protocol://address:port"]
+# 	address         = "https://vault.staging.example.com:8200"
+#
+# 	# [Optional] [Default: 0] [Allowed values: 0, 1]
+# 	tls_skip_verify = 0
+#
+# 	# [Optional] A PEM encoded CA certificate file to verify this cluster's certificate with
+# 	ca_cert         = ""
+# }
+
+# [Optional] login offers a login option at an auth mount. Repeat for each option
+# Without any, each type logs in at vault's default mount, e.g. ldap at auth/ldap
+# Once any are defined, the login page offers tokens and these options only, and no others are accepted
+# Userpass users are managed at the mount of the first userpass login by name, or auth/userpass without one
+# Env overrides for logins are named GOLDFISH_LOGIN_<NAME>_<KEY>
+# login "corp-ldap" {
+# 	# [Optional] [Default: the login's name] [Allowed values: userpass, ldap, github, okta]
+# 	type  = "ldap"
+#
+# 	# [Optional] [Default: "auth/<name>"] The auth mount to log in at
+# 	path  = "auth/corp-ldap"
+#
+# 	# [Optional] [Default: the login's name] What the login page calls it
+# 	label = "Corporate LDAP"
+# }
+
+# [Optional] role limits which goldfish endpoints a user may call, on top of what vault allows
+# Once any role is defined, users may only call the endpoints of roles they hold. Logging in, health
+# checks, and tokens with the root policy are never restricted. Repeat for each role
+# Roles can also be kept in vault, as a json document in the runtime config's "Roles" field, e.g.
+# {"helpdesk": {"policies": ["helpdesk"], "endpoints": ["tokens"]}}. Those replace roles of the same name
+# role "helpdesk" {
+# 	# [Optional] A comma separated list of vault policies. Every token has "default", so a role
+# 	# with that policy applies to everyone
+# 	policies  = "helpdesk"
+#
+# 	# [Optional] A comma separated list of identity group names. At least one of policies or groups
+# 	# is required. Goldfish's policy needs read on identity/entity/id/* and identity/group/id/*
+# 	# Groups only apply to logins on the 'vault' cluster above
+# 	groups    = "helpdesk-team"
+#
+# 	# [Required] A comma separated list of api tags (as in /v1/openapi.json, e.g. "tokens", "secrets"),
+# 	# single endpoints (e.g. "POST /v1/token/revoke-accessor"), or "*" for every endpoint
+# 	endpoints = "tokens, sessions"
+# }
+
+# [Optional] branding changes how the ui looks, so e.g. production and staging can be told apart at a glance
+# branding {
+# 	# [Optional] Shown in the page title and navbar, e.g. "Example Corp (production)"
+# 	organization = ""
+#
+# 	# [Optional] An image file shown in the navbar and used as the favicon. Read each time it's served
+# 	logo_file    = ""
+#
+# 	# [Optional] Text shown above the login form, e.g. "PRODUCTION - changes affect customers"
+# 	login_banner = ""
+#
+# 	# [Optional] [Format: "#rrggbb" or "#rgb"] Color of the bar under the navbar
+# 	accent_color = ""
+# }
+
+# [Optional] token_creation limits the tokens users create through goldfish, on top of vault's own limits
+# Requests over a limit are refused by goldfish before they reach vault. Token requests approved with
+# unseal keys are not limited, since their approvers have already reviewed them
+# Raw requests (POST /v1/raw) to auth/token/create are refused, since they would get around the limits
+# token_creation {
+# 	# [Optional] Ttl given to tokens requested without one, e.g. "24h". Otherwise vault's default applies
+# 	default_ttl  = ""
+#
+# 	# [Optional] The longest ttl, explicit_max_ttl, or period a token may be given, e.g. "768h"
+# 	# Tokens requested without an explicit_max_ttl are given this one, so renewals can't outlast it
+# 	max_ttl      = ""
+#
+# 	# [Optional] [Default: 0] If set, every token must be limited to between 1 and this many uses
+# 	max_num_uses = 0
+#
+# 	# [Optional] [Default: 0] [Allowed values: 0, 1] Set to 1 to only create response-wrapped tokens,
+# 	# so the token is never shown to whoever created it. Only the wrapping token is returned
+# 	require_wrapping = 0
+#
+# 	# [Optional] The longest wrap ttl a wrapped token may be requested with, e.g. "1h"
+# 	max_wrap_ttl = ""
+# }
+
+# [Optional] oidc lets users log in to goldfish through the organization's identity provider, without
+# knowing how to log in to vault. The provider's id token is exchanged for a vault token with a role of a
+# jwt auth method, which must be configured to trust the same provider, e.g.
+#   vault write auth/jwt/config oidc_discovery_url=https://login.example.com
+#   vault write auth/jwt/role/goldfish role_type=jwt bound_audiences=goldfish user_claim=email policies=...
+# oidc {
+# 	# [Required] The provider's issuer url, where /.well-known/openid-configuration is found
+# 	discovery_url      = "https://login.example.com"
+#
+# 	# [Required] The client registered with the provider, and a file holding its secret
+# 	# Logins use pkce, and keep their state in a cookie signed with a key derived from the secret,
+# 	# so any instance sharing the secret can finish them. Rotating the secret restarts unfinished logins
+# 	client_id          = "goldfish"
+# 	client_secret_file = "/etc/goldfish/oidc_client_secret"
+#
+# 	# [Required] Goldfish's address, including any base_path, followed by /v1/login/oidc/callback
+# 	# It must be registered with the provider as a redirect uri
+# 	redirect_url       = "https://goldfish.example.com/v1/login/oidc/callback"
+#
+# 	# [Optional] [Default: "openid,profile,email"] A comma separated list of scopes. openid is always asked for
+# 	scopes             = "openid,profile,email"
+#
+# 	# [Optional] [Default: "jwt"] The jwt auth method's mount, and [Required] the role to log in with
+# 	vault_mount        = "jwt"
+# 	vault_role         = "goldfish"
+# }
+
+# [Optional] chatops lets approvers reject change requests from the slack message announcing them
+# Requests are announced with buttons when the runtime config has a slack webhook. Approving still needs
+# an unseal key, which is never sent through chat, so the approve button links back to goldfish instead
+# Point the slack app's interactivity request url at /v1/chatops/slack
+# chatops {
+# 	# [Required] A file holding the slack app's signing secret. Read each time a callback arrives
+# 	signing_secret_file = ""
+#
+# 	# [Required] Slack user ids mapped to the display names they act as, e.g. "U012AB3CD=ldap-alice"
+# 	# Callbacks from anyone else are refused
+# 	approvers           = ""
+#
+# 	# [Optional] Where users reach goldfish, e.g. "https://goldfish.example.com", for links back to the ui
+# 	ui_address          = ""
+# }
+
+# [Optional] raft_snapshot takes periodic snapshots of vault's integrated (raft) storage
+# Snapshots are taken with goldfish's token, so goldfish's policy needs read on sys/storage/raft/snapshot
+# Recent runs are listed at /v1/raft/snapshots, to users who may read sys/storage/raft/snapshot themselves
+# With several goldfish instances, one takes each snapshot, holding a lease under the state path
+# raft_snapshot {
+# 	# [Required] When to take snapshots, e.g. "@every 6h" or "0 3 * * *" (utc)
+# 	schedule           = "@every 6h"
+#
+# 	# [Optional] [Default: 0] The most recent snapshots kept, older ones are deleted. 0 keeps them all
+# 	retain             = 7
+#
+# 	# [Required] One of: a local directory to write snapshots to, or an s3 bucket to upload them to
+# 	local_dir          = "/var/backups/vault"
+# 	s3_bucket          = ""
+#
+# 	# [Optional] For s3, a prefix for the snapshots' keys, e.g. "vault/"
+# 	s3_prefix          = ""
+#
+# 	# [Optional] [Default: "us-east-1"] For s3, the bucket's region
+# 	s3_region          = ""
+#
+# 	# [Optional] For s3 compatible storage other than aws, e.g. "https://minio.internal:9000"
+# 	s3_endpoint        = ""
+#
+# 	# [Optional] For s3, files holding the access key and secret key. Both or neither must be set
+# 	# Without them, credentials come from the environment, shared credentials file, or instance role
+# 	s3_access_key_file = ""
+# 	s3_secret_key_file = ""
+# }
+
+# [Optional] audit_source reads vault's audit log, counting reads and writes per secret path for the access
+# heatmap. Paths under sys/, auth/ and cubbyhole/ aren't counted
+# audit_source {
+# 	# [Required] [Allowed values: "file", "socket"] file tails a file audit device's log, e.g. from a shared
+# 	# volume. socket listens for a socket audit device, which must use socket_type "tcp"
+# 	type      = "file"
+#
+# 	# [Required] For file, the audit log's path. Rotated logs are followed
+# 	path      = "/var/log/vault/audit.log"
+#
+# 	# [Required] For socket, the address to listen on [Format: "address:port"]. Without an address,
+# 	# e.g. ":9090", only loopback is listened on, since the audit device can't authenticate
+# 	# e.g. vault audit enable socket address=goldfish.internal:9090 socket_type=tcp
+# 	address   = ""
+#
+# 	# [Required for socket not on loopback] [Format: "10.0.0.0/24, 10.0.1.5"] The addresses of vault's
+# 	# nodes. Connections from anywhere else are refused
+# 	allowed_cidrs = ""
+#
+# 	# [Optional] [Default: "168h"] How long access is remembered. Nothing is kept across restarts
+# 	retention = "168h"
+#
+# 	# [Optional] [Default: "1h"] The heatmap's resolution. At most 1000 buckets fit in retention
+# 	bucket    = "1h"
+# }
+
+# [Optional] notifier defines somewhere scheduled reports can be sent. Repeat for each notifier
+# notifier "ops" {
+# 	# [Required] [Allowed values: "slack", "webhook", "email", "pagerduty", "opsgenie"]
+# 	type               = "slack"
+#
+# 	# [Optional] For slack, an incoming webhook url. Defaults to the runtime config's slack webhook
+# 	# [Required] For webhook, a url that is sent a POST with {"title", "text", "timestamp"}
+# 	# [Optional] For pagerduty and opsgenie, their api's url, e.g. "https://api.eu.opsgenie.com/v2/alerts"
+# 	url                = ""
+#
+# 	# [Optional] For slack, the channel to post to, e.g. "#ops"
+# 	channel            = ""
+#
+# 	# [Required] For email, the smtp server to send through [Format: "address:port"]
+# 	smtp_address       = ""
+#
+# 	# [Optional] For email, credentials for the smtp server. The password file is read on each send
+# 	smtp_username      = ""
+# 	smtp_password_file = ""
+#
+# 	# [Required] For email, the sender, and a comma separated list of recipients
+# 	from               = ""
+# 	to                 = ""
+#
+# 	# [Required] For pagerduty, a file holding an events api v2 routing key. For opsgenie, a file
+# 	# holding an api key. Read on each send
+# 	key_file           = ""
+# }
+
+# [Optional] report defines a report goldfish runs on a schedule, and sends to notifiers. Repeat for each report
+# Reports are read with goldfish's token, so its policy must allow them, e.g. list on auth/token/accessors
+# and update on auth/token/lookup-accessor. Reports with nothing in them aren't sent
+# report "expiring-tokens" {
+# 	# [Required] [Allowed values: "expiring_tokens", "expiring_certs", "pending_requests", "unused_policies",
+# 	# "stale_requests", "untagged_secrets", "overdue_rotations"] unused_policies lists policies that no
+# 	# current token holds. stale_requests escalates each change request waiting longer than window, e.g. to
+# 	# pagerduty, which is triggered once per request. untagged_secrets lists secrets without an owner or
+# 	# classification. overdue_rotations reminds of secrets older than the rotation period they were tagged with
+# 	type       = "expiring_tokens"
+#
+# 	# [Required] [Format: "minute hour day-of-month month day-of-week", "@hourly", "@daily", "@weekly",
+# 	# "@monthly", or "@every <duration>"] Cron schedules are in goldfish's local time
+# 	schedule   = "0 9 * * 1-5"
+#
+# 	# [Required] A comma separated list of notifier names
+# 	notify     = "ops"
+#
+# 	# [Optional] [Default: "168h"] For expiring tokens and certificates, how far ahead to look
+# 	# [Required] For stale requests, how long a request may wait, e.g. "4h"
+# 	window     = "168h"
+#
+# 	# [Required] For expiring_certs, a comma separated list of pki mounts
+# 	pki_mounts = ""
+#
+# 	# [Required] For untagged_secrets, a comma separated list of folders, e.g. "secret/". Goldfish's policy
+# 	# must allow listing them
+# 	paths      = ""
+# }
+
+# [Optional] policy_lint defines a rule proposed policies are checked against when a policy request is made,
+# before it reaches approvers. Repeat for each rule. Globs in paths end in '*', as in vault's policies
+# policy_lint "no-sys-sudo" {
+# 	# [Required] [Allowed values: "forbid_capabilities", "require_deny", "policy_name"]
+# 	# forbid_capabilities refuses granting capabilities on paths. require_deny refuses policies that grant
+# 	# anything on or under paths, or reach them through a broader glob without denying them.
+# 	# policy_name refuses policy names that don't match pattern
+# 	type         = "forbid_capabilities"
+#
+# 	# [Required] For forbid_capabilities and require_deny, a comma separated list of paths
+# 	paths        = "sys/*"
+#
+# 	# [Optional] For forbid_capabilities, a comma separated list of capabilities. Defaults to any but deny
+# 	capabilities = "sudo"
+#
+# 	# [Required] For policy_name, a regular expression policy names must match, e.g. "^team-[a-z]+-(ro|rw)$"
+# 	pattern      = ""
+#
+# 	# [Optional] [Default: "block"] [Allowed values: "block", "warn"] warn submits the request anyway,
+# 	# showing the violation to the requester
+# 	action       = "block"
+#
+# 	# [Optional] Shown with violations, e.g. to explain the rule
+# 	message      = ""
+# }
+
+# [Optional] telemetry defines how goldfish exports metrics
+telemetry {
+	# [Optional] [Default: 0] [Allowed values: 0, 1]
+	# Set this to 1 to disable the prometheus /metrics endpoint
+	prometheus_disable = 0
+
+	# [Optional] [Format: "address:port"]
+	# If set, /metrics is served on this address, e.g. one only the prometheus network can reach
+	prometheus_address = ""
+
+	# [Optional] A file holding a token prometheus must send, as an "Authorization: Bearer" header
+	# /metrics is only served on goldfish's own listeners with a token. It is read on each scrape
+	prometheus_token_file = ""
+
+	# [Optional] [Format: "http(s)://host:port"]
+	# If set, http requests and the vault calls made for them are traced, and exported to this
+	# opentelemetry collector over otlp/http. Callers may pass a w3c traceparent header to join a trace
+	# Vault receives the same header. To link vault's audit log entries to spans, run:
+	#   vault write sys/config/auditing/request-headers/traceparent hmac=false
+	otlp_endpoint = ""
+
+	# [Optional] [Format: "name=value,name=value"]
+	# Headers sent with each export, e.g. for authenticating to the collector
+	otlp_headers = ""
+
+	# [Optional] [Default: 1] [Allowed values: 0 to 1]
+	# Fraction of new traces to export. Traces continued from a caller's header keep the caller's choice
+	trace_sample_ratio = 1
+
+	# [Optional] [Format: "host:port"]
+	# If set, metrics are also sent to this statsd server, such as a local datadog agent
+	# Labels are sent as datadog tags, and gauges are sent every 10 seconds
+	# Durations are sent as timers in milliseconds, with _seconds renamed to _milliseconds
+	statsd_address = ""
+
+	# [Optional] [Default: "goldfish."]
+	# Replaces the "goldfish_" prefix of metric names, e.g. goldfish.vault_request_duration_milliseconds
+	statsd_prefix = "goldfish."
+
+	# [Optional] [Format: "tag:value,tag:value"]
+	# Tags added to every metric, e.g. "env:prod,region:us-east-1"
+	statsd_tags = ""
+}
+
+# [Optional] session defines where user sessions are kept
+# Users are only handed an opaque session id, their vault tokens never leave goldfish
+# Stores only hold a hash of each id, and each token is sealed with a key derived from its id,
+# so a copy of the store alone can't recover or replay the tokens in it
+# API tokens for automation (POST /v1/apitokens) are kept here too, and need a file or redis store,
+# since a memory store would forget them on restart and leave their vault tokens live in vault
+session {
+	# [Optional] [Default: "memory"] [Allowed values: "memory", "file", "redis"]
+	# Logins are stateful: with the default memory store, restarting goldfish logs everyone out,
+	# and instances behind a load balancer don't share sessions, so each needs sticky sessions
+	# File sessions survive restarts, but must not be shared
+	# Redis sessions can be shared by several goldfish instances behind a load balancer
+	store          = "memory"
+
+	# [Required for file store] The file sessions are kept in. It is created if it does not exist
+	# Each change is appended to it, and it is compacted once most of its lines are outdated
+	file_path      = ""
+
+	# [Required for redis store] [Format: "address:port"]
+	redis_address  = ""
+
+	# [Optional] Can also be set via GOLDFISH_SESSION_REDIS_PASSWORD
+	redis_password = ""
+
+	# [Optional] [Default: 0]
+	redis_db       = 0
+
+	# [Optional] A transit key used only for session tokens
+	# Without it, sessions are encrypted with the runtime config's server transit key, if one is set
+	# Goldfish's policy needs update on <transit_backend>/encrypt/, decrypt/, and rewrap/<transit_key>
+	# Sessions are rewrapped as they are used, so rotating the key in vault is enough
+	transit_key     = ""
+
+	# [Optional] [Default: "transit"] The mount the transit key lives in
+	transit_backend = ""
+
+	# [Optional] [Default: "0"] How long a session lasts after login or renewal. Sessions never
+	# outlive the vault token they hold, so "0" means as long as the token
+	ttl              = "0"
+
+	# [Optional] [Default: "0"] [Minimum: "2m"] Unused sessions expire after this long. "0" disables it
+	idle_timeout     = "0"
+
+	# [Optional] [Default: "0"] Sessions expire this long after login, however often they are renewed
+	absolute_timeout = "0"
+
+	# [Optional] [Default: "0"] Destructive actions (deleting policies or secrets, revoking tokens, users,
+	# or every session) need credentials re-entered within this long, e.g. "5m". "0" disables it
+	# The ui asks for them again when it sees the "reauth_required" error code, and sends them to
+	# POST /v1/login/reauth. Only sessions created by logging in to goldfish are affected
+	step_up_window   = "0"
+
+	# [Optional] [Default: vault's runtime_config] Users with 'update' capability on this path may
+	# revoke every session at once (POST /v1/sessions/revoke-all), e.g. after a suspected compromise
+	# Only sessions in this instance's store are revoked, so use a shared store with several instances
+	# The same users may mint, list, and revoke api tokens. Revoking all sessions revokes api tokens too,
+# along with the vault tokens behind them
+	# To also invalidate older stateless "vault:" ciphers, goldfish's policy needs update on
+	# <transit_backend>/keys/<server_transit_key>/rotate and /config, and read on the key itself
+	admin_path       = ""
+}
+
+# [Optional] rate_limit limits how often each caller may use the api, so one misbehaving script
+# can't overload vault through goldfish. Callers over their limit get a 429 with a Retry-After header
+# Health endpoints and static assets are never limited
+rate_limit {
+	# [Optional] [Default: 0] Requests per second allowed for each caller, across all routes that
+	# aren't listed in routes. May be fractional, e.g. 0.5 is one request every 2 seconds. 0 disables it
+	requests_per_second = 0
+
+	# [Optional] [Default: requests_per_second, rounded up] Requests allowed at once, after a quiet period
+	burst               = 0
+
+	# [Optional] [Default: "session"] [Allowed values: "session", "ip"]
+	# With "session", requests with a valid session or api token are limited per session, and anyone
+	# else per client address, which is the connecting peer's unless the listener trusts it as a proxy
+	key                 = "session"
+
+	# [Optional] [Format: "route=rate:burst, route=rate"]
+	# Routes limited separately from the rest, each with its own rate. A rate of 0 exempts the route
+	# e.g. "/v1/login=0.2:5, /v1/secrets=20:40"
+	routes              = ""
 }
 
 # [Optional] [Default: 0] [Allowed values: 0, 1]
 # Set to 1 to disable mlock. Implementation is similar to vault - see vault docs for details
 disable_mlock = 0
+
+# [Optional] [Default: 0] [Allowed values: 0, 1] Can also be set with the -read-only flag
+# Set to 1 to serve goldfish read-only: anything that reads still works, but every endpoint that
+# changes state (in goldfish or in vault) is refused with a 403. Logging in and out still works
+read_only = 0
+
+# [Optional] [Default: 0] [Allowed values: 0, 1]
+# Set to 1 to make deleting policies and secrets a two step operation. The first request only
+# describes what would be deleted, with a confirmation token valid for 2 minutes. Repeating it
+# with ?confirmation=<token> performs the delete. Tokens are kept in memory, per instance
+require_confirmation = 0
+
+# [Optional] [Default: 0] [Allowed values: 0, 1]
+# Approved policy changes keep the version they replaced, and can be rolled back from it in one click
+# By default the rollback is made with the user's own token, who must be able to write the policy
+# Set to 1 to have a rollback make a change request instead, which needs approving like any other
+rollback_requires_approval = 0
+
+# [Optional] [Default: 0] [Allowed values: 0, 1]
+# Set to 1 to have /v1/version check github for a newer goldfish release, at most once a day
+# Goldfish needs outbound access to api.github.com
+update_check = 0
+
+# [Optional] [Default: 0] [Allowed values: 0, 1]
+# Set to 1 to serve /v1/status to anyone, without a token: whether goldfish is ready and vault is
+# reachable and sealed, as a page for noc dashboards or json for load balancers. It's a 503 while goldfish
+# isn't ready. No addresses, versions or error messages are shown. To embed it in another site's frame,
+# the listener's frame_options and csp must allow it
+status_page = 0
+
+# [Optional] [Default: 0] [Allowed values: 0, 1]
+# Set to 1 for networks without internet access. Goldfish refuses to start if anything in this file
+# needs the internet (let's encrypt, update_check, or slack notifiers without a url),
+# removes external origins from each listener's csp, and never contacts github or slack.com
+# Vault, telemetry, and notifier addresses are assumed to be inside the network
+air_gapped = 0
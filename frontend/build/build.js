@@ -21,6 +21,11 @@ rm('-rf', assetsPath)
 mkdir('-p', assetsPath)
 cp('-R', 'assets/*', assetsPath)
 
+// swagger ui for /docs is served by goldfish itself, so it works without internet access
+const swaggerUIPath = path.join(assetsPath, 'swagger-ui')
+mkdir('-p', swaggerUIPath)
+cp('node_modules/swagger-ui-dist/swagger-ui.css', 'node_modules/swagger-ui-dist/swagger-ui-bundle.js', swaggerUIPath)
+
 const compiler = webpack(webpackConfig)
 const ProgressPlugin = require('webpack/lib/ProgressPlugin')
 compiler.apply(new ProgressPlugin())
@@ -32,7 +32,9 @@ exports.cssLoaders = options => {
     if (options.extract) {
       return ExtractTextPlugin.extract({
         fallback: 'style-loader',
-        use: sourceLoader
+        use: sourceLoader,
+        // extracted css lives in assets/css, so its urls climb back to the root
+        publicPath: '../../'
       })
     } else {
       return ['vue-style-loader', sourceLoader].join('!')
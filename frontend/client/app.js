@@ -11,6 +11,9 @@ import Notification from 'vue-bulma-notification'
 import Message from 'vue-bulma-message'
 import hljs from 'highlight.js'
 
+// api calls are relative to wherever goldfish is served from, e.g. behind a base_path
+axios.defaults.baseURL = window.location.pathname.replace(/\/+$/, '')
+
 Vue.prototype.$http = axios
 Vue.axios = axios
 Vue.use(NProgress)
@@ -59,6 +62,23 @@ const openNotification = (propsData = {
 Vue.prototype.$notify = openNotification
 
 function handleError (error) {
+  // an expired server-side session can't be recovered, so drop it and ask for a new login
+  if (error.response.data.code === 'session_expired') {
+    window.localStorage.removeItem('session')
+    store.commit('clearSession')
+  }
+
+  // throttling by a vault quota is told apart from other errors, so it isn't taken for missing access
+  if (error.response.data.code === 'quota_exceeded') {
+    openNotification({
+      title: 'Throttled by a vault quota',
+      message: error.response.data.error,
+      type: 'warning',
+      duration: 20000
+    })
+    return
+  }
+
   // if the server gave a response message, print that
   if (error.response.data.error) {
     // duration should be proportional to the error message length
@@ -95,6 +115,46 @@ function handleError (error) {
 }
 Vue.prototype.$onError = handleError
 
+// lists longer than the server's list_max_items are cut short, which would otherwise go unnoticed
+axios.interceptors.response.use((response) => {
+  if (response.data && response.data.truncated === true) {
+    openNotification({
+      title: 'List cut short',
+      message: 'Vault returned more items than goldfish is configured to show',
+      type: 'warning',
+      duration: 20000
+    })
+  }
+  return response
+})
+
+// list responses carry an etag, so a poll sends it back and reuses the last response if nothing changed
+// kept per token too, since each token may see a different list. The key is taken before axios adds
+// the base url, and kept with the request
+const etags = {}
+axios.interceptors.request.use((config) => {
+  if (config.method === 'get') {
+    config.etagKey = config.url + '\n' + (config.headers['X-Vault-Token'] || '')
+    let cached = etags[config.etagKey]
+    if (cached) {
+      config.headers['If-None-Match'] = cached.etag
+    }
+  }
+  return config
+})
+axios.interceptors.response.use((response) => {
+  if (response.config.etagKey && response.headers.etag) {
+    etags[response.config.etagKey] = {etag: response.headers.etag, data: response.data}
+  }
+  return response
+}, (error) => {
+  let cached = error.response && error.response.status === 304 && etags[error.config.etagKey]
+  if (cached) {
+    return Object.assign({}, error.response, {status: 200, data: cached.data})
+  }
+  return Promise.reject(error)
+})
+
 const MessageComponent = Vue.extend(Message)
 const openMessage = (propsData = {
   title: '',
@@ -9,21 +9,33 @@ export default {
   children: [
     {
       name: 'Users',
+      meta: {
+        features: ['tokens.list', 'users.userpass', 'users.approle']
+      },
       path: '/users',
       component: lazyLoading('admin/Users')
     },
     {
       name: 'Policies',
+      meta: {
+        features: ['policies.read']
+      },
       path: '/policies',
       component: lazyLoading('admin/Policies')
     },
     {
       name: 'Mounts',
+      meta: {
+        features: ['mounts.read']
+      },
       path: '/mounts',
       component: lazyLoading('admin/Mounts')
     },
     {
       name: 'Requests',
+      meta: {
+        features: ['requests']
+      },
       path: '/requests',
       component: lazyLoading('admin/Requests')
     }
@@ -12,6 +12,7 @@ import tools from './tools'
 // show: meta.label -> name
 // name: component name
 // meta.label: display label
+// meta.features: hidden once logged in, unless one of these features is usable
 
 const state = {
   items: [
@@ -27,7 +28,8 @@ const state = {
       name: 'Secrets',
       path: '/secrets',
       meta: {
-        icon: 'fa-list'
+        icon: 'fa-list',
+        features: ['secrets']
       },
       component: lazyLoading('secrets', true)
     },
@@ -9,11 +9,22 @@ export default {
   children: [
     {
       name: 'Transit',
+      meta: {
+        features: ['transit']
+      },
       path: '/transit',
       component: lazyLoading('tools/Transit')
     },
+    {
+      name: 'Random & Hash',
+      path: '/random',
+      component: lazyLoading('tools/Random')
+    },
     {
       name: 'Token Creator',
+      meta: {
+        features: ['tokens.create']
+      },
       path: '/create-token',
       component: lazyLoading('tools/CreateToken')
     },
@@ -3,6 +3,7 @@ package github
 import (
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/github"
 	"github.com/hashicorp/hcl"
@@ -10,9 +11,21 @@ import (
 	"golang.org/x/oauth2"
 )
 
+var airGapped bool
+
+var errAirGapped = errors.New("GitHub can't be reached in air-gapped mode")
+
+// set once at startup, from the config file
+func SetAirGapped(on bool) {
+	airGapped = on
+}
+
 // if head commit is somewhere between current commit state and head of target branch,
 // find all hcl files in the path folder of commit, and return contents as a map
 func GetHCLFilesFromPath(accessToken, owner, repo, branch, path, base, head string) (map[string]string, error) {
+	if airGapped {
+		return nil, errAirGapped
+	}
 	if accessToken == "" || owner == "" || repo == "" || head == "" {
 		return nil, errors.New("Config_path does not include GitHub info required")
 	}
@@ -94,3 +107,18 @@ func GetHCLFilesFromPath(accessToken, owner, repo, branch, path, base, head stri
 
 	return policies, nil
 }
+
+// the tag and page of a public repository's latest release, fetched without credentials
+func LatestRelease(owner, repo string) (string, string, error) {
+	if airGapped {
+		return "", "", errAirGapped
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	release, _, err := github.NewClient(nil).Repositories.GetLatestRelease(ctx, owner, repo)
+	if err != nil {
+		return "", "", err
+	}
+	return release.GetTagName(), release.GetHTMLURL(), nil
+}
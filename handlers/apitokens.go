@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/caiyeon/goldfish/session"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/hashicorp/vault/api"
+	"github.com/labstack/echo"
+)
+
+// the endpoints each api token scope allows, as "<method> <route>"
+// scopes are deliberately narrow, since api tokens are meant for automation rather than people
+var apiTokenScopes = map[string][]string{
+	"wrap":         {"POST /v1/wrapping/wrap"},
+	"unwrap":       {"POST /v1/wrapping/unwrap", "POST /v1/wrapping/unwrap-batch"},
+	"request":      {"GET /v1/request", "GET /v1/request/plan", "POST /v1/request/add"},
+	"transit":      {"GET /v1/transit", "POST /v1/transit/encrypt", "POST /v1/transit/decrypt"},
+	"secrets-read": {"GET /v1/secrets"},
+}
+
+func apiTokenAllows(s *session.Session, c echo.Context) bool {
+	endpoint := c.Request().Method + " " + c.Path()
+	for _, scope := range s.Scopes {
+		for _, allowed := range apiTokenScopes[scope] {
+			if allowed == endpoint {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// api tokens are managed by the same users that may revoke everyone's sessions
+// they are minted from a session, so api tokens can't be used to mint more of themselves
+// returns the admin's auth and display name, or nil if the response was already written
+func apiTokenAdmin(c echo.Context) (*vault.AuthInfo, string) {
+	auth := getSession(c)
+	if auth == nil {
+		return nil, ""
+	}
+
+	current := currentSession(c)
+	if current == nil || current.IsAPIToken() {
+		auth.Clear()
+		c.JSON(http.StatusBadRequest, H{
+			"error": "API tokens can only be managed by sessions created by logging in to goldfish",
+		})
+		return nil, ""
+	}
+	if err := auth.CanAdministerSessions(); err != nil {
+		auth.Clear()
+		c.JSON(http.StatusForbidden, H{
+			"error": err.Error(),
+		})
+		return nil, ""
+	}
+	return auth, current.DisplayName
+}
+
+func ListAPITokens() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		auth, _ := apiTokenAdmin(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		tokens, err := session.ListAPITokens()
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		// like sessions, api tokens are identified by hash, and their vault tokens are never returned
+		result := make([]map[string]interface{}, 0, len(tokens))
+		for _, t := range tokens {
+			var expires interface{}
+			if deadline := t.Deadline(); !deadline.IsZero() {
+				expires = deadline.UTC().Format(time.RFC3339)
+			}
+			result = append(result, map[string]interface{}{
+				"id":         t.Hash,
+				"name":       t.DisplayName,
+				"scopes":     t.Scopes,
+				"created_by": t.Owner,
+				"created":    t.Created.UTC().Format(time.RFC3339),
+				"last_used":  t.LastSeen.UTC().Format(time.RFC3339),
+				"expires":    expires,
+			})
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
+func CreateAPIToken() echo.HandlerFunc {
+	type body struct {
+		Name   string
+		Scopes []string
+
+		// the vault token behind the api token. Without a role it is an orphan, so it outlives the admin's session
+		Policies []string
+		Ttl      string
+		Role     string
+	}
+
+	return func(c echo.Context) error {
+		auth, by := apiTokenAdmin(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		b := new(body)
+		if err := c.Bind(b); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid format",
+			})
+		}
+		if b.Name == "" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "API token name cannot be empty",
+			})
+		}
+		if len(b.Scopes) == 0 {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "API token needs at least one scope",
+			})
+		}
+		for _, scope := range b.Scopes {
+			if _, ok := apiTokenScopes[scope]; !ok {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "Unknown API token scope: " + scope + ". Valid scopes are " + strings.Join(apiTokenScopeNames(), ", "),
+				})
+			}
+		}
+
+		// a vault token minted for an api token in a memory store would be left live in vault when goldfish restarts
+		if !session.Persistent() {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "API tokens need a file or redis session store",
+			})
+		}
+
+		resp, err := auth.CreateToken(&api.TokenCreateRequest{
+			Policies:    b.Policies,
+			TTL:         b.Ttl,
+			DisplayName: "goldfish-api-" + b.Name,
+			Metadata: map[string]string{
+				"goldfish_api_token": b.Name,
+				"created_by":         by,
+			},
+		}, b.Role == "", b.Role, "")
+		if err != nil {
+			return parseError(c, err)
+		}
+		bustCache(cacheAccessors)
+
+		// the vault token is stored like a session's, transit encrypted if sessions are
+		minted := &vault.AuthInfo{Type: "token", ID: resp.Auth.ClientToken}
+		defer minted.Clear()
+		key := vault.SessionTransitKey()
+		if key != "" {
+			if err := minted.EncryptSession(key); err != nil {
+				return c.JSON(http.StatusInternalServerError, H{
+					"error": "Goldfish could not use transit key: " + err.Error(),
+				})
+			}
+		}
+
+		id, err := session.NewAPIToken(minted.ID, key, b.Name, by, resp.Auth.Accessor, b.Scopes,
+			time.Duration(resp.Auth.LeaseDuration)*time.Second)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, H{
+				"error": "Goldfish could not store the API token: " + err.Error(),
+			})
+		}
+		log.Printf("[INFO ]: API token %q with scopes %s was created by %s\n", b.Name, strings.Join(b.Scopes, ","), by)
+
+		// this is the only time the api token is ever shown
+		return c.JSON(http.StatusOK, H{
+			"result": map[string]interface{}{
+				"token":    id,
+				"id":       session.Hash(id),
+				"name":     b.Name,
+				"scopes":   b.Scopes,
+				"policies": resp.Auth.Policies,
+				"ttl":      resp.Auth.LeaseDuration,
+			},
+		})
+	}
+}
+
+func RevokeAPIToken() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		auth, by := apiTokenAdmin(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		tokens, err := session.ListAPITokens()
+		if err != nil {
+			return parseError(c, err)
+		}
+		id := c.Param("id")
+		for _, t := range tokens {
+			if t.Hash != id {
+				continue
+			}
+			if err := session.DeleteHash(id); err != nil {
+				return parseError(c, err)
+			}
+			revokeAPITokenAccessors(auth, []*session.Session{t})
+			log.Printf("[INFO ]: API token %q was revoked by %s\n", t.DisplayName, by)
+			return c.JSON(http.StatusOK, H{
+				"result": "API token revoked",
+			})
+		}
+
+		return c.JSON(http.StatusNotFound, H{
+			"error": "API token not found",
+		})
+	}
+}
+
+// revokes the vault tokens behind api tokens that were deleted, returning how many could not be revoked
+// the api tokens are gone either way, so a vault token that already expired is only logged
+func revokeAPITokenAccessors(auth *vault.AuthInfo, tokens []*session.Session) int {
+	failed := 0
+	for _, t := range tokens {
+		if t.Accessor == "" {
+			continue
+		}
+		if err := auth.RevokeTokenByAccessor(t.Accessor); err != nil {
+			log.Println("[WARN ]: Could not revoke the vault token of API token "+t.DisplayName+":", err.Error())
+			failed++
+		}
+	}
+	if len(tokens) > failed {
+		bustCache(cacheAccessors)
+	}
+	return failed
+}
+
+func apiTokenScopeNames() []string {
+	names := make([]string, 0, len(apiTokenScopes))
+	for name := range apiTokenScopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -43,6 +43,11 @@ func DeleteApproleRole() echo.HandlerFunc {
 				"error": "role parameter is required",
 			})
 		}
+		if isDryRun(c) {
+			return dryRun(c, auth, "DELETE", "auth/approle/role/"+role, map[string]interface{}{
+				"role": role,
+			})
+		}
 		if _, err := auth.DeleteRaw("auth/approle/role/" + role); err != nil {
 			return parseError(c, err)
 		}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+
+	"github.com/caiyeon/goldfish/session"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// gives each request a correlation id, returned to the caller and sent to vault with each call made for it
+// a caller's own id is never trusted, since it ends up in vault's audit log
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			b := make([]byte, 16)
+			if _, err := rand.Read(b); err != nil {
+				return err
+			}
+			id := hex.EncodeToString(b)
+			c.Set("request_id", id)
+			c.Response().Header().Set(vault.AuditRequestIDHeader, id)
+			return next(c)
+		}
+	}
+}
+
+// who the request is acting as, logged so vault's audit log can be mapped back to a goldfish user and action
+// s is nil for raw tokens and ciphers, which carry no user
+func auditInfo(c echo.Context, s *session.Session) *vault.AuditInfo {
+	audit := &vault.AuditInfo{}
+	audit.RequestID, _ = c.Get("request_id").(string)
+	audit.Node, _ = c.Get("vault_node").(*vault.ServedNode)
+	user, accessor := "unknown user", "unknown"
+	if s != nil {
+		audit.User, audit.Accessor = s.DisplayName, s.Accessor
+		user, accessor = s.DisplayName, s.Accessor
+	}
+	log.Printf("[INFO ]: Request %s: %s %s by %s (accessor %s)\n",
+		audit.RequestID, c.Request().Method, c.Path(), user, accessor)
+	return audit
+}
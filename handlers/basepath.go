@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+// strips the configured base path before routing, so routes stay registered at the root
+// the bare base path redirects to its trailing slash, so the ui's relative asset urls resolve
+// the base path is kept in the context, since each listener may have its own
+func BasePath(base string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if base == "" {
+			return next
+		}
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.URL.Path == base {
+				target := base + "/"
+				if req.URL.RawQuery != "" {
+					target += "?" + req.URL.RawQuery
+				}
+				return c.Redirect(http.StatusMovedPermanently, target)
+			}
+			if !strings.HasPrefix(req.URL.Path, base+"/") {
+				return echo.ErrNotFound
+			}
+			c.Set("base_path", base)
+			req.URL.Path = strings.TrimPrefix(req.URL.Path, base)
+			if req.URL.RawPath != "" {
+				req.URL.RawPath = strings.TrimPrefix(req.URL.RawPath, base)
+			}
+			return next(c)
+		}
+	}
+}
+
+// the listener's base path for this request, or empty if served at the root
+func requestBasePath(c echo.Context) string {
+	base, _ := c.Get("base_path").(string)
+	return base
+}
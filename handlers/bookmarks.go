@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+const (
+	maxBookmarks = 100
+	maxRecent    = 20
+	// recently read paths of users who haven't read anything for this long are forgotten
+	recentTTL = 7 * 24 * time.Hour
+)
+
+type recentPath struct {
+	Path string    `json:"path"`
+	Read time.Time `json:"read"`
+}
+
+// recently read paths are only kept in memory, newest first. Bookmarks are kept in vault
+var (
+	recentPaths   = make(map[string][]recentPath)
+	recentSwept   time.Time
+	recentLock    = new(sync.Mutex)
+	bookmarksLock = new(sync.Mutex)
+)
+
+// the current user, or "" for api tokens, which are for automation and have no bookmarks
+func bookmarkOwner(c echo.Context) string {
+	s := currentSession(c)
+	if s == nil || s.IsAPIToken() {
+		return ""
+	}
+	return s.Owner
+}
+
+// remembers a secret the user has just read
+func recordRecent(c echo.Context, path string) {
+	owner := bookmarkOwner(c)
+	if owner == "" {
+		return
+	}
+	now := time.Now()
+
+	recentLock.Lock()
+	defer recentLock.Unlock()
+	if now.Sub(recentSwept) > time.Hour {
+		for o, paths := range recentPaths {
+			if now.Sub(paths[0].Read) > recentTTL {
+				delete(recentPaths, o)
+			}
+		}
+		recentSwept = now
+	}
+
+	paths := []recentPath{{Path: path, Read: now}}
+	for _, p := range recentPaths[owner] {
+		if p.Path != path && len(paths) < maxRecent {
+			paths = append(paths, p)
+		}
+	}
+	recentPaths[owner] = paths
+}
+
+func GetRecent() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		recentLock.Lock()
+		paths := append([]recentPath{}, recentPaths[bookmarkOwner(c)]...)
+		recentLock.Unlock()
+
+		return c.JSON(http.StatusOK, H{
+			"result": paths,
+		})
+	}
+}
+
+func ClearRecent() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		recentLock.Lock()
+		delete(recentPaths, bookmarkOwner(c))
+		recentLock.Unlock()
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Recently read secrets cleared",
+		})
+	}
+}
+
+func GetBookmarks() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		owner := bookmarkOwner(c)
+		if owner == "" {
+			return bookmarksRefused(c)
+		}
+		paths, err := vault.GetBookmarks(owner)
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": paths,
+		})
+	}
+}
+
+// bookmarking a path twice does nothing
+func AddBookmark() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		owner := bookmarkOwner(c)
+		if owner == "" {
+			return bookmarksRefused(c)
+		}
+		path := c.QueryParam("path")
+		if path == "" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Path must not be empty",
+			})
+		}
+
+		bookmarksLock.Lock()
+		defer bookmarksLock.Unlock()
+		paths, err := vault.GetBookmarks(owner)
+		if err != nil {
+			return parseError(c, err)
+		}
+		if !containsString(paths, path) {
+			if len(paths) >= maxBookmarks {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "Too many bookmarks, please remove some first",
+				})
+			}
+			if err := vault.SetBookmarks(owner, append(paths, path)); err != nil {
+				return parseError(c, err)
+			}
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Bookmark added",
+		})
+	}
+}
+
+// removes one bookmark, or every bookmark if no path is given
+func DeleteBookmark() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		owner := bookmarkOwner(c)
+		if owner == "" {
+			return bookmarksRefused(c)
+		}
+		path := c.QueryParam("path")
+
+		bookmarksLock.Lock()
+		defer bookmarksLock.Unlock()
+		var kept []string
+		if path != "" {
+			paths, err := vault.GetBookmarks(owner)
+			if err != nil {
+				return parseError(c, err)
+			}
+			for _, p := range paths {
+				if p != path {
+					kept = append(kept, p)
+				}
+			}
+		}
+		if err := vault.SetBookmarks(owner, kept); err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Bookmarks removed",
+		})
+	}
+}
+
+func bookmarksRefused(c echo.Context) error {
+	return c.JSON(http.StatusBadRequest, H{
+		"error": "Only sessions created by logging in to goldfish have bookmarks",
+	})
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/http"
+	"sync"
+
+	"github.com/caiyeon/goldfish/config"
+	"github.com/caiyeon/goldfish/oidc"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+var (
+	branding     *config.BrandingConfig
+	brandingLock = new(sync.RWMutex)
+)
+
+// may be called again at runtime, e.g. when the config file is reloaded
+func SetBranding(b *config.BrandingConfig) {
+	brandingLock.Lock()
+	defer brandingLock.Unlock()
+	branding = b
+}
+
+// an empty config if none is set, so callers needn't check
+func currentBranding() config.BrandingConfig {
+	brandingLock.RLock()
+	defer brandingLock.RUnlock()
+	if branding == nil {
+		return config.BrandingConfig{}
+	}
+	return *branding
+}
+
+// what the ui needs to brand itself. Public, since the login page shows it
+func UIConfig() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		b := currentBranding()
+		return c.JSON(http.StatusOK, H{
+			"result": H{
+				"organization": b.Organization,
+				"login_banner": b.Login_banner,
+				"accent_color": b.Accent_color,
+				"logo":         b.Logo_file != "",
+				// the ui skips its own calls to github
+				"air_gapped": vault.AirGapped(),
+				"oidc":       oidc.Config() != nil,
+				// empty unless the config file names login mounts, in which case only those and tokens are offered
+				"logins": vault.LoginOptions(),
+				// the ui's home link, which is under the listener's base path
+				"base_path": requestBasePath(c),
+			},
+		})
+	}
+}
+
+func UILogo() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		b := currentBranding()
+		if b.Logo_file == "" {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return c.File(b.Logo_file)
+	}
+}
+
+// linked from index.html, since the content security policy may not allow inline styles
+func UITheme() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		css := ""
+		if color := currentBranding().Accent_color; color != "" {
+			// the color was validated as a hex color, so it can't escape the stylesheet
+			css = fmt.Sprintf(":root { --goldfish-accent: %s; }\n"+
+				".app-navbar { border-bottom: 4px solid %s; }\n", color, color)
+		}
+		c.Response().Header().Set("Cache-Control", "no-cache")
+		return c.Blob(http.StatusOK, "text/css; charset=utf-8", []byte(css))
+	}
+}
+
+// brands the ui's index page before any script runs: the title, favicon, and accent color
+func BrandIndex(index []byte) []byte {
+	b := currentBranding()
+	if b == (config.BrandingConfig{}) {
+		return index
+	}
+
+	if b.Organization != "" {
+		index = bytes.Replace(index, []byte("<title>Goldfish</title>"),
+			[]byte("<title>Goldfish - "+html.EscapeString(b.Organization)+"</title>"), 1)
+	}
+	head := `<link rel="stylesheet" href="v1/ui-config/theme.css">`
+	if b.Logo_file != "" {
+		head += `<link rel="icon" href="v1/ui-config/logo">`
+	}
+	return bytes.Replace(index, []byte("</head>"), []byte(head+"</head>"), 1)
+}
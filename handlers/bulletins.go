@@ -3,9 +3,12 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/hashicorp/go-uuid"
 	"github.com/labstack/echo"
 )
 
+// scheduled and expired bulletins are only listed with ?all=true, e.g. for editing them
 func GetBulletins() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		// fetch auth from header or cookie
@@ -15,7 +18,13 @@ func GetBulletins() echo.HandlerFunc {
 		}
 		defer auth.Clear()
 
-		bulletins, err := auth.GetBulletins()
+		var bulletins []map[string]interface{}
+		var err error
+		if c.QueryParam("all") == "true" {
+			bulletins, err = auth.GetAllBulletins()
+		} else {
+			bulletins, err = auth.GetBulletins()
+		}
 		if err != nil {
 			return parseError(c, err)
 		}
@@ -25,3 +34,113 @@ func GetBulletins() echo.HandlerFunc {
 		})
 	}
 }
+
+// bulletins are written with the user's own token, so vault's policies on the bulletin path decide who may post them
+func CreateBulletin() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		b, ok := bindBulletin(c)
+		if !ok {
+			return nil
+		}
+
+		id, err := uuid.GenerateUUID()
+		if err != nil {
+			return parseError(c, err)
+		}
+		if err := auth.WriteBulletin(id, *b); err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": id,
+		})
+	}
+}
+
+func UpdateBulletin() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		id := c.Param("id")
+		if err := vault.CheckBulletinID(id); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+		b, ok := bindBulletin(c)
+		if !ok {
+			return nil
+		}
+
+		// editing never creates a bulletin under an id the user picked
+		existing, err := auth.ReadBulletin(id)
+		if err != nil {
+			return parseError(c, err)
+		}
+		if existing == nil {
+			return c.JSON(http.StatusNotFound, H{
+				"error": "Bulletin not found",
+			})
+		}
+
+		if err := auth.WriteBulletin(id, *b); err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Bulletin updated",
+		})
+	}
+}
+
+func DeleteBulletin() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		id := c.Param("id")
+		if err := vault.CheckBulletinID(id); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+		if err := auth.DeleteBulletin(id); err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Bulletin deleted",
+		})
+	}
+}
+
+// writes a 400 if the body is not a valid bulletin
+func bindBulletin(c echo.Context) (*vault.Bulletin, bool) {
+	b := new(vault.Bulletin)
+	if err := c.Bind(b); err != nil {
+		c.JSON(http.StatusBadRequest, H{
+			"error": "Invalid bulletin format",
+		})
+		return nil, false
+	}
+	if err := b.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, H{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+	return b, true
+}
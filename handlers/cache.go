@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/metrics"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// names of the cached lists, which writes to related endpoints bust
+const (
+	cacheAccessors = "accessors"
+	cachePolicies  = "policies"
+	cacheMounts    = "mounts"
+)
+
+type cacheEntry struct {
+	result  interface{}
+	expires time.Time
+}
+
+// entries are per list, then per session, since each token may see different results
+// the generation counts busts, so a list fetched during a write isn't cached afterwards
+var (
+	cacheTTL        time.Duration
+	cacheEntries    = make(map[string]map[[sha256.Size]byte]cacheEntry)
+	cacheGeneration uint64
+	cacheSwept      time.Time
+	cacheLock       = new(sync.Mutex)
+)
+
+func init() {
+	metrics.Describe("goldfish_list_cache_requests_total", "Number of cacheable list requests, by list and whether they were served from cache")
+}
+
+// may be called again at runtime, e.g. when the config file is reloaded. A ttl of 0 disables caching
+func SetListCacheTTL(ttl time.Duration) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	cacheTTL = ttl
+	cacheEntries = make(map[string]map[[sha256.Size]byte]cacheEntry)
+	cacheGeneration++
+}
+
+// returns the session's cached list, or fetches and caches it
+// a request with "Cache-Control: no-cache" always fetches, e.g. for a refresh button
+func cachedList(c echo.Context, auth *vault.AuthInfo, list string, fetch func() (interface{}, error)) (interface{}, error) {
+	cacheLock.Lock()
+	ttl := cacheTTL
+	cacheLock.Unlock()
+	if ttl == 0 {
+		return fetch()
+	}
+
+	// the token is hashed, so it isn't kept in memory any longer than the request
+	key := sha256.Sum256([]byte(auth.Cluster + "\x00" + auth.Namespace + "\x00" + auth.ID))
+	refresh := strings.Contains(c.Request().Header.Get("Cache-Control"), "no-cache")
+
+	cacheLock.Lock()
+	entry, ok := cacheEntries[list][key]
+	generation := cacheGeneration
+	cacheLock.Unlock()
+	if ok && !refresh && time.Now().Before(entry.expires) {
+		countCacheRequest(list, "hit")
+		return entry.result, nil
+	}
+	countCacheRequest(list, "miss")
+
+	result, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	if generation != cacheGeneration {
+		return result, nil
+	}
+	now := time.Now()
+	if now.Sub(cacheSwept) > time.Minute {
+		for _, entries := range cacheEntries {
+			for k, e := range entries {
+				if now.After(e.expires) {
+					delete(entries, k)
+				}
+			}
+		}
+		cacheSwept = now
+	}
+	if cacheEntries[list] == nil {
+		cacheEntries[list] = make(map[[sha256.Size]byte]cacheEntry)
+	}
+	cacheEntries[list][key] = cacheEntry{result: result, expires: now.Add(cacheTTL)}
+	return result, nil
+}
+
+// drops the lists for every session, since a write by one user changes what all of them see
+func bustCache(lists ...string) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	cacheGeneration++
+	for _, list := range lists {
+		delete(cacheEntries, list)
+	}
+}
+
+func countCacheRequest(list, result string) {
+	metrics.IncrCounter("goldfish_list_cache_requests_total", map[string]string{
+		"list":   list,
+		"result": result,
+	})
+}
+
+// raw requests may write anything, so any that isn't a read busts every list
+func bustCacheForRaw(method string) {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, "LIST":
+	default:
+		bustCache(cacheAccessors, cachePolicies, cacheMounts)
+	}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/config"
+	"github.com/caiyeon/goldfish/request"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// slack callbacks signed longer ago than this are refused, so a captured one can't be replayed
+const slackCallbackMaxAge = 5 * time.Minute
+
+var (
+	chatOps     *config.ChatOpsConfig
+	chatOpsLock = new(sync.RWMutex)
+)
+
+// may be called again at runtime, e.g. when the config file is reloaded
+func SetChatOps(c *config.ChatOpsConfig) {
+	chatOpsLock.Lock()
+	defer chatOpsLock.Unlock()
+	chatOps = c
+}
+
+func getChatOps() *config.ChatOpsConfig {
+	chatOpsLock.RLock()
+	defer chatOpsLock.RUnlock()
+	return chatOps
+}
+
+// the part of slack's interactive message payload goldfish acts on
+type slackCallback struct {
+	CallbackID string `json:"callback_id"`
+	User       struct {
+		ID string `json:"id"`
+	} `json:"user"`
+	Actions []struct {
+		Name     string `json:"name"`
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+}
+
+// checks slack's signature, an hmac of "v0:<timestamp>:<body>" keyed with the app's signing secret
+func verifySlackSignature(secret []byte, timestamp, signature string, body []byte, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("Missing or invalid request timestamp")
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > slackCallbackMaxAge || age < -slackCallbackMaxAge {
+		return errors.New("Request timestamp is too far from the current time")
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.New("Invalid request signature")
+	}
+	return nil
+}
+
+// slack shows the reply in place of the message, or only to the user who clicked if ephemeral
+func slackReply(c echo.Context, text string, replace bool) error {
+	reply := H{
+		"text":             text,
+		"replace_original": replace,
+	}
+	if !replace {
+		reply["response_type"] = "ephemeral"
+	}
+	return c.JSON(http.StatusOK, reply)
+}
+
+// receives the buttons of change requests posted to slack
+// rejecting is done with goldfish's own token, as the approver the clicking slack user is mapped to.
+// Approving needs an unseal key, which is never sent through chat, so approvers are linked to the ui instead
+func SlackCallback() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		conf := getChatOps()
+		if conf == nil {
+			return c.JSON(http.StatusNotFound, H{
+				"error": "Chatops is not configured",
+			})
+		}
+
+		body, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			return parseError(c, err)
+		}
+		secret, err := ioutil.ReadFile(conf.Signing_secret_file)
+		if err != nil {
+			log.Printf("[ERROR]: Could not read chatops signing secret: %s\n", err.Error())
+			return c.JSON(http.StatusInternalServerError, H{
+				"error": "Could not verify the request",
+			})
+		}
+		if err := verifySlackSignature([]byte(strings.TrimSpace(string(secret))),
+			c.Request().Header.Get("X-Slack-Request-Timestamp"),
+			c.Request().Header.Get("X-Slack-Signature"), body, time.Now()); err != nil {
+			log.Printf("[WARN ]: Refused slack callback from %s: %s\n", clientIP(c), err.Error())
+			return c.JSON(http.StatusUnauthorized, H{
+				"error": err.Error(),
+			})
+		}
+
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid request body",
+			})
+		}
+		var cb slackCallback
+		if err := json.Unmarshal([]byte(form.Get("payload")), &cb); err != nil || len(cb.Actions) == 0 {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid callback payload",
+			})
+		}
+
+		approver, ok := conf.Approvers[cb.User.ID]
+		if !ok {
+			log.Printf("[WARN ]: Refused slack callback from unmapped user %s\n", cb.User.ID)
+			return slackReply(c, "You are not a goldfish approver", false)
+		}
+
+		// legacy attachments name their buttons, block kit gives them action ids
+		action := cb.Actions[0]
+		name := action.Name
+		if name == "" {
+			name = action.ActionID
+		}
+		hash := action.Value
+
+		switch name {
+		case "reject":
+			if !vault.Bootstrapped() {
+				return slackReply(c, "Goldfish is not bootstrapped", false)
+			}
+			auth := vault.GoldfishAuth()
+			if err := request.Reject(&auth, hash); err != nil {
+				return slackReply(c, "Could not reject request "+hash+": "+err.Error(), false)
+			}
+			log.Printf("[INFO ]: Request %s rejected by %s from slack (user %s)\n", hash, approver, cb.User.ID)
+			return slackReply(c, "Request *"+hash+"* was rejected by "+approver, true)
+
+		case "approve":
+			text := "Approving needs an unseal key, which must never be sent through chat. Approve request *" + hash + "* in goldfish"
+			if conf.Ui_address != "" {
+				text += ": " + conf.Ui_address + "/#/requests"
+			}
+			return slackReply(c, text, false)
+		}
+		return slackReply(c, "Unknown action "+name, false)
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/caiyeon/goldfish/session"
+	"github.com/caiyeon/goldfish/tracing"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// names the caller's session on the cluster secrets are copied to, alongside X-Vault-Token for the one they come from
+const destinationSessionHeader = "X-Goldfish-Destination-Token"
+
+// one secret of a copy. Status is create, update or unchanged in a dry run, and copied, unchanged or failed once run
+type secretCopy struct {
+	Path   string                 `json:"path"`
+	Status string                 `json:"status"`
+	Diff   map[string]interface{} `json:"diff,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// the caller's session on the destination cluster. Only sessions created by logging in may be used,
+// so both clusters' tokens stay server-side. Writes the refusal if there is none
+func destinationSession(c echo.Context) *vault.AuthInfo {
+	id := c.Request().Header.Get(destinationSessionHeader)
+	if !strings.HasPrefix(id, session.Prefix) {
+		c.JSON(http.StatusBadRequest, H{
+			"error": destinationSessionHeader + " must be a goldfish session on the destination cluster",
+		})
+		return nil
+	}
+	s, err := session.Get(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, H{
+			"error": "Goldfish could not read session: " + err.Error(),
+		})
+		return nil
+	}
+	if s == nil {
+		c.JSON(http.StatusUnauthorized, H{
+			"error": "The destination cluster's session has expired. Please login to it again",
+		})
+		return nil
+	}
+
+	token, err := s.VaultToken(id)
+	if err != nil {
+		c.JSON(http.StatusForbidden, H{
+			"error": "The destination cluster's session is invalid. Please login to it again",
+		})
+		return nil
+	}
+	auth := &vault.AuthInfo{
+		Type:      "token",
+		ID:        token,
+		Cluster:   s.Cluster,
+		Namespace: s.Namespace,
+		Trace:     tracing.FromContext(c),
+		Audit:     auditInfo(c, s),
+	}
+	if s.Transit != "" {
+		if err := auth.DecryptSession(s.Transit); err != nil {
+			c.JSON(http.StatusForbidden, H{
+				"error": "The destination cluster's session is invalid. Please login to it again",
+			})
+			return nil
+		}
+	}
+	// goldfish's own roles apply on the destination too
+	if !checkRoles(c, auth) {
+		auth.Clear()
+		return nil
+	}
+	return auth
+}
+
+func clusterName(cluster string) string {
+	if cluster == "" {
+		return "goldfish's own cluster"
+	}
+	return cluster
+}
+
+// copies a kv secret, or every secret under a folder ending in '/', from the session's cluster to another
+// a dry run shows which keys of each secret would be added, removed or changed. Values are never shown
+func CopySecretsAcrossClusters() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		src := getSession(c)
+		if src == nil {
+			return nil
+		}
+		defer src.Clear()
+		dst := destinationSession(c)
+		if dst == nil {
+			return nil
+		}
+		defer dst.Clear()
+
+		var body struct {
+			Source      string `json:"source"`
+			Destination string `json:"destination"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid request format",
+			})
+		}
+		from := strings.TrimPrefix(body.Source, "/")
+		to := strings.TrimPrefix(body.Destination, "/")
+		if to == "" {
+			to = from
+		}
+		if from == "" || strings.HasSuffix(from, "/") != strings.HasSuffix(to, "/") {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Source and destination must both be secrets, or both be folders ending in '/'",
+			})
+		}
+		if src.Cluster == dst.Cluster && src.Namespace == dst.Namespace && from == to {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Source and destination are the same secret",
+			})
+		}
+
+		// a single secret is a folder of one, relative to its parent
+		paths := []string{""}
+		if strings.HasSuffix(from, "/") {
+			var err error
+			if paths, err = src.ListKV(from); err != nil {
+				return parseError(c, err)
+			}
+		}
+
+		// the source is read once, so a dry run shows exactly what would be written
+		copies := make([]secretCopy, 0, len(paths))
+		data := make(map[string]map[string]interface{}, len(paths))
+		for _, p := range paths {
+			secret, err := src.ReadKV(from + p)
+			if err != nil {
+				return parseError(c, err)
+			}
+			if secret == nil {
+				continue
+			}
+			existing, err := dst.ReadKV(to + p)
+			if err != nil {
+				return parseError(c, err)
+			}
+			raw, err := json.Marshal(secret)
+			if err != nil {
+				return parseError(c, err)
+			}
+			diff, err := secretDiff(existing, string(raw))
+			if err != nil {
+				return parseError(c, err)
+			}
+
+			cp := secretCopy{Path: to + p, Diff: diff, Status: "update"}
+			if existing == nil {
+				cp.Status = "create"
+			} else if len(diff["added"].([]string))+len(diff["removed"].([]string))+len(diff["changed"].([]string)) == 0 {
+				cp.Status = "unchanged"
+			}
+			copies = append(copies, cp)
+			data[cp.Path] = secret
+		}
+
+		if isDryRun(c) {
+			dataPath, err := dst.KVDataPath(to)
+			if err != nil {
+				return parseError(c, err)
+			}
+			return dryRun(c, dst, "POST", dataPath, map[string]interface{}{
+				"source_cluster":      src.Cluster,
+				"destination_cluster": dst.Cluster,
+				"secrets":             copies,
+			})
+		}
+
+		copied := 0
+		for i := range copies {
+			if copies[i].Status == "unchanged" {
+				continue
+			}
+			if err := dst.WriteKV(copies[i].Path, data[copies[i].Path]); err != nil {
+				copies[i].Status = "failed"
+				copies[i].Error = err.Error()
+				continue
+			}
+			copies[i].Status = "copied"
+			copied++
+		}
+		log.Printf("[INFO ]: Request %s: copied %d secrets from %s on %s to %s on %s\n", src.Audit.RequestID,
+			copied, from, clusterName(src.Cluster), to, clusterName(dst.Cluster))
+
+		return c.JSON(http.StatusOK, H{
+			"result": copies,
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// how long a confirmation token may be used for, once the first request has described the action
+const confirmationTTL = 2 * time.Minute
+
+// a pending destructive action. Tokens only confirm the exact action they were issued for, to the same caller
+type confirmation struct {
+	caller  [sha256.Size]byte
+	action  string
+	target  string
+	expires time.Time
+}
+
+var (
+	requireConfirmation bool
+	confirmations       = make(map[[sha256.Size]byte]confirmation)
+	confirmLock         = new(sync.Mutex)
+)
+
+// may be called again at runtime, e.g. when the config file is reloaded
+func SetRequireConfirmation(on bool) {
+	confirmLock.Lock()
+	defer confirmLock.Unlock()
+	requireConfirmation = on
+}
+
+// reports whether a destructive action may go ahead, writing the response if it may not
+// without a confirmation token, the response describes the action and holds a token to confirm it with
+// details returns whatever helps the user check what they're about to delete, e.g. a secret's keys
+func confirmed(c echo.Context, action, target string, details func() interface{}) bool {
+	confirmLock.Lock()
+	on := requireConfirmation
+	confirmLock.Unlock()
+	if !on {
+		return true
+	}
+	return confirmedAlways(c, action, target, details)
+}
+
+// as confirmed, for actions that are confirmed whether or not require_confirmation is set
+func confirmedAlways(c echo.Context, action, target string, details func() interface{}) bool {
+	caller := sha256.Sum256([]byte(sessionHeader(c)))
+	if token := c.QueryParam("confirmation"); token != "" {
+		// tokens are single use, whether or not they match
+		key := sha256.Sum256([]byte(token))
+		confirmLock.Lock()
+		p, ok := confirmations[key]
+		delete(confirmations, key)
+		confirmLock.Unlock()
+		if ok && p.caller == caller && p.action == action && p.target == target && time.Now().Before(p.expires) {
+			return true
+		}
+		c.JSON(http.StatusConflict, H{
+			"error": "Confirmation token is invalid, expired, or for a different action",
+			"code":  "confirmation_invalid",
+		})
+		return false
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		parseError(c, err)
+		return false
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	described := details()
+
+	now := time.Now()
+	confirmLock.Lock()
+	for k, p := range confirmations {
+		if now.After(p.expires) {
+			delete(confirmations, k)
+		}
+	}
+	confirmations[sha256.Sum256([]byte(token))] = confirmation{
+		caller:  caller,
+		action:  action,
+		target:  target,
+		expires: now.Add(confirmationTTL),
+	}
+	confirmLock.Unlock()
+
+	c.JSON(http.StatusPreconditionRequired, H{
+		"error": "Nothing was changed. Repeat the request with ?confirmation=<token> to " + action + " " + target,
+		"code":  "confirmation_required",
+		"result": map[string]interface{}{
+			"confirmation": token,
+			"action":       action,
+			"target":       target,
+			"details":      described,
+			"expires":      now.Add(confirmationTTL).UTC().Format(time.RFC3339),
+		},
+	})
+	return false
+}
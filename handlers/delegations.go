@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// accepts a time, or a date which covers the whole day
+func parseDelegationTime(s string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return t, errors.New("Invalid time " + s + ", expected e.g. 2018-01-02 or 2018-01-02T15:04:05Z")
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// lists the delegations made by or to the caller, including those that have ended
+func GetDelegations() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		user, err := auth.SelfIdentity()
+		if err != nil {
+			return parseError(c, err)
+		}
+		delegations, err := vault.ListDelegations()
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		result := make([]vault.Delegation, 0)
+		for _, d := range delegations {
+			if d.FromEntity == user.EntityID || d.ToEntity == user.EntityID {
+				result = append(result, d)
+			}
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
+// delegates the caller's approvals of change requests to another user, from start (or now) until end
+// the delegate still needs an unseal key of their own to approve
+func CreateDelegation() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		user, err := auth.SelfIdentity()
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		start := time.Now()
+		if s := c.FormValue("start"); s != "" {
+			if start, err = parseDelegationTime(s, false); err != nil {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": err.Error(),
+				})
+			}
+		}
+		end, err := parseDelegationTime(c.FormValue("end"), true)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
+		to, err := vault.LookupIdentity(c.FormValue("to"))
+		if err != nil {
+			return parseError(c, err)
+		}
+		d, err := vault.AddDelegation(user, to, start, end)
+		if err != nil {
+			return parseError(c, err)
+		}
+		log.Printf("[INFO ]: %s delegated approvals to %s from %s until %s\n", d.From, d.To,
+			d.Start.Format(time.RFC3339), d.End.Format(time.RFC3339))
+
+		return c.JSON(http.StatusOK, H{
+			"result": d,
+		})
+	}
+}
+
+func DeleteDelegation() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		user, err := auth.SelfIdentity()
+		if err != nil {
+			return parseError(c, err)
+		}
+		if err := vault.DeleteDelegation(c.Param("id"), user.EntityID); err != nil {
+			return parseError(c, err)
+		}
+		log.Printf("[INFO ]: Delegation %s removed by %s\n", c.Param("id"), user.Name)
+
+		return c.JSON(http.StatusOK, H{
+			"status": "deleted",
+		})
+	}
+}
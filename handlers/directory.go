@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo"
+)
+
+// results of a directory search, unless a smaller limit is asked for
+const maxDirectoryResults = 100
+
+// searches ldap and identity users and groups by name, for pickers that should name real principals
+func SearchDirectory() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		limit := 20
+		if l := c.QueryParam("limit"); l != "" {
+			n, err := strconv.Atoi(l)
+			if err != nil || n < 1 || n > maxDirectoryResults {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "limit must be between 1 and " + strconv.Itoa(maxDirectoryResults),
+				})
+			}
+			limit = n
+		}
+
+		results, err := auth.SearchDirectory(c.QueryParam("q"), c.QueryParam("kind"), c.QueryParam("source"), limit)
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": results,
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// endpoints that honour ?dry_run=true. Since their dry runs change nothing,
+// read-only mode and step-up re-authentication let them through
+var dryRunRoutes = map[string]bool{
+	"POST /v1/secrets":               true,
+	"DELETE /v1/secrets":             true,
+	"POST /v1/secrets/copy":          true,
+	"DELETE /v1/policy":              true,
+	"POST /v1/mount":                 true,
+	"POST /v1/kv/migrate":            true,
+	"POST /v1/quotas":                true,
+	"DELETE /v1/quotas":              true,
+	"POST /v1/token/revoke-accessor": true,
+	"POST /v1/userpass/delete":       true,
+	"POST /v1/approle/delete":        true,
+}
+
+func isDryRun(c echo.Context) bool {
+	return c.QueryParam("dry_run") == "true" && dryRunRoutes[c.Request().Method+" "+c.Path()]
+}
+
+// answers a dry run with what the request would change, once the caller's capabilities allow it
+// method and path are the vault request that would have been made
+func dryRun(c echo.Context, auth *vault.AuthInfo, method, path string, change interface{}) error {
+	if err := auth.RawPreflight(method, path); err != nil {
+		if strings.HasPrefix(err.Error(), "Permission denied") {
+			return c.JSON(http.StatusForbidden, H{
+				"error":   err.Error(),
+				"dry_run": true,
+			})
+		}
+		return parseError(c, err)
+	}
+	return c.JSON(http.StatusOK, H{
+		"dry_run": true,
+		"result": map[string]interface{}{
+			"method": method,
+			"path":   path,
+			"change": change,
+		},
+	})
+}
+
+// compares a secret's keys before and after a write. Values are never shown, only whether they change
+func secretDiff(before map[string]interface{}, raw string) (map[string]interface{}, error) {
+	var after map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &after); err != nil {
+		return nil, err
+	}
+
+	added, removed, changed, unchanged := []string{}, []string{}, []string{}, []string{}
+	for k, v := range after {
+		old, ok := before[k]
+		switch {
+		case !ok:
+			added = append(added, k)
+		case jsonEqual(old, v):
+			unchanged = append(unchanged, k)
+		default:
+			changed = append(changed, k)
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	for _, keys := range [][]string{added, removed, changed, unchanged} {
+		sort.Strings(keys)
+	}
+
+	return map[string]interface{}{
+		"added":     added,
+		"removed":   removed,
+		"changed":   changed,
+		"unchanged": unchanged,
+	}, nil
+}
+
+// vault returns numbers as json.Number, so values are compared by their encoding
+func jsonEqual(a, b interface{}) bool {
+	x, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	y, err := json.Marshal(b)
+	return err == nil && string(x) == string(y)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+// sends a json response with a weak etag of its body, or 304 if the client's If-None-Match already names it,
+// so polling clients only download large lists when they change
+// not for secrets: an etag of a secret's values could be used to check guesses of them
+func jsonWithETag(c echo.Context, i interface{}) error {
+	body, err := json.Marshal(i)
+	if err != nil {
+		return parseError(c, err)
+	}
+	sum := sha256.Sum256(body)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Response().Header().Set("ETag", etag)
+	if etagMatches(c.Request().Header.Get("If-None-Match"), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+	return c.JSONBlob(http.StatusOK, body)
+}
+
+// If-None-Match uses the weak comparison, so W/ prefixes are ignored
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -1,19 +1,152 @@
 package handlers
 
 import (
+	"bufio"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/caiyeon/goldfish/session"
+	"github.com/caiyeon/goldfish/tracing"
 	"github.com/caiyeon/goldfish/vault"
+	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/labstack/echo"
 )
 
 // for returning JSON bodies
 type H map[string]interface{}
 
+// sessions are stateless, so recently seen session headers are tracked by hash instead
+// entries older than activeSessionWindow are swept out as new ones are added, at most once a minute
+var (
+	sessionsSeen     = make(map[[sha256.Size]byte]time.Time)
+	sessionsSwept    time.Time
+	sessionsSeenLock = new(sync.Mutex)
+)
+
+const activeSessionWindow = 30 * time.Minute
+
+func sessionSeen(header string) {
+	sessionsSeenLock.Lock()
+	defer sessionsSeenLock.Unlock()
+	now := time.Now()
+	sessionsSeen[sha256.Sum256([]byte(header))] = now
+	if now.Sub(sessionsSwept) > time.Minute {
+		sweepSessionsSeen(now)
+	}
+}
+
+// must be called with sessionsSeenLock held
+func sweepSessionsSeen(now time.Time) {
+	for k, t := range sessionsSeen {
+		if now.Sub(t) > activeSessionWindow {
+			delete(sessionsSeen, k)
+		}
+	}
+	sessionsSwept = now
+}
+
+// returns the number of distinct sessions that made a request in the last 30 minutes
+func ActiveSessions() int {
+	sessionsSeenLock.Lock()
+	defer sessionsSeenLock.Unlock()
+	sweepSessionsSeen(time.Now())
+	return len(sessionsSeen)
+}
+
+// reports which vault node served each api request's vault calls, in the X-Goldfish-Vault-Node header
+// the node is recorded per request by the vault clients made for it, so concurrent requests never see each other's
+func VaultNodeHeader() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if strings.HasPrefix(c.Request().URL.Path, "/v1/") {
+				served := new(vault.ServedNode)
+				c.Set("vault_node", served)
+				c.Response().Writer = &vaultNodeWriter{c.Response().Writer, served}
+			}
+			return next(c)
+		}
+	}
+}
+
+// the node is only known once the handler is done with vault, so the header is set at the last moment
+// requests that made no vault calls of their own have no node to report
+type vaultNodeWriter struct {
+	http.ResponseWriter
+	served *vault.ServedNode
+}
+
+func (w *vaultNodeWriter) WriteHeader(code int) {
+	if node := w.served.String(); node != "" {
+		w.Header().Set("X-Goldfish-Vault-Node", node)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *vaultNodeWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *vaultNodeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("response can not be hijacked")
+}
+
+// while the vault cluster a request is for is sealed or unreachable, it fails fast with a 503 instead of timing out
+// health endpoints stay available, so the state can be seen
+func VaultCircuitBreaker() echo.MiddlewareFunc {
+	exempt := map[string]bool{
+		"/v1/health":       true,
+		"/v1/health/live":  true,
+		"/v1/health/ready": true,
+		"/v1/vaulthealth":  true,
+		"/v1/clusters":     true,
+		"/v1/openapi.json": true,
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			path := c.Request().URL.Path
+			if !strings.HasPrefix(path, "/v1/") || exempt[path] {
+				return next(c)
+			}
+			if state := vault.GetClusterState(requestCluster(c)); !state.Healthy() {
+				return c.JSON(http.StatusServiceUnavailable, H{
+					"error":       "Vault is " + state.State + ", please try again later",
+					"vault_state": state,
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+// the cluster a request is for: the one named by the replication endpoints, or the session's,
+// or for raw tokens and logins, the one named in the X-Goldfish-Cluster header
+// transit encrypted ciphers carry their own cluster, which is only known once decrypted, so they use the header too
+func requestCluster(c echo.Context) string {
+	if cluster := c.QueryParam("cluster"); cluster != "" {
+		return cluster
+	}
+	if id := sessionHeader(c); strings.HasPrefix(id, session.Prefix) || strings.HasPrefix(id, session.APIPrefix) {
+		if s, err := lookupSession(c, id); err == nil && s != nil {
+			return s.Cluster
+		}
+	}
+	return c.Request().Header.Get("X-Goldfish-Cluster")
+}
+
 // returns the http status code found in the error message
 func parseError(c echo.Context, err error) error {
 	// if error came from vault, relay it
@@ -22,6 +155,14 @@ func parseError(c echo.Context, err error) error {
 	if len(errCode) > 1 && len(errMsgs) > 1 {
 		code := 500
 		fmt.Sscanf(errCode[1], "%d", &code)
+		// throttled by one of vault's quotas, which operators look into rather than the caller's access
+		if code == http.StatusTooManyRequests {
+			log.Printf("[WARN ]: Vault quota exceeded on %s %s\n", c.Request().Method, c.Path())
+			return c.JSON(code, H{
+				"error": "Vault: " + errMsgs[1],
+				"code":  "quota_exceeded",
+			})
+		}
 		return c.JSON(code, H{
 			"error": "Vault: " + errMsgs[1],
 		})
@@ -46,31 +187,164 @@ func VaultHealth() echo.HandlerFunc {
 	}
 }
 
+// goldfish's own checks of vault over time: seal and unseal events, standby transitions, uptime and latency
+// window limits it to e.g. the last "1h". By default everything kept by health_history is returned
+func VaultHealthHistory() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// without a session, the history is only shown if the status page is on,
+		// and like the status page, without node addresses or vault's error messages
+		public := sessionHeader(c) == ""
+		if public && !statusPageEnabled() {
+			return c.JSON(http.StatusForbidden, H{
+				"error": "Please login first",
+			})
+		}
+		if !public {
+			// fetch auth from header or cookie
+			auth := getSession(c)
+			if auth == nil {
+				return nil
+			}
+			auth.Clear()
+		}
+
+		window := time.Duration(0)
+		if w := c.QueryParam("window"); w != "" {
+			var err error
+			if window, err = time.ParseDuration(w); err != nil || window < 0 {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "window must be a duration, e.g. \"1h\"",
+				})
+			}
+		}
+		history := vault.GetHealthHistory(window)
+		if public {
+			history = history.Public()
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": history,
+		})
+	}
+}
+
+// lists the clusters users may choose at login, besides goldfish's own
+func Clusters() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, H{
+			"result": vault.ClusterNames(),
+		})
+	}
+}
+
+// set once at startup, shown by the health endpoint
+var (
+	version   = ""
+	commit    = ""
+	buildDate = ""
+	started   = time.Now()
+)
+
+func SetVersion(v, c, date string) {
+	version, commit, buildDate = v, c, date
+}
+
+// the original fields are kept as they were, so existing checks don't break
 func Health() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		bootstrapped := vault.Bootstrapped()
+		state := vault.GetVaultState()
 
+		// a server token that can't be looked up is reported, rather than failing the whole check
 		deployment_time_utc := ""
+		token := H{}
 		if bootstrapped {
-			// check server token
-			resp, err := vault.LookupSelf()
-			if err != nil {
-				return parseError(c, err)
+			if resp, err := vault.LookupSelf(); err != nil {
+				token["error"] = err.Error()
+			} else {
+				deployment_time_utc = string(resp["creation_time"].(json.Number))
+				token["ttl_seconds"] = int64(tokenTTL(resp["ttl"]) / time.Second)
+				token["renewable"], _ = resp["renewable"].(bool)
+				token["expire_time"] = resp["expire_time"]
 			}
-			deployment_time_utc = string(resp["creation_time"].(json.Number))
 		}
 
 		// check transit encryption config
-		transitEnabled := vault.GetConfig().ServerTransitKey != ""
+		transitEnabled := vault.SessionTransitKey() != ""
+
+		storeType, err := session.Health()
+		store := H{
+			"type":    storeType,
+			"healthy": err == nil,
+		}
+		if err != nil {
+			store["error"] = err.Error()
+		}
 
 		return c.JSON(http.StatusOK, H{
+			"version":             version,
+			"commit":              commit,
+			"started_at":          started.UTC().Format(time.RFC3339),
+			"uptime_seconds":      int64(time.Since(started) / time.Second),
 			"bootstrapped":        bootstrapped,
 			"deployment_time_utc": deployment_time_utc,
+			"server_token":        token,
+			"read_only":           isReadOnly(),
 			"transit_encryption":  transitEnabled,
+			"session_store":       store,
+			"vault_node":          vault.CurrentNode(),
+			"vault_sealed":        state.State == vault.StateSealed,
+			"vault_state":         state,
+		})
+	}
+}
+
+// passes as long as the process can serve requests, so vault maintenance never restarts goldfish
+func Liveness() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, H{
+			"status": "ok",
 		})
 	}
 }
 
+// passes once goldfish is bootstrapped, vault is unsealed and reachable, and the server token is valid
+// with ready_while_sealed, vault being sealed or down is reported without failing the check
+func Readiness() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		r := checkReadiness()
+		status := http.StatusOK
+		if !r.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		return c.JSON(status, H{
+			"ready":        r.Ready,
+			"bootstrapped": r.Bootstrapped,
+			"token_valid":  r.TokenValid,
+			"vault_state":  r.VaultState,
+		})
+	}
+}
+
+type readiness struct {
+	Ready        bool
+	Bootstrapped bool
+	TokenValid   bool
+	VaultState   vault.VaultState
+}
+
+func checkReadiness() readiness {
+	r := readiness{
+		Bootstrapped: vault.Bootstrapped(),
+		VaultState:   vault.GetVaultState(),
+	}
+	if r.Bootstrapped && r.VaultState.Healthy() {
+		_, err := vault.LookupSelf()
+		r.TokenValid = err == nil
+	}
+	r.Ready = r.Bootstrapped && ((r.VaultState.Healthy() && r.TokenValid) || (!r.VaultState.Healthy() && vault.ReadyWhileSealed()))
+	return r
+}
+
 func Bootstrap() echo.HandlerFunc {
 	// scoped struct is fine, nothing else needs to know this
 	type wrapstruct struct {
@@ -110,6 +384,51 @@ func Bootstrap() echo.HandlerFunc {
 	}
 }
 
+// replaces goldfish's vault credentials without a restart
+// the user's token may be sent raw, since goldfish's transit decryption won't work if its token was revoked
+func Rebootstrap() echo.HandlerFunc {
+	type wrapstruct struct {
+		Wrapping_token string
+	}
+
+	return func(c echo.Context) error {
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		if err := auth.CanRebootstrap(); err != nil {
+			return c.JSON(http.StatusForbidden, H{
+				"error": err.Error(),
+			})
+		}
+
+		wrap := new(wrapstruct)
+		if err := c.Bind(wrap); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid format",
+			})
+		}
+		if wrap.Wrapping_token == "" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Empty wrapping token",
+			})
+		}
+
+		if err := vault.StartGoldfishWrapper(wrap.Wrapping_token); err != nil {
+			return c.JSON(http.StatusInternalServerError, H{
+				"error": err.Error(),
+			})
+		}
+
+		log.Println("[INFO ]: Goldfish was re-bootstrapped")
+		return c.JSON(http.StatusOK, H{
+			"result": "success",
+		})
+	}
+}
+
 func Login() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		// if vault wrapper is not initialized, errors for everyone!
@@ -129,45 +448,172 @@ func Login() echo.HandlerFunc {
 				"error": "Invalid auth format",
 			})
 		}
+		auth.Trace = tracing.FromContext(c)
+		auth.Audit = auditInfo(c, nil)
 		if auth.Type == "" || auth.ID == "" {
 			return c.JSON(http.StatusBadRequest, H{
 				"error": "Empty authentication",
 			})
 		}
+		namespace, err := vault.NormalizeNamespace(auth.Namespace)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+		auth.Namespace = namespace
+
+		// repeated failures from one ip or for one username must wait, then are locked out
+		keys := loginKeys(c, auth)
+		if wait := loginWait(keys); wait > 0 {
+			seconds := int((wait + time.Second - 1) / time.Second)
+			c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+			return c.JSON(http.StatusTooManyRequests, H{
+				"error": fmt.Sprintf("Too many failed logins, please try again in %d seconds", seconds),
+			})
+		}
 
 		// verify auth details and create client access token
+		typ := strings.ToLower(auth.Type)
 		data, err := auth.Login()
 		if err != nil {
+			if isAuthFailure(err) {
+				loginFailed(keys)
+				// vault only says permission denied, which reads like a policy problem
+				if typ == "token" {
+					return c.JSON(http.StatusForbidden, H{
+						"error": "The token is invalid, expired, or has been revoked",
+					})
+				}
+			}
 			return parseError(c, err)
 		}
+		loginSucceeded(keys)
+		bustCache(cacheAccessors)
+
+		// an existing token that is about to expire would fail moments into the session
+		if ttl := tokenTTL(data["ttl"]); typ == "token" && ttl > 0 && ttl < minTokenLoginTTL {
+			return c.JSON(http.StatusForbidden, H{
+				"error": fmt.Sprintf("The token expires in %s. Renew it, or log in with a new one", ttl),
+			})
+		}
+
+		return startSession(c, auth, data)
+	}
+}
+
+// creates a session for a token that was just logged in with, and writes the response login returns
+// data is the token's lookup-self data
+func startSession(c echo.Context, auth *vault.AuthInfo, data map[string]interface{}) error {
+	cluster := auth.Cluster
+
+	// if goldfish is configured to use transit encryption
+	key := vault.SessionTransitKey()
+	if key != "" {
+		// encrypt auth.ID with vault's transit backend
+		if err := auth.EncryptSession(key); err != nil {
+			return c.JSON(http.StatusInternalServerError, H{
+				"error": "Goldfish could not use transit key: " + err.Error(),
+			})
+		}
+	}
+
+	// the user only ever sees a session id, the token itself stays server-side
+	displayName, _ := data["display_name"].(string)
+	accessor, _ := data["accessor"].(string)
+	id, err := session.New(auth.ID, key, cluster, auth.Namespace, displayName, sessionOwner(cluster, auth.Namespace, data),
+		auth.Type, accessor, clientIP(c), tokenTTL(data["ttl"]))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, H{
+			"error": "Goldfish could not create a session: " + err.Error(),
+		})
+	}
+	log.Printf("[INFO ]: Request %s: logged in as %s (accessor %s)\n", auth.Audit.RequestID, displayName, accessor)
+
+	// return useful information to user
+	return c.JSON(http.StatusOK, H{
+		"status": "Logged in",
+		"result": map[string]interface{}{
+			"cipher":       id,
+			"cluster":      cluster,
+			"namespace":    auth.Namespace,
+			"display_name": data["display_name"],
+			"meta":         data["meta"],
+			"policies":     data["policies"],
+			"renewable":    data["renewable"],
+			"ttl":          data["ttl"],
+			"expire_time":  data["expire_time"],
+		},
+	})
+}
 
-		// if goldfish is configured to use transit encryption
-		if conf := vault.GetConfig(); conf.ServerTransitKey != "" {
-			// encrypt auth.ID with vault's transit backend
-			if err := auth.EncryptAuth(); err != nil {
+func Logout() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// only server-side sessions can be revoked here, other headers are simply forgotten by the client
+		if id := c.Request().Header.Get("X-Vault-Token"); strings.HasPrefix(id, session.Prefix) {
+			if err := session.Delete(id); err != nil {
 				return c.JSON(http.StatusInternalServerError, H{
-					"error": "Goldfish could not use transit key: " + err.Error(),
+					"error": "Goldfish could not delete session: " + err.Error(),
 				})
 			}
 		}
-
-		// return useful information to user
 		return c.JSON(http.StatusOK, H{
-			"status": "Logged in",
-			"result": map[string]interface{}{
-				"cipher":       auth.ID,
-				"display_name": data["display_name"],
-				"id":           data["id"],
-				"meta":         data["meta"],
-				"policies":     data["policies"],
-				"renewable":    data["renewable"],
-				"ttl":          data["ttl"],
-			},
+			"status": "Logged out",
 		})
 	}
 }
 
+// the ui logs the user out when it sees this error code
+func sessionExpired(c echo.Context) error {
+	return c.JSON(http.StatusUnauthorized, H{
+		"error": "Session expired, please login again",
+		"code":  "session_expired",
+	})
+}
+
+// sessions belong to the token's identity entity, across logins. Display names are not used, since
+// different auth mounts and methods can give different users the same one
+// tokens without an entity, e.g. on vaults before 0.9, belong to the auth mount and name they logged in with,
+// and tokens created directly only belong to themselves
+func sessionOwner(cluster, namespace string, data map[string]interface{}) string {
+	if entity, _ := data["entity_id"].(string); entity != "" {
+		return cluster + "/entity:" + entity
+	}
+	name := ""
+	if path, _ := data["path"].(string); strings.HasPrefix(path, "auth/") && !strings.HasPrefix(path, "auth/token/") {
+		name = "login:" + path
+	} else {
+		accessor, _ := data["accessor"].(string)
+		name = "accessor:" + accessor
+	}
+	// the same username in two namespaces is two different users
+	if namespace != "" {
+		name = namespace + ":" + name
+	}
+	return cluster + "/" + name
+}
+
+// existing tokens with less time left than this are refused at login
+const minTokenLoginTTL = time.Minute
+
+// reads a token's ttl from a lookup-self response
+func tokenTTL(v interface{}) time.Duration {
+	var ttl int64
+	switch t := v.(type) {
+	case json.Number:
+		ttl, _ = t.Int64()
+	case float64:
+		ttl = int64(t)
+	}
+	return time.Duration(ttl) * time.Second
+}
+
 func RenewSelf() echo.HandlerFunc {
+	type body struct {
+		// a duration such as "1h", or a number of seconds. Empty leaves it to vault
+		Increment string
+	}
+
 	return func(c echo.Context) error {
 		// fetch auth from header or cookie
 		auth := getSession(c)
@@ -176,22 +622,98 @@ func RenewSelf() echo.HandlerFunc {
 		}
 		defer auth.Clear()
 
+		// the body is optional
+		b := new(body)
+		if c.Request().ContentLength != 0 {
+			if err := c.Bind(b); err != nil {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "Invalid format",
+				})
+			}
+		}
+		var increment time.Duration
+		if b.Increment != "" {
+			var err error
+			if increment, err = parseutil.ParseDurationSecond(b.Increment); err != nil || increment < 0 {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "Invalid increment: " + b.Increment,
+				})
+			}
+		}
+
 		// verify auth details and create client access token
-		resp, err := auth.RenewSelf()
+		resp, err := auth.RenewSelfIncrement(int(increment / time.Second))
 		if err != nil {
 			return parseError(c, err)
 		}
 
+		// the session lives as long as the token it holds, within the configured limits
+		if s, ok := c.Get("session").(*session.Session); ok {
+			if err := session.Extend(s, time.Duration(resp.Auth.LeaseDuration)*time.Second); err != nil {
+				log.Println("[ERROR]: Could not extend session:", err.Error())
+			}
+		}
+
 		return c.JSON(http.StatusOK, H{
 			"result": map[string]interface{}{
-				"meta":     resp.Auth.Metadata,
-				"policies": resp.Auth.Policies,
-				"ttl":      resp.Auth.LeaseDuration,
+				"meta":      resp.Auth.Metadata,
+				"policies":  resp.Auth.Policies,
+				"ttl":       resp.Auth.LeaseDuration,
+				"renewable": resp.Auth.Renewable,
 			},
 		})
 	}
 }
 
+// revokes the session's vault token as well as the session, unlike logout which only forgets the session
+// the token's children are revoked with it, as with vault's own revoke-self
+func RevokeSelf() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		current := currentSession(c)
+		if current != nil && current.IsAPIToken() {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "API tokens are revoked by the admin that minted them",
+			})
+		}
+
+		if err := auth.RevokeSelf(); err != nil {
+			return parseError(c, err)
+		}
+		bustCache(cacheAccessors)
+
+		if current != nil {
+			if err := session.DeleteHash(current.Hash); err != nil {
+				return c.JSON(http.StatusInternalServerError, H{
+					"error": "Token was revoked, but goldfish could not delete the session: " + err.Error(),
+				})
+			}
+		}
+		return c.JSON(http.StatusOK, H{
+			"status": "Logged out",
+			"result": "Token revoked",
+		})
+	}
+}
+
+// the session id, api token, token or cipher the request was made with
+// api tokens may also be sent as a bearer token, which is what most http clients expect
+func sessionHeader(c echo.Context) string {
+	if header := c.Request().Header.Get("X-Vault-Token"); header != "" {
+		return header
+	}
+	if bearer := c.Request().Header.Get("Authorization"); strings.HasPrefix(bearer, "Bearer "+session.APIPrefix) {
+		return strings.TrimPrefix(bearer, "Bearer ")
+	}
+	return ""
+}
+
 // constructs raw or decrypted authentication info
 func getSession(c echo.Context) *vault.AuthInfo {
 	// if vault wrapper is not initialized, errors for everyone!
@@ -203,19 +725,98 @@ func getSession(c echo.Context) *vault.AuthInfo {
 	}
 
 	var auth = &vault.AuthInfo{
-		Type: "token",
+		Type:  "token",
+		Trace: tracing.FromContext(c),
 	}
 
 	// check headers first
-	if auth.ID = c.Request().Header.Get("X-Vault-Token"); auth.ID == "" {
+	if auth.ID = sessionHeader(c); auth.ID == "" {
 		c.JSON(http.StatusForbidden, H{
 			"error": "Please login first",
 		})
 		return nil
 	}
 
-	// if header is transit encrypted, decrypt first
-	if strings.HasPrefix(auth.ID, "vault:") {
+	// server-side sessions and api tokens hold the token (or its cipher) in the session store
+	header := auth.ID
+	auth.Cluster = c.Request().Header.Get("X-Goldfish-Cluster")
+	namespace, err := vault.NormalizeNamespace(c.Request().Header.Get("X-Goldfish-Namespace"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, H{
+			"error": err.Error(),
+		})
+		return nil
+	}
+	auth.Namespace = namespace
+	if strings.HasPrefix(auth.ID, session.Prefix) || strings.HasPrefix(auth.ID, session.APIPrefix) {
+		s, err := lookupSession(c, auth.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, H{
+				"error": "Goldfish could not read session: " + err.Error(),
+			})
+			return nil
+		}
+		if s == nil && strings.HasPrefix(auth.ID, session.APIPrefix) {
+			c.JSON(http.StatusUnauthorized, H{
+				"error": "API token is invalid or has expired",
+			})
+			return nil
+		}
+		if s == nil {
+			sessionExpired(c)
+			return nil
+		}
+		if s.IsAPIToken() && !apiTokenAllows(s, c) {
+			c.JSON(http.StatusForbidden, H{
+				"error": "This API token's scopes do not allow " + c.Request().Method + " " + c.Path(),
+			})
+			return nil
+		}
+		if stepUpRoutes[c.Request().Method+" "+c.Path()] && s.NeedsStepUp() && !isDryRun(c) {
+			stepUpRequired(c)
+			return nil
+		}
+		c.Set("session", s)
+
+		token, err := s.VaultToken(header)
+		if err != nil {
+			c.JSON(http.StatusForbidden, H{
+				"error": "Session invalid. Please logout and login again",
+			})
+			return nil
+		}
+		auth.ID = token
+		auth.Cluster = s.Cluster
+		auth.Namespace = s.Namespace
+		if s.Transit != "" {
+			if err := auth.DecryptSession(s.Transit); err != nil {
+				c.JSON(http.StatusForbidden, H{
+					"error": "Session invalid. Please logout and login again",
+				})
+				return nil
+			}
+		}
+
+		// sessions from older versions are sealed on first use
+		if s.TouchDue() || s.Unsealed() {
+			// keep up with key rotation, so old key versions can be retired in vault
+			if s.Transit != "" {
+				if cipher, err := vault.RewrapSession(s.Transit, token); err != nil {
+					log.Println("[WARN ]: Could not rewrap session:", err.Error())
+				} else {
+					token = cipher
+				}
+			}
+			if err := s.SetVaultToken(header, token); err != nil {
+				log.Println("[WARN ]: Could not seal session:", err.Error())
+			}
+			if err := session.Touch(s); err != nil {
+				log.Println("[WARN ]: Could not update session:", err.Error())
+			}
+		}
+	} else if strings.HasPrefix(auth.ID, "vault:") {
+		// if header is transit encrypted, decrypt first
+		// encrypted sessions carry their own cluster, raw tokens name it in a header
 		if err := auth.DecryptAuth(); err != nil {
 			c.JSON(http.StatusForbidden, H{
 				"error": "Cipher invalid. Please logout and login again",
@@ -224,5 +825,15 @@ func getSession(c echo.Context) *vault.AuthInfo {
 		}
 	}
 
+	sessionSeen(header)
+
+	auth.Audit = auditInfo(c, currentSession(c))
+
+	// goldfish's own roles may narrow what vault would otherwise allow
+	if !checkRoles(c, auth) {
+		auth.Clear()
+		return nil
+	}
+
 	return auth
 }
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/caiyeon/goldfish/auditlog"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/labstack/echo"
+)
+
+// reads and writes per secret path under a prefix, from vault's audit log, and who made them
+// secret owners may see the access to paths they can read or list
+func GetAccessHeatmap() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		prefix := strings.TrimPrefix(c.QueryParam("prefix"), "/")
+		if !prefixVisible(c, auth, prefix) {
+			return nil
+		}
+
+		// the whole retention, unless a shorter window is asked for
+		var window time.Duration
+		if raw := c.QueryParam("window"); raw != "" {
+			var err error
+			if window, err = parseutil.ParseDurationSecond(raw); err != nil || window < 0 {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "window must be a duration, e.g. \"24h\"",
+				})
+			}
+		}
+
+		status := auditlog.GetStatus()
+		if !status.Configured {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "No audit_source is configured",
+			})
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": auditlog.Query(prefix, window),
+			"status": status,
+		})
+	}
+}
+
+// whether the caller may see goldfish's records of paths under prefix, such as their access or tags
+// a prefix needs read or list on it, and every path needs sudo on sys/audit. Writes the refusal if not
+func prefixVisible(c echo.Context, auth *vault.AuthInfo, prefix string) bool {
+	checked := prefix
+	if checked == "" {
+		checked = "sys/audit"
+	}
+	capabilities, err := auth.CapabilitiesSelf(checked)
+	if err != nil {
+		parseError(c, err)
+		return false
+	}
+	for _, capability := range capabilities {
+		switch capability {
+		case "root", "sudo":
+			return true
+		case "read", "list":
+			if prefix != "" {
+				return true
+			}
+		}
+	}
+
+	message := "Paths under " + prefix + " are only shown with read or list on it"
+	if prefix == "" {
+		message = "Every path is only shown with sudo on sys/audit"
+	}
+	c.JSON(http.StatusForbidden, H{
+		"error": message,
+	})
+	return false
+}
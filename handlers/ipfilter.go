@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+// works out each request's client address: the connecting peer's, unless the peer is one of the trusted proxies,
+// which are believed about who they forward for. Anyone else's forwarded headers are ignored, since they can be forged
+func ClientIP(trusted []string) echo.MiddlewareFunc {
+	proxies := parseNetworks(trusted)
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("client_ip", forwardedClient(c.Request(), proxies))
+			return next(c)
+		}
+	}
+}
+
+// the client's address, as worked out by ClientIP
+// used in place of echo's RealIP, which believes forwarded headers from anyone
+func clientIP(c echo.Context) string {
+	if ip, ok := c.Get("client_ip").(string); ok {
+		return ip
+	}
+	return peerIP(c.Request())
+}
+
+func peerIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return host
+}
+
+// each proxy appends the address it was connected from to X-Forwarded-For, so the client is the last address
+// in it that isn't a trusted proxy. Anything left of that was written by the client, and can't be believed
+func forwardedClient(r *http.Request, proxies []*net.IPNet) string {
+	peer := peerIP(r)
+	// a unix socket's peer has no address, and can only be a process on this host, i.e. the proxy in front
+	if ip := net.ParseIP(peer); ip != nil && !containsIP(proxies, ip) {
+		return peer
+	}
+
+	client := ""
+	hops := strings.Split(strings.Join(r.Header[echo.HeaderXForwardedFor], ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		client = ip.String()
+		if !containsIP(proxies, ip) {
+			return client
+		}
+	}
+	if client != "" {
+		return client
+	}
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get(echo.HeaderXRealIP))); ip != nil {
+		return ip.String()
+	}
+	return peer
+}
+
+// rejects requests from outside allowed (if any are given) or from inside denied
+// the client's address is checked, which is the direct peer's unless it is a trusted proxy
+func IPFilter(allowed, denied []string) echo.MiddlewareFunc {
+	allow, deny := parseNetworks(allowed), parseNetworks(denied)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if len(allow) == 0 && len(deny) == 0 {
+			return next
+		}
+		return func(c echo.Context) error {
+			ip := net.ParseIP(clientIP(c))
+			if ip == nil || (len(allow) > 0 && !containsIP(allow, ip)) || containsIP(deny, ip) {
+				return c.JSON(http.StatusForbidden, H{
+					"error": "Access from this address is not allowed",
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+// refuses disaster recovery replication requests unless the listener has admin_allowed_cidrs, since a dr
+// operation token or promotion can take over the cluster, and must not be served to every network
+func DRNeedsAdminNetwork(adminAllowed []string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if len(adminAllowed) > 0 {
+			return next
+		}
+		return func(c echo.Context) error {
+			if strings.HasPrefix(c.Path(), "/v1/replication/dr/") || c.Param("mode") == "dr" {
+				return c.JSON(http.StatusForbidden, H{
+					"error": "Disaster recovery replication is only served on listeners with admin_allowed_cidrs",
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+// CIDRs are validated when the config is parsed
+func parseNetworks(cidrs []string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// migrates a kv version 1 mount to version 2, by copying its secrets into another mount or upgrading it in place
+// a dry run lists what would happen to each secret. Otherwise each secret's outcome is streamed as a line
+// of json as it is migrated, followed by a line with the totals
+func MigrateKV() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		var m vault.KVMigration
+		if err := c.Bind(&m); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid migration format",
+			})
+		}
+
+		if isDryRun(c) {
+			plan, err := auth.PlanKVMigration(&m)
+			if err != nil {
+				return parseError(c, err)
+			}
+			counts := map[string]int{"copy": 0, "conflict": 0}
+			for _, step := range plan {
+				counts[step.Status]++
+			}
+			path := m.Destination + "data/"
+			if m.Mode == "upgrade" {
+				path = "sys/mounts/" + m.Source + "tune"
+			}
+			return dryRun(c, auth, "POST", path, map[string]interface{}{
+				"mode":        m.Mode,
+				"source":      m.Source,
+				"destination": m.Destination,
+				"overwrite":   m.Overwrite,
+				"counts":      counts,
+				"secrets":     plan,
+			})
+		}
+
+		// the status is only sent once the migration has started, so earlier errors get their own
+		resp := c.Response()
+		enc := json.NewEncoder(resp)
+		started := false
+		totals := map[string]int{"copied": 0, "skipped": 0, "failed": 0, "upgrade started": 0}
+		err := auth.RunKVMigration(&m, func(step vault.KVMigrationStep) error {
+			if !started {
+				resp.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+				resp.WriteHeader(http.StatusOK)
+				started = true
+			}
+			totals[step.Status]++
+			if err := enc.Encode(step); err != nil {
+				return err
+			}
+			resp.Flush()
+			return nil
+		})
+		if m.Mode == "upgrade" {
+			bustCache(cacheMounts)
+		}
+		if err != nil && !started {
+			return parseError(c, err)
+		}
+		if !started {
+			resp.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+			resp.WriteHeader(http.StatusOK)
+		}
+		if err != nil {
+			// the status was already sent, so a failed migration can only be reported in the stream
+			log.Println("[ERROR]: Kv migration of", m.Source, "stopped:", err.Error())
+			return enc.Encode(H{"error": err.Error(), "totals": totals})
+		}
+		if m.Mode == "upgrade" {
+			log.Printf("[INFO ]: Request %s: started upgrading %s to kv version 2: %v\n", auth.Audit.RequestID, m.Source, totals)
+		} else {
+			log.Printf("[INFO ]: Request %s: migrated %s to %s (%s): %v\n", auth.Audit.RequestID, m.Source, m.Destination, m.Mode, totals)
+		}
+		return enc.Encode(H{"done": true, "totals": totals})
+	}
+}
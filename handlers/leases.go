@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+// outstanding leases of dynamic secrets engines by mount and role, to spot runaway credential issuance
+func GetLeaseSummary() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		groups, errs, err := auth.LeaseSummary()
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": groups,
+			"errors": errs,
+		})
+	}
+}
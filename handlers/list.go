@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// clients that accept newline-delimited json get large lists as they are read from vault
+func wantsStream(c echo.Context) bool {
+	return strings.Contains(c.Request().Header.Get(echo.HeaderAccept), "application/x-ndjson")
+}
+
+// writes each key of a listing as a line of json, followed by a line with the count
+// streamed lists bypass the list cache, since caching them would hold the whole list in memory
+func streamList(c echo.Context, auth *vault.AuthInfo, path string) error {
+	// the status is only sent once the first key arrives, so earlier errors get their own
+	resp := c.Response()
+	enc := json.NewEncoder(resp)
+	started := false
+	count := 0
+	start := func() {
+		if !started {
+			resp.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+			resp.WriteHeader(http.StatusOK)
+			started = true
+		}
+	}
+	truncated, err := auth.StreamList(path, func(key string) error {
+		start()
+		count++
+		if err := enc.Encode(H{"key": key}); err != nil {
+			return err
+		}
+		if count%1000 == 0 {
+			resp.Flush()
+		}
+		return nil
+	})
+	if err != nil && !started {
+		return parseError(c, err)
+	}
+	start()
+	if err != nil {
+		// the status was already sent, so a failed list can only be reported in the stream
+		log.Println("[ERROR]: Streaming list of", path, "stopped after", count, "keys:", err.Error())
+		return enc.Encode(H{"error": err.Error(), "count": count})
+	}
+	return enc.Encode(H{"done": true, "count": count, "truncated": truncated})
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/metrics"
+	"github.com/caiyeon/goldfish/slack"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// failed logins for one ip ("ip:<address>") or one username ("user:<type>:<name>")
+type loginAttempts struct {
+	failures    int
+	nextAllowed time.Time
+}
+
+var (
+	loginMaxAttempts int
+	loginBackoff     time.Duration
+	loginLockout     time.Duration
+
+	loginFailures = make(map[string]*loginAttempts)
+	loginSwept    time.Time
+	loginLock     = new(sync.Mutex)
+)
+
+func init() {
+	metrics.Describe("goldfish_login_lockouts_total", "Number of login lockouts, by whether an ip or a username was locked out")
+}
+
+// a max of 0 attempts disables throttling
+func SetLoginLimits(maxAttempts int, backoff, lockout time.Duration) {
+	loginLock.Lock()
+	defer loginLock.Unlock()
+	loginMaxAttempts = maxAttempts
+	loginBackoff = backoff
+	loginLockout = lockout
+}
+
+// failures are forgotten once the caller has been quiet for a lockout period
+func (a *loginAttempts) stale(now time.Time) bool {
+	return now.After(a.nextAllowed.Add(loginLockout))
+}
+
+// tokens are never used as keys, only usernames of auth types that take a password
+func loginKeys(c echo.Context, auth *vault.AuthInfo) []string {
+	keys := []string{"ip:" + clientIP(c)}
+	if auth.Pass != "" {
+		keys = append(keys, "user:"+strings.ToLower(auth.Type)+":"+strings.ToLower(auth.ID))
+	}
+	return keys
+}
+
+// returns how long the caller must wait before another login attempt
+func loginWait(keys []string) time.Duration {
+	loginLock.Lock()
+	defer loginLock.Unlock()
+	if loginMaxAttempts == 0 {
+		return 0
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range keys {
+		if a, ok := loginFailures[key]; ok && a.nextAllowed.Sub(now) > wait {
+			wait = a.nextAllowed.Sub(now)
+		}
+	}
+	return wait
+}
+
+func loginFailed(keys []string) {
+	loginLock.Lock()
+	defer loginLock.Unlock()
+	if loginMaxAttempts == 0 {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(loginSwept) > time.Minute {
+		for key, a := range loginFailures {
+			if a.stale(now) {
+				delete(loginFailures, key)
+			}
+		}
+		loginSwept = now
+	}
+
+	for _, key := range keys {
+		a, ok := loginFailures[key]
+		if !ok || a.stale(now) {
+			a = &loginAttempts{}
+			loginFailures[key] = a
+		}
+		a.failures++
+
+		// the wait doubles with each failure, until the lockout
+		if a.failures >= loginMaxAttempts {
+			a.nextAllowed = now.Add(loginLockout)
+			go loginLockedOut(key, a.failures)
+			continue
+		}
+		wait := loginBackoff << uint(a.failures-1)
+		if wait > loginLockout || wait <= 0 {
+			wait = loginLockout
+		}
+		a.nextAllowed = now.Add(wait)
+	}
+}
+
+func loginSucceeded(keys []string) {
+	loginLock.Lock()
+	defer loginLock.Unlock()
+	for _, key := range keys {
+		delete(loginFailures, key)
+	}
+}
+
+// lockouts are counted, logged, and sent to slack if a webhook is configured
+func loginLockedOut(key string, failures int) {
+	scope := strings.SplitN(key, ":", 2)[0]
+	metrics.IncrCounter("goldfish_login_lockouts_total", map[string]string{
+		"scope": scope,
+	})
+
+	msg := fmt.Sprintf("Login locked out for %s after %d failed attempts", key, failures)
+	log.Println("[WARN ]: " + msg)
+
+	if conf := vault.GetConfig(); conf.SlackWebhook != "" {
+		if err := slack.PostMessageWebhook(conf.SlackChannel, "Goldfish login lockout", msg, conf.SlackWebhook); err != nil {
+			log.Println("[ERROR]: Could not send lockout to slack webhook:", err.Error())
+		}
+	}
+}
+
+// only rejected credentials count as failures, not vault being unavailable
+func isAuthFailure(err error) bool {
+	code := 0
+	if parts := strings.Split(err.Error(), "Code:"); len(parts) > 1 {
+		fmt.Sscanf(parts[1], "%d", &code)
+	}
+	return code >= 400 && code < 500
+}
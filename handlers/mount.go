@@ -18,11 +18,13 @@ func GetMount() echo.HandlerFunc {
 
 		// if no mount is specified, list all mounts
 		if mount := c.QueryParam("mount"); mount == "" {
-			result, err := auth.ListMounts()
+			result, err := cachedList(c, auth, cacheMounts, func() (interface{}, error) {
+				return auth.ListMounts()
+			})
 			if err != nil {
 				return parseError(c, err)
 			}
-			return c.JSON(http.StatusOK, H{
+			return jsonWithETag(c, H{
 				"result": result,
 			})
 		} else {
@@ -30,7 +32,7 @@ func GetMount() echo.HandlerFunc {
 			if err != nil {
 				return parseError(c, err)
 			}
-			return c.JSON(http.StatusOK, H{
+			return jsonWithETag(c, H{
 				"result": result,
 			})
 		}
@@ -53,11 +55,24 @@ func ConfigMount() echo.HandlerFunc {
 			})
 		}
 
+		// shows the mount's current config next to the requested one
+		if mount := c.QueryParam("mount"); isDryRun(c) && mount != "" {
+			current, err := auth.GetMount(mount)
+			if err != nil {
+				return parseError(c, err)
+			}
+			return dryRun(c, auth, "POST", "sys/mounts/"+mount+"/tune", map[string]interface{}{
+				"current":   current,
+				"requested": config,
+			})
+		}
+
 		// fetch results
 		err := auth.TuneMount(c.QueryParam("mount"), *config)
 		if err != nil {
 			return parseError(c, err)
 		}
+		bustCache(cacheMounts)
 
 		return c.JSON(http.StatusOK, H{
 			"result": "ok",
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/caiyeon/goldfish/session"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// the session's vault enterprise namespace, and the namespaces under it that the token may list
+func GetNamespaces() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		children, err := auth.ChildNamespaces()
+		if err != nil {
+			return parseError(c, err)
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": H{
+				"namespace": auth.Namespace,
+				"children":  children,
+			},
+		})
+	}
+}
+
+// moves the session to another namespace, which its token must be usable in
+// raw tokens and ciphers name their namespace with each request instead, in X-Goldfish-Namespace
+func SwitchNamespace() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		current := currentSession(c)
+		if current == nil || current.IsAPIToken() {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Only sessions created by logging in to goldfish can switch namespace",
+			})
+		}
+
+		var body struct {
+			Namespace string `json:"namespace"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid request format",
+			})
+		}
+		namespace, err := vault.NormalizeNamespace(body.Namespace)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+		if err := auth.CanEnterNamespace(namespace); err != nil {
+			return parseError(c, err)
+		}
+
+		current.Namespace = namespace
+		if err := session.Save(current); err != nil {
+			return c.JSON(http.StatusInternalServerError, H{
+				"error": "Goldfish could not update session: " + err.Error(),
+			})
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": H{
+				"namespace": namespace,
+			},
+		})
+	}
+}
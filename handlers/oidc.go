@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/caiyeon/goldfish/config"
+	"github.com/caiyeon/goldfish/oidc"
+	"github.com/caiyeon/goldfish/tracing"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// sends the browser to the identity provider's login page
+func LoginOIDC() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cfg := oidc.Config()
+		authURL, state, err := oidc.AuthURL()
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+		c.SetCookie(oidcCookie(cfg, state, time.Now().Add(oidc.StateTTL)))
+		return c.Redirect(http.StatusFound, authURL)
+	}
+}
+
+// where the identity provider sends the browser back to. The state and code are handed on to the ui,
+// which finishes the login with them, so the session id never appears in a url
+func LoginOIDCCallback() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cfg := oidc.Config()
+		if cfg == nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Single sign on is not configured",
+			})
+		}
+		query := url.Values{}
+		if e := c.QueryParam("error"); e != "" {
+			query.Set("oidc_error", strings.TrimSpace(e+" "+c.QueryParam("error_description")))
+		} else {
+			query.Set("oidc_state", c.QueryParam("state"))
+			query.Set("oidc_code", c.QueryParam("code"))
+		}
+		ui := strings.TrimSuffix(cfg.Redirect_url, c.Path())
+		return c.Redirect(http.StatusFound, ui+"/#/login?"+query.Encode())
+	}
+}
+
+// exchanges the state and code from the callback for an id token, then logs in to vault's jwt auth method
+// with it. Returns the same as login
+func LoginOIDCComplete() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// if vault wrapper is not initialized, errors for everyone!
+		if !vault.Bootstrapped() {
+			return c.JSON(http.StatusForbidden, H{
+				"error": "Goldfish is not initialized!",
+			})
+		}
+		cfg := oidc.Config()
+		if cfg == nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Single sign on is not configured",
+			})
+		}
+
+		var body struct {
+			State string `json:"state"`
+			Code  string `json:"code"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid request format",
+			})
+		}
+		// the state cookie is used once, whether or not the login succeeds
+		state := ""
+		if cookie, err := c.Cookie(oidc.CookieName); err == nil {
+			state = cookie.Value
+		}
+		c.SetCookie(oidcCookie(cfg, "", time.Unix(0, 0)))
+		idToken, err := oidc.Exchange(state, body.State, body.Code)
+		if err != nil {
+			return c.JSON(http.StatusForbidden, H{
+				"error": err.Error(),
+			})
+		}
+
+		auth := new(vault.AuthInfo)
+		defer auth.Clear()
+		auth.Trace = tracing.FromContext(c)
+		auth.Audit = auditInfo(c, nil)
+		data, err := auth.LoginJWT(cfg.Vault_mount, cfg.Vault_role, idToken)
+		if err != nil {
+			return parseError(c, err)
+		}
+		bustCache(cacheAccessors)
+		return startSession(c, auth, data)
+	}
+}
+
+// the cookie a login's state is kept in, sent back only to the oidc endpoints, and never readable by scripts
+// the identity provider redirects back to goldfish with a top level get, so it is sent on those with lax
+func oidcCookie(cfg *config.OIDCConfig, value string, expires time.Time) *http.Cookie {
+	path := "/v1/login/oidc"
+	if u, err := url.Parse(cfg.Redirect_url); err == nil {
+		path = strings.TrimSuffix(u.Path, "/callback")
+	}
+	return &http.Cookie{
+		Name:     oidc.CookieName,
+		Value:    value,
+		Path:     path,
+		Expires:  expires,
+		Secure:   strings.HasPrefix(cfg.Redirect_url, "https://"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+// a query parameter, or a field of the json (or form) body
+type apiParam struct {
+	name        string
+	in          string
+	typ         string
+	description string
+	required    bool
+}
+
+type apiDoc struct {
+	tag     string
+	summary string
+	params  []apiParam
+	public  bool
+}
+
+func queryParam(name, description string, required bool) apiParam {
+	return apiParam{name: name, in: "query", typ: "string", description: description, required: required}
+}
+
+func bodyField(name, typ, description string, required bool) apiParam {
+	return apiParam{name: name, in: "body", typ: typ, description: description, required: required}
+}
+
+var bulletinFields = []apiParam{
+	bodyField("title", "string", "Title of the bulletin", true),
+	bodyField("message", "string", "Body of the bulletin", false),
+	bodyField("severity", "string", "One of info, success, warning, danger. Defaults to info", false),
+	bodyField("publish_at", "string", "RFC3339 time to show the bulletin from, if not immediately", false),
+	bodyField("expire_at", "string", "RFC3339 time to stop showing the bulletin at", false),
+}
+
+// descriptions of each route, by method and path. Routes missing here are still listed, just without details
+var apiDocs = map[string]apiDoc{
+	"GET /v1/health":                                 {tag: "health", summary: "Goldfish and vault status: version, uptime, server token ttl, vault seal status, and session store health", public: true},
+	"GET /v1/health/live":                            {tag: "health", summary: "Liveness probe, always ok while goldfish is running", public: true},
+	"GET /v1/health/ready":                           {tag: "health", summary: "Readiness probe, ok once goldfish is bootstrapped and vault is usable", public: true},
+	"GET /v1/health/history":                         {tag: "health", summary: "Goldfish's health checks of vault over time: seal and unseal events, standby transitions, uptime and latency. Without a session, only served if status_page is on, and without node changes or vault's messages", public: true, params: []apiParam{queryParam("window", "Only the last window of checks, e.g. \"1h\"", false)}},
+	"GET /v1/status":                                 {tag: "health", summary: "Whether goldfish is ready and vault is reachable and unsealed, without sensitive details, if status_page is on. A page for browsers, or json", public: true, params: []apiParam{queryParam("format", "html or json. By default, browsers get html", false)}},
+	"GET /v1/vaulthealth":                            {tag: "health", summary: "Vault's own health status", public: true},
+	"GET /v1/ui-config":                              {tag: "health", summary: "The branding the ui shows: organization, login banner, accent color, whether there's a logo, the login options, and the listener's base path", public: true},
+	"GET /v1/ui-config/logo":                         {tag: "health", summary: "The organization's logo, if branding has a logo_file", public: true},
+	"GET /v1/ui-config/theme.css":                    {tag: "health", summary: "A stylesheet with the branding's accent color, linked from the ui's index page", public: true},
+	"GET /v1/version":                                {tag: "health", summary: "Goldfish's version, commit, build date and go version, and whether a newer release exists if update_check is on", public: true},
+	"GET /v1/clusters":                               {tag: "health", summary: "Names of the vault clusters users may log in to", public: true},
+	"GET /v1/openapi.json":                           {tag: "health", summary: "This document", public: true},
+	"POST /v1/bootstrap":                             {tag: "admin", summary: "Bootstraps goldfish with a wrapped approle secret id", public: true, params: []apiParam{bodyField("wrapping_token", "string", "Wrapping token of goldfish's secret id", true)}},
+	"POST /v1/rebootstrap":                           {tag: "admin", summary: "Replaces goldfish's credentials with a new wrapped secret id", params: []apiParam{bodyField("wrapping_token", "string", "Wrapping token of goldfish's new secret id", true)}},
+	"GET /v1/replication/status":                     {tag: "admin", summary: "A cluster's replication status by mode (vault enterprise)", public: true, params: []apiParam{queryParam("cluster", "Name of the cluster, defaults to goldfish's own", false)}},
+	"GET /v1/replication/dr/operation-token":         {tag: "admin", summary: "The progress of generating a dr operation token", public: true, params: []apiParam{queryParam("cluster", "Name of the cluster, defaults to goldfish's own", false)}},
+	"POST /v1/replication/dr/operation-token":        {tag: "admin", summary: "Starts generating a dr operation token, which unseal key holders then provide their keys to", public: true, params: []apiParam{queryParam("cluster", "Name of the cluster, defaults to goldfish's own", false), bodyField("otp", "string", "One time password to encode the token with", false), bodyField("pgp_key", "string", "Pgp key to encrypt the token with, instead of an otp", false)}},
+	"DELETE /v1/replication/dr/operation-token":      {tag: "admin", summary: "Cancels generating a dr operation token", public: true, params: []apiParam{queryParam("cluster", "Name of the cluster, defaults to goldfish's own", false)}},
+	"POST /v1/replication/dr/operation-token/update": {tag: "admin", summary: "Provides an unseal key to the dr operation token being generated. With the otp, the finished token is decoded", public: true, params: []apiParam{queryParam("cluster", "Name of the cluster, defaults to goldfish's own", false), bodyField("key", "string", "An unseal key", true), bodyField("nonce", "string", "Nonce of the attempt", true), bodyField("otp", "string", "Otp the attempt was started with", false)}},
+	"POST /v1/replication/{mode}/{action}":           {tag: "admin", summary: "Promotes a secondary, demotes a primary, or disables replication, where mode is dr or performance. Always confirmed: the first request describes the cluster's status and returns a confirmation token. A dr secondary needs a dr operation token instead of a session", params: []apiParam{queryParam("cluster", "Name of the cluster, defaults to goldfish's own", false), queryParam("confirmation", "Confirmation token from the first request", false), bodyField("dr_operation_token", "string", "For a dr secondary, the dr operation token", false), bodyField("primary_cluster_addr", "string", "For promote, the cluster address of the new primary", false), bodyField("force", "string", "For promoting a performance secondary, \"true\" to promote even if it may lose data", false)}},
+	"POST /v1/login":                                 {tag: "auth", summary: "Logs in to vault, returning a session id to use as X-Vault-Token", public: true, params: []apiParam{bodyField("Type", "string", "Auth method, e.g. token, userpass, ldap, github, okta, or the name of a login from the config file", true), bodyField("ID", "string", "Token, or username", true), bodyField("password", "string", "Password, for auth methods that take one", false), bodyField("Cluster", "string", "Cluster to log in to, if not goldfish's own", false), bodyField("Namespace", "string", "Vault enterprise namespace to log in to, if not the root", false)}},
+	"GET /v1/login/oidc":                             {tag: "auth", summary: "Redirects to the identity provider's login page, for single sign on, setting the goldfish_oidc state cookie", public: true},
+	"GET /v1/login/oidc/callback":                    {tag: "auth", summary: "Where the identity provider redirects back to. Redirects on to the ui with the state and code", public: true, params: []apiParam{queryParam("state", "State the login was started with", true), queryParam("code", "Authorization code from the identity provider", true)}},
+	"POST /v1/login/oidc/complete":                   {tag: "auth", summary: "Finishes a single sign on login in the browser that started it, with its goldfish_oidc cookie, returning the same as /v1/login", public: true, params: []apiParam{bodyField("state", "string", "State from the callback", true), bodyField("code", "string", "Code from the callback", true)}},
+	"POST /v1/login/renew-self":                      {tag: "auth", summary: "Renews the session's vault token", params: []apiParam{bodyField("increment", "string", "Requested ttl, e.g. 1h, capped by the token's max ttl", false)}},
+	"POST /v1/login/revoke-self":                     {tag: "auth", summary: "Revokes the session's vault token and its children, and deletes the session"},
+	"POST /v1/login/reauth":                          {tag: "auth", summary: "Re-enters the session's credentials, before destructive actions", params: []apiParam{bodyField("Type", "string", "Auth method, as for login", true), bodyField("ID", "string", "Token, or username", true), bodyField("password", "string", "Password, for auth methods that take one", false)}},
+	"POST /v1/logout":                                {tag: "auth", summary: "Deletes the session", public: true},
+	"GET /v1/self":                                   {tag: "auth", summary: "The caller's token, identity, goldfish roles, and which of goldfish's features they may use"},
+	"GET /v1/self/access":                            {tag: "auth", summary: "Which of goldfish's features the caller may use, and the secret and auth mounts their token can see with its capabilities at each, checked in one call to vault"},
+	"GET /v1/namespaces":                             {tag: "auth", summary: "The session's vault enterprise namespace, and the namespaces under it that its token may list"},
+	"POST /v1/namespaces/switch":                     {tag: "auth", summary: "Moves the session to another vault enterprise namespace, which its token must be usable in", params: []apiParam{bodyField("namespace", "string", "Namespace to switch to, empty for the root", true)}},
+	"GET /v1/sessions":                               {tag: "sessions", summary: "Lists the caller's own sessions"},
+	"GET /v1/sessions/all":                           {tag: "sessions", summary: "Lists every user's active sessions"},
+	"DELETE /v1/sessions/all/{id}":                   {tag: "sessions", summary: "Revokes any user's session"},
+	"POST /v1/sessions/revoke-all":                   {tag: "sessions", summary: "Revokes every session", params: []apiParam{bodyField("rotate_transit_key", "boolean", "Also invalidate stateless ciphers by rotating the server transit key", false)}},
+	"DELETE /v1/sessions/{id}":                       {tag: "sessions", summary: "Revokes one of the caller's own sessions"},
+	"GET /v1/apitokens":                              {tag: "sessions", summary: "Lists api tokens"},
+	"POST /v1/apitokens":                             {tag: "sessions", summary: "Mints an api token, returning it once", params: []apiParam{bodyField("name", "string", "Name of the api token, e.g. the ci job using it", true), bodyField("scopes", "array", "Scopes the api token may use: request, secrets-read, transit, unwrap, wrap", true), bodyField("policies", "array", "Policies of the vault token behind the api token", false), bodyField("ttl", "string", "Ttl of the vault token behind the api token", false), bodyField("role", "string", "Token role to create the vault token against, instead of an orphan", false)}},
+	"DELETE /v1/apitokens/{id}":                      {tag: "sessions", summary: "Revokes an api token and its vault token"},
+	"GET /v1/token/accessors":                        {tag: "tokens", summary: "Lists token accessors. Sent as a line of json per accessor to clients accepting application/x-ndjson"},
+	"GET /v1/token/accessors/export":                 {tag: "tokens", summary: "Streams every token's accessor, display name, policies, ttl, creation time and path as csv", params: []apiParam{queryParam("q", "Only export tokens with a field containing this", false), queryParam("regex", "\"true\" to match q as a regular expression", false)}},
+	"GET /v1/token/accessors/{id}":                   {tag: "tokens", summary: "A token's lookup data, lease, and the entity and identity groups it belongs to, if the caller may read them"},
+	"POST /v1/token/lookup-accessor":                 {tag: "tokens", summary: "Looks up tokens by accessor", params: []apiParam{queryParam("accessors", "Comma separated accessors, if not given in the body", false), bodyField("accessors", "string", "Comma separated accessors", false)}},
+	"POST /v1/token/revoke-accessor":                 {tag: "tokens", summary: "Revokes a token by accessor", params: []apiParam{queryParam("accessor", "Accessor of the token to revoke", true), queryParam("dry_run", "\"true\" to check the change and describe it without making it", false)}},
+	"POST /v1/token/create":                          {tag: "tokens", summary: "Creates a token. The body is vault's token create request", params: []apiParam{queryParam("orphan", "\"true\" to create an orphan token", false), queryParam("role", "Token role to create the token against", false), queryParam("wrap_ttl", "Wrap the token with this ttl, returning only the wrapping token. Required if token_creation.require_wrapping is set", false)}},
+	"GET /v1/token/listroles":                        {tag: "tokens", summary: "Lists token roles"},
+	"GET /v1/token/role":                             {tag: "tokens", summary: "Reads a token role", params: []apiParam{queryParam("rolename", "Name of the role", true)}},
+	"GET /v1/userpass/users":                         {tag: "users", summary: "Lists userpass users"},
+	"POST /v1/userpass/delete":                       {tag: "users", summary: "Deletes a userpass user", params: []apiParam{queryParam("username", "Name of the user", true), queryParam("dry_run", "\"true\" to check the change and describe it without making it", false)}},
+	"GET /v1/approle/roles":                          {tag: "users", summary: "Lists approle roles"},
+	"POST /v1/approle/delete":                        {tag: "users", summary: "Deletes an approle role", params: []apiParam{queryParam("role", "Name of the role", true), queryParam("dry_run", "\"true\" to check the change and describe it without making it", false)}},
+	"GET /v1/ldap/groups":                            {tag: "users", summary: "Lists ldap groups"},
+	"GET /v1/ldap/users":                             {tag: "users", summary: "Lists ldap users"},
+	"GET /v1/directory/search":                       {tag: "auth", summary: "Searches users and groups by name in vault's ldap auth mounts and identity store, for pickers. Sources the token can't list are skipped", params: []apiParam{queryParam("q", "Part of the name, case insensitive", false), queryParam("kind", "user or group", false), queryParam("source", "ldap or identity", false), queryParam("limit", "Most results to return, up to 100. Defaults to 20", false)}},
+	"GET /v1/policy":                                 {tag: "policies", summary: "Lists policies, or reads one. Lists are sent as a line of json per policy to clients accepting application/x-ndjson", params: []apiParam{queryParam("policy", "Name of the policy to read. Lists all policies if empty", false)}},
+	"DELETE /v1/policy":                              {tag: "policies", summary: "Deletes a policy", params: []apiParam{queryParam("policy", "Name of the policy", true), queryParam("confirmation", "Confirmation token, with require_confirmation", false), queryParam("dry_run", "\"true\" to check the change and describe it without making it", false)}},
+	"GET /v1/password-policy":                        {tag: "policies", summary: "Lists password policies, or reads one's hcl", params: []apiParam{queryParam("name", "Name of the password policy to read. Lists all password policies if empty", false)}},
+	"POST /v1/password-policy":                       {tag: "policies", summary: "Creates or replaces a password policy. Vault refuses one it can't generate a password from", params: []apiParam{bodyField("name", "string", "Name of the password policy", true), bodyField("policy", "string", "The policy's hcl, e.g. its length and charset rules", true)}},
+	"DELETE /v1/password-policy":                     {tag: "policies", summary: "Deletes a password policy", params: []apiParam{queryParam("name", "Name of the password policy", true)}},
+	"GET /v1/password-policy/generate":               {tag: "policies", summary: "Generates a random password that complies with a password policy", params: []apiParam{queryParam("name", "Name of the password policy", true)}},
+	"GET /v1/policy/snapshots":                       {tag: "policies", summary: "Lists the versions of a policy that approved changes replaced, newest first", params: []apiParam{queryParam("policy", "Name of the policy", true)}},
+	"POST /v1/policy/rollback":                       {tag: "policies", summary: "Restores a policy to before an approved change, if it wasn't changed since. Needs write access to the policy, or makes a change request with rollback_requires_approval", params: []apiParam{bodyField("id", "string", "Id of the snapshot", true)}},
+	"POST /v1/policy/simulate":                       {tag: "policies", summary: "Evaluates whether policies would allow an operation on a path as vault's acl would, and which rule decides it", params: []apiParam{bodyField("policies", "array", "Names of existing policies", false), bodyField("rules", "array", "Pasted policies, as hcl", false), bodyField("path", "string", "The path, e.g. secret/foo. For list, with its trailing slash", true), bodyField("operation", "string", "One of create, read, update, delete, list, sudo", true)}},
+	"GET /v1/request":                                {tag: "requests", summary: "Reads a change request", params: []apiParam{queryParam("hash", "Id of the request", true)}},
+	"GET /v1/request/stats":                          {tag: "requests", summary: "Change review metrics over the last 1000 completed requests: pending requests and the oldest one's age, completed requests, median time to approval, and approvals by approver"},
+	"GET /v1/request/plan":                           {tag: "requests", summary: "Shows what approving a change request would do: the resources it touches, before and after, and the capabilities granted or revoked. Also returned by GET /v1/request", params: []apiParam{queryParam("hash", "Id of the request", true)}},
+	"POST /v1/request/add":                           {tag: "requests", summary: "Submits a change request. Other fields depend on the type. Policy requests breaking a blocking lint rule are refused, and those breaking warning rules are returned as warnings", params: []apiParam{bodyField("type", "string", "Type of request, e.g. policy", true), bodyField("apply_at", "string", "Policy requests only: apply once approved and this rfc3339 time is reached", false), bodyField("apply_until", "string", "Policy requests only: discard the approvals if not applied by this time", false), bodyField("rollback_at", "string", "Policy requests only: propose restoring the previous policy at this time, as a new request that needs its own approvals", false)}},
+	"POST /v1/request/approve":                       {tag: "requests", summary: "Approves a change request with an unseal key", params: []apiParam{bodyField("hash", "string", "Id of the request", true), bodyField("unseal", "string", "An unseal key", true), bodyField("on_behalf_of", "string", "Approve for this vault identity entity, by name or id, which has delegated its approvals to the caller", false)}},
+	"DELETE /v1/request/reject":                      {tag: "requests", summary: "Rejects a change request", params: []apiParam{queryParam("hash", "Id of the request", true)}},
+	"GET /v1/delegations":                            {tag: "requests", summary: "Lists the approval delegations made by or to the caller"},
+	"POST /v1/delegations":                           {tag: "requests", summary: "Delegates the caller's change request approvals to another identity entity for a while, e.g. when out of office. The delegate approves with their own unseal key, so the quorum is unchanged", params: []apiParam{bodyField("to", "string", "Name or id of the delegate's vault identity entity", true), bodyField("start", "string", "When the delegation starts, as a date or rfc3339 time. Defaults to now", false), bodyField("end", "string", "When the delegation ends, as a date (inclusive) or rfc3339 time", true)}},
+	"DELETE /v1/delegations/{id}":                    {tag: "requests", summary: "Ends an approval delegation. Only the delegator or the delegate may"},
+	"POST /v1/chatops/slack":                         {tag: "requests", summary: "Callback for the buttons of change requests posted to slack, signed with the slack app's signing secret. Rejects requests, or links approvers to the ui", public: true},
+	"GET /v1/transit":                                {tag: "transit", summary: "The user transit key goldfish encrypts with"},
+	"POST /v1/tools/random":                          {tag: "transit", summary: "Random bytes from vault's source of randomness", params: []apiParam{bodyField("bytes", "integer", "Number of bytes, 32 if empty", false), bodyField("format", "string", "base64 (the default) or hex", false)}},
+	"POST /v1/tools/hash":                            {tag: "transit", summary: "Hashes input with vault", params: []apiParam{bodyField("input", "string", "Text to hash", true), bodyField("input_base64", "boolean", "Input is base64 encoded bytes rather than text", false), bodyField("algorithm", "string", "sha2-224, sha2-256 (the default), sha2-384 or sha2-512", false), bodyField("format", "string", "hex (the default) or base64", false)}},
+	"POST /v1/transit/encrypt":                       {tag: "transit", summary: "Encrypts a string with a transit key", params: []apiParam{bodyField("plaintext", "string", "Text to encrypt", true), bodyField("key", "string", "Transit key to use", false)}},
+	"POST /v1/transit/decrypt":                       {tag: "transit", summary: "Decrypts a transit cipher", params: []apiParam{bodyField("cipher", "string", "Cipher to decrypt", true), bodyField("key", "string", "Transit key to use", false)}},
+	"POST /v1/transit/backup":                        {tag: "transit", summary: "Backs up an exportable transit key, returning the backup only response-wrapped. Needs sudo on the backup path", params: []apiParam{bodyField("key", "string", "Transit key to back up", true), bodyField("mount", "string", "Transit mount, if not goldfish's transit backend", false), bodyField("wrap_ttl", "string", "Ttl of the wrapping token. Defaults to the wrap_ttl setting", false)}},
+	"POST /v1/transit/restore":                       {tag: "transit", summary: "Restores a transit key backup, e.g. on another cluster. Needs sudo on the restore path", params: []apiParam{bodyField("backup", "string", "The backup", false), bodyField("wrapping_token", "string", "Wrapping token holding the backup, instead of the backup itself", false), bodyField("key", "string", "Name to restore the key as, if not its original name", false), bodyField("mount", "string", "Transit mount, if not goldfish's transit backend", false), bodyField("force", "boolean", "Overwrite an existing key of the same name", false)}},
+	"POST /v1/kv/migrate":                            {tag: "mounts", summary: "Migrates a kv version 1 mount to version 2. Streams each secret's outcome as a line of json, then the totals", params: []apiParam{queryParam("dry_run", "\"true\" to check the change and describe it without making it", false), bodyField("mode", "string", "copy, into another kv version 2 mount, or upgrade, in place", true), bodyField("source", "string", "The kv version 1 mount, e.g. \"secret/\"", true), bodyField("destination", "string", "For copy, the kv version 2 mount", false), bodyField("overwrite", "boolean", "For copy, replace secrets that already exist in the destination", false)}},
+	"GET /v1/quotas":                                 {tag: "mounts", summary: "Vault's rate limit and lease count quotas in the session's namespace, and its quota settings"},
+	"POST /v1/quotas":                                {tag: "mounts", summary: "Creates or replaces a rate limit or lease count quota on a mount, namespace, or every path", params: []apiParam{queryParam("dry_run", "\"true\" to check the change and describe it without making it", false), bodyField("name", "string", "Name of the quota", true), bodyField("type", "string", "rate-limit, or lease-count on vault enterprise", true), bodyField("path", "string", "Mount or namespace the quota applies to, e.g. \"secret/\", empty for every path", false), bodyField("rate", "number", "Requests per interval, for rate limits", false), bodyField("interval", "string", "Interval the rate is over, e.g. \"1s\"", false), bodyField("block_interval", "string", "How long a client that exceeds the rate is refused, e.g. \"1m\"", false), bodyField("max_leases", "integer", "Most leases at once, for lease counts", false)}},
+	"DELETE /v1/quotas":                              {tag: "mounts", summary: "Deletes a quota", params: []apiParam{queryParam("type", "rate-limit or lease-count", true), queryParam("name", "Name of the quota", true), queryParam("dry_run", "\"true\" to check the change and describe it without making it", false)}},
+	"POST /v1/quotas/config":                         {tag: "mounts", summary: "Sets vault's quota settings. Only given fields change", params: []apiParam{bodyField("enable_rate_limit_audit_logging", "boolean", "Audit log requests rejected by rate limits", false), bodyField("enable_rate_limit_response_headers", "boolean", "Send rate limit headers with responses", false), bodyField("rate_limit_exempt_paths", "array", "Paths exempt from rate limits", false)}},
+	"GET /v1/mount":                                  {tag: "mounts", summary: "Lists mounts, or reads one's config", params: []apiParam{queryParam("mount", "Path of the mount to read. Lists all mounts if empty", false)}},
+	"POST /v1/mount":                                 {tag: "mounts", summary: "Tunes a mount. The body is vault's mount config input", params: []apiParam{queryParam("mount", "Path of the mount", true), queryParam("dry_run", "\"true\" to check the change and describe it without making it", false)}},
+	"GET /v1/secrets":                                {tag: "secrets", summary: "Lists secrets under a path ending in '/', or reads one. Lists are sent as a line of json per key to clients accepting application/x-ndjson", params: []apiParam{queryParam("path", "Path to list or read. Defaults to the runtime config's default secret path", false)}},
+	"POST /v1/secrets":                               {tag: "secrets", summary: "Writes a secret", params: []apiParam{queryParam("path", "Path of the secret", true), bodyField("body", "string", "Json encoded key-value pairs of the secret", true), queryParam("dry_run", "\"true\" to check the change and describe it without making it", false)}},
+	"DELETE /v1/secrets":                             {tag: "secrets", summary: "Deletes a secret", params: []apiParam{queryParam("path", "Path of the secret", true), queryParam("confirmation", "Confirmation token, with require_confirmation", false), queryParam("dry_run", "\"true\" to check the change and describe it without making it", false)}},
+	"POST /v1/secrets/copy":                          {tag: "secrets", summary: "Copies a kv secret, or every secret under a folder, from the session's cluster to the cluster of the session in X-Goldfish-Destination-Token", params: []apiParam{queryParam("dry_run", "\"true\" to check the change and describe it without making it", false), bodyField("source", "string", "Secret, or folder ending in '/', to copy", true), bodyField("destination", "string", "Where to copy it to, the same path if empty", false)}},
+	"GET /v1/secrets/tags":                           {tag: "secrets", summary: "A secret's owner, description and data classification, or every tagged secret under a prefix. Needs read or list on the path or prefix, or sudo on sys/audit without one", params: []apiParam{queryParam("path", "Path of one secret", false), queryParam("prefix", "Only secrets under this prefix, if path is empty", false), queryParam("owner", "Only secrets of this owner", false), queryParam("classification", "Only secrets of this classification", false)}},
+	"PUT /v1/secrets/tags":                           {tag: "secrets", summary: "Tags a secret. Needs create or update on it. Empty tags are removed", params: []apiParam{queryParam("path", "Path of the secret", true), bodyField("owner", "string", "Owning team", false), bodyField("description", "string", "What the secret is for", false), bodyField("classification", "string", "One of public, internal, confidential, restricted", false), bodyField("rotation_period", "string", "How often the secret must be rotated, e.g. \"2160h\". Only kv version 2 secrets' age is known", false)}},
+	"GET /v1/secrets/untagged":                       {tag: "secrets", summary: "Secrets under a folder without an owner or classification, as far as the caller can list them", params: []apiParam{queryParam("prefix", "Folder to look under, ending in '/'", true)}},
+	"GET /v1/secrets/stale":                          {tag: "secrets", summary: "Kv version 2 secrets under a folder not updated within a period, nor read as far as the audit_source shows, least recently updated first. Untagged secrets are grouped under an empty owner, and secrets whose metadata the token can't read are listed as skipped", params: []apiParam{queryParam("prefix", "Folder to look under, ending in '/'", true), queryParam("older_than", "How long a secret must have gone unchanged, e.g. \"720h\". Defaults to 90 days", false), queryParam("group_by", "prefix (the default) or owner", false)}},
+	"GET /v1/secrets/rotations":                      {tag: "secrets", summary: "Secrets tagged with a rotation period, and when they are due, soonest first. Needs read or list on the prefix, or sudo on sys/audit without one", params: []apiParam{queryParam("prefix", "Only secrets under this prefix", false), queryParam("overdue", "\"true\" for only the secrets past due", false)}},
+	"GET /v1/bookmarks":                              {tag: "secrets", summary: "Lists the user's bookmarked paths"},
+	"POST /v1/bookmarks":                             {tag: "secrets", summary: "Bookmarks a path", params: []apiParam{queryParam("path", "Path of the secret or folder", true)}},
+	"DELETE /v1/bookmarks":                           {tag: "secrets", summary: "Removes a bookmark, or all of them", params: []apiParam{queryParam("path", "Path to remove. Removes every bookmark if empty", false)}},
+	"GET /v1/recent":                                 {tag: "secrets", summary: "Lists the secrets the user read most recently, newest first"},
+	"DELETE /v1/recent":                              {tag: "secrets", summary: "Clears the user's recently read secrets"},
+	"GET /v1/bulletins":                              {tag: "secrets", summary: "Lists the published bulletins in the runtime config's bulletin path", params: []apiParam{queryParam("all", "\"true\" to include scheduled and expired bulletins", false)}},
+	"POST /v1/bulletins":                             {tag: "secrets", summary: "Posts a bulletin, returning its id", params: bulletinFields},
+	"PUT /v1/bulletins/{id}":                         {tag: "secrets", summary: "Replaces a bulletin", params: bulletinFields},
+	"DELETE /v1/bulletins/{id}":                      {tag: "secrets", summary: "Deletes a bulletin"},
+	"GET /v1/settings":                               {tag: "admin", summary: "Reads the admin-tunable settings, e.g. the banner the ui shows"},
+	"PUT /v1/settings":                               {tag: "admin", summary: "Changes settings. Fields left out keep their current value", params: []apiParam{bodyField("wrap_ttl", "string", "Default ttl of wrapping tokens, e.g. \"1h\"", false), bodyField("approval_quorum", "integer", "Approvals a change request needs, if more than vault's unseal threshold", false), bodyField("features", "object", "Feature flags for the ui, by name", false), bodyField("banner", "string", "Text the ui shows at the top of every page", false)}},
+	"GET /v1/reports":                                {tag: "admin", summary: "Lists the reports scheduled by the config file, with their next and last runs"},
+	"GET /v1/audit/heatmap":                          {tag: "secrets", summary: "Reads, writes and denied requests per secret path over time, and who made them, from vault's audit log. Needs read or list on the prefix, or sudo on sys/audit without one", params: []apiParam{queryParam("prefix", "Only paths under this prefix", false), queryParam("window", "How far back to look, e.g. \"24h\". Defaults to the audit_source's retention", false)}},
+	"GET /v1/raft/snapshots":                         {tag: "admin", summary: "The raft snapshot schedule from the config file, recent snapshots with their size, location or error, and the snapshots kept at the destination"},
+	"GET /v1/usage":                                  {tag: "admin", summary: "Request volume per mount and active entity counts from vault's usage counters, with goldfish's own requests to vault by day as a sample"},
+	"GET /v1/leases":                                 {tag: "admin", summary: "Outstanding leases of dynamic secrets engines by mount and role, with counts and the soonest expiry. Needs sudo on sys/leases/lookup. Expiries are looked up for at most 500 leases"},
+	"POST /v1/wrapping/wrap":                         {tag: "wrapping", summary: "Wraps data in a response wrapping token", params: []apiParam{bodyField("wrapttl", "string", "Ttl of the wrapping token, e.g. \"1h\". Defaults to the wrap_ttl setting", false), bodyField("data", "string", "Json encoded key-value pairs to wrap", true)}},
+	"POST /v1/wrapping/unwrap":                       {tag: "wrapping", summary: "Unwraps a response wrapping token. Logging in isn't required", public: true, params: []apiParam{bodyField("wrappingToken", "string", "The wrapping token", true)}},
+	"POST /v1/wrapping/unwrap-batch":                 {tag: "wrapping", summary: "Unwraps several response wrapping tokens, returning each one's result or error in order. Logging in isn't required", public: true, params: []apiParam{bodyField("wrappingTokens", "array", "The wrapping tokens, at most 100", true)}},
+	"POST /v1/raw":                                   {tag: "admin", summary: "Makes an arbitrary request to vault with the session's token, which is logged", params: []apiParam{bodyField("method", "string", "Http method, or LIST", true), bodyField("path", "string", "Vault api path, without /v1/", true), bodyField("body", "object", "Request body", false)}},
+}
+
+var pathParam = regexp.MustCompile(`:([^/]+)`)
+
+// serves an openapi 3 document of every /v1 route, built once from the server's routes
+// base is the listener's base path, so generated clients call the right urls
+func OpenAPI(routes []*echo.Route, base string) echo.HandlerFunc {
+	spec := openAPISpec(routes, base)
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, spec)
+	}
+}
+
+func openAPISpec(routes []*echo.Route, base string) map[string]interface{} {
+	paths := make(map[string]map[string]interface{})
+	for _, r := range routes {
+		if !strings.HasPrefix(r.Path, "/v1/") {
+			continue
+		}
+		path := pathParam.ReplaceAllString(r.Path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = make(map[string]interface{})
+		}
+		paths[path][strings.ToLower(r.Method)] = openAPIOperation(r.Method, path, r.Path)
+	}
+
+	if base == "" {
+		base = "/"
+	}
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Goldfish",
+			"version": "v1",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": base}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"session": map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "X-Vault-Token",
+					"description": "A session id from /v1/login, or a vault token",
+				},
+				"apiToken": map[string]interface{}{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "An api token from /v1/apitokens, for the endpoints its scopes allow",
+				},
+			},
+			"schemas": map[string]interface{}{
+				"Result": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"result": map[string]interface{}{}},
+				},
+				"Error": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"error": map[string]interface{}{"type": "string"}},
+				},
+			},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"session": []string{}},
+			map[string]interface{}{"apiToken": []string{}},
+		},
+	}
+}
+
+// path is in openapi's form, e.g. /v1/sessions/{id}, and route in echo's, e.g. /v1/sessions/:id
+func openAPIOperation(method, path, route string) map[string]interface{} {
+	doc := apiDocs[method+" "+path]
+	op := map[string]interface{}{
+		"operationId": operationID(method, path),
+		"responses": map[string]interface{}{
+			"200":     openAPIResponse("Success", "Result"),
+			"default": openAPIResponse("Error", "Error"),
+		},
+	}
+	if doc.tag != "" {
+		op["tags"] = []string{doc.tag}
+	}
+	if doc.summary != "" {
+		op["summary"] = doc.summary
+	}
+	if doc.public {
+		op["security"] = []interface{}{}
+	}
+
+	var params []interface{}
+	for _, name := range pathParam.FindAllStringSubmatch(route, -1) {
+		params = append(params, map[string]interface{}{
+			"name":     name[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+	properties := make(map[string]interface{})
+	var required []string
+	for _, p := range doc.params {
+		if p.in == "query" {
+			params = append(params, map[string]interface{}{
+				"name":        p.name,
+				"in":          "query",
+				"description": p.description,
+				"required":    p.required,
+				"schema":      map[string]interface{}{"type": p.typ},
+			})
+			continue
+		}
+		property := map[string]interface{}{"type": p.typ, "description": p.description}
+		if p.typ == "array" {
+			property["items"] = map[string]interface{}{"type": "string"}
+		}
+		properties[p.name] = property
+		if p.required {
+			required = append(required, p.name)
+		}
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	// handlers accept json or form bodies alike
+	if len(properties) > 0 {
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			sort.Strings(required)
+			schema["required"] = required
+		}
+		op["requestBody"] = map[string]interface{}{
+			"required": len(required) > 0,
+			"content": map[string]interface{}{
+				"application/json":                  map[string]interface{}{"schema": schema},
+				"application/x-www-form-urlencoded": map[string]interface{}{"schema": schema},
+			},
+		}
+	}
+	return op
+}
+
+func openAPIResponse(description, schema string) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{"$ref": "#/components/schemas/" + schema},
+			},
+		},
+	}
+}
+
+// e.g. "POST /v1/token/revoke-accessor" becomes postTokenRevokeAccessor
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.FieldsFunc(strings.TrimPrefix(path, "/v1/"), func(r rune) bool {
+		return r == '/' || r == '-' || r == '.' || r == '{' || r == '}'
+	}) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+// swagger ui's files are built into goldfish with the rest of the ui, at the version pinned in frontend/package.json,
+// so the page works without internet access. It allows them along with its own inline script
+var swaggerUIScript = `window.onload = function () {
+	window.ui = SwaggerUIBundle({ url: "v1/openapi.json", dom_id: "#swagger-ui" });
+};`
+
+var swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Goldfish API</title>
+<link rel="stylesheet" href="assets/swagger-ui/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="assets/swagger-ui/swagger-ui-bundle.js"></script>
+<script>` + swaggerUIScript + `</script>
+</body>
+</html>
+`
+
+// serves swagger ui for the openapi document, at /docs
+func SwaggerUI() echo.HandlerFunc {
+	sum := sha256.Sum256([]byte(swaggerUIScript))
+	csp := "default-src 'self'; img-src 'self' data:; style-src 'self' 'unsafe-inline'; " +
+		"script-src 'self' 'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+	return func(c echo.Context) error {
+		c.Response().Header().Set("Content-Security-Policy", csp)
+		return c.HTML(http.StatusOK, swaggerUIPage)
+	}
+}
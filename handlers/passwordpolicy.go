@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+// lists password policies, or reads one's hcl if a name is given
+func GetPasswordPolicy() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		var result interface{}
+		var err error
+		if name := c.QueryParam("name"); name == "" {
+			result, err = auth.ListPasswordPolicies()
+		} else {
+			result, err = auth.GetPasswordPolicy(name)
+		}
+		if err != nil {
+			return parseError(c, err)
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
+// creates or replaces a password policy
+func PutPasswordPolicy() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		var body struct {
+			Name   string `json:"name"`
+			Policy string `json:"policy"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid password policy format",
+			})
+		}
+		if err := auth.PutPasswordPolicy(body.Name, body.Policy); err != nil {
+			return parseError(c, err)
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": "ok",
+		})
+	}
+}
+
+func DeletePasswordPolicy() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		if err := auth.DeletePasswordPolicy(c.QueryParam("name")); err != nil {
+			return parseError(c, err)
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": "ok",
+		})
+	}
+}
+
+// a random password that complies with a password policy, for filling in a secret's value
+func GeneratePassword() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		password, err := auth.GeneratePassword(c.QueryParam("name"))
+		if err != nil {
+			return parseError(c, err)
+		}
+		// a generated password must never be kept by the browser or a proxy
+		c.Response().Header().Set("Cache-Control", "no-store")
+		return c.JSON(http.StatusOK, H{
+			"result": password,
+		})
+	}
+}
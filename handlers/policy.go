@@ -50,20 +50,30 @@ func GetPolicy() echo.HandlerFunc {
 		defer auth.Clear()
 
 		// if policy is empty string, all policies will be fetched
-		var result interface{}
-		var err error
 		policy := c.QueryParam("policy")
 		if policy == "" {
-			result, err = auth.ListPolicies()
-		} else {
-			result, err = auth.GetPolicy(policy)
+			if wantsStream(c) {
+				return streamList(c, auth, "sys/policy")
+			}
+			result, err := cachedList(c, auth, cachePolicies, func() (interface{}, error) {
+				return auth.ListKeys("sys/policy")
+			})
+			if err != nil {
+				return parseError(c, err)
+			}
+			list := result.(vault.KeyList)
+			return jsonWithETag(c, H{
+				"result":    list.Keys,
+				"truncated": list.Truncated,
+			})
 		}
 
+		result, err := auth.GetPolicy(policy)
 		if err != nil {
 			return parseError(c, err)
 		}
 
-		return c.JSON(http.StatusOK, H{
+		return jsonWithETag(c, H{
 			"result": result,
 		})
 	}
@@ -78,10 +88,27 @@ func DeletePolicy() echo.HandlerFunc {
 		}
 		defer auth.Clear()
 
+		// the policy's rules are shown, if the user can read them
+		name := c.QueryParam("policy")
+		rules := func() interface{} {
+			rules, err := auth.GetPolicy(name)
+			if err != nil {
+				return nil
+			}
+			return map[string]interface{}{"rules": rules}
+		}
+		if isDryRun(c) && name != "" {
+			return dryRun(c, auth, "DELETE", "sys/policy/"+name, rules())
+		}
+		if !confirmed(c, "delete policy", name, rules) {
+			return nil
+		}
+
 		// fetch results
-		if err := auth.DeletePolicy(c.QueryParam("policy")); err != nil {
+		if err := auth.DeletePolicy(name); err != nil {
 			return parseError(c, err)
 		}
+		bustCache(cachePolicies)
 
 		return c.JSON(http.StatusOK, H{
 			"result": "Policy deleted",
@@ -602,6 +629,9 @@ func updatePolicyRequestByChangeID(c echo.Context, auth *vault.AuthInfo, hash st
 		ID:   token,
 	}
 
+	// the generated root token shows up in accessor lists until it's revoked, so bust those last
+	defer bustCache(cachePolicies, cacheAccessors)
+
 	// ensure generated root token is revoked, and cubbyhole data is purged
 	defer vault.DeleteFromCubbyhole("requests/" + hash)
 	defer rootauth.RevokeSelf()
@@ -756,6 +786,9 @@ func updatePolicyRequestByCommitHash(c echo.Context, auth *vault.AuthInfo, hash
 		ID:   token,
 	}
 
+	// the generated root token shows up in accessor lists until it's revoked, so bust those last
+	defer bustCache(cachePolicies, cacheAccessors)
+
 	// ensure generated root token is revoked
 	defer rootauth.RevokeSelf()
 
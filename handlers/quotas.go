@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// vault's rate limit and lease count quotas in the session's namespace, and its quota settings
+func GetQuotas() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		config, err := auth.GetQuotaConfig()
+		if err != nil {
+			return parseError(c, err)
+		}
+		quotas, err := auth.ListQuotas()
+		if err != nil {
+			return parseError(c, err)
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": H{
+				"config": config,
+				"quotas": quotas,
+			},
+		})
+	}
+}
+
+// creates or replaces a quota
+func WriteQuota() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		var q vault.Quota
+		if err := c.Bind(&q); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid quota format",
+			})
+		}
+		if err := q.Validate(); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
+		// shows the quota's current settings next to the requested ones
+		if isDryRun(c) {
+			current, err := auth.GetQuota(q.Type, q.Name)
+			if err != nil {
+				return parseError(c, err)
+			}
+			return dryRun(c, auth, "POST", "sys/quotas/"+q.Type+"/"+q.Name, map[string]interface{}{
+				"current":   current,
+				"requested": q,
+			})
+		}
+
+		if err := auth.WriteQuota(q); err != nil {
+			return parseError(c, err)
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": "ok",
+		})
+	}
+}
+
+func DeleteQuota() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		typ, name := c.QueryParam("type"), c.QueryParam("name")
+		if isDryRun(c) {
+			current, err := auth.GetQuota(typ, name)
+			if err != nil {
+				return parseError(c, err)
+			}
+			return dryRun(c, auth, "DELETE", "sys/quotas/"+typ+"/"+name, map[string]interface{}{
+				"current": current,
+			})
+		}
+
+		if err := auth.DeleteQuota(typ, name); err != nil {
+			return parseError(c, err)
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": "ok",
+		})
+	}
+}
+
+// sets whether rejected requests are audit logged, whether rate limit headers are sent,
+// and which paths are exempt from rate limits
+func ConfigQuotas() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		var config struct {
+			EnableAuditLogging    *bool    `json:"enable_rate_limit_audit_logging"`
+			EnableResponseHeaders *bool    `json:"enable_rate_limit_response_headers"`
+			RateLimitExemptPaths  []string `json:"rate_limit_exempt_paths"`
+		}
+		if err := c.Bind(&config); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid config format",
+			})
+		}
+
+		// only what was given is changed
+		data := make(map[string]interface{})
+		if config.EnableAuditLogging != nil {
+			data["enable_rate_limit_audit_logging"] = *config.EnableAuditLogging
+		}
+		if config.EnableResponseHeaders != nil {
+			data["enable_rate_limit_response_headers"] = *config.EnableResponseHeaders
+		}
+		if config.RateLimitExemptPaths != nil {
+			data["rate_limit_exempt_paths"] = config.RateLimitExemptPaths
+		}
+		if err := auth.WriteQuotaConfig(data); err != nil {
+			return parseError(c, err)
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": "ok",
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/caiyeon/goldfish/snapshot"
+	"github.com/labstack/echo"
+)
+
+// the raft snapshot schedule, recent snapshots and their status, and those kept at the destination
+func GetRaftSnapshots() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		// the snapshots are taken with goldfish's token, so only those who could take one themselves see them
+		if err := auth.RawPreflight("GET", "sys/storage/raft/snapshot"); err != nil {
+			if strings.HasPrefix(err.Error(), "Permission denied") {
+				return c.JSON(http.StatusForbidden, H{
+					"error": err.Error(),
+				})
+			}
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": snapshot.GetStatus(),
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/config"
+	"github.com/caiyeon/goldfish/metrics"
+	"github.com/labstack/echo"
+)
+
+// a token bucket for one caller on one route (or on every route without a limit of its own)
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time
+}
+
+var (
+	rateLimits  config.RateLimitConfig
+	rateBuckets = make(map[string]*rateBucket)
+	rateSwept   time.Time
+	rateLock    = new(sync.Mutex)
+)
+
+func init() {
+	metrics.Describe("goldfish_rate_limited_total", "Number of requests rejected by rate limits, by route")
+}
+
+// may be called again at runtime, e.g. when the config file is reloaded
+// every caller starts over with a full bucket
+func SetRateLimits(c *config.RateLimitConfig) {
+	rateLock.Lock()
+	defer rateLock.Unlock()
+	rateLimits = *c
+	rateBuckets = make(map[string]*rateBucket)
+}
+
+// rejects api requests over the caller's rate limit with a 429
+// health endpoints are never limited, so probes can't be starved by other traffic
+func RateLimit() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			path := c.Request().URL.Path
+			if !strings.HasPrefix(path, "/v1/") || path == "/v1/health" || strings.HasPrefix(path, "/v1/health/") {
+				return next(c)
+			}
+
+			key := rateKey(c)
+			if key == "" {
+				return next(c)
+			}
+			route := c.Path()
+			wait := rateWait(route, key)
+			if wait == 0 {
+				return next(c)
+			}
+
+			metrics.IncrCounter("goldfish_rate_limited_total", map[string]string{
+				"route": route,
+			})
+			c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			return c.JSON(http.StatusTooManyRequests, H{
+				"error": "Too many requests, please try again later",
+			})
+		}
+	}
+}
+
+// empty if rate limiting is disabled, since the key is only set along with some limit
+// only sessions and api tokens that exist get a bucket of their own. Anything else in the header is limited
+// by the client's address, or a caller could get a fresh bucket by sending a new made up token each time
+func rateKey(c echo.Context) string {
+	rateLock.Lock()
+	key := rateLimits.Key
+	rateLock.Unlock()
+
+	if key == "" {
+		return ""
+	}
+	if header := sessionHeader(c); key == "session" && header != "" {
+		if s, _ := lookupSession(c, header); s != nil {
+			return "session:" + s.Hash
+		}
+	}
+	return "ip:" + clientIP(c)
+}
+
+// takes a token from the caller's bucket, or returns how long until one is available
+func rateWait(route, key string) time.Duration {
+	rateLock.Lock()
+	defer rateLock.Unlock()
+
+	perSecond, burst := rateLimits.Requests_per_second, rateLimits.Burst
+	if limit, ok := rateLimits.Routes[route]; ok {
+		perSecond, burst = limit.Requests_per_second, limit.Burst
+		key = route + " " + key
+	}
+	if perSecond == 0 {
+		return 0
+	}
+
+	// buckets that have refilled are the same as new ones, so they can be dropped
+	now := time.Now()
+	if now.Sub(rateSwept) > time.Minute {
+		for k, b := range rateBuckets {
+			if now.After(b.full) {
+				delete(rateBuckets, k)
+			}
+		}
+		rateSwept = now
+	}
+
+	b, ok := rateBuckets[key]
+	if !ok {
+		b = &rateBucket{tokens: float64(burst), last: now}
+		rateBuckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	}
+	b.tokens--
+	b.full = now.Add(time.Duration((float64(burst) - b.tokens) / perSecond * float64(time.Second)))
+	return 0
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+func RawRequest() echo.HandlerFunc {
+	// scoped struct is fine, nothing else needs to know this
+	type rawstruct struct {
+		Method string
+		Path   string
+		Body   map[string]interface{}
+	}
+
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		raw := new(rawstruct)
+		if err := c.Bind(raw); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid format",
+			})
+		}
+		if raw.Method == "" || raw.Path == "" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "'method' and 'path' are required",
+			})
+		}
+		if isReadOnly() {
+			switch strings.ToUpper(raw.Method) {
+			case http.MethodGet, "LIST":
+			default:
+				return readOnlyRefused(c)
+			}
+		}
+
+		if rawCreatesToken(raw.Method, raw.Path) {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Tokens must be created through /v1/token/create, where token_creation's limits apply",
+			})
+		}
+
+		// identify the user for the action log
+		self, err := auth.LookupSelf()
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		// only record body keys, since values are likely to be secrets
+		keys := []string{}
+		for k := range raw.Body {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		resp, err := auth.RawRequest(raw.Method, raw.Path, raw.Body)
+		bustCacheForRaw(raw.Method)
+		if err != nil {
+			log.Printf("[INFO ]: Raw %s %s by %v (%v) with keys [%s] failed: %s\n",
+				strings.ToUpper(raw.Method), raw.Path,
+				self.Data["display_name"], self.Data["accessor"],
+				strings.Join(keys, ","), err.Error())
+			return parseError(c, err)
+		}
+		log.Printf("[INFO ]: Raw %s %s by %v (%v) with keys [%s] returned %d\n",
+			strings.ToUpper(raw.Method), raw.Path,
+			self.Data["display_name"], self.Data["accessor"],
+			strings.Join(keys, ","), resp.StatusCode)
+
+		return c.JSON(http.StatusOK, H{
+			"result": resp,
+		})
+	}
+}
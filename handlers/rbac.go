@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/config"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// a token's policies and groups are looked up at most this often
+const identityTTL = time.Minute
+
+type identityEntry struct {
+	policies []string
+	groups   []string
+	// false if groups weren't looked up, because no role needed them at the time
+	withGroups bool
+	expires    time.Time
+}
+
+var (
+	fileRoles     map[string]*config.RoleConfig
+	vaultRolesDoc string
+	vaultRoles    map[string]*config.RoleConfig
+	identities    = make(map[[sha256.Size]byte]identityEntry)
+	identitySwept time.Time
+	rolesLock     = new(sync.Mutex)
+)
+
+// may be called again at runtime, e.g. when the config file is reloaded
+func SetRoles(roles map[string]*config.RoleConfig) {
+	rolesLock.Lock()
+	defer rolesLock.Unlock()
+	fileRoles = roles
+}
+
+// the config file's roles, with any kept in vault's runtime config replacing those of the same name
+func currentRoles() map[string]*config.RoleConfig {
+	doc := vault.GetConfig().Roles
+	rolesLock.Lock()
+	defer rolesLock.Unlock()
+
+	if doc != vaultRolesDoc {
+		// the runtime config is validated as it is loaded, so this can't fail
+		vaultRoles = nil
+		if doc != "" {
+			vaultRoles, _ = config.ParseRolesJSON(doc)
+		}
+		vaultRolesDoc = doc
+	}
+	if len(vaultRoles) == 0 {
+		return fileRoles
+	}
+
+	roles := make(map[string]*config.RoleConfig, len(fileRoles)+len(vaultRoles))
+	for name, r := range fileRoles {
+		roles[name] = r
+	}
+	for name, r := range vaultRoles {
+		roles[name] = r
+	}
+	return roles
+}
+
+// reports whether the caller's roles allow the endpoint, writing a 403 if they don't
+// without any roles configured, every endpoint is left to vault's own policies
+func checkRoles(c echo.Context, auth *vault.AuthInfo) bool {
+	roles := currentRoles()
+	if len(roles) == 0 {
+		return true
+	}
+
+	// logging in and out, and health checks, are always allowed
+	endpoint := c.Request().Method + " " + pathParam.ReplaceAllString(c.Path(), "{$1}")
+	tag := apiDocs[endpoint].tag
+	if tag == "auth" || tag == "health" {
+		return true
+	}
+
+	withGroups := false
+	for _, r := range roles {
+		withGroups = withGroups || len(r.Groups) > 0
+	}
+	id, err := lookupIdentity(auth, withGroups)
+	if err != nil {
+		parseError(c, err)
+		return false
+	}
+	if rolesAllow(roles, id, endpoint) {
+		return true
+	}
+
+	c.JSON(http.StatusForbidden, H{
+		"error": "Your goldfish roles do not allow " + endpoint,
+		"code":  "forbidden_by_role",
+	})
+	return false
+}
+
+// endpoint is "<method> <route>", with path parameters in openapi's form
+func rolesAllow(roles map[string]*config.RoleConfig, id identityEntry, endpoint string) bool {
+	if containsString(id.policies, "root") {
+		return true
+	}
+	tag := apiDocs[endpoint].tag
+	for _, r := range roles {
+		if !intersects(r.Policies, id.policies) && !intersects(r.Groups, id.groups) {
+			continue
+		}
+		for _, e := range r.Endpoints {
+			if e == "*" || e == tag || pathParam.ReplaceAllString(e, "{$1}") == endpoint {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cached per token, since every request of every user needs it
+func lookupIdentity(auth *vault.AuthInfo, withGroups bool) (identityEntry, error) {
+	key := sha256.Sum256([]byte(auth.Cluster + "\x00" + auth.Namespace + "\x00" + auth.ID))
+	now := time.Now()
+
+	rolesLock.Lock()
+	entry, ok := identities[key]
+	rolesLock.Unlock()
+	if ok && now.Before(entry.expires) && (entry.withGroups || !withGroups) {
+		return entry, nil
+	}
+
+	policies, groups, err := auth.Identity(withGroups)
+	if err != nil {
+		return identityEntry{}, err
+	}
+	entry = identityEntry{
+		policies:   policies,
+		groups:     groups,
+		withGroups: withGroups,
+		expires:    now.Add(identityTTL),
+	}
+
+	rolesLock.Lock()
+	defer rolesLock.Unlock()
+	if now.Sub(identitySwept) > identityTTL {
+		for k, e := range identities {
+			if now.After(e.expires) {
+				delete(identities, k)
+			}
+		}
+		identitySwept = now
+	}
+	identities[key] = entry
+	return entry, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, entry := range list {
+		if entry == s {
+			return true
+		}
+	}
+	return false
+}
+
+func intersects(a, b []string) bool {
+	for _, s := range a {
+		if containsString(b, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo"
+)
+
+var (
+	readOnly     bool
+	readOnlyLock = new(sync.RWMutex)
+)
+
+// endpoints that are allowed in read-only mode despite not being GETs, since they change nothing shared
+// bootstrapping is allowed so a read-only instance can still be started, and raw requests check their own method
+// bookmarks only change goldfish's own state, so users keep them in read-only mode too
+var readOnlyAllowed = map[string]bool{
+	"POST /v1/bootstrap":             true,
+	"POST /v1/rebootstrap":           true,
+	"POST /v1/login":                 true,
+	"POST /v1/login/renew-self":      true,
+	"POST /v1/login/reauth":          true,
+	"POST /v1/login/oidc/complete":   true,
+	"POST /v1/namespaces/switch":     true,
+	"POST /v1/logout":                true,
+	"POST /v1/token/lookup-accessor": true,
+	"POST /v1/transit/encrypt":       true,
+	"POST /v1/transit/decrypt":       true,
+	"POST /v1/transit/backup":        true,
+	"POST /v1/tools/random":          true,
+	"POST /v1/tools/hash":            true,
+	"POST /v1/policy/simulate":       true,
+	"POST /v1/wrapping/unwrap":       true,
+	"POST /v1/wrapping/unwrap-batch": true,
+	"POST /v1/raw":                   true,
+	"POST /v1/bookmarks":             true,
+	"DELETE /v1/bookmarks":           true,
+	"DELETE /v1/recent":              true,
+}
+
+// may be called again at runtime, e.g. when the config file is reloaded
+func SetReadOnly(on bool) {
+	readOnlyLock.Lock()
+	defer readOnlyLock.Unlock()
+	readOnly = on
+}
+
+func isReadOnly() bool {
+	readOnlyLock.RLock()
+	defer readOnlyLock.RUnlock()
+	return readOnly
+}
+
+// refuses every state-changing api request while goldfish is in read-only mode
+func ReadOnly() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			method := c.Request().Method
+			if !isReadOnly() || method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions ||
+				!strings.HasPrefix(c.Path(), "/v1/") || readOnlyAllowed[method+" "+c.Path()] || isDryRun(c) {
+				return next(c)
+			}
+			return readOnlyRefused(c)
+		}
+	}
+}
+
+// the ui hides write actions when it sees this error code
+func readOnlyRefused(c echo.Context) error {
+	return c.JSON(http.StatusForbidden, H{
+		"error": "Goldfish is in read-only mode, so changes can't be made from this instance",
+		"code":  "read_only",
+	})
+}
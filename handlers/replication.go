@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/xor"
+	"github.com/labstack/echo"
+)
+
+// replication endpoints take the cluster to act on as a parameter, since a dr secondary can't be logged in to
+// the vault paths they call are documented with vault enterprise's replication api
+
+// a cluster's replication status, by mode. Public, as vault serves it without a token
+func GetReplicationStatus() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		status, err := vault.ReplicationStatus(c.QueryParam("cluster"))
+		if err != nil {
+			return parseError(c, err)
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": status,
+		})
+	}
+}
+
+// reads, starts or cancels generating a dr operation token
+// public, as a dr secondary accepts no vault tokens: vault authorizes each step by its unseal keys
+func DROperationToken() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cluster := c.QueryParam("cluster")
+
+		var result map[string]interface{}
+		var err error
+		switch c.Request().Method {
+		case "GET":
+			result, err = vault.DROperationTokenStatus(cluster)
+		case "POST":
+			result, err = vault.StartDROperationToken(cluster, c.FormValue("otp"), c.FormValue("pgp_key"))
+			if err == nil {
+				log.Printf("[INFO ]: DR operation token generation started on cluster %q from %s\n", cluster, clientIP(c))
+			}
+		case "DELETE":
+			err = vault.CancelDROperationToken(cluster)
+			if err == nil {
+				log.Printf("[INFO ]: DR operation token generation cancelled on cluster %q from %s\n", cluster, clientIP(c))
+			}
+		}
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
+// provides an unseal key to the dr operation token being generated
+// given the attempt's otp, the finished token is decoded too. Keys and the otp are never logged or kept
+func UpdateDROperationToken() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cluster := c.QueryParam("cluster")
+		result, err := vault.UpdateDROperationToken(cluster, c.FormValue("key"), c.FormValue("nonce"))
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		response := H{
+			"result": result,
+		}
+		encoded, _ := result["encoded_token"].(string)
+		if encoded == "" {
+			encoded, _ = result["encoded_root_token"].(string)
+		}
+		if encoded != "" {
+			log.Printf("[INFO ]: DR operation token generated on cluster %q\n", cluster)
+			if otp := c.FormValue("otp"); otp != "" {
+				if b, err := xor.XORBase64(encoded, otp); err == nil {
+					if token, err := uuid.FormatUUID(b); err == nil {
+						response["dr_operation_token"] = token
+					}
+				}
+			}
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": response,
+		})
+	}
+}
+
+// promotes, demotes, or disables replication, after confirming the action with the cluster's current status
+// a dr secondary's actions are authorized by a dr operation token. Every other action needs the user's
+// session, and their token must have sudo on the path
+func ReplicationAction() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cluster := c.QueryParam("cluster")
+		mode, action := c.Param("mode"), c.Param("action")
+
+		path, role, err := vault.ReplicationActionPath(cluster, mode, action)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
+		var auth *vault.AuthInfo
+		drSecondary := mode == "dr" && role == "secondary"
+		if !drSecondary {
+			// fetch auth from header or cookie
+			if auth = getSession(c); auth == nil {
+				return nil
+			}
+			defer auth.Clear()
+		}
+
+		// options vault takes for the action, passed on only if given
+		body := make(map[string]interface{})
+		for _, key := range []string{"primary_cluster_addr", "force", "update_primary_addrs"} {
+			if v := c.FormValue(key); v != "" {
+				body[key] = v
+			}
+		}
+
+		target := mode + " " + role
+		if cluster != "" {
+			target += " " + cluster
+		}
+		// replication actions are always confirmed, whether or not require_confirmation is set
+		if !confirmedAlways(c, action, target, func() interface{} {
+			status, err := vault.ReplicationStatus(cluster)
+			if err != nil {
+				return nil
+			}
+			return H{"status": status[mode], "path": path, "options": body}
+		}) {
+			return nil
+		}
+
+		var result map[string]interface{}
+		if drSecondary {
+			result, err = vault.DRSecondaryAction(cluster, path, c.FormValue("dr_operation_token"), body)
+		} else {
+			result, err = auth.ReplicationAction(path, body)
+		}
+		if err != nil {
+			return parseError(c, err)
+		}
+		log.Printf("[INFO ]: Replication %s on %s from %s\n", action, target, clientIP(c))
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/caiyeon/goldfish/report"
+	"github.com/labstack/echo"
+)
+
+// lists the reports scheduled by the config file, and when they last ran
+func GetReports() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		return c.JSON(http.StatusOK, H{
+			"result": report.Status(),
+		})
+	}
+}
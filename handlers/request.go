@@ -35,14 +35,42 @@ func GetRequest() echo.HandlerFunc {
 			}
 		}
 
-		// return request details
+		// return request details, with what approving it would do if that can be worked out
+		plan, _ := request.NewPlan(req)
 		return c.JSON(http.StatusOK, H{
 			"result": req,
+			"plan":   plan,
 			"error":  "",
 		})
 	}
 }
 
+// renders what approving a request would do, as structured changes and as text
+func GetRequestPlan() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		req, err := request.Get(auth, c.QueryParam("hash"))
+		if err != nil {
+			return parseError(c, err)
+		}
+		plan, err := request.NewPlan(req)
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": plan,
+			"text":   plan.String(),
+		})
+	}
+}
+
 // Adds a request to cubbyhole, that can be rejected/approved later
 // Requires requester to have read access to the policy
 func AddRequest() echo.HandlerFunc {
@@ -86,29 +114,57 @@ func AddRequest() echo.HandlerFunc {
 			}
 		}
 
-		// if config has a slack webhook, send the hash (aka change ID) to the channel
+		// policy requests may break lint rules that only warn, which the requester should see
+		warnings := make([]request.LintViolation, 0)
+		if req, err := request.Get(auth, hash); err == nil {
+			if p, ok := req.(*request.PolicyRequest); ok {
+				if violations, err := request.LintPolicy(p.PolicyName, p.Proposed); err == nil {
+					warnings = violations
+				}
+			}
+		}
+
+		// if config has a slack webhook, send the hash (aka change ID) to the channel, with the request's plan
 		conf := vault.GetConfig()
 		if conf.SlackWebhook != "" {
-			// send a message using webhook
-			err = slack.PostMessageWebhook(
-				conf.SlackChannel,
-				"A new policy change request has been submitted",
-				"Request ID: \n*"+hash+"*",
-				conf.SlackWebhook,
-			)
+			text := "Request ID: \n*" + hash + "*"
+			if req, err := request.Get(auth, hash); err == nil {
+				if plan, err := request.NewPlan(req); err == nil {
+					text += "\n```" + plan.String() + "```"
+				}
+			}
+			// send a message using webhook, with buttons to act on it if chatops is configured
+			if getChatOps() != nil {
+				err = slack.PostRequestWebhook(
+					conf.SlackChannel,
+					"A new policy change request has been submitted",
+					text,
+					hash,
+					conf.SlackWebhook,
+				)
+			} else {
+				err = slack.PostMessageWebhook(
+					conf.SlackChannel,
+					"A new policy change request has been submitted",
+					text,
+					conf.SlackWebhook,
+				)
+			}
 			// change request is fine, just let the frontend know it wasn't slack'd
 			if err != nil {
 				return c.JSON(http.StatusOK, H{
-					"result": hash,
-					"error":  "Could not send to slack webhook",
+					"result":   hash,
+					"warnings": warnings,
+					"error":    "Could not send to slack webhook",
 				})
 			}
 		}
 
 		// if all is good, return hash
 		return c.JSON(http.StatusOK, H{
-			"result": hash,
-			"error":  "",
+			"result":   hash,
+			"warnings": warnings,
+			"error":    "",
 		})
 	}
 }
@@ -145,8 +201,11 @@ func ApproveRequest() echo.HandlerFunc {
 			})
 		}
 
+		// a delegate approving for someone names them
+		onBehalfOf, _ := params["on_behalf_of"].(string)
+
 		// approve the request by hash
-		req, err := request.Approve(auth, hash.(string), unseal.(string))
+		req, err := request.ApproveOnBehalf(auth, hash.(string), unseal.(string), onBehalfOf)
 		if err != nil {
 			// if error contains 403 from vault, forward it to the user
 			if strings.Contains(err.Error(), "Code: 403. Errors:\n\n* permission denied") {
@@ -200,3 +259,24 @@ func RejectRequest() echo.HandlerFunc {
 		})
 	}
 }
+
+// time to approval and approvals by approver, for change review slas
+func GetRequestStats() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		stats, err := request.GetStats()
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": stats,
+		})
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"sort"
 
 	"github.com/caiyeon/goldfish/vault"
 	"github.com/labstack/echo"
@@ -24,12 +25,16 @@ func GetSecrets() echo.HandlerFunc {
 
 		if path == "" || path[len(path)-1:] == "/" {
 			// listing a directory
-			if result, err := auth.ListSecret(path); err != nil {
+			if wantsStream(c) {
+				return streamList(c, auth, path)
+			}
+			if result, err := auth.ListKeys(path); err != nil {
 				return parseError(c, err)
 			} else {
-				return c.JSON(http.StatusOK, H{
-					"result": result,
-					"path":   path,
+				return jsonWithETag(c, H{
+					"result":    result.Keys,
+					"truncated": result.Truncated,
+					"path":      path,
 				})
 			}
 		} else {
@@ -37,6 +42,7 @@ func GetSecrets() echo.HandlerFunc {
 			if result, err := auth.ReadSecret(path); err != nil {
 				return parseError(c, err)
 			} else {
+				recordRecent(c, path)
 				return c.JSON(http.StatusOK, H{
 					"result": result,
 					"path":   path,
@@ -70,6 +76,19 @@ func PostSecrets() echo.HandlerFunc {
 			})
 		}
 
+		// a secret the caller can't read shows up as new
+		if isDryRun(c) {
+			before, err := auth.ReadSecret(path)
+			diff, jsonErr := secretDiff(before, body)
+			if jsonErr != nil {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "Body must be json encoded key-value pairs",
+				})
+			}
+			diff["exists"] = err == nil
+			return dryRun(c, auth, "PUT", path, diff)
+		}
+
 		resp, err := auth.WriteSecret(path, body)
 		if err != nil {
 			return parseError(c, err)
@@ -90,7 +109,28 @@ func DeleteSecrets() echo.HandlerFunc {
 		}
 		defer auth.Clear()
 
-		_, err := auth.DeleteSecret(c.QueryParam("path"))
+		// only the secret's keys are shown, never its values
+		path := c.QueryParam("path")
+		keys := func() interface{} {
+			data, err := auth.ReadSecret(path)
+			if err != nil {
+				return nil
+			}
+			keys := make([]string, 0, len(data))
+			for k := range data {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			return map[string]interface{}{"keys": keys}
+		}
+		if isDryRun(c) {
+			return dryRun(c, auth, "DELETE", path, keys())
+		}
+		if !confirmed(c, "delete secret", path, keys) {
+			return nil
+		}
+
+		_, err := auth.DeleteSecret(path)
 		if err != nil {
 			return parseError(c, err)
 		}
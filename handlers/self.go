@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/caiyeon/goldfish/config"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// everything the ui needs to know about the caller in one call, so it only shows what they can use
+func Self() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		resp, err := auth.LookupSelf()
+		if err != nil {
+			return parseError(c, err)
+		}
+		if resp == nil {
+			return c.JSON(http.StatusInternalServerError, H{
+				"error": "Failed to lookup token",
+			})
+		}
+
+		roles := currentRoles()
+		id, err := lookupIdentity(auth, true)
+		if err != nil {
+			return parseError(c, err)
+		}
+		var held []string
+		for name, r := range roles {
+			if intersects(r.Policies, id.policies) || intersects(r.Groups, id.groups) {
+				held = append(held, name)
+			}
+		}
+		sort.Strings(held)
+
+		allowed, denied := roleFeatures(roles, id)
+		capabilities := auth.CanUseAll(allowed)
+		for _, name := range denied {
+			capabilities[name] = false
+		}
+
+		result := map[string]interface{}{
+			"cluster":           auth.Cluster,
+			"namespace":         auth.Namespace,
+			"display_name":      resp.Data["display_name"],
+			"accessor":          resp.Data["accessor"],
+			"path":              resp.Data["path"],
+			"meta":              resp.Data["meta"],
+			"policies":          resp.Data["policies"],
+			"identity_policies": resp.Data["identity_policies"],
+			"entity_id":         resp.Data["entity_id"],
+			"groups":            id.groups,
+			"ttl":               resp.Data["ttl"],
+			"expire_time":       resp.Data["expire_time"],
+			"renewable":         resp.Data["renewable"],
+			"roles":             held,
+			"capabilities":      capabilities,
+		}
+		if s := currentSession(c); s != nil {
+			result["session"] = sessionInfo(s)
+			result["step_up_required"] = s.NeedsStepUp()
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
+// what the caller can reach: which of goldfish's features they may use, and the mounts their token can see with
+// its capabilities at each, so the ui can hide menus that lead nowhere. Every capability is checked in one call to vault
+func SelfAccess() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		id, err := lookupIdentity(auth, true)
+		if err != nil {
+			return parseError(c, err)
+		}
+		allowed, denied := roleFeatures(currentRoles(), id)
+		access, err := auth.Access(allowed)
+		if err != nil {
+			return parseError(c, err)
+		}
+		for _, name := range denied {
+			access.Features[name] = false
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": access,
+		})
+	}
+}
+
+// splits goldfish's features into those its roles let the user use, which vault's policies must allow too,
+// and those they don't
+func roleFeatures(roles map[string]*config.RoleConfig, id identityEntry) (map[string]vault.Feature, []string) {
+	allowed := make(map[string]vault.Feature)
+	var denied []string
+	for name, f := range vault.Features() {
+		if len(roles) > 0 && !rolesAllow(roles, id, f.Endpoint) {
+			denied = append(denied, name)
+			continue
+		}
+		allowed[name] = f
+	}
+	return allowed, denied
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/caiyeon/goldfish/session"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// returns the server-side session the request was made with, if any
+func currentSession(c echo.Context) *session.Session {
+	s, _ := c.Get("session").(*session.Session)
+	return s
+}
+
+type sessionLookup struct {
+	session *session.Session
+	err     error
+}
+
+// looks up a session or api token once per request, since the rate limiter needs it before the handler does
+func lookupSession(c echo.Context, id string) (*session.Session, error) {
+	if l, ok := c.Get("session_lookup").(sessionLookup); ok {
+		return l.session, l.err
+	}
+	s, err := session.Get(id)
+	c.Set("session_lookup", sessionLookup{session: s, err: err})
+	return s, err
+}
+
+func ListSessions() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		current := currentSession(c)
+		if current == nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Only sessions created by logging in to goldfish can be listed",
+			})
+		}
+
+		sessions, err := session.ListOwner(current.Owner)
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		result := make([]map[string]interface{}, 0, len(sessions))
+		for _, s := range sessions {
+			info := sessionInfo(s)
+			info["current"] = s.Hash == current.Hash
+			result = append(result, info)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
+// tokens are never returned, and sessions are identified by hash since ids are not stored
+func sessionInfo(s *session.Session) map[string]interface{} {
+	var expires interface{}
+	if deadline := s.Deadline(); !deadline.IsZero() {
+		expires = deadline.UTC().Format(time.RFC3339)
+	}
+	return map[string]interface{}{
+		"id":           s.Hash,
+		"cluster":      s.Cluster,
+		"display_name": s.DisplayName,
+		"backend":      s.Backend,
+		"source_ip":    s.SourceIP,
+		"accessor":     s.Accessor,
+		"created":      s.Created.UTC().Format(time.RFC3339),
+		"last_seen":    s.LastSeen.UTC().Format(time.RFC3339),
+		"expires":      expires,
+	}
+}
+
+// lists everyone's sessions, for whoever may revoke them all
+func ListAllSessions() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		if err := auth.CanAdministerSessions(); err != nil {
+			return c.JSON(http.StatusForbidden, H{
+				"error": err.Error(),
+			})
+		}
+
+		sessions, err := session.List()
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		var currentHash string
+		if current := currentSession(c); current != nil {
+			currentHash = current.Hash
+		}
+		result := make([]map[string]interface{}, 0, len(sessions))
+		for _, s := range sessions {
+			info := sessionInfo(s)
+			info["owner"] = s.Owner
+			info["current"] = s.Hash == currentHash
+			result = append(result, info)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
+// revokes anyone's session. The vault token behind it stays valid, and can be revoked by its accessor
+func AdminRevokeSession() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		if err := auth.CanAdministerSessions(); err != nil {
+			return c.JSON(http.StatusForbidden, H{
+				"error": err.Error(),
+			})
+		}
+
+		// api tokens are revoked through their own endpoint, which also revokes their vault token
+		sessions, err := session.List()
+		if err != nil {
+			return parseError(c, err)
+		}
+		id := c.Param("id")
+		for _, s := range sessions {
+			if s.Hash != id {
+				continue
+			}
+			if err := session.DeleteHash(id); err != nil {
+				return parseError(c, err)
+			}
+
+			by := "unknown"
+			if current := currentSession(c); current != nil {
+				by = current.DisplayName
+			}
+			log.Printf("[WARN ]: Session of %s from %s was revoked by %s\n", s.DisplayName, s.SourceIP, by)
+			return c.JSON(http.StatusOK, H{
+				"result": "Session revoked",
+			})
+		}
+
+		return c.JSON(http.StatusNotFound, H{
+			"error": "Session not found",
+		})
+	}
+}
+
+func RevokeSession() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		current := currentSession(c)
+		if current == nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Only sessions created by logging in to goldfish can be revoked",
+			})
+		}
+
+		// users may only revoke their own sessions
+		sessions, err := session.ListOwner(current.Owner)
+		if err != nil {
+			return parseError(c, err)
+		}
+		id := c.Param("id")
+		for _, s := range sessions {
+			if s.Hash == id {
+				if err := session.DeleteHash(id); err != nil {
+					return parseError(c, err)
+				}
+				return c.JSON(http.StatusOK, H{
+					"result": "Session revoked",
+				})
+			}
+		}
+
+		return c.JSON(http.StatusNotFound, H{
+			"error": "Session not found",
+		})
+	}
+}
+
+func RevokeAllSessions() echo.HandlerFunc {
+	type body struct {
+		// also invalidate stateless ciphers, by retiring every version of the server transit key
+		Rotate_transit_key bool
+	}
+
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		if err := auth.CanAdministerSessions(); err != nil {
+			return c.JSON(http.StatusForbidden, H{
+				"error": err.Error(),
+			})
+		}
+
+		// the body is optional
+		b := new(body)
+		if c.Request().ContentLength != 0 {
+			if err := c.Bind(b); err != nil {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "Invalid format",
+				})
+			}
+		}
+
+		// keep an audit trail, since this logs everyone out
+		by := "unknown"
+		if current := currentSession(c); current != nil {
+			by = current.DisplayName
+		}
+
+		// api tokens' vault tokens were minted for them, so they are revoked in vault too, not left live there
+		apiTokens, err := session.ListAPITokens()
+		if err != nil {
+			return parseError(c, err)
+		}
+		count, err := session.DeleteAll()
+		if err != nil {
+			return parseError(c, err)
+		}
+		log.Printf("[WARN ]: All %d sessions were revoked by %s\n", count, by)
+		failed := revokeAPITokenAccessors(auth, apiTokens)
+
+		if b.Rotate_transit_key {
+			if err := vault.RetireServerTransitKey(); err != nil {
+				return c.JSON(http.StatusInternalServerError, H{
+					"error": "Sessions were revoked, but the server transit key could not be rotated: " + err.Error(),
+				})
+			}
+			log.Println("[WARN ]: Server transit key was rotated and its older versions retired by " + by)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": map[string]interface{}{
+				"sessions_revoked":         count,
+				"api_token_vault_failures": failed,
+				"transit_key_rotated":      b.Rotate_transit_key,
+			},
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+func GetSettings() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		return c.JSON(http.StatusOK, H{
+			"result": vault.GetSettings(),
+		})
+	}
+}
+
+// fields left out of the body keep their current value, and so do features that aren't mentioned
+func UpdateSettings() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		s := vault.GetSettings()
+		features := make(map[string]bool, len(s.Features))
+		for name, on := range s.Features {
+			features[name] = on
+		}
+		s.Features = features
+
+		if err := c.Bind(&s); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid settings format",
+			})
+		}
+		if err := s.Validate(); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
+		if err := auth.WriteSettings(s); err != nil {
+			return parseError(c, err)
+		}
+		log.Println("[INFO ]: Settings were changed by", currentSession(c).DisplayName)
+
+		return c.JSON(http.StatusOK, H{
+			"result": s,
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+// evaluates whether policies would allow an operation on a path, and which rule decides it
+// existing policies are read with the caller's token, so they must be allowed to read them
+func SimulateACL() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		var body struct {
+			Policies  []string `json:"policies"`
+			Rules     []string `json:"rules"`
+			Path      string   `json:"path"`
+			Operation string   `json:"operation"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Body must be in JSON format",
+			})
+		}
+
+		result, err := auth.SimulateACL(body.Policies, body.Rules, body.Path, body.Operation)
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/caiyeon/goldfish/request"
+	"github.com/labstack/echo"
+)
+
+// lists the versions of a policy that approved changes replaced, newest first
+func GetPolicySnapshots() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		snapshots, err := request.ListSnapshots(auth, c.QueryParam("policy"))
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": snapshots,
+		})
+	}
+}
+
+// restores a policy from a snapshot, or requests to if rollbacks must be approved
+func RollbackPolicy() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		hash, err := request.RollbackPolicy(auth, c.FormValue("id"))
+		if err != nil {
+			return parseError(c, err)
+		}
+		bustCache(cachePolicies)
+
+		if hash != "" {
+			return c.JSON(http.StatusOK, H{
+				"result": "Rollback requested",
+				"hash":   hash,
+			})
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": "Policy rolled back",
+		})
+	}
+}
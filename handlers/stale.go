@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/caiyeon/goldfish/auditlog"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/labstack/echo"
+)
+
+const defaultStaleAge = 90 * 24 * time.Hour
+
+// a kv version 2 secret not written, nor read as far as the audit log shows, within the period
+type staleSecret struct {
+	vault.KVSecret
+	LastRead       *time.Time `json:"last_read"`
+	Owner          string     `json:"owner"`
+	Classification string     `json:"classification"`
+}
+
+type staleGroup struct {
+	Name    string        `json:"name"`
+	Secrets []staleSecret `json:"secrets"`
+}
+
+// kv version 2 secrets under a folder that weren't updated within older_than, grouped by the folder they
+// are in, or by their owner tag. With an audit_source, secrets read within the period aren't stale either,
+// though reads from before audit_coverage_seconds are unknown. Secrets are found with the caller's token,
+// and those whose metadata it can't read are listed as skipped
+func GetStaleSecrets() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		prefix := strings.TrimPrefix(c.QueryParam("prefix"), "/")
+		age := defaultStaleAge
+		if raw := c.QueryParam("older_than"); raw != "" {
+			var err error
+			if age, err = parseutil.ParseDurationSecond(raw); err != nil || age <= 0 {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "older_than must be a duration, e.g. \"2160h\"",
+				})
+			}
+		}
+		groupBy := c.QueryParam("group_by")
+		if groupBy == "" {
+			groupBy = "prefix"
+		}
+		if groupBy != "prefix" && groupBy != "owner" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "group_by must be prefix or owner",
+			})
+		}
+
+		secrets, skipped, err := auth.KVSecrets(prefix)
+		if err != nil {
+			return parseError(c, err)
+		}
+		tags, err := vault.ListSecretTags(prefix, vault.SecretTags{})
+		if err != nil {
+			return parseError(c, err)
+		}
+		tagsOf := make(map[string]vault.SecretTags)
+		for _, t := range tags {
+			tagsOf[t.Path] = t
+		}
+
+		cutoff := time.Now().Add(-age)
+		groups := make(map[string]*staleGroup)
+		for _, s := range secrets {
+			if s.Updated.After(cutoff) {
+				continue
+			}
+			stale := staleSecret{
+				KVSecret:       s,
+				Owner:          tagsOf[s.Path].Owner,
+				Classification: tagsOf[s.Path].Classification,
+			}
+			if read := auditlog.LastRead(s.DataPath()); !read.IsZero() {
+				if read.After(cutoff) {
+					continue
+				}
+				stale.LastRead = &read
+			}
+
+			name := path.Dir(s.Path) + "/"
+			if groupBy == "owner" {
+				name = stale.Owner
+			}
+			if groups[name] == nil {
+				groups[name] = &staleGroup{Name: name}
+			}
+			groups[name].Secrets = append(groups[name].Secrets, stale)
+		}
+
+		// least recently updated first
+		result := make([]staleGroup, 0, len(groups))
+		for _, g := range groups {
+			sort.Slice(g.Secrets, func(i, j int) bool { return g.Secrets[i].Updated.Before(g.Secrets[j].Updated) })
+			result = append(result, *g)
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+		return c.JSON(http.StatusOK, H{
+			"result": H{
+				"older_than_seconds":     age.Seconds(),
+				"audit_coverage_seconds": auditlog.Coverage().Seconds(),
+				"groups":                 result,
+				"skipped":                skipped,
+			},
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// anyone may load the status page, so vault is asked about goldfish's token at most this often
+const statusCacheTTL = 5 * time.Second
+
+type statusReport struct {
+	Status    string    `json:"status"`
+	Ready     bool      `json:"goldfish_ready"`
+	Reachable bool      `json:"vault_reachable"`
+	Sealed    bool      `json:"vault_sealed"`
+	State     string    `json:"vault_state"`
+	Since     time.Time `json:"vault_state_since"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+var (
+	statusPageOn bool
+	lastStatus   *statusReport
+	statusLock   = new(sync.Mutex)
+)
+
+// the status page is off unless the config file enables it
+func SetStatusPage(on bool) {
+	statusLock.Lock()
+	defer statusLock.Unlock()
+	statusPageOn = on
+	lastStatus = nil
+}
+
+func statusPageEnabled() bool {
+	statusLock.Lock()
+	defer statusLock.Unlock()
+	return statusPageOn
+}
+
+// whether goldfish and vault are up, for noc dashboards and load balancers. Needs no token,
+// so it shows only states: never addresses, versions, or vault's error messages
+// status is ok, degraded (vault is down but goldfish is ready anyway, with ready_while_sealed) or down, which is a 503
+// browsers get a page that refreshes itself, everything else json
+func StatusPage() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		s := currentStatus()
+		if s == nil {
+			return c.NoContent(http.StatusNotFound)
+		}
+		code := http.StatusOK
+		if s.Status == "down" {
+			code = http.StatusServiceUnavailable
+		}
+		c.Response().Header().Set("Cache-Control", "no-cache")
+
+		if c.QueryParam("format") == "html" ||
+			(c.QueryParam("format") == "" && strings.Contains(c.Request().Header.Get(echo.HeaderAccept), "text/html")) {
+			return c.HTML(code, statusHTML(s))
+		}
+		return c.JSON(code, s)
+	}
+}
+
+// nil if the status page is off
+func currentStatus() *statusReport {
+	statusLock.Lock()
+	defer statusLock.Unlock()
+	if !statusPageOn {
+		return nil
+	}
+	if lastStatus != nil && time.Since(lastStatus.CheckedAt) < statusCacheTTL {
+		return lastStatus
+	}
+
+	r := checkReadiness()
+	s := &statusReport{
+		Status:    "ok",
+		Ready:     r.Ready,
+		Reachable: r.VaultState.State != vault.StateUnreachable,
+		Sealed:    r.VaultState.State == vault.StateSealed,
+		State:     r.VaultState.State,
+		Since:     r.VaultState.Since,
+		CheckedAt: time.Now(),
+	}
+	if !r.Ready {
+		s.Status = "down"
+	} else if !r.VaultState.Healthy() {
+		s.Status = "degraded"
+	}
+	lastStatus = s
+	return s
+}
+
+func statusHTML(s *statusReport) string {
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+	rows := [][2]string{
+		{"Goldfish ready", yesNo(s.Ready)},
+		{"Vault reachable", yesNo(s.Reachable)},
+		{"Vault sealed", yesNo(s.Sealed)},
+		{"Vault state", s.State + " since " + s.Since.UTC().Format(time.RFC3339)},
+		{"Checked at", s.CheckedAt.UTC().Format(time.RFC3339)},
+	}
+	table := ""
+	for _, r := range rows {
+		table += fmt.Sprintf("<tr><th>%s</th><td>%s</td></tr>\n", r[0], html.EscapeString(r[1]))
+	}
+	// no inline styles or scripts, which the content security policy may not allow
+	return fmt.Sprintf("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n"+
+		"<meta http-equiv=\"refresh\" content=\"30\">\n<title>Goldfish status: %s</title>\n</head>\n"+
+		"<body>\n<h1>Goldfish status: %s</h1>\n<table>\n%s</table>\n</body>\n</html>\n",
+		s.Status, s.Status, table)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/caiyeon/goldfish/session"
+	"github.com/caiyeon/goldfish/tracing"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// destructive endpoints, which need credentials re-entered recently if the session config asks for it
+var stepUpRoutes = map[string]bool{
+	"DELETE /v1/policy":              true,
+	"DELETE /v1/secrets":             true,
+	"POST /v1/token/revoke-accessor": true,
+	"POST /v1/userpass/delete":       true,
+	"POST /v1/approle/delete":        true,
+	"POST /v1/sessions/revoke-all":   true,
+	"POST /v1/kv/migrate":            true,
+}
+
+// the ui asks for credentials again when it sees this error code, then retries the request
+func stepUpRequired(c echo.Context) error {
+	return c.JSON(http.StatusUnauthorized, H{
+		"error": "Please re-enter your credentials to continue",
+		"code":  "reauth_required",
+	})
+}
+
+// takes the same credentials as a login, which must belong to whoever owns the current session
+func Reauthenticate() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		current := currentSession(c)
+		if current == nil || current.IsAPIToken() {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Only sessions created by logging in to goldfish can re-authenticate",
+			})
+		}
+
+		reauth := new(vault.AuthInfo)
+		defer reauth.Clear()
+		if err := c.Bind(reauth); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid auth format",
+			})
+		}
+		if reauth.Type == "" || reauth.ID == "" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Empty authentication",
+			})
+		}
+		reauth.Cluster = current.Cluster
+		reauth.Namespace = current.LoginNamespace
+		reauth.Trace = tracing.FromContext(c)
+		reauth.Audit = auditInfo(c, current)
+
+		// guessing at a stolen session's password is throttled like any other login
+		keys := loginKeys(c, reauth)
+		if wait := loginWait(keys); wait > 0 {
+			seconds := int((wait + time.Second - 1) / time.Second)
+			c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+			return c.JSON(http.StatusTooManyRequests, H{
+				"error": fmt.Sprintf("Too many failed logins, please try again in %d seconds", seconds),
+			})
+		}
+
+		typ := reauth.Type
+		data, err := reauth.Login()
+		if err != nil {
+			if isAuthFailure(err) {
+				loginFailed(keys)
+			}
+			return parseError(c, err)
+		}
+		loginSucceeded(keys)
+
+		// logins other than by token create a token, which the session has no use for
+		if typ != "token" {
+			defer reauth.RevokeSelf()
+		}
+
+		if sessionOwner(current.Cluster, current.LoginNamespace, data) != current.Owner {
+			return c.JSON(http.StatusForbidden, H{
+				"error": "These credentials do not belong to the logged in user",
+			})
+		}
+		if err := session.Reauthenticated(current); err != nil {
+			return c.JSON(http.StatusInternalServerError, H{
+				"error": "Goldfish could not update session: " + err.Error(),
+			})
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": "Re-authenticated",
+		})
+	}
+}
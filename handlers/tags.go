@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// a secret's tags if path is given, otherwise every tagged secret under prefix, optionally of an owner
+// or classification. Tags are shown to those who may read or list the paths, as for the access heatmap
+func GetSecretTags() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		if path := strings.TrimPrefix(c.QueryParam("path"), "/"); path != "" {
+			if !prefixVisible(c, auth, path) {
+				return nil
+			}
+			tags, err := vault.GetSecretTags(path)
+			if err != nil {
+				return parseError(c, err)
+			}
+			return c.JSON(http.StatusOK, H{
+				"result": tags,
+			})
+		}
+
+		prefix := strings.TrimPrefix(c.QueryParam("prefix"), "/")
+		if !prefixVisible(c, auth, prefix) {
+			return nil
+		}
+		tags, err := vault.ListSecretTags(prefix, vault.SecretTags{
+			Owner:          c.QueryParam("owner"),
+			Classification: c.QueryParam("classification"),
+		})
+		if err != nil {
+			return parseError(c, err)
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": tags,
+		})
+	}
+}
+
+// replaces a secret's owner, description, classification and rotation period. Only those who may write the secret may
+// tag it, though the secret itself needn't exist yet. Empty tags are removed
+func SetSecretTags() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		tags := vault.SecretTags{
+			Path:           c.QueryParam("path"),
+			Owner:          c.FormValue("owner"),
+			Description:    c.FormValue("description"),
+			Classification: c.FormValue("classification"),
+			RotationPeriod: c.FormValue("rotation_period"),
+		}
+		if err := tags.Validate(); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+
+		capabilities, err := auth.CapabilitiesSelf(tags.Path)
+		if err != nil {
+			return parseError(c, err)
+		}
+		allowed := false
+		for _, capability := range capabilities {
+			switch capability {
+			case "root", "sudo", "create", "update":
+				allowed = true
+			}
+		}
+		if !allowed {
+			return c.JSON(http.StatusForbidden, H{
+				"error": "Tagging " + tags.Path + " needs create or update on it",
+			})
+		}
+
+		by := ""
+		if s := currentSession(c); s != nil {
+			by = s.DisplayName
+		}
+		if err := vault.SetSecretTags(tags, by); err != nil {
+			return parseError(c, err)
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": "Tags of " + tags.Path + " saved",
+		})
+	}
+}
+
+// secrets under a folder that have no owner or classification, found with the caller's own token
+func GetUntaggedSecrets() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		paths, err := auth.UntaggedSecrets(strings.TrimPrefix(c.QueryParam("prefix"), "/"))
+		if err != nil {
+			return parseError(c, err)
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": paths,
+		})
+	}
+}
+
+// secrets under a prefix with a rotation period, and when they are due, soonest first
+// with overdue=true, only those past due. Their age is read with the caller's token
+func GetRotations() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		prefix := strings.TrimPrefix(c.QueryParam("prefix"), "/")
+		if !prefixVisible(c, auth, prefix) {
+			return nil
+		}
+		rotations, err := auth.Rotations(prefix)
+		if err != nil {
+			return parseError(c, err)
+		}
+		if c.QueryParam("overdue") == "true" {
+			overdue := make([]vault.Rotation, 0)
+			for _, r := range rotations {
+				if r.Overdue {
+					overdue = append(overdue, r)
+				}
+			}
+			rotations = overdue
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": rotations,
+		})
+	}
+}
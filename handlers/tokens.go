@@ -1,10 +1,23 @@
 package handlers
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/caiyeon/goldfish/config"
+	"github.com/caiyeon/goldfish/vault"
 	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/parseutil"
 	"github.com/labstack/echo"
 )
 
@@ -17,16 +30,162 @@ func GetTokenAccessors() echo.HandlerFunc {
 		}
 		defer auth.Clear()
 
-		// fetch results
-		result, err := auth.GetTokenAccessors()
+		if wantsStream(c) {
+			return streamList(c, auth, "auth/token/accessors")
+		}
+
+		// fetch results, which may take many calls to vault
+		result, err := cachedList(c, auth, cacheAccessors, func() (interface{}, error) {
+			return auth.ListKeys("auth/token/accessors")
+		})
 		if err != nil {
 			return parseError(c, err)
 		}
 
-		return c.JSON(http.StatusOK, H{
-			"result": result,
+		list := result.(vault.KeyList)
+		return jsonWithETag(c, H{
+			"result":    list.Keys,
+			"truncated": list.Truncated,
+		})
+	}
+}
+
+// rows are written as each token is looked up, so large inventories are never held in memory
+// the listing is fetched fresh rather than from the cache, since the export is meant as audit evidence
+// q keeps only tokens with a field containing it, or matching it as a regular expression with regex=true,
+// as the ui's token search does
+func ExportTokenAccessors() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		match, err := tokenSearch(c.QueryParam("q"), c.QueryParam("regex") == "true")
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
+		accessors, err := auth.GetTokenAccessors()
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		// the status is sent with the first row, so a failure before then is still reported as one
+		resp := c.Response()
+		w := csv.NewWriter(resp)
+		start := func() {
+			resp.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+			resp.Header().Set(echo.HeaderContentDisposition,
+				"attachment; filename=token-accessors-"+time.Now().UTC().Format("20060102")+".csv")
+			resp.WriteHeader(http.StatusOK)
+			w.Write([]string{"accessor", "display_name", "policies", "ttl", "creation_time", "path"})
+		}
+		rows := 0
+		err = auth.EachTokenByAccessor(accessors, func(data map[string]interface{}) error {
+			fields := accessorFields(data)
+			if !match(fields) {
+				return nil
+			}
+			if !resp.Committed {
+				start()
+			}
+			w.Write(csvRow(fields))
+			if rows++; rows%100 == 0 {
+				w.Flush()
+				resp.Flush()
+			}
+			return w.Error()
 		})
+		if err != nil && !resp.Committed {
+			return parseError(c, err)
+		}
+		if !resp.Committed {
+			start()
+		}
+		w.Flush()
+		if err == nil {
+			err = w.Error()
+		}
+		if err != nil {
+			// the status was already sent, so the connection is dropped rather than the csv ended,
+			// for the client to see the export failed instead of taking it as complete
+			log.Println("[ERROR]: Token accessor export stopped after", rows, "rows:", err.Error())
+			if conn, _, err := resp.Hijack(); err == nil {
+				conn.Close()
+			}
+		}
+		return nil
+	}
+}
+
+// matches a token's fields against a search, which matches everything if it is empty
+func tokenSearch(q string, regex bool) (func([]string) bool, error) {
+	if q == "" {
+		return func([]string) bool { return true }, nil
+	}
+	contains := func(field string) bool { return strings.Contains(field, q) }
+	if regex {
+		re, err := regexp.Compile(q)
+		if err != nil {
+			return nil, errors.New("Invalid regex: " + err.Error())
+		}
+		contains = re.MatchString
 	}
+	return func(fields []string) bool {
+		for _, f := range fields {
+			if contains(f) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func csvRow(fields []string) []string {
+	row := make([]string, len(fields))
+	for i, f := range fields {
+		row[i] = csvSafe(f)
+	}
+	return row
+}
+
+// a token's exported fields, before they are made safe for spreadsheets
+func accessorFields(data map[string]interface{}) []string {
+	var policies []string
+	if raw, ok := data["policies"].([]interface{}); ok {
+		for _, p := range raw {
+			policies = append(policies, fmt.Sprint(p))
+		}
+	}
+	created := ""
+	if n, ok := data["creation_time"].(json.Number); ok {
+		if t, err := n.Int64(); err == nil {
+			created = time.Unix(t, 0).UTC().Format(time.RFC3339)
+		}
+	}
+	accessor, _ := data["accessor"].(string)
+	name, _ := data["display_name"].(string)
+	path, _ := data["path"].(string)
+	return []string{
+		accessor,
+		name,
+		strings.Join(policies, ";"),
+		fmt.Sprint(int64(tokenTTL(data["ttl"]) / time.Second)),
+		created,
+		path,
+	}
+}
+
+// spreadsheets run cells starting with these as formulas, and display names are chosen by whoever logged in
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsAny(s[:1], "=+-@\t\r") {
+		return "'" + s
+	}
+	return s
 }
 
 func LookupTokenByAccessor() echo.HandlerFunc {
@@ -72,6 +231,27 @@ func LookupTokenByAccessor() echo.HandlerFunc {
 	}
 }
 
+// everything known about one token, to judge what revoking it would affect
+func GetAccessorDetail() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		result, err := auth.AccessorDetail(c.Param("id"))
+		if err != nil {
+			return parseError(c, err)
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
 func RevokeTokenByAccessor() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		// fetch auth from header or cookie
@@ -81,12 +261,24 @@ func RevokeTokenByAccessor() echo.HandlerFunc {
 		}
 		defer auth.Clear()
 
+		// shows the token that would be revoked
+		if isDryRun(c) {
+			tokens, err := auth.LookupTokenByAccessor(c.QueryParam("accessor"))
+			if err != nil {
+				return parseError(c, err)
+			}
+			return dryRun(c, auth, "PUT", "auth/token/revoke-accessor", map[string]interface{}{
+				"tokens": tokens,
+			})
+		}
+
 		err := auth.RevokeTokenByAccessor(c.QueryParam("accessor"))
 		if err != nil {
 			return c.JSON(http.StatusBadRequest, H{
 				"error": err.Error(),
 			})
 		}
+		bustCache(cacheAccessors)
 
 		return c.JSON(http.StatusOK, H{
 			"result": "Token deleted successfully",
@@ -94,6 +286,111 @@ func RevokeTokenByAccessor() echo.HandlerFunc {
 	}
 }
 
+var (
+	tokenCreation     *config.TokenCreationConfig
+	tokenCreationLock = new(sync.RWMutex)
+)
+
+// may be called again at runtime, e.g. when the config file is reloaded
+func SetTokenCreation(t *config.TokenCreationConfig) {
+	tokenCreationLock.Lock()
+	defer tokenCreationLock.Unlock()
+	tokenCreation = t
+}
+
+// applies the configured default ttl, and refuses requests over the configured limits
+// ttls of "0" or "" leave the choice to vault, which is also how the ui sends an empty field
+func limitTokenCreation(r *api.TokenCreateRequest, wrapTTL string) error {
+	tokenCreationLock.RLock()
+	limits := tokenCreation
+	tokenCreationLock.RUnlock()
+	if limits == nil {
+		return nil
+	}
+
+	ttls := []struct {
+		name  string
+		value *string
+	}{
+		{"ttl", &r.TTL},
+		{"explicit_max_ttl", &r.ExplicitMaxTTL},
+		{"period", &r.Period},
+	}
+	for _, t := range ttls {
+		if *t.value == "" {
+			continue
+		}
+		d, err := parseutil.ParseDurationSecond(*t.value)
+		if err != nil || d < 0 {
+			return fmt.Errorf("Invalid %s: %s", t.name, *t.value)
+		}
+		if limits.Max_ttl != 0 && d > limits.Max_ttl {
+			return fmt.Errorf("%s can not be more than %s", t.name, limits.Max_ttl)
+		}
+		if d == 0 {
+			*t.value = ""
+		}
+	}
+
+	if r.TTL == "" && limits.Default_ttl != 0 {
+		r.TTL = durationSeconds(limits.Default_ttl)
+	}
+	if r.ExplicitMaxTTL == "" && limits.Max_ttl != 0 {
+		r.ExplicitMaxTTL = durationSeconds(limits.Max_ttl)
+	}
+
+	if limits.Max_num_uses != 0 && (r.NumUses < 1 || r.NumUses > limits.Max_num_uses) {
+		return fmt.Errorf("num_uses must be between 1 and %d", limits.Max_num_uses)
+	}
+	if r.NumUses < 0 {
+		return errors.New("num_uses can not be negative")
+	}
+
+	if wrapTTL == "" {
+		if limits.Require_wrapping {
+			return errors.New("Tokens must be created response-wrapped, with a wrap_ttl")
+		}
+		return nil
+	}
+	wrap, err := parseutil.ParseDurationSecond(wrapTTL)
+	if err != nil || wrap <= 0 {
+		return errors.New("Invalid wrap_ttl: " + wrapTTL)
+	}
+	if limits.Max_wrap_ttl != 0 && wrap > limits.Max_wrap_ttl {
+		return fmt.Errorf("wrap_ttl can not be more than %s", limits.Max_wrap_ttl)
+	}
+	return nil
+}
+
+// whether a raw request to path would create a token, in any namespace
+// raw requests can't be held to token_creation's limits, so these are refused while any are configured
+func rawCreatesToken(method, p string) bool {
+	tokenCreationLock.RLock()
+	limited := tokenCreation != nil
+	tokenCreationLock.RUnlock()
+	if !limited {
+		return false
+	}
+	switch strings.ToUpper(method) {
+	case http.MethodPost, http.MethodPut:
+	default:
+		return false
+	}
+	p = path.Clean("/" + strings.TrimPrefix(strings.TrimPrefix(p, "/"), "v1/"))
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	for i := 0; i+2 < len(segments); i++ {
+		if segments[i] == "auth" && segments[i+1] == "token" &&
+			(segments[i+2] == "create" || segments[i+2] == "create-orphan") {
+			return true
+		}
+	}
+	return false
+}
+
+func durationSeconds(d time.Duration) string {
+	return strconv.Itoa(int(d/time.Second)) + "s"
+}
+
 func CreateToken() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		// fetch auth from header or cookie
@@ -103,25 +400,43 @@ func CreateToken() echo.HandlerFunc {
 		}
 		defer auth.Clear()
 
-		var request = &api.TokenCreateRequest{}
+		var request = &vault.TokenRequest{}
 		if err := c.Bind(request); err != nil {
 			return c.JSON(http.StatusBadRequest, H{
 				"error": "Invalid token creation format",
 			})
 		}
+		wrapTTL := c.QueryParam("wrap_ttl")
+		if err := limitTokenCreation(&request.TokenCreateRequest, wrapTTL); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": err.Error(),
+			})
+		}
 
-		if resp, err := auth.CreateToken(
+		resp, err := auth.CreateTokenRequest(
 			request,
 			c.QueryParam("orphan") == "true",
 			c.QueryParam("role"),
-			c.QueryParam("wrap_ttl"),
-		); err != nil {
+			wrapTTL,
+		)
+		if err != nil {
 			return parseError(c, err)
-		} else {
+		}
+		bustCache(cacheAccessors)
+
+		// a wrapped token is handed over as the wrapping token alone, for the recipient to unwrap
+		if resp.WrapInfo != nil {
+			log.Printf("[INFO ]: Request %s: wrapped token created with accessor %s\n",
+				auth.Audit.RequestID, resp.WrapInfo.WrappedAccessor)
 			return c.JSON(http.StatusOK, H{
-				"result": resp,
+				"result": map[string]interface{}{
+					"wrap_info": resp.WrapInfo,
+				},
 			})
 		}
+		return c.JSON(http.StatusOK, H{
+			"result": resp,
+		})
 	}
 }
 
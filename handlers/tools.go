@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+// random bytes generated by vault, for secrets that need more entropy than a password policy's
+func RandomBytes() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		var body struct {
+			Bytes  int    `json:"bytes"`
+			Format string `json:"format"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid request format",
+			})
+		}
+		if body.Bytes == 0 {
+			body.Bytes = 32
+		}
+
+		random, err := auth.RandomBytes(body.Bytes, body.Format)
+		if err != nil {
+			return parseError(c, err)
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": random,
+		})
+	}
+}
+
+// hashes text, or base64 encoded bytes if input_base64 is set, with vault
+func Hash() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		var body struct {
+			Input       string `json:"input"`
+			InputBase64 bool   `json:"input_base64"`
+			Algorithm   string `json:"algorithm"`
+			Format      string `json:"format"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "Invalid request format",
+			})
+		}
+		input := []byte(body.Input)
+		if body.InputBase64 {
+			var err error
+			if input, err = base64.StdEncoding.DecodeString(body.Input); err != nil {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "Input is not valid base64",
+				})
+			}
+		}
+
+		sum, err := auth.Hash(input, body.Algorithm, body.Format)
+		if err != nil {
+			return parseError(c, err)
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": sum,
+		})
+	}
+}
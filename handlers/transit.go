@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/caiyeon/goldfish/vault"
@@ -86,3 +87,80 @@ func DecryptString() echo.HandlerFunc {
 		})
 	}
 }
+
+// the backup is returned only as a wrapping token, for a restore through goldfish or vault
+func BackupTransitKey() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		wrapttl := c.FormValue("wrap_ttl")
+		if wrapttl == "" {
+			wrapttl = vault.GetSettings().WrapTTL
+		}
+		if wrapttl == "" {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "wrap_ttl cannot be 0",
+			})
+		}
+
+		key := c.FormValue("key")
+		wrapInfo, err := auth.BackupTransitKey(c.FormValue("mount"), key, wrapttl)
+		if err != nil {
+			return parseError(c, err)
+		}
+		log.Printf("[INFO ]: Transit key %s backed up, wrapped with accessor %s\n", key, wrapInfo.WrappedAccessor)
+
+		return c.JSON(http.StatusOK, H{
+			"wrap_info": wrapInfo,
+		})
+	}
+}
+
+// restores a backup, given either the backup itself or the wrapping token it was returned in
+// the wrapping token is unwrapped on the session's cluster, so backups from another cluster must be unwrapped first
+func RestoreTransitKey() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		backup := c.FormValue("backup")
+		if token := c.FormValue("wrapping_token"); token != "" {
+			if backup != "" {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "Provide either a backup or a wrapping token, not both",
+				})
+			}
+			resp, err := auth.UnwrapData(token)
+			if err != nil {
+				return parseError(c, err)
+			}
+			if resp != nil {
+				backup, _ = resp.Data["backup"].(string)
+			}
+			if backup == "" {
+				return c.JSON(http.StatusBadRequest, H{
+					"error": "The wrapping token did not hold a transit key backup",
+				})
+			}
+		}
+
+		key := c.FormValue("key")
+		if err := auth.RestoreTransitKey(c.FormValue("mount"), key, backup, c.FormValue("force") == "true"); err != nil {
+			return parseError(c, err)
+		}
+		log.Printf("[INFO ]: Transit key %s restored\n", key)
+
+		return c.JSON(http.StatusOK, H{
+			"status": "restored",
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/labstack/echo"
+)
+
+// request volume per mount and active entity counts, from vault's usage counters
+// goldfish's own requests to vault are included as a sample, for vaults without counters
+func GetUsage() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// fetch auth from header or cookie
+		auth := getSession(c)
+		if auth == nil {
+			return nil
+		}
+		defer auth.Clear()
+
+		counters, errs := auth.UsageCounters()
+		result := H{
+			"vault":  counters,
+			"errors": errs,
+		}
+
+		// the sample covers every user's requests, so it's shown only to those who may see the mounts
+		if auth.CanUse(vault.Features()["mounts.read"]) {
+			days, since := vault.GoldfishUsage()
+			result["goldfish"] = H{
+				"since": since.Format(time.RFC3339),
+				"days":  days,
+			}
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/caiyeon/goldfish/vault"
 	"github.com/labstack/echo"
 )
 
@@ -43,7 +44,13 @@ func DeleteUserpassUser() echo.HandlerFunc {
 				"error": "username parameter is required",
 			})
 		}
-		if _, err := auth.DeleteRaw("auth/userpass/users/" + username); err != nil {
+		path := vault.UserpassUserPath(username)
+		if isDryRun(c) {
+			return dryRun(c, auth, "DELETE", path, map[string]interface{}{
+				"username": username,
+			})
+		}
+		if _, err := auth.DeleteRaw(path); err != nil {
 			return parseError(c, err)
 		}
 
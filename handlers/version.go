@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/github"
+	"github.com/labstack/echo"
+)
+
+// how long github's answer is trusted, so fleets of goldfish don't hit its rate limit
+const (
+	updateCheckInterval      = 24 * time.Hour
+	updateCheckRetryInterval = time.Hour
+)
+
+type updateCheck struct {
+	Latest    string    `json:"latest,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	Available bool      `json:"update_available"`
+	CheckedAt time.Time `json:"checked_at"`
+	Error     string    `json:"error,omitempty"`
+}
+
+var (
+	updateCheckOn   bool
+	lastUpdateCheck *updateCheck
+	updateCheckLock = new(sync.Mutex)
+)
+
+// the update check is off unless the config file enables it
+func SetUpdateCheck(on bool) {
+	updateCheckLock.Lock()
+	defer updateCheckLock.Unlock()
+	updateCheckOn = on
+}
+
+func Version() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		result := H{
+			"version":    version,
+			"commit":     commit,
+			"build_date": buildDate,
+			"go_version": runtime.Version(),
+			"os":         runtime.GOOS,
+			"arch":       runtime.GOARCH,
+		}
+		if u := checkForUpdate(); u != nil {
+			result["update_check"] = u
+		}
+		return c.JSON(http.StatusOK, H{
+			"result": result,
+		})
+	}
+}
+
+// asks github for the latest release, unless it was asked recently
+// callers wait for the check, which only happens about once a day
+func checkForUpdate() *updateCheck {
+	updateCheckLock.Lock()
+	defer updateCheckLock.Unlock()
+	if !updateCheckOn {
+		return nil
+	}
+
+	if u := lastUpdateCheck; u != nil {
+		interval := updateCheckInterval
+		if u.Error != "" {
+			interval = updateCheckRetryInterval
+		}
+		if time.Since(u.CheckedAt) < interval {
+			return u
+		}
+	}
+
+	u := &updateCheck{CheckedAt: time.Now()}
+	if tag, url, err := github.LatestRelease("caiyeon", "goldfish"); err != nil {
+		u.Error = err.Error()
+	} else {
+		u.Latest, u.URL = tag, url
+		u.Available = newerVersion(tag, version)
+	}
+	lastUpdateCheck = u
+	return u
+}
+
+// compares versions like "v0.7.1" numerically. A pre-release, e.g. "v0.7.1-dev", is older than its release
+func newerVersion(latest, current string) bool {
+	a, aPre := splitVersion(latest)
+	b, bPre := splitVersion(current)
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	return bPre && !aPre
+}
+
+func splitVersion(v string) ([]int, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	pre := false
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		pre = v[i] == '-'
+		v = v[:i]
+	}
+	var parts []int
+	for _, p := range strings.Split(v, ".") {
+		n, _ := strconv.Atoi(p)
+		parts = append(parts, n)
+	}
+	return parts, pre
+}
@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/caiyeon/goldfish/vault"
 	"github.com/labstack/echo"
 )
 
+// provisioning flows hand out a few dozen wrapped secret ids at most
+const maxBatchUnwrap = 100
+
 func WrapHandler() echo.HandlerFunc {
 	return func(c echo.Context) error {
 		// fetch auth from header or cookie
@@ -18,6 +21,9 @@ func WrapHandler() echo.HandlerFunc {
 		defer auth.Clear()
 
 		wrapttl := c.FormValue("wrapttl")
+		if wrapttl == "" {
+			wrapttl = vault.GetSettings().WrapTTL
+		}
 		if wrapttl == "" {
 			return c.JSON(http.StatusBadRequest, H{
 				"error": "wrapttl cannot be 0",
@@ -44,17 +50,14 @@ func UnwrapHandler() echo.HandlerFunc {
 			Type: "token",
 			ID:   "",
 		}
-		defer auth.Clear()
 
-		// fetch auth from header or cookie
-		auth.ID = c.Request().Header.Get("X-Vault-Token")
-		if strings.HasPrefix(auth.ID, "vault:") {
-			if err := auth.DecryptAuth(); err != nil {
-				return c.JSON(http.StatusForbidden, H{
-					"error": "Cipher invalid. Please logout and login again",
-				})
+		// unwrapping needs no login, but is done as the caller if they have one, so vault audits it
+		if sessionHeader(c) != "" {
+			if auth = getSession(c); auth == nil {
+				return nil
 			}
 		}
+		defer auth.Clear()
 
 		wrappingToken := c.FormValue("wrappingToken")
 		if wrappingToken == "" {
@@ -74,3 +77,58 @@ func UnwrapHandler() echo.HandlerFunc {
 		})
 	}
 }
+
+// unwraps each token in turn, so one that is invalid or already used doesn't fail the rest
+// results are in the order the tokens were given, each holding either the unwrapped response or an error
+func UnwrapBatchHandler() echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var auth = &vault.AuthInfo{
+			Type: "token",
+			ID:   "",
+		}
+
+		// unwrapping needs no login, but is done as the caller if they have one, so vault audits it
+		if sessionHeader(c) != "" {
+			if auth = getSession(c); auth == nil {
+				return nil
+			}
+		}
+		defer auth.Clear()
+
+		var body struct {
+			WrappingTokens []string `json:"wrappingTokens" form:"wrappingTokens"`
+		}
+		if err := c.Bind(&body); err != nil {
+			return parseError(c, err)
+		}
+		if len(body.WrappingTokens) == 0 {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": "No wrapping tokens provided",
+			})
+		}
+		// excessive numbers of tokens are not allowed, to avoid stress on vault
+		if len(body.WrappingTokens) > maxBatchUnwrap {
+			return c.JSON(http.StatusBadRequest, H{
+				"error": fmt.Sprintf("Maximum number of wrapping tokens: %d", maxBatchUnwrap),
+			})
+		}
+
+		results := make([]H, len(body.WrappingTokens))
+		for i, token := range body.WrappingTokens {
+			if token == "" {
+				results[i] = H{"error": "Wrapping token cannot be empty"}
+				continue
+			}
+			resp, err := auth.UnwrapData(token)
+			if err != nil {
+				results[i] = H{"error": err.Error()}
+				continue
+			}
+			results[i] = H{"result": resp}
+		}
+
+		return c.JSON(http.StatusOK, H{
+			"result": results,
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/caiyeon/goldfish/config"
+)
+
+// listens on the socket at the listener's address, for use behind a local reverse proxy
+// a socket left over from an unclean shutdown is replaced, but any other file is not
+func unixListener(l *config.ListenerConfig) (net.Listener, error) {
+	if info, err := os.Lstat(l.Address); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", l.Address)
+		}
+		if err := os.Remove(l.Address); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen("unix", l.Address)
+	if err != nil {
+		return nil, err
+	}
+	if err := chownSocket(l); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	if err := os.Chmod(l.Address, l.Socket_mode); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// an empty user or group leaves that ownership unchanged
+func chownSocket(l *config.ListenerConfig) error {
+	uid, gid := -1, -1
+	if l.Socket_user != "" {
+		u, err := user.Lookup(l.Socket_user)
+		if err != nil {
+			return err
+		}
+		if uid, err = strconv.Atoi(u.Uid); err != nil {
+			return err
+		}
+	}
+	if l.Socket_group != "" {
+		g, err := user.LookupGroup(l.Socket_group)
+		if err != nil {
+			return err
+		}
+		if gid, err = strconv.Atoi(g.Gid); err != nil {
+			return err
+		}
+	}
+	if uid == -1 && gid == -1 {
+		return nil
+	}
+	return os.Chown(l.Address, uid, gid)
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+func init() {
+	Describe("goldfish_http_requests_total", "Number of HTTP requests served, by route and status")
+	Describe("goldfish_http_request_duration_seconds", "Latency of HTTP requests served, by route")
+	Describe("goldfish_vault_requests_total", "Number of requests made to vault, by method and status")
+	Describe("goldfish_vault_request_duration_seconds", "Latency of requests made to vault, by method")
+	Describe("goldfish_vault_connections_total", "Number of connections requests to vault were sent on, by whether an open one was reused")
+}
+
+// records request counts and latencies per route
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// let echo write the error response, so the status code is known
+				c.Error(err)
+			}
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			IncrCounter("goldfish_http_requests_total", map[string]string{
+				"route":  route,
+				"method": c.Request().Method,
+				"status": strconv.Itoa(c.Response().Status),
+			})
+			ObserveDuration("goldfish_http_request_duration_seconds", map[string]string{
+				"route": route,
+			}, time.Since(start))
+
+			return nil
+		}
+	}
+}
+
+type instrumentedTransport struct {
+	base http.RoundTripper
+}
+
+// wraps a vault client's transport to record latencies and errors of vault calls, and how often connections are reused
+func InstrumentTransport(base http.RoundTripper) http.RoundTripper {
+	return &instrumentedTransport{base: base}
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			IncrCounter("goldfish_vault_connections_total", map[string]string{
+				"reused": strconv.FormatBool(info.Reused),
+			})
+		},
+	}))
+	resp, err := t.base.RoundTrip(req)
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	IncrCounter("goldfish_vault_requests_total", map[string]string{
+		"method": req.Method,
+		"status": status,
+	})
+	ObserveDuration("goldfish_vault_request_duration_seconds", map[string]string{
+		"method": req.Method,
+	}, time.Since(start))
+
+	return resp, err
+}
@@ -0,0 +1,206 @@
+package metrics
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latency buckets in seconds, same as prometheus client defaults
+var buckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+var (
+	lock       sync.Mutex
+	help       = make(map[string]string)
+	counters   = make(map[string]map[string]float64)
+	histograms = make(map[string]map[string]*histogram)
+	gauges     = make(map[string]func() float64)
+)
+
+// sets the help text displayed for a metric
+func Describe(name, text string) {
+	lock.Lock()
+	defer lock.Unlock()
+	help[name] = text
+}
+
+// adds 1 to a counter with the given labels
+func IncrCounter(name string, labels map[string]string) {
+	emitStatsd(name, "1", "c", labels)
+	lock.Lock()
+	defer lock.Unlock()
+	if _, ok := counters[name]; !ok {
+		counters[name] = make(map[string]float64)
+	}
+	counters[name][formatLabels(labels)]++
+}
+
+// records a duration into a histogram with the given labels
+// statsd timers are in milliseconds, so they are renamed from _seconds to match
+func ObserveDuration(name string, labels map[string]string, d time.Duration) {
+	emitStatsd(strings.TrimSuffix(name, "_seconds")+"_milliseconds", formatFloat(d.Seconds()*1000), "ms", labels)
+	lock.Lock()
+	defer lock.Unlock()
+	if _, ok := histograms[name]; !ok {
+		histograms[name] = make(map[string]*histogram)
+	}
+	l := formatLabels(labels)
+	h, ok := histograms[name][l]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(buckets))}
+		histograms[name][l] = h
+	}
+
+	v := d.Seconds()
+	for i, b := range buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// registers a gauge which is evaluated on every scrape
+func SetGaugeFunc(name string, f func() float64) {
+	lock.Lock()
+	defer lock.Unlock()
+	gauges[name] = f
+}
+
+// serves all metrics in prometheus text exposition format
+// with a token file, scrapers must send its token as a bearer token. The file is read on each scrape,
+// so the token can be rotated without a reload
+func Handler(tokenFile string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tokenFile != "" {
+			token, err := ioutil.ReadFile(tokenFile)
+			sent := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if err != nil || len(bytes.TrimSpace(token)) == 0 ||
+				subtle.ConstantTimeCompare(bytes.TrimSpace(token), []byte(sent)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "A valid bearer token is required", http.StatusUnauthorized)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(render())
+	})
+}
+
+func render() []byte {
+	// gauges are evaluated outside of the lock, since they may be slow
+	lock.Lock()
+	gaugeFuncs := make(map[string]func() float64, len(gauges))
+	for name, f := range gauges {
+		gaugeFuncs[name] = f
+	}
+	lock.Unlock()
+	gaugeValues := make(map[string]float64, len(gaugeFuncs))
+	for name, f := range gaugeFuncs {
+		gaugeValues[name] = f()
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	var buf bytes.Buffer
+	for _, name := range sortedKeys(counters) {
+		writeHeader(&buf, name, "counter")
+		for _, l := range sortedKeys(counters[name]) {
+			fmt.Fprintf(&buf, "%s%s %s\n", name, l, formatFloat(counters[name][l]))
+		}
+	}
+
+	for _, name := range sortedKeys(histograms) {
+		writeHeader(&buf, name, "histogram")
+		for _, l := range sortedKeys(histograms[name]) {
+			h := histograms[name][l]
+			for i, b := range buckets {
+				fmt.Fprintf(&buf, "%s_bucket%s %d\n", name, withLabel(l, "le", formatFloat(b)), h.counts[i])
+			}
+			fmt.Fprintf(&buf, "%s_bucket%s %d\n", name, withLabel(l, "le", "+Inf"), h.count)
+			fmt.Fprintf(&buf, "%s_sum%s %s\n", name, l, formatFloat(h.sum))
+			fmt.Fprintf(&buf, "%s_count%s %d\n", name, l, h.count)
+		}
+	}
+
+	for _, name := range sortedKeys(gaugeValues) {
+		writeHeader(&buf, name, "gauge")
+		fmt.Fprintf(&buf, "%s %s\n", name, formatFloat(gaugeValues[name]))
+	}
+
+	return buf.Bytes()
+}
+
+func writeHeader(buf *bytes.Buffer, name, t string) {
+	if text, ok := help[name]; ok {
+		fmt.Fprintf(buf, "# HELP %s %s\n", name, text)
+	}
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, t)
+}
+
+// labels are rendered once and used as a map key, e.g. {method="GET",route="/v1/health"}
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels))
+	for _, k := range sortedKeys(labels) {
+		pairs = append(pairs, k+"="+strconv.Quote(labels[k]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func withLabel(rendered, key, value string) string {
+	pair := key + "=" + strconv.Quote(value)
+	if rendered == "" {
+		return "{" + pair + "}"
+	}
+	return rendered[:len(rendered)-1] + "," + pair + "}"
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch t := m.(type) {
+	case map[string]string:
+		for k := range t {
+			keys = append(keys, k)
+		}
+	case map[string]float64:
+		for k := range t {
+			keys = append(keys, k)
+		}
+	case map[string]map[string]float64:
+		for k := range t {
+			keys = append(keys, k)
+		}
+	case map[string]*histogram:
+		for k := range t {
+			keys = append(keys, k)
+		}
+	case map[string]map[string]*histogram:
+		for k := range t {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
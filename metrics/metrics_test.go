@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMetricsRendering(t *testing.T) {
+	Convey("Metrics should render in prometheus text format", t, func() {
+		Describe("test_requests_total", "A test counter")
+		IncrCounter("test_requests_total", map[string]string{"route": "/v1/health", "method": "GET"})
+		IncrCounter("test_requests_total", map[string]string{"route": "/v1/health", "method": "GET"})
+		ObserveDuration("test_duration_seconds", nil, 30*time.Millisecond)
+		SetGaugeFunc("test_gauge", func() float64 { return 42 })
+
+		out := string(render())
+		So(out, ShouldContainSubstring, "# HELP test_requests_total A test counter\n")
+		So(out, ShouldContainSubstring, "# TYPE test_requests_total counter\n")
+		So(out, ShouldContainSubstring, `test_requests_total{method="GET",route="/v1/health"} 2`)
+		So(out, ShouldContainSubstring, `test_duration_seconds_bucket{le="0.025"} 0`)
+		So(out, ShouldContainSubstring, `test_duration_seconds_bucket{le="0.05"} 1`)
+		So(out, ShouldContainSubstring, `test_duration_seconds_bucket{le="+Inf"} 1`)
+		So(out, ShouldContainSubstring, "test_duration_seconds_count 1\n")
+		So(out, ShouldContainSubstring, "test_gauge 42\n")
+	})
+}
+
+func TestStatsd(t *testing.T) {
+	Convey("Metrics should be sent to statsd with labels as tags", t, func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer conn.Close()
+		So(ConfigureStatsd(conn.LocalAddr().String(), "goldfish.", []string{"env:test"}), ShouldBeNil)
+
+		IncrCounter("goldfish_http_requests_total", map[string]string{"route": "/v1/health", "method": "GET"})
+		ObserveDuration("goldfish_vault_request_duration_seconds", nil, 30*time.Millisecond)
+
+		buf := make([]byte, statsdPacketSize)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		So(err, ShouldBeNil)
+		So(string(buf[:n]), ShouldEqual, "goldfish.http_requests_total:1|c|#env:test,method:GET,route:/v1/health\n"+
+			"goldfish.vault_request_duration_milliseconds:30|ms|#env:test")
+	})
+}
+
+func TestVaultConnectionReuse(t *testing.T) {
+	Convey("Requests on a kept-alive connection should be counted as reused", t, func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+		client := &http.Client{Transport: InstrumentTransport(&http.Transport{})}
+
+		for i := 0; i < 2; i++ {
+			resp, err := client.Get(server.URL)
+			So(err, ShouldBeNil)
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+
+		out := string(render())
+		So(out, ShouldContainSubstring, `goldfish_vault_connections_total{reused="false"} 1`)
+		So(out, ShouldContainSubstring, `goldfish_vault_connections_total{reused="true"} 1`)
+	})
+}
@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"bytes"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// keeps each packet within a typical mtu, as the datadog agent expects
+	statsdPacketSize    = 1432
+	statsdFlushInterval = time.Second
+	statsdGaugeInterval = 10 * time.Second
+)
+
+var (
+	statsdConn   net.Conn
+	statsdPrefix string
+	statsdTags   []string
+	statsdLines  chan string
+	statsdLock   = new(sync.RWMutex)
+)
+
+// also sends every metric to a statsd server, using datadog's tag extension for labels
+// names lose their "goldfish_" prefix in favour of prefix, e.g. goldfish.http_requests_total
+func ConfigureStatsd(address, prefix string, tags []string) error {
+	if address == "" {
+		return nil
+	}
+	statsdLock.Lock()
+	defer statsdLock.Unlock()
+	if statsdConn != nil {
+		return nil
+	}
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return err
+	}
+	statsdConn = conn
+	statsdPrefix = prefix
+	statsdTags = tags
+	statsdLines = make(chan string, 1024)
+	go sendStatsd()
+	go sendGauges()
+	return nil
+}
+
+// queues a line such as "goldfish.http_requests_total:1|c|#method:GET,route:/v1/health"
+// lines are dropped rather than blocking requests if the agent can't keep up
+func emitStatsd(name, value, kind string, labels map[string]string) {
+	statsdLock.RLock()
+	defer statsdLock.RUnlock()
+	if statsdConn == nil {
+		return
+	}
+
+	line := statsdPrefix + strings.TrimPrefix(name, "goldfish_") + ":" + value + "|" + kind
+	tags := make([]string, 0, len(statsdTags)+len(labels))
+	tags = append(tags, statsdTags...)
+	for _, k := range sortedKeys(labels) {
+		tags = append(tags, k+":"+statsdEscape(labels[k]))
+	}
+	if len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+
+	select {
+	case statsdLines <- line:
+	default:
+	}
+}
+
+// the separators of the statsd format can't appear in tag values
+func statsdEscape(s string) string {
+	return strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_").Replace(s)
+}
+
+// packs queued lines into as few packets as possible
+func sendStatsd() {
+	var buf bytes.Buffer
+	failing := false
+	flush := func() {
+		if buf.Len() == 0 {
+			return
+		}
+		// only the first of a run of failures is logged, e.g. while the agent is restarting
+		_, err := statsdConn.Write(buf.Bytes())
+		if err != nil && !failing {
+			log.Println("[WARN ]: Could not send metrics to statsd:", err.Error())
+		}
+		failing = err != nil
+		buf.Reset()
+	}
+
+	ticker := time.NewTicker(statsdFlushInterval)
+	for {
+		select {
+		case line := <-statsdLines:
+			if buf.Len() > 0 && buf.Len()+1+len(line) > statsdPacketSize {
+				flush()
+			}
+			if buf.Len() > 0 {
+				buf.WriteByte('\n')
+			}
+			buf.WriteString(line)
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// statsd has no scrapes, so gauges are evaluated and sent periodically instead
+func sendGauges() {
+	for range time.Tick(statsdGaugeInterval) {
+		lock.Lock()
+		names := make([]string, 0, len(gauges))
+		funcs := make(map[string]func() float64, len(gauges))
+		for name, f := range gauges {
+			names = append(names, name)
+			funcs[name] = f
+		}
+		lock.Unlock()
+
+		sort.Strings(names)
+		for _, name := range names {
+			emitStatsd(name, formatFloat(funcs[name]()), "g", nil)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// sends goldfish's notifications, such as scheduled reports, to the notifiers in the config file
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/config"
+	"github.com/caiyeon/goldfish/slack"
+	"github.com/caiyeon/goldfish/vault"
+)
+
+type Message struct {
+	Title string
+	Text  string
+	// what the message is about, so pagerduty and opsgenie raise one alert however often it is sent
+	Key string
+}
+
+const (
+	pagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+	opsgenieURL  = "https://api.opsgenie.com/v2/alerts"
+)
+
+var (
+	notifiers map[string]*config.NotifierConfig
+	lock      = new(sync.RWMutex)
+	client    = &http.Client{Timeout: 10 * time.Second}
+)
+
+// may be called again at runtime, e.g. when the config file is reloaded
+func Configure(n map[string]*config.NotifierConfig) {
+	lock.Lock()
+	defer lock.Unlock()
+	notifiers = n
+}
+
+// sends the message to each named notifier, returning the errors of any that failed
+func Send(names []string, m Message) error {
+	var failed []string
+	for _, name := range names {
+		lock.RLock()
+		n, ok := notifiers[name]
+		lock.RUnlock()
+
+		var err error
+		if !ok {
+			err = errors.New("not configured")
+		} else {
+			err = send(n, m)
+		}
+		if err != nil {
+			failed = append(failed, name+": "+err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return errors.New("Could not notify " + strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func send(n *config.NotifierConfig, m Message) error {
+	switch n.Type {
+	case "slack":
+		return sendSlack(n, m)
+	case "webhook":
+		return sendWebhook(n, m)
+	case "email":
+		return sendEmail(n, m)
+	case "pagerduty":
+		return sendPagerDuty(n, m)
+	case "opsgenie":
+		return sendOpsgenie(n, m)
+	}
+	return errors.New("unknown notifier type " + n.Type)
+}
+
+func sendSlack(n *config.NotifierConfig, m Message) error {
+	url, channel := n.Url, n.Channel
+	if url == "" {
+		conf := vault.GetConfig()
+		if conf.SlackWebhook == "" {
+			return errors.New("no url is configured, and the runtime config has no slack webhook")
+		}
+		url = conf.SlackWebhook
+		if channel == "" {
+			channel = conf.SlackChannel
+		}
+	}
+	return slack.PostMessageWebhook(channel, m.Title, m.Text, url)
+}
+
+func sendWebhook(n *config.NotifierConfig, m Message) error {
+	return postJSON(n.Url, "", map[string]interface{}{
+		"title":     m.Title,
+		"text":      m.Text,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// triggers an event with pagerduty's events api v2. Events with the same key are grouped into one incident
+func sendPagerDuty(n *config.NotifierConfig, m Message) error {
+	key, err := readKey(n.Key_file)
+	if err != nil {
+		return err
+	}
+	url := n.Url
+	if url == "" {
+		url = pagerDutyURL
+	}
+	event := map[string]interface{}{
+		"routing_key":  key,
+		"event_action": "trigger",
+		"payload": map[string]interface{}{
+			"summary":        m.Title,
+			"source":         "goldfish",
+			"severity":       "warning",
+			"custom_details": map[string]interface{}{"text": m.Text},
+		},
+	}
+	if m.Key != "" {
+		event["dedup_key"] = m.Key
+	}
+	return postJSON(url, "", event)
+}
+
+// creates an opsgenie alert. Alerts with the same key are deduplicated while open
+func sendOpsgenie(n *config.NotifierConfig, m Message) error {
+	key, err := readKey(n.Key_file)
+	if err != nil {
+		return err
+	}
+	url := n.Url
+	if url == "" {
+		url = opsgenieURL
+	}
+	alert := map[string]interface{}{
+		// opsgenie truncates messages longer than this
+		"message":     truncate(m.Title, 130),
+		"description": m.Text,
+		"source":      "goldfish",
+	}
+	if m.Key != "" {
+		alert["alias"] = m.Key
+	}
+	return postJSON(url, "GenieKey "+key, alert)
+}
+
+// the key file is read on each send, so it can be rotated in place
+func readKey(path string) (string, error) {
+	key, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(key)), nil
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+func postJSON(url, authorization string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s responded with %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
+
+// the password file is read on each send, so it can be rotated in place
+func sendEmail(n *config.NotifierConfig, m Message) error {
+	var auth smtp.Auth
+	if n.Smtp_username != "" {
+		password, err := ioutil.ReadFile(n.Smtp_password_file)
+		if err != nil {
+			return err
+		}
+		host, _, _ := net.SplitHostPort(n.Smtp_address)
+		auth = smtp.PlainAuth("", n.Smtp_username, strings.TrimSpace(string(password)), host)
+	}
+
+	msg := "From: " + n.From + "\r\n" +
+		"To: " + strings.Join(n.To, ", ") + "\r\n" +
+		"Subject: " + strings.NewReplacer("\r", "", "\n", " ").Replace(m.Title) + "\r\n" +
+		"Date: " + time.Now().Format(time.RFC1123Z) + "\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		strings.Replace(m.Text, "\n", "\r\n", -1) + "\r\n"
+	return smtp.SendMail(n.Smtp_address, auth, n.From, n.To, []byte(msg))
+}
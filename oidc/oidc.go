@@ -0,0 +1,298 @@
+// lets goldfish act as an openid connect relying party, for single sign on through an identity provider
+// the id token it receives is not verified here, but by the vault jwt auth method it is exchanged with
+package oidc
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/config"
+	"golang.org/x/oauth2"
+)
+
+// how long a user has to log in at the provider
+const StateTTL = 10 * time.Minute
+
+// the cookie a login's state is kept in, in the browser that started it
+const CookieName = "goldfish_oidc"
+
+// the parts of the provider's discovery document goldfish uses
+type provider struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+// what a login was started with. It is kept in a signed cookie rather than in goldfish, so any instance
+// can finish the login, only the browser that started it can, and unfinished logins take up no memory
+type loginState struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	Expires  int64  `json:"e"`
+}
+
+var (
+	lock       = new(sync.Mutex)
+	current    *config.OIDCConfig
+	discovered *provider
+
+	httpClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// a nil config turns single sign on off
+// may be called again at runtime, e.g. when the config file is reloaded
+func Configure(c *config.OIDCConfig) {
+	lock.Lock()
+	defer lock.Unlock()
+	current = c
+	discovered = nil
+}
+
+// the current config, or nil if single sign on is off
+func Config() *config.OIDCConfig {
+	lock.Lock()
+	defer lock.Unlock()
+	return current
+}
+
+// the provider's endpoints, fetched once per config
+func discover(c *config.OIDCConfig) (*provider, error) {
+	lock.Lock()
+	p := discovered
+	lock.Unlock()
+	if p != nil {
+		return p, nil
+	}
+
+	resp, err := httpClient.Get(c.Discovery_url + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("Identity provider's discovery document returned " + resp.Status)
+	}
+	p = &provider{}
+	if err := json.NewDecoder(resp.Body).Decode(p); err != nil {
+		return nil, errors.New("Identity provider's discovery document could not be read: " + err.Error())
+	}
+	if p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" {
+		return nil, errors.New("Identity provider's discovery document has no authorization or token endpoint")
+	}
+
+	lock.Lock()
+	if current == c {
+		discovered = p
+	}
+	lock.Unlock()
+	return p, nil
+}
+
+func oauthConfig(c *config.OIDCConfig, p *provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:    c.Client_id,
+		RedirectURL: c.Redirect_url,
+		Scopes:      c.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  p.AuthorizationEndpoint,
+			TokenURL: p.TokenEndpoint,
+		},
+	}
+}
+
+// the provider's login page to send the user to, with a new state, nonce, and pkce challenge,
+// and the cookie to keep them in until the login finishes
+func AuthURL() (string, string, error) {
+	c := Config()
+	if c == nil {
+		return "", "", errors.New("Single sign on is not configured")
+	}
+	p, err := discover(c)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := clientSecret(c)
+	if err != nil {
+		return "", "", err
+	}
+	login := loginState{Expires: time.Now().Add(StateTTL).Unix()}
+	for _, v := range []*string{&login.State, &login.Nonce, &login.Verifier} {
+		if *v, err = randomString(); err != nil {
+			return "", "", err
+		}
+	}
+	cookie, err := sealState(secret, login)
+	if err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(login.Verifier))
+	authURL := oauthConfig(c, p).AuthCodeURL(login.State,
+		oauth2.SetAuthURLParam("nonce", login.Nonce),
+		oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+	return authURL, cookie, nil
+}
+
+// exchanges the code the provider redirected back with for an id token, if the state matches the one
+// in the cookie of the browser that started the login
+// the token comes straight from the provider's token endpoint, so only its nonce is checked here
+func Exchange(cookie, state, code string) (string, error) {
+	c := Config()
+	if c == nil {
+		return "", errors.New("Single sign on is not configured")
+	}
+	secret, err := clientSecret(c)
+	if err != nil {
+		return "", err
+	}
+	login, err := openState(secret, cookie)
+	if err != nil || time.Now().Unix() > login.Expires ||
+		subtle.ConstantTimeCompare([]byte(login.State), []byte(state)) != 1 {
+		return "", errors.New("The login has expired or was started in another browser, please try again")
+	}
+	if code == "" {
+		return "", errors.New("The identity provider returned no code")
+	}
+
+	p, err := discover(c)
+	if err != nil {
+		return "", err
+	}
+	idToken, err := redeem(c, p, secret, code, login.Verifier)
+	if err != nil {
+		return "", err
+	}
+	if nonce, err := tokenNonce(idToken); err != nil || nonce != login.Nonce {
+		return "", errors.New("The identity provider's id token is not for this login")
+	}
+	return idToken, nil
+}
+
+// the secret is read on each login, so it can be rotated without a reload
+func clientSecret(c *config.OIDCConfig) (string, error) {
+	secret, err := ioutil.ReadFile(c.Client_secret_file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(secret)), nil
+}
+
+// the state cookie is signed with a key derived from the client secret, which every instance shares
+// logins started before the secret is rotated have to be started again
+func stateMAC(secret string, payload []byte) []byte {
+	key := hmac.New(sha256.New, []byte(secret))
+	key.Write([]byte("goldfish oidc state"))
+	mac := hmac.New(sha256.New, key.Sum(nil))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func sealState(secret string, login loginState) (string, error) {
+	payload, err := json.Marshal(login)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(stateMAC(secret, payload)), nil
+}
+
+func openState(secret, cookie string) (loginState, error) {
+	var login loginState
+	parts := strings.Split(cookie, ".")
+	if len(parts) != 2 {
+		return login, errors.New("malformed state")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return login, err
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return login, err
+	}
+	if !hmac.Equal(mac, stateMAC(secret, payload)) {
+		return login, errors.New("state was not signed by goldfish")
+	}
+	err = json.Unmarshal(payload, &login)
+	return login, err
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// redeems the code at the provider's token endpoint, along with the pkce verifier
+// the vendored oauth2 can't send extra parameters on the exchange, so the request is made here
+func redeem(c *config.OIDCConfig, p *provider, secret, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.Redirect_url},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequest("POST", p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.Client_id), url.QueryEscape(secret))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken     string `json:"id_token"`
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", errors.New("Identity provider's token response could not be read: " + err.Error())
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return "", errors.New(strings.TrimSpace("Identity provider refused the code: " + body.Error + " " + body.Description))
+	}
+	if body.IDToken == "" {
+		return "", errors.New("The identity provider returned no id token")
+	}
+	return body.IDToken, nil
+}
+
+// reads the nonce claim of a jwt, without verifying its signature
+func tokenNonce(jwt string) (string, error) {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed jwt")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return "", err
+	}
+	var claims struct {
+		Nonce string `json:"nonce"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", err
+	}
+	return claims.Nonce, nil
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/auditlog"
+	"github.com/caiyeon/goldfish/config"
+	"github.com/caiyeon/goldfish/handlers"
+	"github.com/caiyeon/goldfish/notify"
+	"github.com/caiyeon/goldfish/oidc"
+	"github.com/caiyeon/goldfish/report"
+	"github.com/caiyeon/goldfish/request"
+	"github.com/caiyeon/goldfish/snapshot"
+	"github.com/caiyeon/goldfish/vault"
+)
+
+// cert files are checked for changes at most this often, on the next tls handshake
+const certCheckInterval = 10 * time.Second
+
+// holds the listener's certificate, so it can be swapped without a restart
+// the files are reloaded on SIGHUP, or when they change on disk (e.g. rotated by cert-manager)
+type certReloader struct {
+	lock     sync.RWMutex
+	cert     *tls.Certificate
+	certFile string
+	keyFile  string
+	stamp    string
+	checked  time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{}
+	if err := r.Reload(certFile, keyFile); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) Reload(certFile, keyFile string) error {
+	stamp := certStamp(certFile, keyFile)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.cert = &cert
+	r.certFile, r.keyFile = certFile, keyFile
+	r.stamp = stamp
+	r.checked = time.Now()
+	return nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.reloadIfChanged()
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.cert, nil
+}
+
+// a half written pair fails to load, so the current certificate is kept until the next check
+func (r *certReloader) reloadIfChanged() {
+	r.lock.Lock()
+	if time.Since(r.checked) < certCheckInterval {
+		r.lock.Unlock()
+		return
+	}
+	r.checked = time.Now()
+	certFile, keyFile, stamp := r.certFile, r.keyFile, r.stamp
+	r.lock.Unlock()
+
+	if certStamp(certFile, keyFile) == stamp {
+		return
+	}
+	if err := r.Reload(certFile, keyFile); err != nil {
+		log.Println("[ERROR]: Certificate files changed, but could not be reloaded:", err.Error())
+		return
+	}
+	log.Println("[INFO ]: Certificate files changed, reloaded them")
+}
+
+// stat follows symlinks, so kubernetes' atomic secret updates are noticed too
+func certStamp(paths ...string) string {
+	stamp := ""
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			stamp += fmt.Sprintf("%s:%d:%d;", path, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return stamp
+}
+
+// re-reads the config file, applying any settings that can be changed at runtime
+// sessions are unaffected, since the session store is only switched on restart
+// cfg is updated in place, with cfgLock held throughout, so reloads never overlap or race readers
+func reloadConfig() {
+	if devMode {
+		log.Println("[INFO ]: Config reload is not supported in dev mode")
+		return
+	}
+	cfgLock.Lock()
+	defer cfgLock.Unlock()
+
+	newCfg, err := config.LoadConfigFile(cfgPath)
+	if err != nil {
+		log.Println("[ERROR]: Config reload failed, keeping current config:", err.Error())
+		return
+	}
+
+	changes := config.Diff(cfg, newCfg)
+	for _, change := range changes {
+		log.Println("[INFO ]: Config changed:", change)
+	}
+
+	// certificate files are reloaded even if the paths are the same, since they may be rotated in place
+	// listeners are matched up by their order in the config
+	oldListeners, newListeners := cfg.Listeners(), newCfg.Listeners()
+	for i, reloader := range certs {
+		if reloader == nil || i >= len(newListeners) {
+			continue
+		}
+		l := newListeners[i]
+		if err := reloader.Reload(l.Tls_cert_file, l.Tls_key_file); err != nil {
+			log.Println("[ERROR]: Certificate reload failed, keeping current certificate:", err.Error())
+		} else {
+			oldListeners[i].Tls_cert_file = l.Tls_cert_file
+			oldListeners[i].Tls_key_file = l.Tls_key_file
+			log.Println("[INFO ]: Certificate reloaded for", oldListeners[i].Address)
+		}
+	}
+
+	// new vault clients will pick up the new settings
+	vault.SetConfig(newCfg.Vault)
+	vault.SetClusters(newCfg.Clusters)
+	// cached lists may have come from a vault that's no longer configured
+	if !reflect.DeepEqual(newCfg.Vault, cfg.Vault) || !reflect.DeepEqual(newCfg.Clusters, cfg.Clusters) {
+		handlers.SetListCacheTTL(newCfg.Vault.List_cache_ttl)
+	}
+	cfg.Vault = newCfg.Vault
+	cfg.Clusters = newCfg.Clusters
+	vault.SetLogins(newCfg.Logins)
+	cfg.Logins = newCfg.Logins
+
+	// callers start over with full buckets, so only reset them if the limits changed
+	if !reflect.DeepEqual(newCfg.RateLimit, cfg.RateLimit) {
+		handlers.SetRateLimits(newCfg.RateLimit)
+		cfg.RateLimit = newCfg.RateLimit
+	}
+
+	if !reflect.DeepEqual(newCfg.Roles, cfg.Roles) {
+		handlers.SetRoles(newCfg.Roles)
+		cfg.Roles = newCfg.Roles
+	}
+
+	if newCfg.RequireConfirmation != cfg.RequireConfirmation {
+		handlers.SetRequireConfirmation(newCfg.RequireConfirmation)
+		cfg.RequireConfirmation = newCfg.RequireConfirmation
+	}
+
+	if newCfg.RollbackApproval != cfg.RollbackApproval {
+		request.SetRollbackApproval(newCfg.RollbackApproval)
+		cfg.RollbackApproval = newCfg.RollbackApproval
+	}
+
+	if !reflect.DeepEqual(newCfg.PolicyLints, cfg.PolicyLints) {
+		request.SetLintRules(newCfg.PolicyLints)
+		cfg.PolicyLints = newCfg.PolicyLints
+	}
+
+	if !reflect.DeepEqual(newCfg.Notifiers, cfg.Notifiers) {
+		notify.Configure(newCfg.Notifiers)
+		cfg.Notifiers = newCfg.Notifiers
+	}
+
+	if !reflect.DeepEqual(newCfg.AuditSource, cfg.AuditSource) {
+		auditlog.Configure(newCfg.AuditSource)
+		cfg.AuditSource = newCfg.AuditSource
+	}
+
+	if !reflect.DeepEqual(newCfg.OIDC, cfg.OIDC) {
+		oidc.Configure(newCfg.OIDC)
+		cfg.OIDC = newCfg.OIDC
+	}
+
+	if !reflect.DeepEqual(newCfg.RaftSnapshot, cfg.RaftSnapshot) {
+		snapshot.Configure(newCfg.RaftSnapshot)
+		cfg.RaftSnapshot = newCfg.RaftSnapshot
+	}
+
+	// reports are rescheduled from scratch, even if only their notifiers changed
+	if !reflect.DeepEqual(newCfg.Reports, cfg.Reports) {
+		report.Configure(newCfg.Reports)
+		cfg.Reports = newCfg.Reports
+	}
+
+	if !reflect.DeepEqual(newCfg.Branding, cfg.Branding) {
+		handlers.SetBranding(newCfg.Branding)
+		cfg.Branding = newCfg.Branding
+	}
+
+	if !reflect.DeepEqual(newCfg.TokenCreation, cfg.TokenCreation) {
+		handlers.SetTokenCreation(newCfg.TokenCreation)
+		cfg.TokenCreation = newCfg.TokenCreation
+	}
+	if !reflect.DeepEqual(newCfg.ChatOps, cfg.ChatOps) {
+		handlers.SetChatOps(newCfg.ChatOps)
+		cfg.ChatOps = newCfg.ChatOps
+	}
+
+	if newCfg.UpdateCheck != cfg.UpdateCheck {
+		handlers.SetUpdateCheck(newCfg.UpdateCheck)
+		cfg.UpdateCheck = newCfg.UpdateCheck
+	}
+	if newCfg.StatusPage != cfg.StatusPage {
+		handlers.SetStatusPage(newCfg.StatusPage)
+		cfg.StatusPage = newCfg.StatusPage
+	}
+
+	// the flag can't be overridden by the config file
+	if newCfg.ReadOnly != cfg.ReadOnly {
+		handlers.SetReadOnly(newCfg.ReadOnly || readOnlyFlag)
+		cfg.ReadOnly = newCfg.ReadOnly
+	}
+
+	// anything else is bound at startup
+	if listenersChanged(oldListeners, newListeners) ||
+		!reflect.DeepEqual(newCfg.Telemetry, cfg.Telemetry) ||
+		!reflect.DeepEqual(newCfg.Session, cfg.Session) ||
+		newCfg.DisableMlock != cfg.DisableMlock ||
+		newCfg.AirGapped != cfg.AirGapped {
+		log.Println("[WARN ]: Some listener, telemetry, session, mlock, or air-gapped changes require a restart to take effect")
+	}
+
+	if len(changes) == 0 {
+		log.Println("[INFO ]: Config reloaded, no changes found")
+	}
+}
+
+// true if listeners were added or removed, or changed other than by their certificate files
+func listenersChanged(old, new []*config.ListenerConfig) bool {
+	if len(old) != len(new) {
+		return true
+	}
+	for i := range new {
+		listener := *new[i]
+		listener.Tls_cert_file = old[i].Tls_cert_file
+		listener.Tls_key_file = old[i].Tls_key_file
+		if !reflect.DeepEqual(&listener, old[i]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,323 @@
+// runs the scheduled reports in the config file, and sends them to their notifiers
+package report
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/caiyeon/goldfish/config"
+	"github.com/caiyeon/goldfish/notify"
+	"github.com/caiyeon/goldfish/request"
+	"github.com/caiyeon/goldfish/schedule"
+	"github.com/caiyeon/goldfish/vault"
+)
+
+// longer reports are cut short, since they are read in chat and email
+const maxLines = 50
+
+// policies every vault has, which are never reported as unused
+var builtinPolicies = map[string]bool{
+	"root":              true,
+	"default":           true,
+	"response-wrapping": true,
+}
+
+// schedules the reports, replacing any scheduled before
+// may be called again at runtime, e.g. when the config file is reloaded
+func Configure(reports map[string]*config.ReportConfig) {
+	var jobs []schedule.Job
+	for _, r := range reports {
+		// the config file has already been validated
+		s, err := schedule.Parse(r.Schedule)
+		if err != nil {
+			log.Printf("[ERROR]: Report %q not scheduled: %s\n", r.Name, err.Error())
+			continue
+		}
+		r := r
+		jobs = append(jobs, schedule.Job{
+			Name:     r.Name,
+			Schedule: s,
+			Run:      func() error { return Run(r) },
+		})
+	}
+	schedule.Replace("report", jobs)
+	if len(jobs) > 0 {
+		log.Printf("[INFO ]: %d reports scheduled\n", len(jobs))
+	}
+}
+
+// the scheduled reports, and when they last ran
+func Status() []schedule.Status {
+	return schedule.List("report")
+}
+
+// builds the report with goldfish's own token, so goldfish's policy must allow what the report reads
+// empty reports are not sent
+func Run(r *config.ReportConfig) error {
+	if !vault.Bootstrapped() {
+		return errors.New("goldfish is not bootstrapped")
+	}
+
+	var (
+		title string
+		lines []string
+		err   error
+	)
+	switch r.Type {
+	case "expiring_tokens":
+		title = fmt.Sprintf("Tokens expiring within %s", r.Window)
+		lines, err = expiringTokens(r.Window)
+	case "expiring_certs":
+		title = fmt.Sprintf("Certificates expiring within %s", r.Window)
+		lines, err = expiringCerts(r.Pki_mounts, r.Window)
+	case "pending_requests":
+		title = "Requests waiting for approval"
+		lines, err = pendingRequests()
+	case "unused_policies":
+		title = "Policies not held by any token"
+		lines, err = unusedPolicies()
+	case "stale_requests":
+		return escalateStaleRequests(r)
+	case "untagged_secrets":
+		title = "Secrets without an owner or classification"
+		lines, err = untaggedSecrets(r.Paths)
+	case "overdue_rotations":
+		title = "Secrets due for rotation"
+		lines, err = overdueRotations()
+	default:
+		return fmt.Errorf("unknown report type %s", r.Type)
+	}
+	if err != nil || len(lines) == 0 {
+		return err
+	}
+
+	return notify.Send(r.Notify, notify.Message{
+		Title: fmt.Sprintf("[goldfish] %s (%d)", title, len(lines)),
+		Text:  truncate(lines),
+	})
+}
+
+func truncate(lines []string) string {
+	if len(lines) > maxLines {
+		lines = append(lines[:maxLines:maxLines], fmt.Sprintf("...and %d more", len(lines)-maxLines))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// calls fn with the details of every token
+func eachToken(fn func(data map[string]interface{})) error {
+	auth := vault.GoldfishAuth()
+	accessors, err := auth.GetTokenAccessors()
+	if err != nil {
+		return err
+	}
+	return auth.EachTokenByAccessor(accessors, func(data map[string]interface{}) error {
+		fn(data)
+		return nil
+	})
+}
+
+func expiringTokens(window time.Duration) ([]string, error) {
+	type token struct {
+		name     string
+		accessor string
+		ttl      time.Duration
+	}
+	var expiring []token
+	err := eachToken(func(data map[string]interface{}) {
+		// tokens without a ttl never expire
+		ttl := seconds(data["ttl"])
+		if ttl <= 0 || ttl > window {
+			return
+		}
+		t := token{ttl: ttl}
+		t.name, _ = data["display_name"].(string)
+		t.accessor, _ = data["accessor"].(string)
+		expiring = append(expiring, t)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(expiring, func(i, j int) bool { return expiring[i].ttl < expiring[j].ttl })
+	lines := make([]string, len(expiring))
+	for i, t := range expiring {
+		lines[i] = fmt.Sprintf("%s (accessor %s) expires in %s", t.name, t.accessor, t.ttl)
+	}
+	return lines, nil
+}
+
+func expiringCerts(mounts []string, window time.Duration) ([]string, error) {
+	type cert struct {
+		mount    string
+		serial   string
+		name     string
+		notAfter time.Time
+	}
+	var expiring []cert
+	deadline := time.Now().Add(window)
+	auth := vault.GoldfishAuth()
+	for _, mount := range mounts {
+		err := auth.EachCertificate(mount, func(serial string, c *x509.Certificate) error {
+			// certificates that have already expired are left to vault's tidy
+			if c.NotAfter.After(deadline) || c.NotAfter.Before(time.Now()) {
+				return nil
+			}
+			expiring = append(expiring, cert{mount, serial, c.Subject.CommonName, c.NotAfter})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(expiring, func(i, j int) bool { return expiring[i].notAfter.Before(expiring[j].notAfter) })
+	lines := make([]string, len(expiring))
+	for i, c := range expiring {
+		lines[i] = fmt.Sprintf("%s in %s (serial %s) expires at %s", c.name, c.mount, c.serial, c.notAfter.UTC().Format(time.RFC3339))
+	}
+	return lines, nil
+}
+
+func pendingRequests() ([]string, error) {
+	pending, err := request.ListPending()
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, len(pending))
+	for i, p := range pending {
+		lines[i] = fmt.Sprintf("%s request %s by %s has %d of %d approvals", p.Type, p.Hash, p.Requester, p.Approvals, p.Required)
+	}
+	sort.Strings(lines)
+	return lines, nil
+}
+
+// each stale request is sent on its own, keyed by its hash, so pagerduty and opsgenie alert once per request
+// however many times the report runs while it waits
+func escalateStaleRequests(r *config.ReportConfig) error {
+	pending, err := request.ListPending()
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, p := range pending {
+		// requests from before creation times were recorded can't be aged
+		if p.Created == 0 {
+			continue
+		}
+		age := time.Since(time.Unix(p.Created, 0))
+		if age < r.Window {
+			continue
+		}
+		age = age.Truncate(time.Minute)
+
+		lines := []string{
+			fmt.Sprintf("Request: %s", p.Hash),
+			fmt.Sprintf("Type: %s", p.Type),
+			fmt.Sprintf("Requester: %s", p.Requester),
+		}
+		if p.PolicyName != "" {
+			lines = append(lines, fmt.Sprintf("Policy: %s", p.PolicyName))
+		}
+		if p.CommitHash != "" {
+			lines = append(lines, fmt.Sprintf("Commit: %s", p.CommitHash))
+		}
+		lines = append(lines,
+			fmt.Sprintf("Waiting: %s", age),
+			fmt.Sprintf("Approvals: %d of %d", p.Approvals, p.Required),
+		)
+		if p.Plan != "" {
+			lines = append(lines, "", p.Plan)
+		}
+
+		err := notify.Send(r.Notify, notify.Message{
+			Title: fmt.Sprintf("[goldfish] %s request by %s has waited %s for approval", p.Type, p.Requester, age),
+			Text:  strings.Join(lines, "\n"),
+			Key:   "goldfish-request-" + p.Hash,
+		})
+		if err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func unusedPolicies() ([]string, error) {
+	policies, err := vault.GoldfishAuth().ListPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	held := make(map[string]bool)
+	err = eachToken(func(data map[string]interface{}) {
+		raw, _ := data["policies"].([]interface{})
+		for _, p := range raw {
+			if name, ok := p.(string); ok {
+				held[name] = true
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, p := range policies {
+		if !held[p] && !builtinPolicies[p] {
+			lines = append(lines, p)
+		}
+	}
+	sort.Strings(lines)
+	return lines, nil
+}
+
+func untaggedSecrets(paths []string) ([]string, error) {
+	var lines []string
+	for _, path := range paths {
+		untagged, err := vault.GoldfishAuth().UntaggedSecrets(path)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, untagged...)
+	}
+	return lines, nil
+}
+
+// secrets whose age can't be found are listed too, since their rotation can't be checked
+func overdueRotations() ([]string, error) {
+	rotations, err := vault.GoldfishAuth().Rotations("")
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, r := range rotations {
+		owner := r.Owner
+		if owner == "" {
+			owner = "no owner"
+		}
+		switch {
+		case r.Error != "":
+			lines = append(lines, fmt.Sprintf("%s (%s) can not be checked: %s", r.Path, owner, r.Error))
+		case r.Overdue:
+			lines = append(lines, fmt.Sprintf("%s (%s) was due for rotation at %s, every %s", r.Path, owner,
+				r.Due.UTC().Format(time.RFC3339), r.RotationPeriod))
+		}
+	}
+	return lines, nil
+}
+
+func seconds(v interface{}) time.Duration {
+	n, _ := v.(json.Number)
+	s, _ := n.Int64()
+	return time.Duration(s) * time.Second
+}
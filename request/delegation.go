@@ -0,0 +1,88 @@
+package request
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/caiyeon/goldfish/vault"
+)
+
+// the display name approvals are recorded under, like a request's requester
+func approverName(auth *vault.AuthInfo) string {
+	if self, err := auth.LookupSelf(); err == nil && self != nil {
+		if name, _ := self.Data["display_name"].(string); name != "" {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// the entities already approved for by delegation are kept with the request's unseals,
+// so they are purged along with them when the request completes, is rejected, or is reset
+func delegatedApprovals(hash string) ([]string, error) {
+	resp, err := vault.ReadFromCubbyhole("unseal_wrapping_tokens/" + hash)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	raw, _ := resp.Data["delegated"].(string)
+	if raw == "" {
+		return nil, nil
+	}
+	return strings.Split(raw, ";"), nil
+}
+
+// a delegate may approve for a user only while the delegation lasts, and only once per request
+// the approval still takes the delegate's own unseal key, so it never adds to the quorum
+// both users are identified by their identity entity. Returns the delegator's
+func checkDelegation(auth *vault.AuthInfo, hash, onBehalfOf string) (vault.Identity, error) {
+	approver, err := auth.SelfIdentity()
+	if err != nil {
+		return vault.Identity{}, err
+	}
+	delegator, err := vault.LookupIdentity(onBehalfOf)
+	if err != nil {
+		return vault.Identity{}, err
+	}
+	if delegator.EntityID == approver.EntityID {
+		return vault.Identity{}, errors.New("Approvals can not be given on behalf of oneself")
+	}
+	active, err := vault.DelegationActive(delegator.EntityID, approver.EntityID, time.Now())
+	if err != nil {
+		return vault.Identity{}, err
+	}
+	if !active {
+		return vault.Identity{}, errors.New(delegator.Name + " has not delegated their approvals to you at this time")
+	}
+
+	delegated, err := delegatedApprovals(hash)
+	if err != nil {
+		return vault.Identity{}, err
+	}
+	for _, d := range delegated {
+		if d == delegator.EntityID {
+			return vault.Identity{}, errors.New("This request has already been approved on behalf of " + delegator.Name)
+		}
+	}
+	return delegator, nil
+}
+
+func recordDelegatedApproval(hash string, onBehalfOf vault.Identity, complete bool) {
+	// completed requests have nothing left to approve
+	if complete {
+		return
+	}
+	resp, err := vault.ReadFromCubbyhole("unseal_wrapping_tokens/" + hash)
+	if err == nil && resp != nil {
+		delegated, _ := resp.Data["delegated"].(string)
+		if delegated != "" {
+			delegated += ";"
+		}
+		resp.Data["delegated"] = delegated + onBehalfOf.EntityID
+		_, err = vault.WriteToCubbyhole("unseal_wrapping_tokens/"+hash, resp.Data)
+	}
+	if err != nil {
+		log.Printf("[WARN ]: Could not record approval of %s on behalf of %s: %s\n", hash, onBehalfOf.Name, err.Error())
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"strings"
 	"reflect"
+	"sort"
+	"time"
 
 	"github.com/caiyeon/goldfish/github"
 	"github.com/caiyeon/goldfish/vault"
@@ -21,6 +23,8 @@ type GithubRequest struct {
 	RequesterHash string
 	Required      int
 	Progress      int `hash:"ignore"`
+	// unix time the request was made, to measure how long approval takes
+	Created int64 `hash:"ignore"`
 }
 
 type PolicyDiff struct {
@@ -63,6 +67,7 @@ func CreateGithubRequest(auth *vault.AuthInfo, raw map[string]interface{}) (*Git
 		return nil, err
 	}
 	r.Required = status.Required
+	r.Created = time.Now().Unix()
 	r.Progress = 0
 
 	// fetch changes from github
@@ -129,6 +134,22 @@ func CreateGithubRequest(auth *vault.AuthInfo, raw map[string]interface{}) (*Git
 		return nil, errors.New("No changes detected")
 	}
 
+	// refuse policies breaking lint rules before they reach approvers, as with single policy requests
+	names := make([]string, 0, len(r.Changes))
+	for name := range r.Changes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		violations, err := LintPolicy(name, r.Changes[name].Proposed)
+		if err != nil {
+			return nil, errors.New(name + ": " + err.Error())
+		}
+		if err := lintError(violations); err != nil {
+			return nil, errors.New(name + ": " + err.Error())
+		}
+	}
+
 	return r, nil
 }
 
@@ -189,7 +210,7 @@ func (r *GithubRequest) Approve(hash string, unsealKey string) error {
 	}
 
 	// if there aren't enough unseals yet, update progress
-	if r.Required > len(wrappingTokens) {
+	if vault.ApprovalsNeeded(r.Required) > len(wrappingTokens) {
 		r.Progress = len(wrappingTokens)
 		_, err = vault.WriteToCubbyhole("requests/"+hash, structs.Map(r))
 		return err
@@ -227,7 +248,13 @@ func (r *GithubRequest) Approve(hash string, unsealKey string) error {
 	for name, diff := range r.Changes {
 		if err := rootAuth.PutPolicy(name, diff.Proposed); err != nil {
 			multierr = multierror.Append(multierr, err)
+			continue
+		}
+		applied, err := rootAuth.GetPolicy(name)
+		if err != nil {
+			applied = diff.Proposed
 		}
+		saveSnapshot(name, diff.Previous, applied, r.CommitHash, r.Requester)
 	}
 	return multierr
 }
@@ -0,0 +1,212 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/caiyeon/goldfish/config"
+
+	vaultcore "github.com/hashicorp/vault/vault"
+)
+
+var (
+	lintRules     map[string]*config.PolicyLintConfig
+	lintRulesLock = new(sync.RWMutex)
+)
+
+// may be called again at runtime, e.g. when the config file is reloaded
+func SetLintRules(rules map[string]*config.PolicyLintConfig) {
+	lintRulesLock.Lock()
+	defer lintRulesLock.Unlock()
+	lintRules = rules
+}
+
+// a lint rule a proposed policy breaks. Action is block or warn, as configured
+type LintViolation struct {
+	Rule    string `json:"rule"`
+	Action  string `json:"action"`
+	Message string `json:"message"`
+}
+
+// checks a proposed policy against the configured lint rules, sorted by rule name
+// deleting a policy breaks no rules, since it can only take access away
+func LintPolicy(name, rules string) ([]LintViolation, error) {
+	lintRulesLock.RLock()
+	defer lintRulesLock.RUnlock()
+
+	violations := make([]LintViolation, 0)
+	if len(lintRules) == 0 || strings.TrimSpace(rules) == "" {
+		return violations, nil
+	}
+	policy, err := vaultcore.Parse(rules)
+	if err != nil {
+		return nil, errors.New("Policy can not be parsed: " + err.Error())
+	}
+
+	names := make([]string, 0, len(lintRules))
+	for n := range lintRules {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		l := lintRules[n]
+		var problems []string
+		switch l.Type {
+		case "forbid_capabilities":
+			problems = forbiddenCapabilities(policy, l)
+		case "require_deny":
+			problems = missingDenies(policy, l)
+		case "policy_name":
+			// the pattern was compiled when the config was parsed
+			if !regexp.MustCompile(l.Pattern).MatchString(name) {
+				problems = append(problems, fmt.Sprintf("policy name %q does not match %s", name, l.Pattern))
+			}
+		}
+		for _, p := range problems {
+			if l.Message != "" {
+				p += ". " + l.Message
+			}
+			violations = append(violations, LintViolation{
+				Rule:    n,
+				Action:  l.Action,
+				Message: p,
+			})
+		}
+	}
+	return violations, nil
+}
+
+// refuses a request if any violation blocks it
+func lintError(violations []LintViolation) error {
+	var blocking []string
+	for _, v := range violations {
+		if v.Action == "block" {
+			blocking = append(blocking, v.Rule+": "+v.Message)
+		}
+	}
+	if len(blocking) == 0 {
+		return nil
+	}
+	return errors.New("Policy breaks lint rules: " + strings.Join(blocking, "; "))
+}
+
+// a path of a lint rule, in the form vault parses policy paths into
+type lintPath struct {
+	prefix string
+	glob   bool
+}
+
+func parseLintPath(path string) lintPath {
+	path = strings.TrimPrefix(path, "/")
+	if strings.HasSuffix(path, "*") {
+		return lintPath{prefix: strings.TrimSuffix(path, "*"), glob: true}
+	}
+	return lintPath{prefix: path}
+}
+
+func (l lintPath) String() string {
+	if l.glob {
+		return l.prefix + "*"
+	}
+	return l.prefix
+}
+
+// whether some path is matched by both the lint path and the policy's rule
+func (l lintPath) overlaps(pc *vaultcore.PathCapabilities) bool {
+	switch {
+	case l.glob && pc.Glob:
+		return strings.HasPrefix(l.prefix, pc.Prefix) || strings.HasPrefix(pc.Prefix, l.prefix)
+	case l.glob:
+		return strings.HasPrefix(pc.Prefix, l.prefix)
+	case pc.Glob:
+		return strings.HasPrefix(l.prefix, pc.Prefix)
+	}
+	return l.prefix == pc.Prefix
+}
+
+// whether every path the policy's rule matches is matched by the lint path
+func (l lintPath) contains(pc *vaultcore.PathCapabilities) bool {
+	if l.glob {
+		return strings.HasPrefix(pc.Prefix, l.prefix)
+	}
+	return !pc.Glob && l.prefix == pc.Prefix
+}
+
+func ruleString(pc *vaultcore.PathCapabilities) string {
+	return lintPath{prefix: pc.Prefix, glob: pc.Glob}.String()
+}
+
+func denies(pc *vaultcore.PathCapabilities) bool {
+	for _, c := range pc.Capabilities {
+		if c == "deny" {
+			return true
+		}
+	}
+	return false
+}
+
+// rules granting any of the lint's capabilities, or any capability if it lists none, on its paths
+func forbiddenCapabilities(policy *vaultcore.Policy, l *config.PolicyLintConfig) []string {
+	var problems []string
+	for _, path := range l.Paths {
+		lp := parseLintPath(path)
+		for _, pc := range policy.Paths {
+			if denies(pc) || !lp.overlaps(pc) {
+				continue
+			}
+			var granted []string
+			for _, c := range pc.Capabilities {
+				if len(l.Capabilities) == 0 || isListed(l.Capabilities, c) {
+					granted = append(granted, c)
+				}
+			}
+			if len(granted) > 0 {
+				problems = append(problems, fmt.Sprintf("path %q grants %s, which is forbidden on %s",
+					ruleString(pc), strings.Join(granted, ", "), lp))
+			}
+		}
+	}
+	return problems
+}
+
+// rules granting anything on the lint's paths. A broader glob may grant around them only if the
+// policy denies the lint's path itself, which vault's acl then prefers as the more specific rule
+func missingDenies(policy *vaultcore.Policy, l *config.PolicyLintConfig) []string {
+	var problems []string
+	for _, path := range l.Paths {
+		lp := parseLintPath(path)
+		denied := false
+		for _, pc := range policy.Paths {
+			if denies(pc) && pc.Prefix == lp.prefix && pc.Glob == lp.glob {
+				denied = true
+			}
+		}
+		for _, pc := range policy.Paths {
+			if denies(pc) || len(pc.Capabilities) == 0 || !lp.overlaps(pc) {
+				continue
+			}
+			if lp.contains(pc) {
+				problems = append(problems, fmt.Sprintf("path %q grants %s inside %s, which must be denied",
+					ruleString(pc), strings.Join(pc.Capabilities, ", "), lp))
+			} else if !denied {
+				problems = append(problems, fmt.Sprintf("path %q reaches %s, which must be denied with its own rule",
+					ruleString(pc), lp))
+			}
+		}
+	}
+	return problems
+}
+
+func isListed(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package request
+
+import (
+	"strings"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/mitchellh/mapstructure"
+)
+
+// a request that is waiting for approvals, as shown in scheduled reports
+type Pending struct {
+	Hash      string
+	Type      string
+	Requester string
+	Required  int
+	Approvals int
+	Created   int64
+	// what the request changes, for policy and github requests
+	PolicyName string
+	CommitHash string
+	// what approving the request would do, empty if it can't be worked out
+	Plan string
+}
+
+// lists every request in goldfish's cubbyhole. Unlike Get, this does not verify them
+func ListPending() ([]Pending, error) {
+	hashes, err := vault.ListCubbyhole("requests/")
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Pending
+	for _, hash := range hashes {
+		resp, err := vault.ReadFromCubbyhole("requests/" + hash)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil {
+			continue
+		}
+		var p Pending
+		if err := mapstructure.WeakDecode(resp.Data, &p); err != nil {
+			return nil, err
+		}
+		// the hash is not in the stored request, and approvals are kept separately
+		p.Hash = hash
+		if resp, err := vault.ReadFromCubbyhole("unseal_wrapping_tokens/" + hash); err == nil && resp != nil {
+			if raw, _ := resp.Data["wrapping_tokens"].(string); raw != "" {
+				p.Approvals = len(strings.Split(raw, ";"))
+			}
+		}
+		if plan, err := planFromData(resp.Data); err == nil {
+			p.Plan = plan.String()
+		}
+		pending = append(pending, p)
+	}
+	return pending, nil
+}
+
+// counts the requests in goldfish's cubbyhole without reading them
+func PendingCount() (int, error) {
+	hashes, err := vault.ListCubbyhole("requests/")
+	return len(hashes), err
+}
@@ -0,0 +1,258 @@
+package request
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+
+	vaultcore "github.com/hashicorp/vault/vault"
+)
+
+// what a change request would do if approved, for approvers to review instead of the raw request
+type Plan struct {
+	Resources []PlanResource `json:"resources"`
+	Create    int            `json:"create"`
+	Change    int            `json:"change"`
+	Destroy   int            `json:"destroy"`
+}
+
+// Action is one of create, update or delete. Before is empty for a create, and After for a delete
+type PlanResource struct {
+	Action       string             `json:"action"`
+	Type         string             `json:"type"`
+	Name         string             `json:"name"`
+	Before       string             `json:"before"`
+	After        string             `json:"after"`
+	Capabilities []CapabilityChange `json:"capabilities,omitempty"`
+}
+
+// the capabilities a policy change grants or revokes on a path. Paths ending in '*' are globs
+type CapabilityChange struct {
+	Path    string   `json:"path"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// builds the plan of a request, which needs no vault access, so it can be shown to anyone who may see the request
+func NewPlan(req Request) (*Plan, error) {
+	p := &Plan{}
+	switch r := req.(type) {
+	case *PolicyRequest:
+		if err := p.addPolicy(r.PolicyName, r.Previous, r.Proposed); err != nil {
+			return nil, err
+		}
+
+	case *GithubRequest:
+		names := make([]string, 0, len(r.Changes))
+		for name := range r.Changes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := p.addPolicy(name, r.Changes[name].Previous, r.Changes[name].Proposed); err != nil {
+				return nil, err
+			}
+		}
+
+	case *TokenRequest:
+		p.add(PlanResource{
+			Action: "create",
+			Type:   "token",
+			Name:   r.Role,
+			After:  tokenPlan(r),
+		})
+
+	default:
+		return nil, errors.New("Unsupported request type")
+	}
+	return p, nil
+}
+
+// the plan of a request as stored in goldfish's cubbyhole, without verifying it
+func planFromData(data map[string]interface{}) (*Plan, error) {
+	t, _ := data["Type"].(string)
+	var req Request
+	switch strings.ToLower(t) {
+	case "policy":
+		req = &PolicyRequest{}
+	case "github":
+		req = &GithubRequest{}
+	case "token":
+		req = &TokenRequest{}
+	default:
+		return nil, errors.New("Invalid request type: " + t)
+	}
+	if err := mapstructure.Decode(data, req); err != nil {
+		return nil, err
+	}
+	return NewPlan(req)
+}
+
+func (p *Plan) add(r PlanResource) {
+	switch r.Action {
+	case "create":
+		p.Create++
+	case "update":
+		p.Change++
+	case "delete":
+		p.Destroy++
+	}
+	p.Resources = append(p.Resources, r)
+}
+
+// an empty policy is one that doesn't exist, as in policy requests
+func (p *Plan) addPolicy(name, before, after string) error {
+	if before == after {
+		return nil
+	}
+	action := "update"
+	if before == "" {
+		action = "create"
+	} else if after == "" {
+		action = "delete"
+	}
+
+	beforeCaps, err := policyCapabilities(before)
+	if err != nil {
+		return fmt.Errorf("Current policy %s can not be parsed: %s", name, err.Error())
+	}
+	afterCaps, err := policyCapabilities(after)
+	if err != nil {
+		return fmt.Errorf("Proposed policy %s can not be parsed: %s", name, err.Error())
+	}
+
+	p.add(PlanResource{
+		Action:       action,
+		Type:         "policy",
+		Name:         name,
+		Before:       before,
+		After:        after,
+		Capabilities: capabilityChanges(beforeCaps, afterCaps),
+	})
+	return nil
+}
+
+// the capabilities a policy grants, by path. The old policy = "write" style is expanded as vault does
+func policyCapabilities(rules string) (map[string]map[string]bool, error) {
+	caps := make(map[string]map[string]bool)
+	if strings.TrimSpace(rules) == "" {
+		return caps, nil
+	}
+	policy, err := vaultcore.Parse(rules)
+	if err != nil {
+		return nil, err
+	}
+	for _, pc := range policy.Paths {
+		path := pc.Prefix
+		if pc.Glob {
+			path += "*"
+		}
+		if caps[path] == nil {
+			caps[path] = make(map[string]bool)
+		}
+		for _, c := range pc.Capabilities {
+			caps[path][c] = true
+		}
+	}
+	return caps, nil
+}
+
+func capabilityChanges(before, after map[string]map[string]bool) []CapabilityChange {
+	paths := make(map[string]bool)
+	for path := range before {
+		paths[path] = true
+	}
+	for path := range after {
+		paths[path] = true
+	}
+
+	var changes []CapabilityChange
+	for path := range paths {
+		change := CapabilityChange{Path: path}
+		for c := range after[path] {
+			if !before[path][c] {
+				change.Added = append(change.Added, c)
+			}
+		}
+		for c := range before[path] {
+			if !after[path][c] {
+				change.Removed = append(change.Removed, c)
+			}
+		}
+		if len(change.Added) > 0 || len(change.Removed) > 0 {
+			sort.Strings(change.Added)
+			sort.Strings(change.Removed)
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+func tokenPlan(r *TokenRequest) string {
+	var lines []string
+	if r.Role != "" {
+		lines = append(lines, "role = "+r.Role)
+	}
+	if c := r.CreateRequest; c != nil {
+		if len(c.Policies) > 0 {
+			lines = append(lines, "policies = "+strings.Join(c.Policies, ", "))
+		}
+		if c.TTL != "" {
+			lines = append(lines, "ttl = "+c.TTL)
+		}
+		if c.ExplicitMaxTTL != "" {
+			lines = append(lines, "explicit_max_ttl = "+c.ExplicitMaxTTL)
+		}
+		if c.Period != "" {
+			lines = append(lines, "period = "+c.Period)
+		}
+		if c.NumUses > 0 {
+			lines = append(lines, fmt.Sprintf("num_uses = %d", c.NumUses))
+		}
+		if c.NoDefaultPolicy {
+			lines = append(lines, "no_default_policy = true")
+		}
+	}
+	if r.Orphan != "" {
+		lines = append(lines, "orphan = "+r.Orphan)
+	}
+	if r.Wrap_ttl != "" {
+		lines = append(lines, "wrap_ttl = "+r.Wrap_ttl)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renders the plan as terraform does, a line per resource and per path whose capabilities change
+func (p *Plan) String() string {
+	symbols := map[string]string{"create": "+", "update": "~", "delete": "-"}
+
+	var b bytes.Buffer
+	for _, r := range p.Resources {
+		fmt.Fprintf(&b, "%s %s %q\n", symbols[r.Action], r.Type, r.Name)
+		for _, c := range r.Capabilities {
+			switch {
+			case len(c.Removed) == 0:
+				fmt.Fprintf(&b, "    + path %q: %s\n", c.Path, strings.Join(c.Added, ", "))
+			case len(c.Added) == 0:
+				fmt.Fprintf(&b, "    - path %q: %s\n", c.Path, strings.Join(c.Removed, ", "))
+			default:
+				fmt.Fprintf(&b, "    ~ path %q: +%s -%s\n", c.Path,
+					strings.Join(c.Added, " +"), strings.Join(c.Removed, " -"))
+			}
+		}
+		if r.Type == "token" {
+			for _, line := range strings.Split(r.After, "\n") {
+				if line != "" {
+					fmt.Fprintf(&b, "    + %s\n", line)
+				}
+			}
+		}
+	}
+	fmt.Fprintf(&b, "Plan: %d to create, %d to change, %d to destroy.", p.Create, p.Change, p.Destroy)
+	return b.String()
+}
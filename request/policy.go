@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/caiyeon/goldfish/vault"
 	"github.com/fatih/structs"
@@ -21,6 +22,13 @@ type PolicyRequest struct {
 	RequesterHash string
 	Required      int
 	Progress      int `hash:"ignore"`
+	// unix time the request was made, to measure how long approval takes
+	Created int64 `hash:"ignore"`
+	// unix times of the window an approved change is applied in, and of its automatic rollback, if set
+	// they are fixed when the request is made and are part of its hash, so approvals are for this window only
+	ApplyAt    int64
+	ApplyUntil int64
+	RollbackAt int64
 }
 
 func (r PolicyRequest) IsRootOnly() bool {
@@ -75,12 +83,25 @@ func CreatePolicyRequest(auth *vault.AuthInfo, raw map[string]interface{}) (*Pol
 		return nil, "", errors.New("Request contains no changes to policy")
 	}
 
+	// refuse policies breaking lint rules before they reach approvers
+	violations, err := LintPolicy(r.PolicyName, r.Proposed)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := lintError(violations); err != nil {
+		return nil, "", err
+	}
+
 	// collect vault sys info
 	status, err := vault.GenerateRootStatus()
 	if err != nil {
 		return nil, "", err
 	}
 	r.Required = status.Required
+	r.Created = time.Now().Unix()
+	if err := r.parseWindow(raw); err != nil {
+		return nil, "", err
+	}
 	r.Progress = 0
 
 	// calculate hash
@@ -134,19 +155,30 @@ func (r *PolicyRequest) Approve(hash string, unsealKey string) error {
 		return errors.New("Unseal key cannot be empty")
 	}
 
-	// append unseal key to cubbyhole
-	wrappingTokens, err := appendUnseal(hash, unsealKey)
+	if r.ApplyUntil != 0 && time.Now().Unix() > r.ApplyUntil {
+		return errors.New("The window of this change has passed")
+	}
+	if r.ApplyAt > time.Now().Unix() && r.Progress >= vault.ApprovalsNeeded(r.Required) {
+		return errors.New("This change is already approved, and waits for its window")
+	}
+
+	// append unseal key to cubbyhole, kept wrapped until the change's window if it has one
+	wrappingTokens, err := appendUnsealTTL(hash, unsealKey, unsealTTL(r.ApplyAt))
 	if err != nil {
 		return err
 	}
 
-	// if there aren't enough unseals yet, update progress
-	if r.Required > len(wrappingTokens) {
+	// if there aren't enough unseals yet, or the change waits for its window, update progress
+	if vault.ApprovalsNeeded(r.Required) > len(wrappingTokens) || r.ApplyAt > time.Now().Unix() {
 		r.Progress = len(wrappingTokens)
 		_, err = vault.WriteToCubbyhole("requests/"+hash, structs.Map(r))
 		return err
 	}
+	return r.apply(hash, wrappingTokens)
+}
 
+// generates a root token from the approvals' unseals, and makes the change with it
+func (r *PolicyRequest) apply(hash string, wrappingTokens []string) error {
 	// prepare cleanup
 	r.Progress = 0
 	defer vault.DeleteFromCubbyhole("unseal_wrapping_tokens/" + hash)
@@ -177,6 +209,7 @@ func (r *PolicyRequest) Approve(hash string, unsealKey string) error {
 	defer rootAuth.RevokeSelf()
 
 	// make requested change
+	previous := r.Previous
 	if r.Proposed == "" {
 		// if the request was to delete the policy
 		if err := rootAuth.DeletePolicy(r.PolicyName); err != nil {
@@ -196,6 +229,12 @@ func (r *PolicyRequest) Approve(hash string, unsealKey string) error {
 		}
 	}
 
+	saveSnapshot(r.PolicyName, previous, r.Previous, hash, r.Requester)
+
+	// the unseals are not kept for the rollback, which is proposed as a new request when it is due
+	if r.RollbackAt > 0 {
+		return scheduleRollback(hash, r.PolicyName, previous, r.Previous, r.Requester, r.RollbackAt)
+	}
 	return nil
 }
 
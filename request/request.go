@@ -183,6 +183,12 @@ func Get(auth *vault.AuthInfo, hash string) (Request, error) {
 // if unseal is nonempty string, approve request with current auth
 // otherwise, add unseal to list of unseals to generate root token later
 func Approve(auth *vault.AuthInfo, hash string, unseal string) (Request, error) {
+	return ApproveOnBehalf(auth, hash, unseal, "")
+}
+
+// approves a request as Approve does. If onBehalfOf is not empty, the approval is given for that user,
+// who must have delegated their approvals to the current user
+func ApproveOnBehalf(auth *vault.AuthInfo, hash, unseal, onBehalfOf string) (Request, error) {
 	// lock hash in map before writing to vault cubbyhole
 	lockMap.Lock()
 	defer lockMap.Unlock()
@@ -201,6 +207,15 @@ func Approve(auth *vault.AuthInfo, hash string, unseal string) (Request, error)
 		return nil, errors.New("Request ID not found")
 	}
 
+	// both identities are recorded when approving on someone's behalf
+	approver := approverName(auth)
+	var delegator vault.Identity
+	if onBehalfOf != "" {
+		if delegator, err = checkDelegation(auth, hash, onBehalfOf); err != nil {
+			return nil, err
+		}
+	}
+
 	// decode secret to a request
 	t := ""
 	if typeRaw, ok := resp.Data["Type"]; !ok {
@@ -233,6 +248,7 @@ func Approve(auth *vault.AuthInfo, hash string, unseal string) (Request, error)
 		if err := req.Approve(hash, unseal); err != nil {
 			return nil, err
 		}
+		recordApproval(approver, delegator, hash, t, req.Created)
 		return &req, nil
 
 	case "github":
@@ -248,6 +264,7 @@ func Approve(auth *vault.AuthInfo, hash string, unseal string) (Request, error)
 		if err := req.Approve(hash, unseal); err != nil {
 			return nil, err
 		}
+		recordApproval(approver, delegator, req.CommitHash, t, req.Created)
 		return &req, nil
 
 	case "token":
@@ -263,6 +280,7 @@ func Approve(auth *vault.AuthInfo, hash string, unseal string) (Request, error)
 		if err := req.Approve(hash, unseal); err != nil {
 			return nil, err
 		}
+		recordApproval(approver, delegator, hash, t, req.Created)
 		return &req, nil
 
 	default:
@@ -272,6 +290,14 @@ func Approve(auth *vault.AuthInfo, hash string, unseal string) (Request, error)
 
 // deletes request, if user is authorized to read resource
 func Reject(auth *vault.AuthInfo, hash string) error {
+	if err := reject(auth, hash); err != nil {
+		return err
+	}
+	forgetApprovals(hash)
+	return nil
+}
+
+func reject(auth *vault.AuthInfo, hash string) error {
 	// lock hash in map before writing to vault cubbyhole
 	lockMap.Lock()
 	defer lockMap.Unlock()
@@ -370,7 +396,11 @@ func generateRootToken(unsealKeys []string) (string, error) {
 	}
 
 	if status.EncodedRootToken == "" {
+		// approvals beyond vault's threshold aren't needed to generate the token
 		for _, s := range unsealKeys {
+			if status.Complete {
+				break
+			}
 			status, err = vault.GenerateRootUpdate(s, status.Nonce)
 			// an error likely means one of the unseals was not valid
 			if err != nil {
@@ -403,6 +433,11 @@ func generateRootToken(unsealKeys []string) (string, error) {
 
 // writes the provided unseal in and returns a slice of all unseals in hash
 func appendUnseal(hash, unseal string) ([]string, error) {
+	return appendUnsealTTL(hash, unseal, "60m")
+}
+
+// as appendUnseal, with the unseal wrapped for ttl instead
+func appendUnsealTTL(hash, unseal, ttl string) ([]string, error) {
 	// read current request from cubbyhole
 	resp, err := vault.ReadFromCubbyhole("unseal_wrapping_tokens/" + hash)
 	if err != nil {
@@ -410,9 +445,14 @@ func appendUnseal(hash, unseal string) ([]string, error) {
 	}
 
 	var wrappingTokens []string
+	// other fields, e.g. delegated approvals, are kept as they are
+	data := make(map[string]interface{})
 
 	// if there are already unseals, read them and append
 	if resp != nil {
+		for k, v := range resp.Data {
+			data[k] = v
+		}
 		raw := ""
 		if temp, ok := resp.Data["wrapping_tokens"]; ok {
 			raw, _ = temp.(string)
@@ -424,7 +464,7 @@ func appendUnseal(hash, unseal string) ([]string, error) {
 	}
 
 	// wrap the unseal token
-	newWrappingToken, err := vault.WrapData("60m", map[string]interface{}{
+	newWrappingToken, err := vault.WrapData(ttl, map[string]interface{}{
 		"unseal_token": unseal,
 	})
 	if err != nil {
@@ -435,11 +475,8 @@ func appendUnseal(hash, unseal string) ([]string, error) {
 	wrappingTokens = append(wrappingTokens, newWrappingToken)
 
 	// write the unseals back to the cubbyhole
-	_, err = vault.WriteToCubbyhole("unseal_wrapping_tokens/"+hash,
-		map[string]interface{}{
-			"wrapping_tokens": strings.Trim(strings.Join(strings.Fields(fmt.Sprint(wrappingTokens)), ";"), "[]"),
-		},
-	)
+	data["wrapping_tokens"] = strings.Trim(strings.Join(strings.Fields(fmt.Sprint(wrappingTokens)), ";"), "[]")
+	_, err = vault.WriteToCubbyhole("unseal_wrapping_tokens/"+hash, data)
 	return wrappingTokens, err
 }
 
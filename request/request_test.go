@@ -204,3 +204,82 @@ func TestRequestSystem(t *testing.T) {
 		})
 	})
 }
+
+func TestPlan(t *testing.T) {
+	Convey("Plans should show capability changes", t, func() {
+		plan, err := NewPlan(&PolicyRequest{
+			PolicyName: "dev",
+			Previous:   `path "secret/dev/*" { capabilities = ["read", "list"] } path "secret/old" { policy = "read" }`,
+			Proposed:   `path "secret/dev/*" { capabilities = ["read", "update"] } path "secret/new" { capabilities = ["create"] }`,
+		})
+		So(err, ShouldBeNil)
+		So(plan.Change, ShouldEqual, 1)
+		So(plan.Resources[0].Capabilities, ShouldResemble, []CapabilityChange{
+			{Path: "secret/dev/*", Added: []string{"update"}, Removed: []string{"list"}},
+			{Path: "secret/new", Added: []string{"create"}},
+			{Path: "secret/old", Removed: []string{"list", "read"}},
+		})
+		So(plan.String(), ShouldEqual, `~ policy "dev"
+    ~ path "secret/dev/*": +update -list
+    + path "secret/new": create
+    - path "secret/old": list, read
+Plan: 0 to create, 1 to change, 0 to destroy.`)
+	})
+
+	Convey("Plans should count created and deleted policies", t, func() {
+		plan, err := NewPlan(&GithubRequest{Changes: map[string]PolicyDiff{
+			"a": {Proposed: `path "secret/a" { capabilities = ["read"] }`},
+			"b": {Previous: `path "secret/b" { capabilities = ["read"] }`},
+		}})
+		So(err, ShouldBeNil)
+		So(plan.Create, ShouldEqual, 1)
+		So(plan.Destroy, ShouldEqual, 1)
+		So(plan.Resources[0].Name, ShouldEqual, "a")
+	})
+}
+
+func TestLintPolicy(t *testing.T) {
+	SetLintRules(map[string]*config.PolicyLintConfig{
+		"no-sys-sudo": {Type: "forbid_capabilities", Paths: []string{"sys/*"}, Capabilities: []string{"sudo"}, Action: "block"},
+		"admin":       {Type: "require_deny", Paths: []string{"secret/admin/*"}, Action: "block"},
+		"naming":      {Type: "policy_name", Pattern: "^team-[a-z]+$", Action: "warn", Message: "see the wiki"},
+	})
+	defer SetLintRules(nil)
+
+	Convey("Policies breaking no rules should pass", t, func() {
+		violations, err := LintPolicy("team-dev", `
+			path "secret/dev/*" { capabilities = ["read"] }
+			path "sys/policy/*" { capabilities = ["read", "list"] }`)
+		So(err, ShouldBeNil)
+		So(violations, ShouldBeEmpty)
+
+		violations, err = LintPolicy("team-dev", `
+			path "secret/*" { capabilities = ["read"] }
+			path "secret/admin/*" { capabilities = ["deny"] }`)
+		So(err, ShouldBeNil)
+		So(violations, ShouldBeEmpty)
+	})
+
+	Convey("Policies breaking rules should be reported by rule", t, func() {
+		violations, err := LintPolicy("dev", `
+			path "*" { capabilities = ["read", "sudo"] }
+			path "secret/admin/keys" { capabilities = ["read"] }`)
+		So(err, ShouldBeNil)
+		So(len(violations), ShouldEqual, 4)
+		So(violations[0].Rule, ShouldEqual, "admin")
+		So(violations[2], ShouldResemble, LintViolation{
+			Rule:    "naming",
+			Action:  "warn",
+			Message: `policy name "dev" does not match ^team-[a-z]+$. see the wiki`,
+		})
+		So(violations[3].Message, ShouldEqual, `path "*" grants sudo, which is forbidden on sys/*`)
+		So(lintError(violations), ShouldNotBeNil)
+		So(lintError(violations[2:3]), ShouldBeNil)
+	})
+
+	Convey("Deleting a policy should break no rules", t, func() {
+		violations, err := LintPolicy("dev", "")
+		So(err, ShouldBeNil)
+		So(violations, ShouldBeEmpty)
+	})
+}
@@ -0,0 +1,151 @@
+package request
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/fatih/structs"
+	"github.com/hashicorp/go-uuid"
+	"github.com/mitchellh/mapstructure"
+)
+
+// the version of a policy an approved change replaced, so the change can be undone
+// Previous is empty if the change created the policy, and Applied is empty if it deleted it
+type Snapshot struct {
+	ID         string `json:"id"`
+	PolicyName string `json:"policy_name"`
+	Previous   string `json:"previous"`
+	Applied    string `json:"applied"`
+	Request    string `json:"request"`
+	Requester  string `json:"requester"`
+	Created    int64  `json:"created"`
+}
+
+var (
+	rollbackApproval     bool
+	rollbackApprovalLock = new(sync.RWMutex)
+)
+
+// may be called again at runtime, e.g. when the config file is reloaded
+func SetRollbackApproval(on bool) {
+	rollbackApprovalLock.Lock()
+	defer rollbackApprovalLock.Unlock()
+	rollbackApproval = on
+}
+
+func snapshotKey(id string) string {
+	return "policy_snapshots/" + id
+}
+
+// a failure to snapshot is logged rather than returned, since the change itself has been made
+func saveSnapshot(policyName, previous, applied, hash, requester string) {
+	id, err := uuid.GenerateUUID()
+	if err == nil {
+		err = vault.WriteState(snapshotKey(id), structs.Map(Snapshot{
+			ID:         id,
+			PolicyName: policyName,
+			Previous:   previous,
+			Applied:    applied,
+			Request:    hash,
+			Requester:  requester,
+			Created:    time.Now().Unix(),
+		}))
+	}
+	if err != nil {
+		log.Printf("[ERROR]: Could not snapshot policy %s before change %s: %s\n", policyName, hash, err.Error())
+	}
+}
+
+func getSnapshot(id string) (*Snapshot, error) {
+	resp, err := vault.ReadState(snapshotKey(id))
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	var s Snapshot
+	if err := mapstructure.WeakDecode(resp.Data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// the snapshots of a policy, newest first. The user must be able to read the policy
+func ListSnapshots(auth *vault.AuthInfo, policyName string) ([]Snapshot, error) {
+	if _, err := auth.GetPolicy(policyName); err != nil {
+		return nil, err
+	}
+	ids, err := vault.ListState("policy_snapshots/")
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]Snapshot, 0)
+	for _, id := range ids {
+		s, err := getSnapshot(id)
+		if err != nil {
+			return nil, err
+		}
+		if s != nil && s.PolicyName == policyName {
+			snapshots = append(snapshots, *s)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Created > snapshots[j].Created })
+	return snapshots, nil
+}
+
+// restores the policy a snapshot was taken of, if it is still as the change left it
+// with rollback approval configured, this makes a change request for the rollback and returns its hash.
+// Otherwise the user's own token must be allowed to write the policy, and the hash is empty
+func RollbackPolicy(auth *vault.AuthInfo, id string) (string, error) {
+	s, err := getSnapshot(id)
+	if err != nil {
+		return "", err
+	}
+	if s == nil {
+		return "", errors.New("Snapshot not found")
+	}
+
+	current, err := auth.GetPolicy(s.PolicyName)
+	if err != nil {
+		return "", err
+	}
+	if current != s.Applied {
+		return "", errors.New("Policy has been changed since, rolling back would undo the later change too")
+	}
+
+	rollbackApprovalLock.RLock()
+	needsApproval := rollbackApproval
+	rollbackApprovalLock.RUnlock()
+	if needsApproval {
+		return Add(auth, map[string]interface{}{
+			"type":       "policy",
+			"policyname": s.PolicyName,
+			"rules":      s.Previous,
+		})
+	}
+
+	// a change that created the policy is rolled back by deleting it
+	method := "PUT"
+	if s.Previous == "" {
+		method = "DELETE"
+	}
+	if err := auth.RawPreflight(method, "sys/policy/"+s.PolicyName); err != nil {
+		return "", err
+	}
+	if s.Previous == "" {
+		err = auth.DeletePolicy(s.PolicyName)
+	} else {
+		err = auth.PutPolicy(s.PolicyName, s.Previous)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// the snapshot is spent, the policy is as it was before
+	vault.DeleteState(snapshotKey(id))
+	log.Printf("[INFO ]: Policy %s rolled back to before change %s by %s\n", s.PolicyName, s.Request, approverName(auth))
+	return "", nil
+}
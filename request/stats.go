@@ -0,0 +1,236 @@
+package request
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/metrics"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/fatih/structs"
+	"github.com/mitchellh/mapstructure"
+)
+
+// completed requests kept for the stats, the oldest are dropped first
+const maxApprovalTimes = 1000
+
+// how long the median approval time for metrics is reused before the records are read again
+const medianCacheTTL = time.Minute
+
+// each request's approvals are kept with it, at requests/<hash> on the state path,
+// so the stats survive restarts and are the same from every goldfish instance
+// unlike the request in goldfish's cubbyhole, the record is kept once the request completes
+type approvalRecord struct {
+	Type      string
+	Created   int64
+	Completed int64
+	Approvers []string
+}
+
+var (
+	statsLock    = new(sync.Mutex)
+	median       time.Duration
+	medianExpiry time.Time
+)
+
+type Stats struct {
+	Since                 time.Time      `json:"since"`
+	Pending               int            `json:"pending"`
+	OldestPendingSeconds  float64        `json:"oldest_pending_seconds"`
+	Completed             map[string]int `json:"completed"`
+	MedianApprovalSeconds float64        `json:"median_approval_seconds"`
+	Approvers             map[string]int `json:"approvers"`
+}
+
+// records an approval, and the time taken if it completed the request
+// completed requests are removed from goldfish's cubbyhole once applied, which is how they are told apart
+// onBehalfOf has no entity unless the approval was delegated
+func recordApproval(approver string, onBehalfOf vault.Identity, hash, t string, created int64) {
+	resp, err := vault.ReadFromCubbyhole("requests/" + hash)
+	complete := err == nil && resp == nil
+
+	if onBehalfOf.EntityID != "" {
+		log.Printf("[INFO ]: Request %s approved by %s on behalf of %s\n", hash, approver, onBehalfOf.Name)
+		recordDelegatedApproval(hash, onBehalfOf, complete)
+	} else {
+		log.Printf("[INFO ]: Request %s approved by %s\n", hash, approver)
+	}
+
+	t = strings.ToLower(t)
+	metrics.IncrCounter("goldfish_request_approvals_total", map[string]string{"approver": approver})
+	if complete {
+		metrics.IncrCounter("goldfish_requests_completed_total", map[string]string{"type": t})
+	}
+
+	statsLock.Lock()
+	defer statsLock.Unlock()
+	record, err := readApprovalRecord(hash)
+	if err == nil {
+		record.Type = t
+		record.Created = created
+		record.Approvers = append(record.Approvers, approver)
+		if complete {
+			record.Completed = time.Now().Unix()
+			medianExpiry = time.Time{}
+		}
+		err = vault.WriteState("requests/"+hash, structs.Map(record))
+	}
+	if err != nil {
+		log.Printf("[WARN ]: Could not record approval of %s in the request stats: %s\n", hash, err.Error())
+		return
+	}
+	if complete {
+		if err := pruneApprovalRecords(); err != nil {
+			log.Println("[WARN ]: Could not prune the request stats:", err.Error())
+		}
+	}
+}
+
+// stops keeping the approvals of a request that will not complete
+func forgetApprovals(hash string) {
+	statsLock.Lock()
+	defer statsLock.Unlock()
+	if err := vault.DeleteState("requests/" + hash); err != nil {
+		log.Printf("[WARN ]: Could not remove %s from the request stats: %s\n", hash, err.Error())
+	}
+}
+
+func readApprovalRecord(hash string) (approvalRecord, error) {
+	var r approvalRecord
+	resp, err := vault.ReadState("requests/" + hash)
+	if err != nil || resp == nil {
+		return r, err
+	}
+	err = mapstructure.WeakDecode(resp.Data, &r)
+	return r, err
+}
+
+// every request's approval record, by hash
+func readApprovalRecords() (map[string]approvalRecord, error) {
+	hashes, err := vault.ListState("requests/")
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string]approvalRecord, len(hashes))
+	for _, hash := range hashes {
+		r, err := readApprovalRecord(hash)
+		if err != nil {
+			return nil, err
+		}
+		records[hash] = r
+	}
+	return records, nil
+}
+
+// the hashes of completed requests, most recently completed first
+func completedHashes(records map[string]approvalRecord) []string {
+	hashes := []string{}
+	for hash, r := range records {
+		if r.Completed > 0 {
+			hashes = append(hashes, hash)
+		}
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return records[hashes[i]].Completed > records[hashes[j]].Completed
+	})
+	return hashes
+}
+
+// must be called with statsLock held
+func pruneApprovalRecords() error {
+	records, err := readApprovalRecords()
+	if err != nil {
+		return err
+	}
+	hashes := completedHashes(records)
+	for len(hashes) > maxApprovalTimes {
+		if err := vault.DeleteState("requests/" + hashes[len(hashes)-1]); err != nil {
+			return err
+		}
+		hashes = hashes[:len(hashes)-1]
+	}
+	return nil
+}
+
+// the median time to approval of recently completed requests, or 0 if it can not be read
+// read again at most once a minute, as metrics are scraped often
+func MedianApprovalTime() time.Duration {
+	statsLock.Lock()
+	defer statsLock.Unlock()
+	if time.Now().Before(medianExpiry) {
+		return median
+	}
+	if !vault.Bootstrapped() {
+		return 0
+	}
+	records, err := readApprovalRecords()
+	if err != nil {
+		return 0
+	}
+	median, medianExpiry = medianApproval(records), time.Now().Add(medianCacheTTL)
+	return median
+}
+
+func medianApproval(records map[string]approvalRecord) time.Duration {
+	times := []time.Duration{}
+	for _, hash := range completedHashes(records) {
+		if r := records[hash]; r.Created > 0 {
+			times = append(times, time.Unix(r.Completed, 0).Sub(time.Unix(r.Created, 0)))
+		}
+	}
+	if len(times) == 0 {
+		return 0
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	mid := len(times) / 2
+	if len(times)%2 == 0 {
+		return (times[mid-1] + times[mid]) / 2
+	}
+	return times[mid]
+}
+
+// the approval stats, with the requests currently waiting read from goldfish's cubbyhole
+// requests made before goldfish recorded creation times are counted, but not timed
+func GetStats() (*Stats, error) {
+	pending, err := ListPending()
+	if err != nil {
+		return nil, err
+	}
+	records, err := readApprovalRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Stats{
+		Pending:   len(pending),
+		Completed: make(map[string]int),
+		Approvers: make(map[string]int),
+	}
+	now := time.Now()
+	for _, p := range pending {
+		if p.Created > 0 {
+			if age := now.Sub(time.Unix(p.Created, 0)).Seconds(); age > s.OldestPendingSeconds {
+				s.OldestPendingSeconds = age
+			}
+		}
+	}
+	for _, r := range records {
+		if r.Completed > 0 {
+			s.Completed[r.Type]++
+		}
+		for _, a := range r.Approvers {
+			s.Approvers[a]++
+		}
+		first := r.Created
+		if first == 0 {
+			first = r.Completed
+		}
+		if first > 0 && (s.Since.IsZero() || time.Unix(first, 0).Before(s.Since)) {
+			s.Since = time.Unix(first, 0).UTC()
+		}
+	}
+	s.MedianApprovalSeconds = medianApproval(records).Seconds()
+	return s, nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/caiyeon/goldfish/vault"
 	"github.com/fatih/structs"
@@ -24,6 +25,8 @@ type TokenRequest struct {
 	RequesterHash  string
 	Required       int
 	Progress       int `hash:"ignore"`
+	// unix time the request was made, to measure how long approval takes
+	Created int64 `hash:"ignore"`
 }
 
 func (r TokenRequest) IsRootOnly() bool {
@@ -102,6 +105,7 @@ func CreateTokenRequest(auth *vault.AuthInfo, raw map[string]interface{}) (*Toke
 		return nil, "", err
 	}
 	r.Required = status.Required
+	r.Created = time.Now().Unix()
 	r.Progress = 0
 
 	// calculate hash
@@ -155,7 +159,7 @@ func (r *TokenRequest) Approve(hash string, unsealKey string) error {
 	}
 
 	// if there aren't enough unseals yet, update progress
-	if r.Required > len(wrappingTokens) {
+	if vault.ApprovalsNeeded(r.Required) > len(wrappingTokens) {
 		r.Progress = len(wrappingTokens)
 		_, err = vault.WriteToCubbyhole("requests/"+hash, structs.Map(r))
 		return err
@@ -0,0 +1,268 @@
+package request
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caiyeon/goldfish/schedule"
+	"github.com/caiyeon/goldfish/vault"
+	"github.com/fatih/structs"
+	"github.com/mitchellh/hashstructure"
+	"github.com/mitchellh/mapstructure"
+)
+
+// unseals are kept wrapped until a change's window, and vault caps wrapping ttls at its max ttl
+const maxWindowAhead = 30 * 24 * time.Hour
+
+// the scheduled job runs every minute, so a lease of two outlives one missed run
+const changeJobLease = 2 * time.Minute
+
+// a change to roll back. No unseals are kept for it: at the rollback time it becomes a new change request,
+// which needs a fresh quorum of approvals
+type rollback struct {
+	PolicyName string
+	Previous   string
+	Applied    string
+	RollbackAt int64
+	Requester  string
+}
+
+// reads the optional apply_at, apply_until and rollback_at of a policy request, as rfc3339 times
+func (r *PolicyRequest) parseWindow(raw map[string]interface{}) error {
+	times := []struct {
+		key   string
+		field *int64
+	}{
+		{"apply_at", &r.ApplyAt},
+		{"apply_until", &r.ApplyUntil},
+		{"rollback_at", &r.RollbackAt},
+	}
+	for _, t := range times {
+		v, _ := raw[t.key].(string)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return fmt.Errorf("'%s' must be a time, e.g. 2018-01-02T15:04:05Z", t.key)
+		}
+		*t.field = parsed.Unix()
+	}
+
+	now := time.Now()
+	start := now.Unix()
+	if r.ApplyAt != 0 {
+		if r.ApplyAt <= start {
+			return errors.New("'apply_at' must be in the future")
+		}
+		start = r.ApplyAt
+	}
+	if time.Unix(start, 0).Sub(now) > maxWindowAhead {
+		return fmt.Errorf("'apply_at' can be at most %s ahead", maxWindowAhead)
+	}
+	if r.ApplyUntil != 0 && (r.ApplyAt == 0 || r.ApplyUntil <= r.ApplyAt) {
+		return errors.New("'apply_until' must be after 'apply_at'")
+	}
+	if r.RollbackAt != 0 {
+		if r.RollbackAt <= start || (r.ApplyUntil != 0 && r.RollbackAt <= r.ApplyUntil) {
+			return errors.New("'rollback_at' must be after the change is applied")
+		}
+		if time.Unix(r.RollbackAt, 0).Sub(time.Unix(start, 0)) > maxWindowAhead {
+			return fmt.Errorf("'rollback_at' can be at most %s after the change is applied", maxWindowAhead)
+		}
+	}
+	return nil
+}
+
+// how long an approval's unseal is kept wrapped: an hour, or until an hour after the change's window opens
+func unsealTTL(until int64) string {
+	ttl := time.Hour
+	if wait := time.Until(time.Unix(until, 0)); wait > 0 {
+		ttl += wait
+	}
+	return fmt.Sprintf("%ds", int64(ttl.Seconds()))
+}
+
+func scheduleRollback(hash, policyName, previous, applied, requester string, at int64) error {
+	_, err := vault.WriteToCubbyhole("rollbacks/"+hash, structs.Map(rollback{
+		PolicyName: policyName,
+		Previous:   previous,
+		Applied:    applied,
+		RollbackAt: at,
+		Requester:  requester,
+	}))
+	if err == nil {
+		log.Printf("[INFO ]: Change %s to policy %s will be proposed for rollback at %s\n",
+			hash, policyName, time.Unix(at, 0).UTC().Format(time.RFC3339))
+	}
+	return err
+}
+
+// checks every minute for approved changes whose window has opened, and for changes due to be rolled back
+// only one goldfish instance runs the checks, so a change is never applied twice
+// may be called again at runtime, but needs no config
+func ScheduleChanges() {
+	s, _ := schedule.Parse("@every 1m")
+	schedule.Replace("change", []schedule.Job{{
+		Name:     "scheduled changes",
+		Schedule: s,
+		Run: func() error {
+			return vault.RunExclusive("changes", changeJobLease, ApplyScheduled)
+		},
+	}})
+}
+
+func ApplyScheduled() error {
+	if !vault.Bootstrapped() {
+		return nil
+	}
+
+	var failed []string
+	hashes, err := vault.ListCubbyhole("requests/")
+	if err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if err := applyIfDue(hash); err != nil {
+			failed = append(failed, hash+": "+err.Error())
+		}
+	}
+
+	hashes, err = vault.ListCubbyhole("rollbacks/")
+	if err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if err := rollbackIfDue(hash); err != nil {
+			failed = append(failed, "rollback of "+hash+": "+err.Error())
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func applyIfDue(hash string) error {
+	// lock hash in map before writing to vault cubbyhole
+	lockMap.Lock()
+	defer lockMap.Unlock()
+	if _, locked := lockHash[hash]; locked {
+		return nil
+	}
+	lockHash[hash] = true
+	defer delete(lockHash, hash)
+
+	resp, err := vault.ReadFromCubbyhole("requests/" + hash)
+	if err != nil || resp == nil {
+		return err
+	}
+	if t, _ := resp.Data["Type"].(string); t != "policy" {
+		return nil
+	}
+	var req PolicyRequest
+	if err := mapstructure.Decode(resp.Data, &req); err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	if req.ApplyAt == 0 || req.ApplyAt > now {
+		return nil
+	}
+
+	unseals, err := vault.ReadFromCubbyhole("unseal_wrapping_tokens/" + hash)
+	if err != nil || unseals == nil {
+		return err
+	}
+	raw, _ := unseals.Data["wrapping_tokens"].(string)
+	wrappingTokens := strings.Split(raw, ";")
+	if raw == "" || len(wrappingTokens) < vault.ApprovalsNeeded(req.Required) {
+		return nil
+	}
+
+	// approvals are void once the window has passed, or if the change no longer applies
+	voidApprovals := func(reason string) error {
+		vault.DeleteFromCubbyhole("unseal_wrapping_tokens/" + hash)
+		req.Progress = 0
+		vault.WriteToCubbyhole("requests/"+hash, structs.Map(req))
+		return errors.New(reason + ", so its approvals were discarded")
+	}
+	if req.ApplyUntil != 0 && now > req.ApplyUntil {
+		return voidApprovals("the change's window passed before it could be applied")
+	}
+	hash_uint64, err := hashstructure.Hash(req, nil)
+	if err != nil || strconv.FormatUint(hash_uint64, 16) != hash {
+		return errors.New("Hashes do not match")
+	}
+	auth := vault.GoldfishAuth()
+	if err := req.Verify(&auth); err != nil {
+		return voidApprovals(err.Error())
+	}
+
+	if err := req.apply(hash, wrappingTokens); err != nil {
+		return err
+	}
+	log.Printf("[INFO ]: Scheduled change %s to policy %s applied\n", hash, req.PolicyName)
+	return nil
+}
+
+// proposes restoring the policy as a new change request, which approvers must approve like any other
+// a policy that was changed again since is left as it is, since rolling back would undo the later change too
+func rollbackIfDue(hash string) error {
+	resp, err := vault.ReadFromCubbyhole("rollbacks/" + hash)
+	if err != nil || resp == nil {
+		return err
+	}
+	var r rollback
+	if err := mapstructure.WeakDecode(resp.Data, &r); err != nil {
+		return err
+	}
+	if r.RollbackAt > time.Now().Unix() {
+		return nil
+	}
+
+	// the rollback is kept until it is proposed, so errors reading from vault are retried on the next run
+	auth := vault.GoldfishAuth()
+	current, err := auth.GetPolicy(r.PolicyName)
+	if err != nil {
+		return err
+	}
+	if current != r.Applied {
+		vault.DeleteFromCubbyhole("rollbacks/" + hash)
+		return errors.New("policy " + r.PolicyName + " was changed again since, so its rollback was not proposed")
+	}
+
+	status, err := vault.GenerateRootStatus()
+	if err != nil {
+		return err
+	}
+	req := &PolicyRequest{
+		Type:          "policy",
+		PolicyName:    r.PolicyName,
+		Previous:      r.Applied,
+		Proposed:      r.Previous,
+		Requester:     r.Requester,
+		RequesterHash: fmt.Sprintf("%x", sha256.Sum256([]byte(r.Requester))),
+		Required:      status.Required,
+		Created:       time.Now().Unix(),
+	}
+	hash_uint64, err := hashstructure.Hash(req, nil)
+	if err != nil {
+		return err
+	}
+	rollbackHash := strconv.FormatUint(hash_uint64, 16)
+
+	lockMap.Lock()
+	defer lockMap.Unlock()
+	if _, err := vault.WriteToCubbyhole("requests/"+rollbackHash, structs.Map(req)); err != nil {
+		return err
+	}
+	vault.DeleteFromCubbyhole("rollbacks/" + hash)
+	log.Printf("[INFO ]: Rollback of change %s to policy %s proposed as request %s\n", hash, r.PolicyName, rollbackHash)
+	return nil
+}
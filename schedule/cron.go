@@ -0,0 +1,168 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// a parsed schedule, either cron fields or a fixed interval
+// cron schedules use the standard five fields: minute, hour, day of month, month, day of week (0 is sunday)
+type Schedule struct {
+	spec   string
+	every  time.Duration
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	// cron runs a job when either day field matches, if both are restricted
+	domAny bool
+	dowAny bool
+}
+
+var macros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// accepts "m h dom mon dow", a macro such as "@daily", or "@every 6h"
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	s := Schedule{spec: spec}
+
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return s, err
+		}
+		if d < time.Minute {
+			return s, errors.New("@every must be at least 1m")
+		}
+		s.every = d
+		return s, nil
+	}
+	if m, ok := macros[spec]; ok {
+		spec = m
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return s, fmt.Errorf("expected 5 fields, a macro like @daily, or @every <duration>, got %q", spec)
+	}
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return s, fmt.Errorf("minute: %s", err.Error())
+	}
+	if s.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return s, fmt.Errorf("hour: %s", err.Error())
+	}
+	if s.dom, err = parseField(fields[2], 1, 31); err != nil {
+		return s, fmt.Errorf("day of month: %s", err.Error())
+	}
+	if s.month, err = parseField(fields[3], 1, 12); err != nil {
+		return s, fmt.Errorf("month: %s", err.Error())
+	}
+	if s.dow, err = parseField(fields[4], 0, 7); err != nil {
+		return s, fmt.Errorf("day of week: %s", err.Error())
+	}
+	// 7 is also sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domAny = strings.HasPrefix(fields[2], "*")
+	s.dowAny = strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// a field is a comma separated list of "*", "n", or "a-b", each optionally followed by "/step"
+func parseField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil || lo > hi {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for n := lo; n <= hi; n += step {
+			bits |= 1 << uint(n)
+		}
+	}
+	return bits, nil
+}
+
+func (s Schedule) String() string {
+	return s.spec
+}
+
+// the first time after t that the schedule runs, in t's location
+// a schedule that can never run, e.g. "0 0 31 2 *", returns the zero time
+func (s Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
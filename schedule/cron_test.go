@@ -0,0 +1,54 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSchedule(t *testing.T) {
+	// a monday
+	start := time.Date(2018, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	Convey("Cron schedules should run at the next matching minute", t, func() {
+		s, err := Parse("0 9 * * 1-5")
+		So(err, ShouldBeNil)
+		So(s.Next(start), ShouldResemble, time.Date(2018, 1, 2, 9, 0, 0, 0, time.UTC))
+
+		s, err = Parse("*/15 * * * *")
+		So(err, ShouldBeNil)
+		So(s.Next(start), ShouldResemble, time.Date(2018, 1, 1, 10, 45, 0, 0, time.UTC))
+
+		s, err = Parse("@monthly")
+		So(err, ShouldBeNil)
+		So(s.Next(start), ShouldResemble, time.Date(2018, 2, 1, 0, 0, 0, 0, time.UTC))
+
+		// either day field may match once both are restricted
+		s, err = Parse("0 0 15 * 0")
+		So(err, ShouldBeNil)
+		So(s.Next(start), ShouldResemble, time.Date(2018, 1, 7, 0, 0, 0, 0, time.UTC))
+	})
+
+	Convey("Interval schedules should run after the interval", t, func() {
+		s, err := Parse("@every 6h")
+		So(err, ShouldBeNil)
+		So(s.Next(start), ShouldResemble, start.Add(6*time.Hour))
+
+		_, err = Parse("@every 1s")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Impossible schedules should never run", t, func() {
+		s, err := Parse("0 0 31 2 *")
+		So(err, ShouldBeNil)
+		So(s.Next(start).IsZero(), ShouldBeTrue)
+	})
+
+	Convey("Invalid schedules should be rejected", t, func() {
+		for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "@yearly"} {
+			_, err := Parse(spec)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
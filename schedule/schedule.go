@@ -0,0 +1,106 @@
+// runs goldfish's background jobs, such as reports, on cron-like schedules
+package schedule
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Job struct {
+	Name     string
+	Schedule Schedule
+	Run      func() error
+}
+
+// what the status endpoint shows about a job
+type Status struct {
+	Name      string    `json:"name"`
+	Schedule  string    `json:"schedule"`
+	Next      time.Time `json:"next"`
+	LastRun   time.Time `json:"last_run,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+type running struct {
+	job    Job
+	stop   chan struct{}
+	status Status
+}
+
+// jobs are kept in groups, e.g. "report", so each subsystem can replace its own jobs on a config reload
+var (
+	groups = make(map[string][]*running)
+	lock   = new(sync.Mutex)
+)
+
+// stops the group's current jobs and starts the given ones
+// a job that is running when it is replaced still finishes
+func Replace(group string, jobs []Job) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	for _, r := range groups[group] {
+		close(r.stop)
+	}
+	groups[group] = nil
+	for _, job := range jobs {
+		r := &running{
+			job:  job,
+			stop: make(chan struct{}),
+			status: Status{
+				Name:     job.Name,
+				Schedule: job.Schedule.String(),
+			},
+		}
+		groups[group] = append(groups[group], r)
+		go r.loop(group)
+	}
+}
+
+func (r *running) loop(group string) {
+	for {
+		next := r.job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("[WARN ]: Scheduled %s %q will never run\n", group, r.job.Name)
+			return
+		}
+		lock.Lock()
+		r.status.Next = next
+		lock.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-r.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		err := r.job.Run()
+		lock.Lock()
+		r.status.LastRun = time.Now()
+		r.status.LastError = ""
+		if err != nil {
+			r.status.LastError = err.Error()
+		}
+		lock.Unlock()
+		if err != nil {
+			log.Printf("[ERROR]: Scheduled %s %q failed: %s\n", group, r.job.Name, err.Error())
+		}
+	}
+}
+
+// the group's jobs, sorted by name
+func List(group string) []Status {
+	lock.Lock()
+	defer lock.Unlock()
+
+	statuses := make([]Status, 0, len(groups[group]))
+	for _, r := range groups[group] {
+		statuses = append(statuses, r.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/caiyeon/goldfish/systemd"
+	"github.com/caiyeon/goldfish/vault"
+)
+
+// under a Type=notify systemd unit, goldfish is only reported ready once it is bootstrapped
+// the watchdog is only fed while vault's latest health check passed and is recent,
+// so systemd restarts goldfish if the health watcher hangs or vault stays unhealthy
+func notifySystemd() {
+	if ok, err := systemd.Notify("STATUS=Waiting for bootstrap"); err != nil {
+		log.Println("[WARN ]: Could not notify systemd:", err.Error())
+		return
+	} else if !ok {
+		return
+	}
+
+	interval, err := systemd.WatchdogInterval()
+	if err != nil {
+		log.Println("[WARN ]: systemd watchdog disabled:", err.Error())
+	}
+	if interval > 0 {
+		go func() {
+			// pinging at half the interval leaves room for a slow tick
+			for range time.Tick(interval / 2) {
+				state := vault.GetVaultState()
+				if state.State == vault.StateOK && time.Since(state.CheckedAt) < interval {
+					systemd.Notify("WATCHDOG=1")
+				}
+			}
+		}()
+	}
+
+	for !vault.Bootstrapped() {
+		time.Sleep(time.Second)
+	}
+	systemd.Notify("READY=1\nSTATUS=Bootstrapped, serving requests")
+}
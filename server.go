@@ -1,71 +1,97 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/caiyeon/goldfish/auditlog"
 	"github.com/caiyeon/goldfish/config"
+	"github.com/caiyeon/goldfish/github"
 	"github.com/caiyeon/goldfish/handlers"
+	"github.com/caiyeon/goldfish/metrics"
+	"github.com/caiyeon/goldfish/notify"
+	"github.com/caiyeon/goldfish/oidc"
+	"github.com/caiyeon/goldfish/report"
+	"github.com/caiyeon/goldfish/request"
+	"github.com/caiyeon/goldfish/session"
+	"github.com/caiyeon/goldfish/snapshot"
+	"github.com/caiyeon/goldfish/systemd"
+	"github.com/caiyeon/goldfish/tracing"
 	"github.com/caiyeon/goldfish/vault"
 	"github.com/hashicorp/vault/helper/mlock"
+	"github.com/hashicorp/vault/helper/tlsutil"
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
 
-	rice "github.com/GeertJohan/go.rice"
-
 	"golang.org/x/crypto/acme/autocert"
 )
 
 var (
 	devMode       bool
+	readOnlyFlag  bool
 	wrappingToken string
 	cfgPath       string
 	cfg           *config.Config
 	devVaultCh    chan struct{}
 	err           error
 	printVersion  bool
+	certs         []*certReloader
 )
 
-func init() {
-	// customized help message
-	flag.Usage = func() {
+// reloads replace parts of cfg, so once the reload handler is running, cfg is only read or written with this held
+var cfgLock = new(sync.RWMutex)
+
+// launches goldfish. Flags are kept identical to the pre-subcommand cli
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.Usage = func() {
 		fmt.Fprintf(os.Stderr, helpMessage)
 	}
+	flags.BoolVar(&devMode, "dev", false, "Set to true to save time in development. DO NOT SET TO TRUE IN PRODUCTION!!")
+	flags.BoolVar(&printVersion, "version", false, "Display goldfish's version and exit")
+	flags.StringVar(&wrappingToken, "token", "", "Token generated from approle (must be wrapped!)")
+	flags.StringVar(&cfgPath, "config", "", "The path of the deployment config HCL file")
+	flags.BoolVar(&readOnlyFlag, "read-only", false, "Refuse every state-changing request, regardless of the config file")
+	flags.Parse(args)
 
-	// cmd line args
-	flag.BoolVar(&devMode, "dev", false, "Set to true to save time in development. DO NOT SET TO TRUE IN PRODUCTION!!")
-	flag.BoolVar(&printVersion, "version", false, "Display goldfish's version and exit")
-	flag.StringVar(&wrappingToken, "token", "", "Token generated from approle (must be wrapped!)")
-	flag.StringVar(&cfgPath, "config", "", "The path of the deployment config HCL file")
+	// if --version, print and exit success
+	if printVersion {
+		log.Println(versionString)
+		os.Exit(0)
+	}
 
-	// if vault dev core is active, relay shutdown signal
+	// drain in-flight requests, and if vault dev core is active, relay shutdown signal
+	// a second signal skips the drain
 	shutdownCh := make(chan os.Signal, 4)
 	signal.Notify(shutdownCh, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-shutdownCh
 		log.Println("\n\n==> Goldfish shutdown triggered")
+		go func() {
+			<-shutdownCh
+			log.Println("[WARN ]: Shutdown forced, in-flight requests were dropped")
+			os.Exit(1)
+		}()
+		shutdown()
 		if devVaultCh != nil {
 			close(devVaultCh)
+			time.Sleep(time.Second)
 		}
-		time.Sleep(time.Second)
 		os.Exit(0)
 	}()
-}
-
-func main() {
-	// if --version, print and exit success
-	flag.Parse()
-	if printVersion {
-		log.Println(versionString)
-		os.Exit(0)
-	}
 
 	// if dev mode, run a localhost dev vault instance
 	if devMode {
@@ -86,13 +112,61 @@ func main() {
 		}
 	}
 
+	if err := session.Configure(cfg.Session); err != nil {
+		log.Fatalf("Could not open session store: %s", err.Error())
+	}
+
 	vault.SetConfig(cfg.Vault)
+	vault.SetClusters(cfg.Clusters)
+	vault.SetLogins(cfg.Logins)
+	vault.SetSessionConfig(cfg.Session)
+	handlers.SetLoginLimits(cfg.Listener.Login_max_attempts, cfg.Listener.Login_backoff, cfg.Listener.Login_lockout)
+	handlers.SetRateLimits(cfg.RateLimit)
+	handlers.SetListCacheTTL(cfg.Vault.List_cache_ttl)
+	handlers.SetReadOnly(cfg.ReadOnly || readOnlyFlag)
+	handlers.SetRoles(cfg.Roles)
+	handlers.SetRequireConfirmation(cfg.RequireConfirmation)
+	handlers.SetVersion(version, commit, buildDate)
+	handlers.SetUpdateCheck(cfg.UpdateCheck)
+	handlers.SetStatusPage(cfg.StatusPage)
+	handlers.SetBranding(cfg.Branding)
+	handlers.SetTokenCreation(cfg.TokenCreation)
+	handlers.SetChatOps(cfg.ChatOps)
+	request.SetRollbackApproval(cfg.RollbackApproval)
+	request.SetLintRules(cfg.PolicyLints)
+	vault.SetAirGapped(cfg.AirGapped)
+	github.SetAirGapped(cfg.AirGapped)
+	if cfg.AirGapped {
+		log.Println("[INFO ]: Air-gapped mode, goldfish will not contact github or slack.com")
+	}
+	if cfg.ReadOnly || readOnlyFlag {
+		log.Println("[INFO ]: Read-only mode, state-changing requests will be refused")
+	}
+	go vault.WatchVaultHealth()
+
+	// reports run with goldfish's token, so those scheduled before bootstrapping fail until it is
+	notify.Configure(cfg.Notifiers)
+	report.Configure(cfg.Reports)
+	request.ScheduleChanges()
+	snapshot.Configure(cfg.RaftSnapshot)
+	auditlog.Configure(cfg.AuditSource)
+	oidc.Configure(cfg.OIDC)
 
 	// if wrapping token is provided, bootstrap goldfish immediately
 	if wrappingToken != "" {
 		if err := vault.StartGoldfishWrapper(wrappingToken); err != nil {
 			panic(err)
 		}
+	} else if cfg.Vault.Token_file != "" {
+		// otherwise, vault agent may be providing a token
+		if err := vault.StartGoldfishWithTokenFile(cfg.Vault.Token_file); err != nil {
+			panic(err)
+		}
+	} else if cfg.Vault.Kubernetes_role != "" {
+		// or goldfish may be running in kubernetes, with a service account vault trusts
+		if err := vault.StartGoldfishWithKubernetes(); err != nil {
+			panic(err)
+		}
 	}
 
 	// display welcome message
@@ -101,19 +175,163 @@ func main() {
 	}
 	fmt.Printf(versionString + initString)
 
+	// unless disabled, export prometheus metrics either on a separate address, or on each listener with a token
+	if metricsEnabled() {
+		describeMetrics()
+	}
+	if telemetry := cfg.Telemetry; !telemetry.Prometheus_disable && telemetry.Prometheus_address != "" {
+		go func() {
+			log.Fatal(http.ListenAndServe(telemetry.Prometheus_address, metrics.Handler(telemetry.Prometheus_token_file)))
+		}()
+	}
+
+	// metrics are also pushed to a statsd agent, if one is configured
+	if err := metrics.ConfigureStatsd(cfg.Telemetry.Statsd_address, cfg.Telemetry.Statsd_prefix, cfg.Telemetry.Statsd_tags); err != nil {
+		panic(err)
+	}
+
+	// traces are exported only if an otlp endpoint is configured
+	tracing.Configure(cfg.Telemetry.Otlp_endpoint, cfg.Telemetry.Otlp_headers, cfg.Telemetry.Trace_sample_ratio)
+
+	// under systemd socket activation, the n-th socket is used for the n-th listener
+	var activated []net.Listener
+	if activated, err = systemd.Listeners(); err != nil {
+		panic(err)
+	}
+
+	// each listener has its own server, so tls and middleware settings are independent
+	listeners := cfg.Listeners()
+	if len(activated) > len(listeners) {
+		log.Printf("[WARN ]: systemd passed %d sockets, but only %d listeners are configured\n", len(activated), len(listeners))
+	}
+	serves := make([]func() error, len(listeners))
+	certs = make([]*certReloader, len(listeners))
+	echos := make([]*echo.Echo, len(listeners))
+	for i, l := range listeners {
+		var socket net.Listener
+		if i < len(activated) {
+			socket = activated[i]
+		}
+		echos[i] = newServer(l)
+		if serves[i], certs[i], err = listen(echos[i], l, socket); err != nil {
+			panic(err)
+		}
+	}
+	setServers(echos, cfg.Listener.Shutdown_timeout)
+	go notifySystemd()
+
+	// reload config on SIGHUP, once the certificate reloaders are set up
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go func() {
+		for range hupCh {
+			reloadConfig()
+		}
+	}()
+
+	// the primary listener runs in the foreground
+	for _, serve := range serves[1:] {
+		go serveUntilShutdown(serve)
+	}
+	serveUntilShutdown(serves[0])
+}
+
+// prometheus and statsd share the same metrics, so they are recorded if either is in use
+func metricsEnabled() bool {
+	cfgLock.RLock()
+	defer cfgLock.RUnlock()
+	return !cfg.Telemetry.Prometheus_disable || cfg.Telemetry.Statsd_address != ""
+}
+
+func describeMetrics() {
+	metrics.Describe("goldfish_active_sessions", "Number of distinct sessions seen in the last 30 minutes")
+	metrics.SetGaugeFunc("goldfish_active_sessions", func() float64 {
+		return float64(handlers.ActiveSessions())
+	})
+	metrics.Describe("goldfish_vault_up", "1 if vault is reachable and unsealed, otherwise 0")
+	metrics.SetGaugeFunc("goldfish_vault_up", func() float64 {
+		if vault.GetVaultState().State == vault.StateOK {
+			return 1
+		}
+		return 0
+	})
+	metrics.Describe("goldfish_server_token_ttl_seconds", "Remaining ttl of goldfish's server token, or -1 if unknown")
+	metrics.SetGaugeFunc("goldfish_server_token_ttl_seconds", func() float64 {
+		ttl, err := vault.ServerTokenTTL()
+		if err != nil {
+			return -1
+		}
+		return float64(ttl)
+	})
+	metrics.Describe("goldfish_requests_pending", "Change requests waiting for approval, or -1 if unknown")
+	metrics.SetGaugeFunc("goldfish_requests_pending", func() float64 {
+		if !vault.Bootstrapped() {
+			return -1
+		}
+		n, err := request.PendingCount()
+		if err != nil {
+			return -1
+		}
+		return float64(n)
+	})
+	metrics.Describe("goldfish_request_approval_median_seconds", "Median time from a change request being made to it being applied, over recent requests")
+	metrics.SetGaugeFunc("goldfish_request_approval_median_seconds", func() float64 {
+		return request.MedianApprovalTime().Seconds()
+	})
+	metrics.Describe("goldfish_request_approvals_total", "Approvals given to change requests, by approver")
+	metrics.Describe("goldfish_requests_completed_total", "Change requests that were fully approved and applied, by type")
+}
+
+// sets up middleware and routes, with the settings of the listener the server is for
+func newServer(l *config.ListenerConfig) *echo.Echo {
 	// instantiate echo web server
 	e := echo.New()
 	e.HideBanner = true
-	e.Server.ReadTimeout = 10 * time.Second
-	e.Server.WriteTimeout = 2 * time.Minute
+	for _, s := range []*http.Server{e.Server, e.TLSServer} {
+		s.ReadTimeout = l.Read_timeout
+		s.WriteTimeout = l.Write_timeout
+		s.IdleTimeout = l.Idle_timeout
+	}
+
+	// behind a path based reverse proxy, everything is served under the base path
+	e.Pre(handlers.BasePath(l.Base_path))
 
 	// setup middleware
 	e.Use(middleware.Logger())
+	// outside of recover, so requests that panic are still traced as errors
+	e.Use(tracing.Middleware())
 	e.Use(middleware.Recover())
-	e.Use(middleware.BodyLimit("32M"))
-	e.Use(middleware.GzipWithConfig(middleware.GzipConfig{
-		Level: 5,
-	}))
+	// tags every call to vault, so its audit log can be traced back to the goldfish user and request
+	e.Use(handlers.RequestID())
+	e.Use(handlers.ClientIP(l.Trusted_proxies))
+	e.Use(handlers.IPFilter(l.Allowed_cidrs, l.Denied_cidrs))
+	e.Use(handlers.RateLimit())
+	e.Use(middleware.BodyLimit(l.Body_limit))
+	if l.Gzip_level > 0 {
+		e.Use(middleware.GzipWithConfig(middleware.GzipConfig{
+			Level: l.Gzip_level,
+		}))
+	}
+
+	if metricsEnabled() {
+		e.Use(metrics.Middleware())
+	}
+	// the listeners are reachable by anyone, so /metrics is only served on them behind a token
+	cfgLock.RLock()
+	telemetry := cfg.Telemetry
+	cfgLock.RUnlock()
+	if !telemetry.Prometheus_disable && telemetry.Prometheus_address == "" && telemetry.Prometheus_token_file != "" {
+		e.GET("/metrics", echo.WrapHandler(metrics.Handler(telemetry.Prometheus_token_file)))
+	}
+
+	// report which vault node served each api request
+	e.Use(handlers.VaultNodeHeader())
+
+	// refuse writes before they reach vault, while in read-only mode
+	e.Use(handlers.ReadOnly())
+
+	// fail fast while vault is sealed or down
+	e.Use(handlers.VaultCircuitBreaker())
 
 	// prevent caching by client (e.g. Safari)
 	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -124,27 +342,29 @@ func main() {
 	})
 
 	// unless explicitly disabled, some extra https configurations need to be set
-	if !cfg.Listener.Tls_disable {
+	if !l.Tls_disable {
 		// add extra security headers
 		e.Use(middleware.SecureWithConfig(middleware.SecureConfig{
 			XSSProtection:         "1; mode=block",
 			ContentTypeNosniff:    "nosniff",
-			XFrameOptions:         "SAMEORIGIN",
-			ContentSecurityPolicy: "default-src 'self' https://api.github.com",
+			XFrameOptions:         l.Frame_options,
+			ContentSecurityPolicy: l.Csp,
 		}))
+		e.Use(securityHeaders(l))
 
-		// if redirect is set, forward port 80 to port 443
-		if cfg.Listener.Tls_autoredirect {
+		// if redirect is set, forward plain http (port 80 by default) to https
+		if l.Tls_autoredirect {
 			e.Pre(middleware.HTTPSRedirect())
-			go func(c *echo.Echo) {
-				e.Logger.Fatal(e.Start(":80"))
-			}(e)
+			go serveUntilShutdown(func() error {
+				return e.Start(l.Tls_redirect_address)
+			})
 		}
 
 		// if cert file and key file are not provided, try using let's encrypt
-		if cfg.Listener.Tls_cert_file == "" && cfg.Listener.Tls_key_file == "" {
-			e.AutoTLSManager.Cache = autocert.DirCache("/var/www/.cache")
-			e.AutoTLSManager.HostPolicy = autocert.HostWhitelist(cfg.Listener.Address)
+		if l.Tls_cert_file == "" && l.Tls_key_file == "" {
+			e.AutoTLSManager.Cache = autocert.DirCache(l.Autocert_cache_dir)
+			e.AutoTLSManager.Email = l.Autocert_email
+			e.AutoTLSManager.HostPolicy = autocert.HostWhitelist(autocertHosts(l)...)
 			e.Use(middleware.HTTPSRedirectWithConfig(middleware.RedirectConfig{
 				Code: 301,
 			}))
@@ -154,24 +374,64 @@ func main() {
 	// for production, static files are packed inside binary
 	// for development, npm dev should serve the static files instead
 	if !devMode {
-		// use rice for static files instead of regular file system
-		assetHandler := http.FileServer(rice.MustFindBox("public").HTTPBox())
-		e.GET("/", echo.WrapHandler(assetHandler))
+		assets := assetFileSystem(l.Assets_dir)
+		assetHandler := http.FileServer(assets)
+		e.GET("/", indexHandler(assets))
 		e.GET("/assets/css/*", echo.WrapHandler(http.StripPrefix("/", assetHandler)))
 		e.GET("/assets/js/*", echo.WrapHandler(http.StripPrefix("/", assetHandler)))
 		e.GET("/assets/fonts/*", echo.WrapHandler(http.StripPrefix("/", assetHandler)))
 		e.GET("/assets/img/*", echo.WrapHandler(http.StripPrefix("/", assetHandler)))
+		e.GET("/assets/swagger-ui/*", echo.WrapHandler(http.StripPrefix("/", assetHandler)))
 	}
 
 	// API routing
 	e.GET("/v1/health", handlers.Health())
+	e.GET("/v1/health/live", handlers.Liveness())
+	e.GET("/v1/health/ready", handlers.Readiness())
 	e.GET("/v1/vaulthealth", handlers.VaultHealth())
-	e.POST("/v1/bootstrap", handlers.Bootstrap())
+	e.GET("/v1/health/history", handlers.VaultHealthHistory())
+	e.GET("/v1/status", handlers.StatusPage())
+	e.GET("/v1/version", handlers.Version())
+	e.GET("/v1/ui-config", handlers.UIConfig())
+	e.GET("/v1/ui-config/logo", handlers.UILogo())
+	e.GET("/v1/ui-config/theme.css", handlers.UITheme())
+	e.GET("/v1/clusters", handlers.Clusters())
+	// admin endpoints may be further restricted to an admin network
+	admin := handlers.IPFilter(l.Admin_allowed_cidrs, nil)
+	e.POST("/v1/bootstrap", handlers.Bootstrap(), admin)
+	e.POST("/v1/rebootstrap", handlers.Rebootstrap(), admin)
+	e.GET("/v1/replication/status", handlers.GetReplicationStatus(), admin)
+	dr := handlers.DRNeedsAdminNetwork(l.Admin_allowed_cidrs)
+	e.GET("/v1/replication/dr/operation-token", handlers.DROperationToken(), admin, dr)
+	e.POST("/v1/replication/dr/operation-token", handlers.DROperationToken(), admin, dr)
+	e.DELETE("/v1/replication/dr/operation-token", handlers.DROperationToken(), admin, dr)
+	e.POST("/v1/replication/dr/operation-token/update", handlers.UpdateDROperationToken(), admin, dr)
+	e.POST("/v1/replication/:mode/:action", handlers.ReplicationAction(), admin, dr)
 
 	e.POST("/v1/login", handlers.Login())
+	e.GET("/v1/login/oidc", handlers.LoginOIDC())
+	e.GET("/v1/login/oidc/callback", handlers.LoginOIDCCallback())
+	e.POST("/v1/login/oidc/complete", handlers.LoginOIDCComplete())
 	e.POST("/v1/login/renew-self", handlers.RenewSelf())
+	e.POST("/v1/login/revoke-self", handlers.RevokeSelf())
+	e.POST("/v1/login/reauth", handlers.Reauthenticate())
+	e.POST("/v1/logout", handlers.Logout())
+	e.GET("/v1/self", handlers.Self())
+	e.GET("/v1/self/access", handlers.SelfAccess())
+	e.GET("/v1/namespaces", handlers.GetNamespaces())
+	e.POST("/v1/namespaces/switch", handlers.SwitchNamespace())
+	e.GET("/v1/sessions", handlers.ListSessions())
+	e.GET("/v1/sessions/all", handlers.ListAllSessions(), admin)
+	e.DELETE("/v1/sessions/all/:id", handlers.AdminRevokeSession(), admin)
+	e.POST("/v1/sessions/revoke-all", handlers.RevokeAllSessions(), admin)
+	e.DELETE("/v1/sessions/:id", handlers.RevokeSession())
+	e.GET("/v1/apitokens", handlers.ListAPITokens(), admin)
+	e.POST("/v1/apitokens", handlers.CreateAPIToken(), admin)
+	e.DELETE("/v1/apitokens/:id", handlers.RevokeAPIToken(), admin)
 
 	e.GET("/v1/token/accessors", handlers.GetTokenAccessors())
+	e.GET("/v1/token/accessors/export", handlers.ExportTokenAccessors())
+	e.GET("/v1/token/accessors/:id", handlers.GetAccessorDetail())
 	e.POST("/v1/token/lookup-accessor", handlers.LookupTokenByAccessor())
 	e.POST("/v1/token/revoke-accessor", handlers.RevokeTokenByAccessor())
 	e.POST("/v1/token/create", handlers.CreateToken())
@@ -186,49 +446,252 @@ func main() {
 
 	e.GET("/v1/ldap/groups", handlers.GetLDAPGroups())
 	e.GET("/v1/ldap/users", handlers.GetLDAPUsers())
+	e.GET("/v1/directory/search", handlers.SearchDirectory())
 
 	e.GET("/v1/policy", handlers.GetPolicy())
 	e.DELETE("/v1/policy", handlers.DeletePolicy())
+	e.GET("/v1/policy/snapshots", handlers.GetPolicySnapshots())
+	e.POST("/v1/policy/rollback", handlers.RollbackPolicy())
+	e.POST("/v1/policy/simulate", handlers.SimulateACL())
+
+	e.GET("/v1/password-policy", handlers.GetPasswordPolicy())
+	e.POST("/v1/password-policy", handlers.PutPasswordPolicy())
+	e.DELETE("/v1/password-policy", handlers.DeletePasswordPolicy())
+	e.GET("/v1/password-policy/generate", handlers.GeneratePassword())
 
 	e.GET("/v1/request", handlers.GetRequest())
+	e.GET("/v1/request/stats", handlers.GetRequestStats())
+	e.GET("/v1/request/plan", handlers.GetRequestPlan())
 	e.POST("/v1/request/add", handlers.AddRequest())
 	e.POST("/v1/request/approve", handlers.ApproveRequest())
 	e.DELETE("/v1/request/reject", handlers.RejectRequest())
+	e.GET("/v1/delegations", handlers.GetDelegations())
+	e.POST("/v1/delegations", handlers.CreateDelegation())
+	e.DELETE("/v1/delegations/:id", handlers.DeleteDelegation())
+	e.POST("/v1/chatops/slack", handlers.SlackCallback())
 
 	e.GET("/v1/transit", handlers.TransitInfo())
 	e.POST("/v1/transit/encrypt", handlers.EncryptString())
 	e.POST("/v1/transit/decrypt", handlers.DecryptString())
+	e.POST("/v1/transit/backup", handlers.BackupTransitKey())
+	e.POST("/v1/transit/restore", handlers.RestoreTransitKey())
+	e.POST("/v1/tools/random", handlers.RandomBytes())
+	e.POST("/v1/tools/hash", handlers.Hash())
 
 	e.GET("/v1/mount", handlers.GetMount())
 	e.POST("/v1/mount", handlers.ConfigMount())
+	e.POST("/v1/kv/migrate", handlers.MigrateKV())
+
+	e.GET("/v1/quotas", handlers.GetQuotas())
+	e.POST("/v1/quotas", handlers.WriteQuota())
+	e.DELETE("/v1/quotas", handlers.DeleteQuota())
+	e.POST("/v1/quotas/config", handlers.ConfigQuotas())
 
 	e.GET("/v1/secrets", handlers.GetSecrets())
 	e.POST("/v1/secrets", handlers.PostSecrets())
 	e.DELETE("/v1/secrets", handlers.DeleteSecrets())
+	e.POST("/v1/secrets/copy", handlers.CopySecretsAcrossClusters())
+	e.GET("/v1/secrets/tags", handlers.GetSecretTags())
+	e.PUT("/v1/secrets/tags", handlers.SetSecretTags())
+	e.GET("/v1/secrets/untagged", handlers.GetUntaggedSecrets())
+	e.GET("/v1/secrets/stale", handlers.GetStaleSecrets())
+	e.GET("/v1/secrets/rotations", handlers.GetRotations())
+
+	e.GET("/v1/bookmarks", handlers.GetBookmarks())
+	e.POST("/v1/bookmarks", handlers.AddBookmark())
+	e.DELETE("/v1/bookmarks", handlers.DeleteBookmark())
+	e.GET("/v1/recent", handlers.GetRecent())
+	e.DELETE("/v1/recent", handlers.ClearRecent())
 
 	e.GET("/v1/bulletins", handlers.GetBulletins())
+	e.POST("/v1/bulletins", handlers.CreateBulletin())
+	e.PUT("/v1/bulletins/:id", handlers.UpdateBulletin())
+	e.DELETE("/v1/bulletins/:id", handlers.DeleteBulletin())
+
+	e.GET("/v1/settings", handlers.GetSettings())
+	e.PUT("/v1/settings", handlers.UpdateSettings())
+	e.GET("/v1/reports", handlers.GetReports())
+	e.GET("/v1/raft/snapshots", handlers.GetRaftSnapshots())
+	e.GET("/v1/usage", handlers.GetUsage())
+	e.GET("/v1/leases", handlers.GetLeaseSummary())
+	e.GET("/v1/audit/heatmap", handlers.GetAccessHeatmap())
 
 	e.POST("/v1/wrapping/wrap", handlers.WrapHandler())
 	e.POST("/v1/wrapping/unwrap", handlers.UnwrapHandler())
+	e.POST("/v1/wrapping/unwrap-batch", handlers.UnwrapBatchHandler())
 
-	// serving both static folder and API
-	if cfg.Listener.Tls_disable {
+	e.POST("/v1/raw", handlers.RawRequest())
+
+	// generated from the routes above, so it can't fall out of date
+	e.GET("/v1/openapi.json", handlers.OpenAPI(e.Routes(), l.Base_path))
+	if l.Swagger_ui {
+		e.GET("/docs", handlers.SwaggerUI())
+	}
+
+	return e
+}
+
+// sets up the listener's socket and tls, returning a function that serves until it fails
+// the certificate reloader is nil unless the listener uses certificate files
+// activated is a socket passed by systemd, used instead of the listener's address if set
+func listen(e *echo.Echo, l *config.ListenerConfig, activated net.Listener) (func() error, *certReloader, error) {
+	listener := activated
+	if listener == nil && l.Type == "unix" {
+		var err error
+		if listener, err = unixListener(l); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if l.Tls_disable {
 		// launch http-only listener
-		e.Logger.Fatal(e.Start(cfg.Listener.Address))
-	} else if cfg.Listener.Tls_cert_file == "" && cfg.Listener.Tls_key_file == "" {
+		e.Listener = listener
+		return func() error { return e.Start(l.Address) }, nil, nil
+	}
+
+	// only the tls listener is preset, since e.Listener would be picked up by the redirect listener
+	tlsConfig, err := listenerTLSConfig(l)
+	if err != nil {
+		return nil, nil, err
+	}
+	var reloader *certReloader
+	if l.Tls_cert_file == "" && l.Tls_key_file == "" {
 		// if https is enabled, but no cert provided, try let's encrypt
-		e.Logger.Fatal(e.StartAutoTLS(":443"))
+		tlsConfig.GetCertificate = e.AutoTLSManager.GetCertificate
+		e.TLSServer.Addr = ":443"
 	} else {
-		// launch listener in https
-		e.Logger.Fatal(e.StartTLS(
-			cfg.Listener.Address,
-			cfg.Listener.Tls_cert_file,
-			cfg.Listener.Tls_key_file,
-		))
+		// launch listener in https, with a certificate that can be reloaded
+		if reloader, err = newCertReloader(l.Tls_cert_file, l.Tls_key_file); err != nil {
+			return nil, nil, err
+		}
+		tlsConfig.GetCertificate = reloader.GetCertificate
+		e.TLSServer.Addr = l.Address
+		if l.Tls_require_client_cert {
+			if err := requireClientCerts(tlsConfig, l); err != nil {
+				return nil, nil, err
+			}
+		}
 	}
+	e.TLSServer.TLSConfig = tlsConfig
+	if listener != nil {
+		e.TLSListener = tls.NewListener(listener, tlsConfig)
+	}
+	return func() error { return e.StartServer(e.TLSServer) }, reloader, nil
 }
 
-const versionString = "Goldfish version: v0.7.1-dev"
+// certificates are only requested for the listener's own host name and any extra hosts
+func autocertHosts(l *config.ListenerConfig) []string {
+	hosts := append([]string(nil), l.Autocert_hosts...)
+	host, _, err := net.SplitHostPort(l.Address)
+	if err != nil {
+		host = l.Address
+	}
+	if host != "" {
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// the tls settings shared by both certificate sources
+func listenerTLSConfig(l *config.ListenerConfig) (*tls.Config, error) {
+	t := &tls.Config{
+		MinVersion: tlsutil.TLSLookup[l.Tls_min_version],
+		NextProtos: []string{"h2"},
+	}
+	if len(l.Tls_cipher_suites) > 0 {
+		suites, err := tlsutil.ParseCiphers(strings.Join(l.Tls_cipher_suites, ","))
+		if err != nil {
+			return nil, err
+		}
+		t.CipherSuites = suites
+		t.PreferServerCipherSuites = true
+	}
+	if l.Tls_cipher_preset == "fips" {
+		t.CurvePreferences = []tls.CurveID{tls.CurveP256, tls.CurveP384}
+	}
+	return t, nil
+}
+
+// refuses the tls handshake unless the client presents a certificate from the configured CA
+// if CNs or OUs are listed, the certificate must also carry one of them
+func requireClientCerts(t *tls.Config, l *config.ListenerConfig) error {
+	pem, err := ioutil.ReadFile(l.Tls_client_ca_file)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return errors.New("No certificates found in " + l.Tls_client_ca_file)
+	}
+	t.ClientCAs = pool
+	t.ClientAuth = tls.RequireAndVerifyClientCert
+
+	if len(l.Tls_client_allowed_cns) == 0 && len(l.Tls_client_allowed_ous) == 0 {
+		return nil
+	}
+	t.VerifyPeerCertificate = func(_ [][]byte, chains [][]*x509.Certificate) error {
+		if len(chains) == 0 || len(chains[0]) == 0 {
+			return errors.New("no verified client certificate")
+		}
+		subject := chains[0][0].Subject
+		if len(l.Tls_client_allowed_cns) > 0 && !containsAny(l.Tls_client_allowed_cns, []string{subject.CommonName}) {
+			return errors.New("client certificate CN is not allowed: " + subject.CommonName)
+		}
+		if len(l.Tls_client_allowed_ous) > 0 && !containsAny(l.Tls_client_allowed_ous, subject.OrganizationalUnit) {
+			return errors.New("client certificate OU is not allowed: " + strings.Join(subject.OrganizationalUnit, ","))
+		}
+		return nil
+	}
+	return nil
+}
+
+func containsAny(list, values []string) bool {
+	for _, a := range list {
+		for _, b := range values {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sets the headers echo's secure middleware can't: hsts with preload, and the referrer policy
+func securityHeaders(l *config.ListenerConfig) echo.MiddlewareFunc {
+	hsts := ""
+	if l.Hsts_max_age > 0 {
+		hsts = fmt.Sprintf("max-age=%d", int64(l.Hsts_max_age/time.Second))
+		if l.Hsts_include_subdomains {
+			hsts += "; includeSubDomains"
+		}
+		if l.Hsts_preload {
+			hsts += "; preload"
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if hsts != "" {
+				c.Response().Header().Set("Strict-Transport-Security", hsts)
+			}
+			if l.Referrer_policy != "" {
+				c.Response().Header().Set("Referrer-Policy", l.Referrer_policy)
+			}
+			return next(c)
+		}
+	}
+}
+
+const (
+	version       = "v0.7.1-dev"
+	versionString = "Goldfish version: " + version
+)
+
+// set at build time, e.g. -ldflags "-X main.commit=$(git rev-parse --short HEAD)"
+var (
+	commit    = ""
+	buildDate = ""
+)
 
 const devInitString = `
 
@@ -270,23 +733,47 @@ sudo setcap cap_ipc_lock=+ep $(readlink -f $(which goldfish))
 To disable mlock entirely, set disable_mlock to "1" in config file
 `
 
-const helpMessage = `Usage: goldfish [options]
+const helpMessage = `Usage: goldfish [command] [options]
 See https://github.com/Caiyeon/goldfish/wiki for details
 
-Required Arguments:
+Commands:
+
+  serve                   Launch goldfish (default if no command is given)
+  validate                Check a deployment config file and exit
+  bootstrap               Bootstrap a running goldfish instance
+  service                 Install, uninstall, or run goldfish as a windows service
+  version                 Print the version and exit
+
+Run 'goldfish <command> -h' for a command's options
+
+Serve Arguments:
 
   -config=config.hcl      The deployment config file
                           See github.com/caiyeon/goldfish/config/sample.hcl
                           for a full list of options
-
-Optional Arguments:
+                          Send SIGHUP to reload it without a restart
+                          Values can also be set with GOLDFISH_* env variables
 
   -token=<uuid>           A wrapping token which contains a secret_id
                           Can be provided after launch, on Login page
                           Generate with 'vault write -f transit/keys/goldfish'
 
+  -read-only              Refuse every state-changing request
+                          Same as read_only = 1 in the config file
+
   -version                Print the version and exit
 
   -dev                    Launch goldfish in dev mode
                           A localhost dev vault instance will be launched
+
+Service Commands (windows only, from an administrator console):
+
+  service install -config=C:\goldfish\config.hcl [-name=goldfish] [-log-file=<path>] [-read-only]
+                          Registers an automatically started service
+                          The log defaults to goldfish.log next to the binary
+
+  service uninstall [-name=goldfish]
+                          Removes the service, once it has stopped
+
+  service run             Used by the service control manager to start goldfish
 `
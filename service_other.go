@@ -0,0 +1,14 @@
+// +build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// elsewhere, goldfish runs under systemd or a container runtime instead
+func service(args []string) int {
+	fmt.Fprintln(os.Stderr, "Windows services are only supported on windows, use 'goldfish serve' instead")
+	return 1
+}
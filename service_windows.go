@@ -0,0 +1,269 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// the service control manager's api, from advapi32.dll
+var (
+	advapi32                     = syscall.NewLazyDLL("advapi32.dll")
+	procOpenSCManagerW           = advapi32.NewProc("OpenSCManagerW")
+	procCreateServiceW           = advapi32.NewProc("CreateServiceW")
+	procOpenServiceW             = advapi32.NewProc("OpenServiceW")
+	procDeleteService            = advapi32.NewProc("DeleteService")
+	procCloseServiceHandle       = advapi32.NewProc("CloseServiceHandle")
+	procChangeServiceConfig2W    = advapi32.NewProc("ChangeServiceConfig2W")
+	procStartServiceCtrlDispatch = advapi32.NewProc("StartServiceCtrlDispatcherW")
+	procRegisterServiceCtrlEx    = advapi32.NewProc("RegisterServiceCtrlHandlerExW")
+	procSetServiceStatus         = advapi32.NewProc("SetServiceStatus")
+)
+
+const (
+	scManagerCreateService   = 0x0002
+	serviceAllAccess         = 0xF01FF
+	serviceDelete            = 0x10000
+	serviceWin32OwnProcess   = 0x10
+	serviceAutoStart         = 2
+	serviceErrorNormal       = 1
+	serviceConfigDescription = 1
+
+	serviceStopped      = 1
+	serviceStartPending = 2
+	serviceStopPending  = 3
+	serviceRunning      = 4
+
+	serviceControlStop        = 1
+	serviceControlInterrogate = 4
+	serviceControlShutdown    = 5
+	serviceAcceptStop         = 1
+	serviceAcceptShutdown     = 4
+
+	errorCallNotImplemented = 120
+	// returned by the dispatcher when goldfish wasn't started by the service control manager
+	errorFailedServiceControllerConnect = 1063
+)
+
+type serviceStatus struct {
+	ServiceType             uint32
+	CurrentState            uint32
+	ControlsAccepted        uint32
+	Win32ExitCode           uint32
+	ServiceSpecificExitCode uint32
+	CheckPoint              uint32
+	WaitHint                uint32
+}
+
+type serviceTableEntry struct {
+	name *uint16
+	proc uintptr
+}
+
+// what the service control manager's callbacks need, since they can't be closures
+var (
+	serviceName   string
+	serviceArgs   []string
+	serviceHandle uintptr
+	serviceStop   = make(chan struct{})
+	stopOnce      = new(sync.Once)
+)
+
+// installs, removes, or runs goldfish as a windows service
+func service(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: goldfish service <install|uninstall|run> [options]")
+		return 1
+	}
+
+	flags := flag.NewFlagSet("service "+args[0], flag.ExitOnError)
+	name := flags.String("name", "goldfish", "The name of the windows service")
+	cfg := flags.String("config", "", "The path of the deployment config file (for install)")
+	logFile := flags.String("log-file", "", "A file to write goldfish's log to, since services have no console (for install)")
+	readOnly := flags.Bool("read-only", false, "Refuse every state-changing request (for install)")
+	flags.Parse(args[1:])
+
+	var err error
+	switch args[0] {
+	case "install":
+		err = installService(*name, *cfg, *logFile, *readOnly)
+	case "uninstall":
+		err = uninstallService(*name)
+	case "run":
+		err = runService(*name, *logFile, flags.Args())
+	default:
+		err = errors.New("Unknown service command: " + args[0])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	return 0
+}
+
+// the service runs 'goldfish service run', with serve's arguments after --
+func installService(name, cfg, logFile string, readOnly bool) error {
+	if cfg == "" {
+		return errors.New("A config file must be provided with -config")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	// services start in the system directory, so relative paths would point there
+	if cfg, err = filepath.Abs(cfg); err != nil {
+		return err
+	}
+	if logFile == "" {
+		logFile = filepath.Join(filepath.Dir(exe), "goldfish.log")
+	}
+	if logFile, err = filepath.Abs(logFile); err != nil {
+		return err
+	}
+
+	cmd := fmt.Sprintf(`"%s" service run -name "%s" -log-file "%s" -- -config "%s"`, exe, name, logFile, cfg)
+	if readOnly {
+		cmd += " -read-only"
+	}
+
+	scm, err := openSCManager(scManagerCreateService)
+	if err != nil {
+		return err
+	}
+	defer closeServiceHandle(scm)
+
+	// pointers are converted to uintptr in the call itself, so the strings are kept alive until it returns
+	namePtr := syscall.StringToUTF16Ptr(name)
+	displayPtr := syscall.StringToUTF16Ptr("Goldfish Vault UI")
+	cmdPtr := syscall.StringToUTF16Ptr(cmd)
+	h, _, err := procCreateServiceW.Call(scm, uintptr(unsafe.Pointer(namePtr)), uintptr(unsafe.Pointer(displayPtr)),
+		serviceAllAccess, serviceWin32OwnProcess, serviceAutoStart, serviceErrorNormal,
+		uintptr(unsafe.Pointer(cmdPtr)), 0, 0, 0, 0, 0)
+	if h == 0 {
+		return fmt.Errorf("Could not create service %s: %s", name, err.Error())
+	}
+	defer closeServiceHandle(h)
+
+	description := struct{ text *uint16 }{syscall.StringToUTF16Ptr("Goldfish, a ui for hashicorp vault")}
+	procChangeServiceConfig2W.Call(h, serviceConfigDescription, uintptr(unsafe.Pointer(&description)))
+
+	fmt.Printf("Service %s installed, logging to %s\nStart it with 'sc start %s'\n", name, logFile, name)
+	return nil
+}
+
+func uninstallService(name string) error {
+	scm, err := openSCManager(scManagerCreateService)
+	if err != nil {
+		return err
+	}
+	defer closeServiceHandle(scm)
+
+	namePtr := syscall.StringToUTF16Ptr(name)
+	h, _, err := procOpenServiceW.Call(scm, uintptr(unsafe.Pointer(namePtr)), serviceDelete)
+	if h == 0 {
+		return fmt.Errorf("Could not open service %s: %s", name, err.Error())
+	}
+	defer closeServiceHandle(h)
+
+	// a running service is removed once it stops
+	if ok, _, err := procDeleteService.Call(h); ok == 0 {
+		return fmt.Errorf("Could not remove service %s: %s", name, err.Error())
+	}
+	fmt.Printf("Service %s removed\n", name)
+	return nil
+}
+
+// hands the process to the service control manager, which calls serviceMain
+// blocks until the service has stopped
+func runService(name, logFile string, args []string) error {
+	if logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		log.SetOutput(f)
+		os.Stdout, os.Stderr = f, f
+	}
+	serviceName, serviceArgs = name, args
+
+	table := []serviceTableEntry{
+		{name: syscall.StringToUTF16Ptr(name), proc: syscall.NewCallback(serviceMain)},
+		{},
+	}
+	if ok, _, err := procStartServiceCtrlDispatch.Call(uintptr(unsafe.Pointer(&table[0]))); ok == 0 {
+		if errno, isErrno := err.(syscall.Errno); isErrno && errno == errorFailedServiceControllerConnect {
+			return errors.New("'goldfish service run' is only for the service control manager, use 'goldfish serve' from a console")
+		}
+		return err
+	}
+	return nil
+}
+
+// called by the service control manager on its own thread. Returning means the service has stopped
+func serviceMain(argc, argv uintptr) uintptr {
+	namePtr := syscall.StringToUTF16Ptr(serviceName)
+	serviceHandle, _, _ = procRegisterServiceCtrlEx.Call(uintptr(unsafe.Pointer(namePtr)), syscall.NewCallback(serviceControl), 0)
+	if serviceHandle == 0 {
+		return 0
+	}
+	setServiceStatus(serviceStartPending, 0)
+
+	go serve(serviceArgs)
+	setServiceStatus(serviceRunning, serviceAcceptStop|serviceAcceptShutdown)
+
+	<-serviceStop
+	log.Println("\n\n==> Goldfish shutdown triggered by the service control manager")
+	setServiceStatus(serviceStopPending, 0)
+	shutdown()
+	setServiceStatus(serviceStopped, 0)
+	return 0
+}
+
+func serviceControl(control, eventType, eventData, context uintptr) uintptr {
+	switch control {
+	case serviceControlStop, serviceControlShutdown:
+		stopOnce.Do(func() { close(serviceStop) })
+		return 0
+	case serviceControlInterrogate:
+		return 0
+	}
+	return errorCallNotImplemented
+}
+
+func setServiceStatus(state, accepted uint32) {
+	status := serviceStatus{
+		ServiceType:      serviceWin32OwnProcess,
+		CurrentState:     state,
+		ControlsAccepted: accepted,
+	}
+	// draining requests may take up to the shutdown timeout
+	if state == serviceStartPending || state == serviceStopPending {
+		serversLock.Lock()
+		status.WaitHint = uint32((time.Minute + shutdownTimeout) / time.Millisecond)
+		serversLock.Unlock()
+	}
+	procSetServiceStatus.Call(serviceHandle, uintptr(unsafe.Pointer(&status)))
+}
+
+func openSCManager(access uintptr) (uintptr, error) {
+	h, _, err := procOpenSCManagerW.Call(0, 0, access)
+	if h == 0 {
+		if errno, ok := err.(syscall.Errno); ok && errno == syscall.ERROR_ACCESS_DENIED {
+			return 0, errors.New("Could not open the service control manager, run this from an administrator console")
+		}
+		return 0, err
+	}
+	return h, nil
+}
+
+func closeServiceHandle(h uintptr) {
+	procCloseServiceHandle.Call(h)
+}
@@ -0,0 +1,197 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// the log is compacted once it holds this many more records than there are sessions
+const fileCompactSlack = 1000
+
+// sessions survive restarts, but the file must not be shared between instances
+// each change is appended to the file as a line of json, and the file is only rewritten
+// from the sessions in memory once most of its lines are outdated
+type fileStore struct {
+	lock     sync.Mutex
+	path     string
+	file     *os.File
+	records  int
+	sessions map[string]Session
+}
+
+// a line of the file: a session written, or the hash of one deleted
+type fileRecord struct {
+	Put    *Session `json:"put,omitempty"`
+	Delete string   `json:"delete,omitempty"`
+}
+
+func newFileStore(path string) (*fileStore, error) {
+	f := &fileStore{path: path, sessions: make(map[string]Session)}
+	in, err := os.Open(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		defer in.Close()
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var r fileRecord
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				// a crash may leave the last line cut short, which loses only that change
+				continue
+			}
+			if r.Put == nil && r.Delete == "" {
+				// older versions kept every session in a single json object
+				var legacy map[string]Session
+				if json.Unmarshal(scanner.Bytes(), &legacy) == nil {
+					for hash, s := range legacy {
+						f.sessions[hash] = s
+					}
+				}
+				continue
+			}
+			f.apply(r)
+			f.records++
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.compact(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *fileStore) apply(r fileRecord) {
+	if r.Put != nil {
+		f.sessions[r.Put.Hash] = *r.Put
+	}
+	if r.Delete != "" {
+		delete(f.sessions, r.Delete)
+	}
+}
+
+// appends a change to the file, compacting it first if most of its lines are outdated
+func (f *fileStore) append(r fileRecord) error {
+	if f.file == nil {
+		return errors.New("The session file is closed")
+	}
+	f.apply(r)
+	if f.records > 2*len(f.sessions)+fileCompactSlack {
+		return f.compact()
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err := f.file.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	f.records++
+	return f.file.Sync()
+}
+
+// rewrites the file with one line per unexpired session
+// writes to a temp file first, so a crash never leaves a truncated file behind
+func (f *fileStore) compact() error {
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), ".goldfish-sessions")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	records := 0
+	for hash, s := range f.sessions {
+		if s.Expired() {
+			delete(f.sessions, hash)
+			continue
+		}
+		s := s
+		b, err := json.Marshal(fileRecord{Put: &s})
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		w.Write(append(b, '\n'))
+		records++
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if f.file != nil {
+		f.file.Close()
+	}
+	f.file = file
+	f.records = records
+	return nil
+}
+
+func (f *fileStore) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *fileStore) Get(hash string) (*Session, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	s, ok := f.sessions[hash]
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (f *fileStore) Put(s *Session) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.append(fileRecord{Put: s})
+}
+
+func (f *fileStore) Delete(hash string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if _, ok := f.sessions[hash]; !ok {
+		return nil
+	}
+	return f.append(fileRecord{Delete: hash})
+}
+
+func (f *fileStore) List() ([]*Session, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	sessions := make([]*Session, 0, len(f.sessions))
+	for _, s := range f.sessions {
+		s := s
+		sessions = append(sessions, &s)
+	}
+	return sessions, nil
+}
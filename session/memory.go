@@ -0,0 +1,73 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// expired sessions are swept out of memory at most this often, as sessions are written
+const memoryPruneInterval = time.Minute
+
+// sessions are lost when goldfish restarts, and are not shared between instances
+type memoryStore struct {
+	lock      sync.RWMutex
+	sessions  map[string]Session
+	lastPrune time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{sessions: make(map[string]Session), lastPrune: time.Now()}
+}
+
+func (m *memoryStore) Get(hash string) (*Session, error) {
+	m.lock.RLock()
+	s, ok := m.sessions[hash]
+	m.lock.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+	if s.Expired() {
+		m.Delete(hash)
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (m *memoryStore) Put(s *Session) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.sessions[s.Hash] = *s
+	if time.Since(m.lastPrune) >= memoryPruneInterval {
+		m.prune()
+	}
+	return nil
+}
+
+func (m *memoryStore) Delete(hash string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.sessions, hash)
+	return nil
+}
+
+func (m *memoryStore) List() ([]*Session, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.prune()
+	sessions := make([]*Session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		s := s
+		sessions = append(sessions, &s)
+	}
+	return sessions, nil
+}
+
+// the caller holds the lock
+func (m *memoryStore) prune() {
+	for hash, s := range m.sessions {
+		if s.Expired() {
+			delete(m.sessions, hash)
+		}
+	}
+	m.lastPrune = time.Now()
+}
@@ -0,0 +1,242 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	redisKeyPrefix = "goldfish:session:"
+	redisTimeout   = 5 * time.Second
+)
+
+// sessions are shared by every goldfish instance pointing at the same redis
+// expiry is left to redis, so stale sessions never need cleaning up
+type redisStore struct {
+	address  string
+	password string
+	db       int
+
+	// a single connection is plenty, since each command is tiny
+	lock sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// an error reply from redis. The connection is still usable after one of these
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func newRedisStore(address, password string, db int) (*redisStore, error) {
+	r := &redisStore{address: address, password: password, db: db}
+	// fail at startup rather than on the first login
+	if _, err := r.do("PING"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *redisStore) dial() error {
+	conn, err := net.DialTimeout("tcp", r.address, redisTimeout)
+	if err != nil {
+		return err
+	}
+	r.conn = conn
+	r.r = bufio.NewReader(conn)
+
+	if r.password != "" {
+		if _, err := r.command("AUTH", r.password); err != nil {
+			r.close()
+			return err
+		}
+	}
+	if r.db != 0 {
+		if _, err := r.command("SELECT", strconv.Itoa(r.db)); err != nil {
+			r.close()
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *redisStore) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.close()
+	return nil
+}
+
+func (r *redisStore) close() {
+	if r.conn != nil {
+		r.conn.Close()
+	}
+	r.conn = nil
+	r.r = nil
+}
+
+func (r *redisStore) do(args ...string) (interface{}, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.conn == nil {
+		if err := r.dial(); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := r.command(args...)
+	if _, ok := err.(redisError); err != nil && !ok {
+		// the connection is in an unknown state, so start over next time
+		r.close()
+	}
+	return reply, err
+}
+
+func (r *redisStore) command(args ...string) (interface{}, error) {
+	r.conn.SetDeadline(time.Now().Add(redisTimeout))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(r.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readReply(r.r)
+}
+
+// parses a single RESP reply. Nil bulk strings and arrays are returned as nil
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		replies := make([]interface{}, n)
+		for i := range replies {
+			if replies[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return replies, nil
+	}
+	return nil, errors.New("redis: malformed reply: " + line)
+}
+
+func (r *redisStore) Ping() error {
+	_, err := r.do("PING")
+	return err
+}
+
+func (r *redisStore) Get(hash string) (*Session, error) {
+	reply, err := r.do("GET", redisKeyPrefix+hash)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := reply.(string)
+	if !ok {
+		return nil, nil
+	}
+	s := &Session{}
+	if err := json.Unmarshal([]byte(value), s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *redisStore) Put(s *Session) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	args := []string{"SET", redisKeyPrefix + s.Hash, string(b)}
+	if deadline := s.Deadline(); !deadline.IsZero() {
+		ttl := time.Until(deadline) / time.Millisecond
+		if ttl <= 0 {
+			return r.Delete(s.Hash)
+		}
+		args = append(args, "PX", strconv.FormatInt(int64(ttl), 10))
+	}
+	_, err = r.do(args...)
+	return err
+}
+
+func (r *redisStore) Delete(hash string) error {
+	_, err := r.do("DEL", redisKeyPrefix+hash)
+	return err
+}
+
+func (r *redisStore) List() ([]*Session, error) {
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := r.do("SCAN", cursor, "MATCH", redisKeyPrefix+"*", "COUNT", "100")
+		if err != nil {
+			return nil, err
+		}
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 2 {
+			return nil, errors.New("redis: unexpected reply to SCAN")
+		}
+		cursor, _ = parts[0].(string)
+		batch, _ := parts[1].([]interface{})
+		for _, key := range batch {
+			if k, ok := key.(string); ok {
+				keys = append(keys, k)
+			}
+		}
+		if cursor == "0" || cursor == "" {
+			break
+		}
+	}
+
+	sessions := make([]*Session, 0, len(keys))
+	for _, key := range keys {
+		// a key may expire between SCAN and here, in which case it is skipped
+		s, err := r.Get(strings.TrimPrefix(key, redisKeyPrefix))
+		if err != nil {
+			return nil, err
+		}
+		if s != nil {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, nil
+}
@@ -0,0 +1,434 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/config"
+)
+
+// session ids handed to users carry this prefix, to tell them apart from raw tokens and legacy ciphers
+const Prefix = "gfs:"
+
+// api tokens are stored alongside sessions, but carry their own prefix so neither can pass for the other
+const APIPrefix = "gft:"
+
+// last seen times are only written back this often, to keep store writes down
+const touchInterval = time.Minute
+
+type Session struct {
+	// sha256 of the session id. Ids themselves are never stored, so a leaked store can't be replayed
+	Hash string `json:"hash"`
+
+	// the user's vault token, sealed with a key derived from the session id, so the store alone can't recover it
+	// inside the seal, it is transit encrypted if Transit names the key ("<backend>/<key>")
+	// the key is kept per session, so sessions outlive a change of key in the config
+	Token   string `json:"token"`
+	Transit string `json:"transit"`
+
+	Cluster     string    `json:"cluster"`
+	DisplayName string    `json:"display_name"`
+	Created     time.Time `json:"created"`
+	LastSeen    time.Time `json:"last_seen"`
+
+	// the vault enterprise namespace the session works in, which its user may switch between,
+	// and the one it logged in to, where its credentials are re-entered. Empty is the root
+	Namespace      string `json:"namespace,omitempty"`
+	LoginNamespace string `json:"login_namespace,omitempty"`
+
+	// who may list and revoke this session, see the handlers for how it is derived
+	Owner string `json:"owner"`
+
+	// how the session was created, for administrators. Empty for sessions from older versions
+	Backend  string `json:"backend,omitempty"`
+	SourceIP string `json:"source_ip,omitempty"`
+
+	// zero means the session never expires
+	Expires time.Time `json:"expires"`
+
+	// when credentials were last entered, at login or since. Zero for sessions from older versions
+	Authenticated time.Time `json:"authenticated,omitempty"`
+
+	// set for api tokens only, which may only call the endpoints these scopes allow
+	Scopes []string `json:"scopes,omitempty"`
+
+	// the accessor of the vault token, so it can be looked up or revoked without the token itself
+	Accessor string `json:"accessor,omitempty"`
+}
+
+func (s *Session) IsAPIToken() bool {
+	return len(s.Scopes) > 0
+}
+
+// returns when the session expires, counting the idle timeout. Zero means never
+func (s *Session) Deadline() time.Time {
+	deadline := s.Expires
+	if idle := getConfig().Idle_timeout; idle != 0 && !s.IsAPIToken() {
+		if idleDeadline := s.LastSeen.Add(idle); deadline.IsZero() || idleDeadline.Before(deadline) {
+			deadline = idleDeadline
+		}
+	}
+	return deadline
+}
+
+// reports whether credentials must be re-entered before a destructive action
+func (s *Session) NeedsStepUp() bool {
+	window := getConfig().Step_up_window
+	if window == 0 {
+		return false
+	}
+	authenticated := s.Authenticated
+	if authenticated.IsZero() {
+		authenticated = s.Created
+	}
+	return time.Since(authenticated) > window
+}
+
+func (s *Session) Expired() bool {
+	deadline := s.Deadline()
+	return !deadline.IsZero() && time.Now().After(deadline)
+}
+
+// a backend that sessions are kept in. Shared backends let several goldfish instances share sessions
+type Store interface {
+	// returns nil if the session does not exist
+	Get(hash string) (*Session, error)
+	Put(s *Session) error
+	Delete(hash string) error
+	List() ([]*Session, error)
+}
+
+var (
+	store     Store = newMemoryStore()
+	conf            = config.SessionConfig{}
+	storeLock       = new(sync.RWMutex)
+)
+
+func Configure(c *config.SessionConfig) error {
+	var s Store
+	var err error
+	switch c.Store {
+	case "", "memory":
+		s = newMemoryStore()
+		log.Println("[WARN ]: Sessions are kept in memory, so restarting goldfish logs everyone out, " +
+			"and instances behind a load balancer don't share them. Set session.store to \"redis\" to share them")
+	case "file":
+		s, err = newFileStore(c.File_path)
+	case "redis":
+		s, err = newRedisStore(c.Redis_address, c.Redis_password, c.Redis_db)
+	default:
+		err = errors.New("Unknown session store: " + c.Store)
+	}
+	if err != nil {
+		return err
+	}
+
+	storeLock.Lock()
+	defer storeLock.Unlock()
+	store = s
+	conf = *c
+	return nil
+}
+
+// releases the store's connection, if it keeps one open
+func Close() error {
+	if c, ok := getStore().(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// the store in use, and whether it can be reached
+func Health() (string, error) {
+	name := getConfig().Store
+	if name == "" {
+		name = "memory"
+	}
+	if p, ok := getStore().(interface{ Ping() error }); ok {
+		return name, p.Ping()
+	}
+	return name, nil
+}
+
+// reports whether sessions outlive a restart of goldfish
+func Persistent() bool {
+	_, memory := getStore().(*memoryStore)
+	return !memory
+}
+
+func getStore() Store {
+	storeLock.RLock()
+	defer storeLock.RUnlock()
+	return store
+}
+
+func getConfig() config.SessionConfig {
+	storeLock.RLock()
+	defer storeLock.RUnlock()
+	return conf
+}
+
+// a session lasts as long as its token, the configured ttl, and the absolute timeout all allow
+// api tokens are meant to be long-lived, so only their vault token's ttl applies
+func expiry(s *Session, tokenTTL time.Duration) time.Time {
+	c := getConfig()
+	if s.IsAPIToken() {
+		c = config.SessionConfig{}
+	}
+	now := time.Now()
+
+	var expires time.Time
+	limit := func(t time.Time) {
+		if expires.IsZero() || t.Before(expires) {
+			expires = t
+		}
+	}
+	if tokenTTL > 0 {
+		limit(now.Add(tokenTTL))
+	}
+	if c.Ttl > 0 {
+		limit(now.Add(c.Ttl))
+	}
+	if c.Absolute_timeout > 0 {
+		limit(s.Created.Add(c.Absolute_timeout))
+	}
+	return expires
+}
+
+func Hash(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+// creates a session for a vault token, returning the id to hand to the user
+// tokenTTL is the remaining lifetime of the token, zero if it never expires
+func New(token, transit, cluster, namespace, displayName, owner, backend, accessor, sourceIP string, tokenTTL time.Duration) (string, error) {
+	return create(Prefix, &Session{
+		Token:          token,
+		Transit:        transit,
+		Cluster:        cluster,
+		Namespace:      namespace,
+		LoginNamespace: namespace,
+		DisplayName:    displayName,
+		Owner:          owner,
+		Backend:        backend,
+		Accessor:       accessor,
+		SourceIP:       sourceIP,
+	}, tokenTTL)
+}
+
+// creates an api token for a vault token, returning the id to hand to the admin that minted it
+// the owner is whoever minted it, and the accessor lets the vault token be revoked along with it
+func NewAPIToken(token, transit, name, owner, accessor string, scopes []string, tokenTTL time.Duration) (string, error) {
+	if len(scopes) == 0 {
+		return "", errors.New("An api token needs at least one scope")
+	}
+	if !Persistent() {
+		return "", errors.New("API tokens need a file or redis session store")
+	}
+	return create(APIPrefix, &Session{
+		Token:       token,
+		Transit:     transit,
+		DisplayName: name,
+		Owner:       owner,
+		Scopes:      scopes,
+		Accessor:    accessor,
+	}, tokenTTL)
+}
+
+func create(prefix string, s *Session, tokenTTL time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := prefix + base64.RawURLEncoding.EncodeToString(b)
+	if err := s.SetVaultToken(id, s.Token); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	s.Hash = Hash(id)
+	s.Created = now
+	s.LastSeen = now
+	s.Authenticated = now
+	s.Expires = expiry(s, tokenTTL)
+	if err := getStore().Put(s); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// returns the session or api token for an id, or nil if it does not exist or has expired
+func Get(id string) (*Session, error) {
+	isAPIToken := strings.HasPrefix(id, APIPrefix)
+	if !isAPIToken && !strings.HasPrefix(id, Prefix) {
+		return nil, nil
+	}
+	s, err := getStore().Get(Hash(id))
+	if err != nil || s == nil || s.IsAPIToken() != isAPIToken {
+		return nil, err
+	}
+	if s.Expired() {
+		getStore().Delete(s.Hash)
+		return nil, nil
+	}
+	return s, nil
+}
+
+// sealed tokens carry this prefix. Sessions from older versions hold the token as it is, until it is next sealed
+const sealedPrefix = "sealed:"
+
+// the key a session's token is sealed with. The store only holds the id's sha256, which this can't be derived from
+func sealKey(id string) []byte {
+	mac := hmac.New(sha256.New, []byte(id))
+	mac.Write([]byte("goldfish session token"))
+	return mac.Sum(nil)
+}
+
+// seals the vault token, or its transit cipher, with the session's id
+func (s *Session) SetVaultToken(id, token string) error {
+	block, err := aes.NewCipher(sealKey(id))
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	s.Token = sealedPrefix + base64.RawStdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(token), []byte(Hash(id))))
+	return nil
+}
+
+// the vault token, or its transit cipher if Transit is set, unsealed with the session's id
+func (s *Session) VaultToken(id string) (string, error) {
+	if !strings.HasPrefix(s.Token, sealedPrefix) {
+		return s.Token, nil
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(s.Token, sealedPrefix))
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(sealKey(id))
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("Sealed session token is too short")
+	}
+	token, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(Hash(id)))
+	if err != nil {
+		return "", errors.New("Session token could not be unsealed")
+	}
+	return string(token), nil
+}
+
+// reports whether the session's token is from an older version, and should be sealed when next saved
+func (s *Session) Unsealed() bool {
+	return !strings.HasPrefix(s.Token, sealedPrefix)
+}
+
+func Save(s *Session) error {
+	return getStore().Put(s)
+}
+
+// pushes a session's expiry back after its token was renewed, within the configured limits
+func Extend(s *Session, tokenTTL time.Duration) error {
+	s.Expires = expiry(s, tokenTTL)
+	return getStore().Put(s)
+}
+
+// reports whether a session's last seen time is due to be written back
+func (s *Session) TouchDue() bool {
+	return time.Since(s.LastSeen) >= touchInterval
+}
+
+// records that the session's credentials were just re-entered
+func Reauthenticated(s *Session) error {
+	s.Authenticated = time.Now()
+	return getStore().Put(s)
+}
+
+// records that a session was used
+func Touch(s *Session) error {
+	s.LastSeen = time.Now()
+	return getStore().Put(s)
+}
+
+func Delete(id string) error {
+	return getStore().Delete(Hash(id))
+}
+
+// deletes a session by its hash, which is all that is shown when listing sessions
+func DeleteHash(hash string) error {
+	return getStore().Delete(hash)
+}
+
+// deletes every session and api token, expired or not, returning how many there were
+func DeleteAll() (int, error) {
+	all, err := getStore().List()
+	if err != nil {
+		return 0, err
+	}
+	for i, s := range all {
+		if err := getStore().Delete(s.Hash); err != nil {
+			return i, err
+		}
+	}
+	return len(all), nil
+}
+
+// returns every unexpired session
+func List() ([]*Session, error) {
+	return ListOwner("")
+}
+
+// returns every unexpired session belonging to owner, or every session if owner is empty
+func ListOwner(owner string) ([]*Session, error) {
+	return list(func(s *Session) bool {
+		return !s.IsAPIToken() && (owner == "" || s.Owner == owner)
+	})
+}
+
+// returns every unexpired api token, whoever minted it
+func ListAPITokens() ([]*Session, error) {
+	return list(func(s *Session) bool {
+		return s.IsAPIToken()
+	})
+}
+
+func list(match func(*Session) bool) ([]*Session, error) {
+	all, err := getStore().List()
+	if err != nil {
+		return nil, err
+	}
+	sessions := all[:0]
+	for _, s := range all {
+		if !s.Expired() && match(s) {
+			sessions = append(sessions, s)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Created.Before(sessions[j].Created)
+	})
+	return sessions, nil
+}
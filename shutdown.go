@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/session"
+	"github.com/caiyeon/goldfish/systemd"
+	"github.com/caiyeon/goldfish/tracing"
+	"github.com/labstack/echo"
+)
+
+var (
+	servers         []*echo.Echo
+	shutdownTimeout time.Duration
+	serversLock     = new(sync.Mutex)
+)
+
+// servers are registered once their listeners are set up, so a shutdown can drain them
+func setServers(s []*echo.Echo, timeout time.Duration) {
+	serversLock.Lock()
+	defer serversLock.Unlock()
+	servers = s
+	shutdownTimeout = timeout
+}
+
+// stops accepting connections, and waits up to the shutdown timeout for in-flight requests
+// the session store is closed and queued spans exported afterwards, since draining requests may still be using them
+func shutdown() {
+	serversLock.Lock()
+	defer serversLock.Unlock()
+	systemd.Notify("STOPPING=1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	wg := new(sync.WaitGroup)
+	for _, e := range servers {
+		// the plain server also serves the redirect listener of a tls listener
+		for _, s := range []*http.Server{e.Server, e.TLSServer} {
+			wg.Add(1)
+			go func(s *http.Server) {
+				defer wg.Done()
+				if err := s.Shutdown(ctx); err != nil {
+					log.Println("[WARN ]: Some requests did not finish before the shutdown timeout:", err.Error())
+				}
+			}(s)
+		}
+	}
+	wg.Wait()
+	tracing.Flush()
+
+	if err := session.Close(); err != nil {
+		log.Println("[WARN ]: Could not close session store:", err.Error())
+	}
+}
+
+// a server closed by shutdown blocks here instead, until draining is done and the process exits
+func serveUntilShutdown(serve func() error) {
+	if err := serve(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	select {}
+}
@@ -32,3 +32,52 @@ func PostMessageWebhook(channel, main_text, attachment_text, webhook string) (er
 	}
 	return
 }
+
+// posts a change request with approve and reject buttons, which slack sends to goldfish's chatops callback
+// buttons only work with the incoming webhook of a slack app that has interactivity enabled
+func PostRequestWebhook(channel, main_text, attachment_text, hash, webhook string) (err error) {
+	payload, err := json.Marshal(
+		map[string]interface{}{
+			"channel":  channel,
+			"username": "Goldfish Vault UI",
+			"icon_url": icon_url,
+			"text":     main_text,
+			"attachments": []interface{}{
+				map[string]interface{}{
+					"mrkdwn_in":   []string{"text"},
+					"text":        attachment_text,
+					"callback_id": "goldfish_request",
+					"actions": []interface{}{
+						map[string]interface{}{
+							"name":  "approve",
+							"text":  "Approve",
+							"type":  "button",
+							"style": "primary",
+							"value": hash,
+						},
+						map[string]interface{}{
+							"name":  "reject",
+							"text":  "Reject",
+							"type":  "button",
+							"style": "danger",
+							"value": hash,
+							"confirm": map[string]interface{}{
+								"title":        "Reject this request?",
+								"text":         "The request and its approvals so far will be deleted",
+								"ok_text":      "Reject",
+								"dismiss_text": "Cancel",
+							},
+						},
+					},
+					"footer":      "<https://github.com/Caiyeon/goldfish|Goldfish Vault UI>",
+					"footer_icon": icon_url,
+					"ts":          time.Now().Unix(),
+				},
+			},
+		},
+	)
+	if err == nil {
+		_, err = http.Post(webhook, "application/json", bytes.NewReader(payload))
+	}
+	return
+}
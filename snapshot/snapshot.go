@@ -0,0 +1,159 @@
+// takes scheduled snapshots of vault's integrated (raft) storage, and keeps the most recent at a destination
+package snapshot
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/config"
+	"github.com/caiyeon/goldfish/schedule"
+	"github.com/caiyeon/goldfish/vault"
+)
+
+// recent runs kept for the status endpoint, the oldest are dropped first
+const maxRecent = 20
+
+// one snapshot taken, or attempted. Location is where it was stored
+type Run struct {
+	Name     string    `json:"name"`
+	Started  time.Time `json:"started"`
+	Seconds  float64   `json:"seconds"`
+	Size     int64     `json:"size"`
+	Location string    `json:"location,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type Status struct {
+	Configured bool              `json:"configured"`
+	Schedule   []schedule.Status `json:"schedule"`
+	Recent     []Run             `json:"recent"`
+	Stored     []string          `json:"stored"`
+	StoreError string            `json:"store_error,omitempty"`
+}
+
+var (
+	lock    = new(sync.Mutex)
+	current *config.RaftSnapshotConfig
+	recent  []Run
+)
+
+// schedules snapshots, replacing any scheduled before. A nil config stops them
+// may be called again at runtime, e.g. when the config file is reloaded
+func Configure(c *config.RaftSnapshotConfig) {
+	lock.Lock()
+	current = c
+	lock.Unlock()
+
+	if c == nil {
+		schedule.Replace("raft_snapshot", nil)
+		return
+	}
+	// the config file has already been validated
+	s, err := schedule.Parse(c.Schedule)
+	if err != nil {
+		log.Printf("[ERROR]: Raft snapshots not scheduled: %s\n", err.Error())
+		return
+	}
+	schedule.Replace("raft_snapshot", []schedule.Job{{
+		Name:     "raft snapshot",
+		Schedule: s,
+		// every instance has the schedule, so a lease lets one take each snapshot, and keeps it until the next
+		Run: func() error {
+			lease := time.Until(s.Next(time.Now())) + time.Minute
+			return vault.RunExclusive("raft_snapshot", lease, func() error { return Take(c) })
+		},
+	}})
+	log.Printf("[INFO ]: Raft snapshots scheduled %s\n", c.Schedule)
+}
+
+// takes a snapshot and stores it, then deletes the oldest beyond the number retained
+// the snapshot is spooled to a temporary file, since uploads need its size
+func Take(c *config.RaftSnapshotConfig) error {
+	run := Run{
+		Name:    namePrefix + time.Now().UTC().Format("20060102T150405Z") + nameSuffix,
+		Started: time.Now().UTC(),
+	}
+	err := take(c, &run)
+	run.Seconds = time.Since(run.Started).Seconds()
+	if err != nil {
+		run.Error = err.Error()
+		log.Printf("[ERROR]: Raft snapshot %s failed: %s\n", run.Name, err.Error())
+	} else {
+		log.Printf("[INFO ]: Raft snapshot stored at %s (%d bytes)\n", run.Location, run.Size)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	recent = append(recent, run)
+	if len(recent) > maxRecent {
+		recent = recent[len(recent)-maxRecent:]
+	}
+	return err
+}
+
+func take(c *config.RaftSnapshotConfig, run *Run) error {
+	if !vault.Bootstrapped() {
+		return errors.New("goldfish is not bootstrapped")
+	}
+	s, err := newStore(c)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile("", "goldfish-snapshot-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if run.Size, err = vault.RaftSnapshot(f); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return err
+	}
+	if run.Location, err = s.put(run.Name, f); err != nil {
+		return err
+	}
+
+	if c.Retain > 0 {
+		if err := prune(s, c.Retain); err != nil {
+			return errors.New("snapshot stored, but older snapshots could not be deleted: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// the schedule, recent runs, and the snapshots at the destination, newest last
+func GetStatus() Status {
+	lock.Lock()
+	c := current
+	status := Status{
+		Configured: c != nil,
+		Recent:     make([]Run, len(recent)),
+		Stored:     make([]string, 0),
+	}
+	copy(status.Recent, recent)
+	lock.Unlock()
+
+	status.Schedule = schedule.List("raft_snapshot")
+	if c == nil {
+		return status
+	}
+	s, err := newStore(c)
+	if err == nil {
+		var names []string
+		if names, err = s.list(); err == nil && names != nil {
+			status.Stored = names
+		}
+	}
+	if err != nil {
+		status.StoreError = err.Error()
+	}
+	return status
+}
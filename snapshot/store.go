@@ -0,0 +1,191 @@
+package snapshot
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/caiyeon/goldfish/config"
+)
+
+// snapshots are named by when they were taken, so they sort oldest first
+const (
+	namePrefix = "vault-"
+	nameSuffix = ".snap"
+)
+
+// where snapshots are kept. Only files named as goldfish names snapshots are listed, and so pruned
+type store interface {
+	put(name string, f *os.File) (string, error)
+	list() ([]string, error)
+	remove(name string) error
+}
+
+func newStore(c *config.RaftSnapshotConfig) (store, error) {
+	if c.Local_dir != "" {
+		return &localStore{dir: c.Local_dir}, nil
+	}
+
+	cfg := aws.NewConfig().WithRegion(c.S3_region)
+	if c.S3_endpoint != "" {
+		// s3 compatible storage rarely serves buckets as subdomains
+		cfg = cfg.WithEndpoint(c.S3_endpoint).WithS3ForcePathStyle(true)
+	}
+	if c.S3_access_key_file != "" {
+		accessKey, err := readKey(c.S3_access_key_file)
+		if err != nil {
+			return nil, err
+		}
+		secretKey, err := readKey(c.S3_secret_key_file)
+		if err != nil {
+			return nil, err
+		}
+		cfg = cfg.WithCredentials(credentials.NewStaticCredentials(accessKey, secretKey, ""))
+	}
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Store{
+		client: s3.New(sess),
+		bucket: c.S3_bucket,
+		prefix: c.S3_prefix,
+	}, nil
+}
+
+func readKey(file string) (string, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	key := strings.TrimSpace(string(b))
+	if key == "" {
+		return "", errors.New(file + " is empty")
+	}
+	return key, nil
+}
+
+// deletes all but the most recent retain snapshots
+func prune(s store, retain int) error {
+	names, err := s.list()
+	if err != nil {
+		return err
+	}
+	for len(names) > retain {
+		if err := s.remove(names[0]); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+type localStore struct {
+	dir string
+}
+
+// written under a temporary name first, so a partial snapshot is never mistaken for a whole one
+func (s *localStore) put(name string, f *os.File) (string, error) {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.dir, name)
+	out, err := os.OpenFile(path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(out, f)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		os.Remove(path + ".tmp")
+		return "", err
+	}
+	return path, nil
+}
+
+func (s *localStore) list() ([]string, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		if !f.IsDir() && strings.HasPrefix(f.Name(), namePrefix) && strings.HasSuffix(f.Name(), nameSuffix) {
+			names = append(names, f.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *localStore) remove(name string) error {
+	return os.Remove(filepath.Join(s.dir, name))
+}
+
+type s3Store struct {
+	client *s3.S3
+	bucket string
+	prefix string
+}
+
+func (s *s3Store) put(name string, f *os.File) (string, error) {
+	_, err := s.client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.prefix + name),
+		Body:   f,
+	})
+	if err != nil {
+		return "", err
+	}
+	return "s3://" + s.bucket + "/" + s.prefix + name, nil
+}
+
+func (s *s3Store) list() ([]string, error) {
+	var names []string
+	input := &s3.ListObjectsInput{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.prefix + namePrefix),
+	}
+	for {
+		out, err := s.client.ListObjects(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range out.Contents {
+			name := strings.TrimPrefix(aws.StringValue(o.Key), s.prefix)
+			if strings.HasSuffix(name, nameSuffix) && !strings.Contains(name, "/") {
+				names = append(names, name)
+			}
+		}
+		if !aws.BoolValue(out.IsTruncated) || len(out.Contents) == 0 {
+			break
+		}
+		input.Marker = out.Contents[len(out.Contents)-1].Key
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *s3Store) remove(name string) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.prefix + name),
+	})
+	return err
+}
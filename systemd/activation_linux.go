@@ -0,0 +1,43 @@
+package systemd
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// file descriptors passed by socket activation start after stdin, stdout and stderr
+const listenFdsStart = 3
+
+// returns the sockets passed by a systemd .socket unit, in the order they are declared there
+// the env variables are cleared, so processes started by goldfish don't inherit them
+func Listeners() ([]net.Listener, error) {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+	defer os.Unsetenv("LISTEN_FDNAMES")
+
+	fds := os.Getenv("LISTEN_FDS")
+	if fds == "" || os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 0 {
+		return nil, errors.New("Invalid LISTEN_FDS: " + fds)
+	}
+
+	listeners := make([]net.Listener, 0, n)
+	for fd := listenFdsStart; fd < listenFdsStart+n; fd++ {
+		syscall.CloseOnExec(fd)
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		l, err := net.FileListener(f)
+		// the listener holds its own copy of the descriptor
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
@@ -0,0 +1,10 @@
+// +build !linux
+
+package systemd
+
+import "net"
+
+// socket activation is only available with systemd, which only runs on linux
+func Listeners() ([]net.Listener, error) {
+	return nil, nil
+}
@@ -0,0 +1,45 @@
+package systemd
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// sends a state such as "READY=1" to systemd. Returns false if not running under a Type=notify unit
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// how often systemd expects "WATCHDOG=1", or 0 if the unit has no WatchdogSec
+func WatchdogInterval() (time.Duration, error) {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if usec == "" || !forThisProcess("WATCHDOG_PID") {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, errors.New("Invalid WATCHDOG_USEC: " + usec)
+	}
+	return time.Duration(n) * time.Microsecond, nil
+}
+
+// systemd may set the pid along with these variables, in case they leak to a child process
+func forThisProcess(pidEnv string) bool {
+	pid := os.Getenv(pidEnv)
+	return pid == "" || pid == strconv.Itoa(os.Getpid())
+}
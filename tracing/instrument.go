@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+
+	"github.com/labstack/echo"
+)
+
+const contextKey = "tracing.span"
+
+// vault puts request_id first in its responses, so only the start of the body needs to be kept
+const bodyHeadSize = 256
+
+var requestIDPattern = regexp.MustCompile(`"request_id":\s*"([^"]+)"`)
+
+// starts a span for each request, continuing the caller's trace if it sent a traceparent header
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !enabled() {
+				return next(c)
+			}
+
+			req := c.Request()
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			span := StartRemote(req.Method+" "+route, KindServer, req.Header.Get("traceparent"))
+			c.Set(contextKey, span)
+
+			err := next(c)
+			if err != nil {
+				// let echo write the error response, so the status code is known
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			span.SetAttribute("http.method", req.Method)
+			span.SetAttribute("http.route", route)
+			span.SetAttribute("http.status_code", status)
+			if status >= http.StatusInternalServerError {
+				span.SetError(err)
+			}
+			span.End()
+			return nil
+		}
+	}
+}
+
+// the request's span, or nil if tracing is disabled
+func FromContext(c echo.Context) *Span {
+	span, _ := c.Get(contextKey).(*Span)
+	return span
+}
+
+type tracedTransport struct {
+	base   http.RoundTripper
+	parent *Span
+}
+
+// wraps a vault client's transport, so each call to vault is a span under parent
+// the traceparent header is sent along, so vault's audit log can record it if told to
+func Transport(base http.RoundTripper, parent *Span) http.RoundTripper {
+	if parent == nil {
+		return base
+	}
+	return &tracedTransport{base: base, parent: parent}
+}
+
+func (t *tracedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	span := Start("vault "+req.Method, KindClient, t.parent)
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("vault.path", req.URL.Path)
+	span.SetAttribute("server.address", req.URL.Host)
+
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	r.Header.Set("traceparent", span.Traceparent())
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		span.SetError(err)
+		span.End()
+		return nil, err
+	}
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetError(nil)
+	}
+
+	// the span ends when the body is closed, once vault's request id has been read
+	resp.Body = &tracedBody{ReadCloser: resp.Body, span: span}
+	return resp, nil
+}
+
+type tracedBody struct {
+	io.ReadCloser
+	span *Span
+	head []byte
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := bodyHeadSize - len(b.head); room > 0 {
+		if n < room {
+			room = n
+		}
+		b.head = append(b.head, p[:room]...)
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	if m := requestIDPattern.FindSubmatch(b.head); m != nil {
+		b.span.SetAttribute("vault.request_id", string(m[1]))
+	}
+	b.head = nil
+	b.span.End()
+	return b.ReadCloser.Close()
+}
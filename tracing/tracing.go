@@ -0,0 +1,338 @@
+package tracing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// span kinds and status codes, as numbered by otlp
+const (
+	KindServer = 2
+	KindClient = 3
+
+	statusError = 2
+)
+
+const (
+	batchSize     = 256
+	flushInterval = 5 * time.Second
+	exportTimeout = 10 * time.Second
+)
+
+type Span struct {
+	TraceID  [16]byte
+	SpanID   [8]byte
+	ParentID [8]byte
+	Name     string
+	Kind     int
+	Start    time.Time
+
+	sampled bool
+	lock    sync.Mutex
+	attrs   map[string]interface{}
+	failed  bool
+	end     time.Time
+}
+
+var (
+	endpoint string
+	headers  map[string]string
+	ratio    float64
+	spans    chan *Span
+	flushed  chan chan struct{}
+	confLock = new(sync.RWMutex)
+)
+
+// starts exporting spans to an otlp/http collector. An empty endpoint leaves tracing disabled
+// ratio is the fraction of new traces that are sampled. Traces started by a caller keep the caller's choice
+func Configure(otlpEndpoint string, otlpHeaders map[string]string, sampleRatio float64) {
+	if otlpEndpoint == "" {
+		return
+	}
+	confLock.Lock()
+	defer confLock.Unlock()
+	if spans != nil {
+		return
+	}
+	endpoint = strings.TrimSuffix(otlpEndpoint, "/") + "/v1/traces"
+	headers = otlpHeaders
+	ratio = sampleRatio
+	spans = make(chan *Span, 4*batchSize)
+	flushed = make(chan chan struct{})
+	go export()
+}
+
+func enabled() bool {
+	confLock.RLock()
+	defer confLock.RUnlock()
+	return spans != nil
+}
+
+// starts a span as a child of parent, or as the root of a new trace if parent is nil
+// returns nil if tracing is disabled, which every method of Span accepts
+func Start(name string, kind int, parent *Span) *Span {
+	if !enabled() {
+		return nil
+	}
+	s := &Span{
+		Name:  name,
+		Kind:  kind,
+		Start: time.Now(),
+		attrs: make(map[string]interface{}),
+	}
+	rand.Read(s.SpanID[:])
+	if parent != nil {
+		s.TraceID, s.ParentID, s.sampled = parent.TraceID, parent.SpanID, parent.sampled
+	} else {
+		rand.Read(s.TraceID[:])
+		s.sampled = sample(s.TraceID)
+	}
+	return s
+}
+
+// continues the trace in a w3c traceparent header, e.g. from a load balancer or the browser
+// falls back to a new trace if the header is missing or malformed
+func StartRemote(name string, kind int, traceparent string) *Span {
+	if parent := parseTraceparent(traceparent); parent != nil {
+		return Start(name, kind, parent)
+	}
+	return Start(name, kind, nil)
+}
+
+// nil unless the header is version 00, with non-zero ids
+func parseTraceparent(header string) *Span {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return nil
+	}
+	parent := &Span{}
+	if _, err := hex.Decode(parent.TraceID[:], []byte(parts[1])); err != nil {
+		return nil
+	}
+	if _, err := hex.Decode(parent.SpanID[:], []byte(parts[2])); err != nil {
+		return nil
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil || len(parts[3]) != 2 || parent.TraceID == [16]byte{} || parent.SpanID == [8]byte{} {
+		return nil
+	}
+	parent.sampled = flags&1 == 1
+	return parent
+}
+
+// the trace id decides, so every service sampling at the same ratio keeps the same traces
+func sample(traceID [16]byte) bool {
+	confLock.RLock()
+	defer confLock.RUnlock()
+	if ratio >= 1 {
+		return true
+	}
+	return float64(binary.BigEndian.Uint64(traceID[8:])>>11)/(1<<53) < ratio
+}
+
+// the w3c header that makes the next service's spans children of this one
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	flags := "00"
+	if s.sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%x-%x-%s", s.TraceID, s.SpanID, flags)
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.attrs[key] = value
+}
+
+// marks the span as failed, recording the error's message
+func (s *Span) SetError(err error) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failed = true
+	if err != nil {
+		s.attrs["error.message"] = err.Error()
+	}
+}
+
+// finishes the span and queues it for export. Later calls do nothing
+// spans are dropped rather than blocking requests if the collector falls behind
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	ended := !s.end.IsZero()
+	if !ended {
+		s.end = time.Now()
+	}
+	s.lock.Unlock()
+	if ended || !s.sampled {
+		return
+	}
+
+	select {
+	case spans <- s:
+	default:
+	}
+}
+
+// exports any queued spans, waiting at most the export timeout. Called on shutdown
+func Flush() {
+	if !enabled() {
+		return
+	}
+	done := make(chan struct{})
+	select {
+	case flushed <- done:
+		<-done
+	case <-time.After(exportTimeout):
+	}
+}
+
+func export() {
+	batch := make([]*Span, 0, batchSize)
+	ticker := time.NewTicker(flushInterval)
+	for {
+		select {
+		case s := <-spans:
+			if batch = append(batch, s); len(batch) < batchSize {
+				continue
+			}
+		case <-ticker.C:
+		case done := <-flushed:
+			for len(spans) > 0 {
+				batch = append(batch, <-spans)
+			}
+			send(batch)
+			batch = batch[:0]
+			close(done)
+			continue
+		}
+		send(batch)
+		batch = batch[:0]
+	}
+}
+
+func send(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+	body, err := json.Marshal(encode(batch))
+	if err != nil {
+		log.Println("[ERROR]: Could not encode trace spans:", err.Error())
+		return
+	}
+
+	confLock.RLock()
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err == nil {
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+	}
+	confLock.RUnlock()
+	if err != nil {
+		log.Println("[ERROR]: Could not export trace spans:", err.Error())
+		return
+	}
+
+	client := &http.Client{Timeout: exportTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Println("[WARN ]: Could not export trace spans:", err.Error())
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Printf("[WARN ]: Trace collector rejected %d spans with status %d\n", len(batch), resp.StatusCode)
+	}
+}
+
+// the otlp/json encoding of a batch of spans, in which ids are hex and 64 bit numbers are strings
+func encode(batch []*Span) map[string]interface{} {
+	encoded := make([]map[string]interface{}, 0, len(batch))
+	for _, s := range batch {
+		s.lock.Lock()
+		attrs := make([]map[string]interface{}, 0, len(s.attrs))
+		for _, k := range sortedKeys(s.attrs) {
+			attrs = append(attrs, attribute(k, s.attrs[k]))
+		}
+		span := map[string]interface{}{
+			"traceId":           hex.EncodeToString(s.TraceID[:]),
+			"spanId":            hex.EncodeToString(s.SpanID[:]),
+			"name":              s.Name,
+			"kind":              s.Kind,
+			"startTimeUnixNano": strconv.FormatInt(s.Start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.end.UnixNano(), 10),
+			"attributes":        attrs,
+		}
+		if s.ParentID != [8]byte{} {
+			span["parentSpanId"] = hex.EncodeToString(s.ParentID[:])
+		}
+		if s.failed {
+			span["status"] = map[string]interface{}{"code": statusError}
+		}
+		s.lock.Unlock()
+		encoded = append(encoded, span)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": []interface{}{attribute("service.name", "goldfish")},
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "github.com/caiyeon/goldfish/tracing"},
+						"spans": encoded,
+					},
+				},
+			},
+		},
+	}
+}
+
+func attribute(key string, value interface{}) map[string]interface{} {
+	var v map[string]interface{}
+	switch value := value.(type) {
+	case int:
+		v = map[string]interface{}{"intValue": strconv.Itoa(value)}
+	case bool:
+		v = map[string]interface{}{"boolValue": value}
+	default:
+		v = map[string]interface{}{"stringValue": fmt.Sprint(value)}
+	}
+	return map[string]interface{}{"key": key, "value": v}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
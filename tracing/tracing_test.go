@@ -0,0 +1,32 @@
+package tracing
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTraceparent(t *testing.T) {
+	Convey("Traceparent headers should round trip", t, func() {
+		header := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+		parent := parseTraceparent(header)
+		So(parent, ShouldNotBeNil)
+		So(parent.sampled, ShouldBeTrue)
+		So(parent.Traceparent(), ShouldEqual, header)
+	})
+
+	Convey("Invalid traceparent headers should be ignored", t, func() {
+		So(parseTraceparent(""), ShouldBeNil)
+		So(parseTraceparent("01-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"), ShouldBeNil)
+		So(parseTraceparent("00-00000000000000000000000000000000-b7ad6b7169203331-01"), ShouldBeNil)
+		So(parseTraceparent("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-zz"), ShouldBeNil)
+	})
+
+	Convey("Spans should be nil and harmless while tracing is disabled", t, func() {
+		span := Start("GET /v1/health", KindServer, nil)
+		So(span, ShouldBeNil)
+		span.SetAttribute("http.status_code", 200)
+		span.End()
+		So(span.Traceparent(), ShouldEqual, "")
+	})
+}
@@ -5,6 +5,12 @@ path "secret/goldfish*" {
   capabilities = ["read", "update"]
 }
 
+# [mandatory]
+# goldfish's own records, and the leases that run scheduled jobs on one goldfish instance only
+path "secret/goldfish/state/*" {
+  capabilities = ["create", "read", "update", "delete", "list"]
+}
+
 
 # [optional]
 # to enable transit encryption:
@@ -17,3 +23,12 @@ path "transit/encrypt/goldfish" {
 path "transit/decrypt/goldfish" {
   capabilities = ["read", "update"]
 }
+
+# [optional]
+# to delegate change request approvals, which are made to and from vault identity entities
+path "identity/entity/name/*" {
+  capabilities = ["read"]
+}
+path "identity/entity/id/*" {
+  capabilities = ["read"]
+}
@@ -1,7 +1,7 @@
 #!/bin/bash
 
 echo 'Downloading go...'
-curl -s https://storage.googleapis.com/golang/go1.8.linux-amd64.tar.gz -o go.tar.gz
+curl -s https://storage.googleapis.com/golang/go1.18.10.linux-amd64.tar.gz -o go.tar.gz
 sudo tar -xvf go.tar.gz
 rm go.tar.gz
 sudo rm -r /usr/local/go
@@ -14,10 +14,12 @@ echo "export GOROOT=/usr/local/go" >> /home/vagrant/.profile
 mkdir /home/vagrant/go
 cat /home/vagrant/.profile | grep "GOPATH" || \
 echo 'export GOPATH=/home/vagrant/go
+export GO111MODULE=off
 export PATH=$PATH:/usr/local/go/bin:/vagrant/bin' >> /home/vagrant/.profile
 
 export GOROOT=/usr/local/go
 export GOPATH=/home/vagrant/go
+export GO111MODULE=off
 export PATH=$PATH:/usr/local/go/bin:/vagrant/bin
 
 echo 'Downloading goldfish...'
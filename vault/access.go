@@ -0,0 +1,208 @@
+package vault
+
+import (
+	"sort"
+	"sync"
+)
+
+// a mount the user's token can see, and what it may do at the mount's top level
+type MountAccess struct {
+	Path        string `json:"path"`
+	Type        string `json:"type"`
+	Version     string `json:"version,omitempty"`
+	Description string `json:"description"`
+	// checked where the mount's secrets are listed, i.e. below metadata/ for kv version 2
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// what a token can reach: goldfish's features, and the secret and auth mounts vault shows it
+type Access struct {
+	Features   map[string]bool `json:"features"`
+	Mounts     []MountAccess   `json:"mounts"`
+	AuthMounts []MountAccess   `json:"auth_mounts"`
+}
+
+// the capabilities the token has on each path, asked of vault in one request
+// vaults that don't answer for each path, i.e. before 0.10, are asked about the paths one at a time
+func (auth *AuthInfo) CapabilitiesSelfBatch(paths []string) (map[string][]string, error) {
+	result := make(map[string][]string, len(paths))
+	if len(paths) == 0 {
+		return result, nil
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	r := client.NewRequest("POST", "/v1/sys/capabilities-self")
+	if err := r.SetJSONBody(map[string]interface{}{"paths": paths}); err != nil {
+		return nil, err
+	}
+	resp, err := client.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil && (resp == nil || resp.StatusCode != 400) {
+		return nil, err
+	}
+
+	var body map[string]interface{}
+	if err == nil {
+		if err := resp.DecodeJSON(&body); err != nil {
+			return nil, err
+		}
+	}
+	data, _ := body["data"].(map[string]interface{})
+	var missing []string
+	for _, path := range paths {
+		raw, ok := body[path].([]interface{})
+		if !ok {
+			raw, ok = data[path].([]interface{})
+		}
+		if !ok {
+			missing = append(missing, path)
+			continue
+		}
+		capabilities := make([]string, 0, len(raw))
+		for _, c := range raw {
+			if s, ok := c.(string); ok {
+				capabilities = append(capabilities, s)
+			}
+		}
+		result[path] = capabilities
+	}
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var failed error
+	for _, path := range missing {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			capabilities, err := auth.CapabilitiesSelf(path)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				failed = err
+				return
+			}
+			result[path] = capabilities
+		}(path)
+	}
+	wg.Wait()
+	return result, failed
+}
+
+// reports which features vault's policies let the user use, with one capabilities check for all of them
+// a failed check allows none of the features that needed it
+func (auth *AuthInfo) CanUseAll(features map[string]Feature) map[string]bool {
+	allowed, _ := auth.checkAccess(features, nil)
+	return allowed
+}
+
+// the features the user may use and the mounts their token can see, with a single capabilities check for both
+// vaults without the mount listing of vault's own ui, i.e. before 0.10, have no mounts in the summary
+func (auth *AuthInfo) Access(features map[string]Feature) (Access, error) {
+	mounts, authMounts, err := auth.visibleMounts()
+	if err != nil {
+		return Access{}, err
+	}
+	allowed, capabilities := auth.checkAccess(features, mounts)
+	for i := range mounts {
+		mounts[i].Capabilities = capabilities[mountListPath(mounts[i])]
+	}
+	return Access{Features: allowed, Mounts: mounts, AuthMounts: authMounts}, nil
+}
+
+func (auth *AuthInfo) checkAccess(features map[string]Feature, mounts []MountAccess) (map[string]bool, map[string][]string) {
+	allowed := make(map[string]bool, len(features))
+	var paths []string
+	for name, f := range features {
+		switch {
+		case f.Disabled || (f.OwnCluster && auth.Cluster != ""):
+			allowed[name] = false
+		case f.Method == "":
+			allowed[name] = true
+		default:
+			paths = append(paths, f.Path)
+		}
+	}
+	for _, m := range mounts {
+		paths = append(paths, mountListPath(m))
+	}
+
+	capabilities, err := auth.CapabilitiesSelfBatch(paths)
+	if err != nil {
+		capabilities = map[string][]string{}
+	}
+	for name, f := range features {
+		if _, done := allowed[name]; !done {
+			allowed[name] = capabilitiesAllow(capabilities[f.Path], rawMethodCapabilities[f.Method])
+		}
+	}
+	return allowed, capabilities
+}
+
+// the secret and auth mounts the token has any access to, read from the endpoint vault's own ui uses
+func (auth *AuthInfo) visibleMounts() ([]MountAccess, []MountAccess, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := client.Logical().Read("sys/internal/ui/mounts")
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp == nil {
+		return []MountAccess{}, []MountAccess{}, nil
+	}
+	secret, _ := resp.Data["secret"].(map[string]interface{})
+	authMounts, _ := resp.Data["auth"].(map[string]interface{})
+	return parseMounts(secret, ""), parseMounts(authMounts, "auth/"), nil
+}
+
+func parseMounts(raw map[string]interface{}, prefix string) []MountAccess {
+	mounts := make([]MountAccess, 0, len(raw))
+	for path, v := range raw {
+		m, _ := v.(map[string]interface{})
+		t, _ := m["type"].(string)
+		description, _ := m["description"].(string)
+		mount := MountAccess{Path: prefix + path, Type: t, Description: description}
+		if t == "kv" || t == "generic" {
+			options, _ := m["options"].(map[string]interface{})
+			mount.Version, _ = options["version"].(string)
+			if mount.Version != "2" {
+				mount.Version = "1"
+			}
+		}
+		mounts = append(mounts, mount)
+	}
+	sort.Slice(mounts, func(i, j int) bool { return mounts[i].Path < mounts[j].Path })
+	return mounts
+}
+
+// where a mount's secrets are listed from
+func mountListPath(m MountAccess) string {
+	if m.Version == "2" {
+		return m.Path + "metadata/"
+	}
+	return m.Path
+}
+
+// whether the capabilities include any of allowed. root allows everything, and deny nothing
+func capabilitiesAllow(capabilities, allowed []string) bool {
+	for _, capability := range capabilities {
+		if capability == "deny" {
+			return false
+		}
+		if capability == "root" {
+			return true
+		}
+		for _, a := range allowed {
+			if capability == a {
+				return true
+			}
+		}
+	}
+	return false
+}
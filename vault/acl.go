@@ -0,0 +1,130 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	vaultcore "github.com/hashicorp/vault/vault"
+)
+
+// the capability each operation needs. Vault checks list on the path with its trailing slash
+var operationCapabilities = map[string]string{
+	"create": "create",
+	"read":   "read",
+	"update": "update",
+	"delete": "delete",
+	"list":   "list",
+	"sudo":   "sudo",
+}
+
+// the outcome of evaluating policies against a path, as vault's acl would
+// Matched is the rule deciding it: the exact path if a policy names it, otherwise the longest matching glob
+type ACLSimulation struct {
+	Allowed      bool      `json:"allowed"`
+	Capabilities []string  `json:"capabilities"`
+	Matched      *ACLMatch `json:"matched"`
+}
+
+type ACLMatch struct {
+	Path     string   `json:"path"`
+	Glob     bool     `json:"glob"`
+	Policies []string `json:"policies"`
+}
+
+// evaluates existing policies, read with the user's token, and pasted ones against path and operation
+// pasted policies are named "pasted-1", "pasted-2" and so on, in the order given
+func (auth AuthInfo) SimulateACL(names, rules []string, path, operation string) (*ACLSimulation, error) {
+	capability, ok := operationCapabilities[operation]
+	if !ok {
+		return nil, errors.New("Operation must be one of create, read, update, delete, list, sudo")
+	}
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return nil, errors.New("Path is required")
+	}
+	if len(names) == 0 && len(rules) == 0 {
+		return nil, errors.New("At least one policy name or pasted policy is required")
+	}
+
+	var policies []*vaultcore.Policy
+	for _, name := range names {
+		raw, err := auth.GetPolicy(name)
+		if err != nil {
+			return nil, err
+		}
+		if raw == "" && name != "root" {
+			return nil, errors.New("Policy " + name + " does not exist")
+		}
+		// the root policy has no rules, vault grants it everything
+		p := &vaultcore.Policy{Name: name}
+		if name != "root" {
+			if p, err = vaultcore.Parse(raw); err != nil {
+				return nil, fmt.Errorf("Policy %s: %s", name, err.Error())
+			}
+			p.Name = name
+		}
+		policies = append(policies, p)
+	}
+	for i, raw := range rules {
+		p, err := vaultcore.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("Pasted policy %d: %s", i+1, err.Error())
+		}
+		p.Name = fmt.Sprintf("pasted-%d", i+1)
+		policies = append(policies, p)
+	}
+
+	acl, err := vaultcore.NewACL(policies)
+	if err != nil {
+		return nil, err
+	}
+	result := &ACLSimulation{
+		Capabilities: acl.Capabilities(path),
+		Matched:      matchedRule(policies, path),
+	}
+	for _, c := range result.Capabilities {
+		if c == capability || c == "root" {
+			result.Allowed = true
+		}
+	}
+	return result, nil
+}
+
+// finds the rule vault's acl decides by: an exact path rule wins over globs, and a longer glob over a shorter one
+func matchedRule(policies []*vaultcore.Policy, path string) *ACLMatch {
+	var match *ACLMatch
+	for _, p := range policies {
+		for _, pc := range p.Paths {
+			if (!pc.Glob && pc.Prefix != path) || (pc.Glob && !strings.HasPrefix(path, pc.Prefix)) {
+				continue
+			}
+			better := match == nil ||
+				(match.Glob && !pc.Glob) ||
+				(match.Glob && pc.Glob && len(pc.Prefix) > len(match.Path))
+			if better {
+				match = &ACLMatch{Path: pc.Prefix, Glob: pc.Glob}
+			}
+			if match.Path == pc.Prefix && match.Glob == pc.Glob && !contains(match.Policies, p.Name) {
+				match.Policies = append(match.Policies, p.Name)
+			}
+		}
+	}
+	if match != nil {
+		if match.Glob {
+			match.Path += "*"
+		}
+		sort.Strings(match.Policies)
+	}
+	return match
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
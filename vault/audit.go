@@ -0,0 +1,69 @@
+package vault
+
+import (
+	"net/http"
+	"strings"
+)
+
+// headers sent with every call made on a user's behalf, so vault's audit log can be traced back to them
+// vault only records request headers it is told to, e.g.
+// vault write sys/config/auditing/request-headers/x-goldfish-request-id hmac=false
+const (
+	AuditRequestIDHeader = "X-Goldfish-Request-Id"
+	auditUserHeader      = "X-Goldfish-User"
+	auditAccessorHeader  = "X-Goldfish-Accessor"
+)
+
+// who a request to goldfish was made by. User and Accessor are empty for raw tokens and ciphers
+// Node, if set, records which vault node served the request's calls
+type AuditInfo struct {
+	RequestID string
+	User      string
+	Accessor  string
+	Node      *ServedNode
+}
+
+type auditTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+func newAuditTransport(base http.RoundTripper, audit *AuditInfo) http.RoundTripper {
+	if audit == nil {
+		return base
+	}
+	headers := make(http.Header)
+	for name, value := range map[string]string{
+		AuditRequestIDHeader: audit.RequestID,
+		auditUserHeader:      audit.User,
+		auditAccessorHeader:  audit.Accessor,
+	} {
+		if value = headerSafe(value); value != "" {
+			headers.Set(name, value)
+		}
+	}
+	return &auditTransport{base: base, headers: headers}
+}
+
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+len(t.headers))
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	for k, v := range t.headers {
+		r.Header[k] = v
+	}
+	return t.base.RoundTrip(r)
+}
+
+// display names come from auth backends, and a newline in one would fail the whole request
+func headerSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -3,6 +3,7 @@ package vault
 import (
 	"encoding/base64"
 	"errors"
+	"strings"
 
 	"github.com/hashicorp/vault/api"
 )
@@ -12,6 +13,8 @@ func (auth *AuthInfo) Clear() {
 	auth.Type = ""
 	auth.ID = ""
 	auth.Pass = ""
+	auth.Cluster = ""
+	auth.Namespace = ""
 }
 
 func (auth AuthInfo) RevokeSelf() error {
@@ -22,19 +25,30 @@ func (auth AuthInfo) RevokeSelf() error {
 	return client.Auth().Token().RevokeSelf("")
 }
 
+// sessions on other clusters are encrypted as "<cluster>\n<token>", so the cluster can't be swapped
+const clusterSeparator = "\n"
+
 // encrypt auth details with transit backend
 func (auth *AuthInfo) EncryptAuth() error {
+	c := GetConfig()
+	return auth.encryptAuth(c.TransitBackend, c.ServerTransitKey)
+}
+
+func (auth *AuthInfo) encryptAuth(backend, key string) error {
 	client, err := NewGoldfishVaultClient()
 	if err != nil {
 		return err
 	}
 
-	c := GetConfig()
+	plaintext := auth.ID
+	if auth.Cluster != "" {
+		plaintext = auth.Cluster + clusterSeparator + auth.ID
+	}
 
 	resp, err := client.Logical().Write(
-		c.TransitBackend + "/encrypt/" + c.ServerTransitKey,
+		backend+"/encrypt/"+key,
 		map[string]interface{}{
-			"plaintext": base64.StdEncoding.EncodeToString([]byte(auth.ID)),
+			"plaintext": base64.StdEncoding.EncodeToString([]byte(plaintext)),
 		})
 	if err != nil {
 		return err
@@ -51,15 +65,18 @@ func (auth *AuthInfo) EncryptAuth() error {
 
 // decrypt auth details with transit backend
 func (auth *AuthInfo) DecryptAuth() error {
+	c := GetConfig()
+	return auth.decryptAuth(c.TransitBackend, c.ServerTransitKey)
+}
+
+func (auth *AuthInfo) decryptAuth(backend, key string) error {
 	client, err := NewGoldfishVaultClient()
 	if err != nil {
 		return err
 	}
 
-	c := GetConfig()
-
 	resp, err := client.Logical().Write(
-		c.TransitBackend + "/decrypt/" + c.ServerTransitKey,
+		backend+"/decrypt/"+key,
 		map[string]interface{}{
 			"ciphertext": auth.ID,
 		})
@@ -78,6 +95,10 @@ func (auth *AuthInfo) DecryptAuth() error {
 	}
 
 	auth.ID = string(rawbytes)
+	auth.Cluster = ""
+	if parts := strings.SplitN(auth.ID, clusterSeparator, 2); len(parts) == 2 {
+		auth.Cluster, auth.ID = parts[0], parts[1]
+	}
 	return nil
 }
 
@@ -0,0 +1,42 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// bookmarks are kept under goldfish's state path, so users need no vault path of their own
+// owners are hashed, since they may be user names
+func bookmarksKey(owner string) string {
+	sum := sha256.Sum256([]byte(owner))
+	return "bookmarks/" + hex.EncodeToString(sum[:])
+}
+
+// returns the owner's bookmarked paths, in the order they were added
+func GetBookmarks(owner string) ([]string, error) {
+	resp, err := ReadState(bookmarksKey(owner))
+	if err != nil || resp == nil {
+		return []string{}, err
+	}
+
+	raw, _ := resp.Data["paths"].([]interface{})
+	paths := make([]string, 0, len(raw))
+	for _, p := range raw {
+		if path, ok := p.(string); ok {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// replaces the owner's bookmarks. An empty list removes them
+func SetBookmarks(owner string, paths []string) error {
+	if len(paths) == 0 {
+		err := DeleteState(bookmarksKey(owner))
+		return err
+	}
+	err := WriteState(bookmarksKey(owner), map[string]interface{}{
+		"paths": paths,
+	})
+	return err
+}
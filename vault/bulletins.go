@@ -1,8 +1,80 @@
 package vault
 
-import ()
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
 
+// bulletins are shown by the ui as bulma notifications, e.g. "is-warning"
+var bulletinSeverities = map[string]bool{
+	"info":    true,
+	"success": true,
+	"warning": true,
+	"danger":  true,
+}
+
+var bulletinID = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// a bulletin as it is written. Publish and expiry times are RFC3339, and may be empty
+type Bulletin struct {
+	Title     string `json:"title"`
+	Message   string `json:"message"`
+	Severity  string `json:"severity"`
+	PublishAt string `json:"publish_at"`
+	ExpireAt  string `json:"expire_at"`
+}
+
+// ids are the bulletin's key under the bulletin path, so they can't hold a '/'
+func CheckBulletinID(id string) error {
+	if !bulletinID.MatchString(id) || id == "." || id == ".." {
+		return errors.New("Bulletin id may only contain letters, numbers, '.', '_' and '-'")
+	}
+	return nil
+}
+
+// fills in the default severity
+func (b *Bulletin) Validate() error {
+	if b.Title == "" {
+		return errors.New("Bulletin title must not be empty")
+	}
+	if b.Severity == "" {
+		b.Severity = "info"
+	}
+	if !bulletinSeverities[b.Severity] {
+		return errors.New("Bulletin severity must be one of info, success, warning, danger")
+	}
+
+	var publish, expire time.Time
+	var err error
+	if b.PublishAt != "" {
+		if publish, err = time.Parse(time.RFC3339, b.PublishAt); err != nil {
+			return errors.New("Bulletin publish_at must be an RFC3339 time")
+		}
+	}
+	if b.ExpireAt != "" {
+		if expire, err = time.Parse(time.RFC3339, b.ExpireAt); err != nil {
+			return errors.New("Bulletin expire_at must be an RFC3339 time")
+		}
+		if !publish.IsZero() && !expire.After(publish) {
+			return errors.New("Bulletin expire_at must be after publish_at")
+		}
+	}
+	return nil
+}
+
+// lists the bulletins that are currently published
 func (auth AuthInfo) GetBulletins() ([]map[string]interface{}, error) {
+	return auth.bulletins(false)
+}
+
+// lists every bulletin, including those scheduled for later or already expired
+func (auth AuthInfo) GetAllBulletins() ([]map[string]interface{}, error) {
+	return auth.bulletins(true)
+}
+
+func (auth AuthInfo) bulletins(all bool) ([]map[string]interface{}, error) {
 	c := GetConfig()
 
 	bulletins, err := auth.ListSecret(c.BulletinPath)
@@ -10,18 +82,86 @@ func (auth AuthInfo) GetBulletins() ([]map[string]interface{}, error) {
 		return nil, err
 	}
 
-	results := make([]map[string]interface{}, len(bulletins))
-	for i, bulletin := range bulletins {
+	now := time.Now()
+	results := make([]map[string]interface{}, 0, len(bulletins))
+	for _, bulletin := range bulletins {
 		b, ok := bulletin.(string)
-		if ok {
-			data, err := auth.ReadSecret(c.BulletinPath + b)
-			if err != nil {
-				return nil, err
-			} else {
-				results[i] = data
-			}
+		if !ok || strings.HasSuffix(b, "/") {
+			continue
+		}
+		data, err := auth.ReadSecret(c.BulletinPath + b)
+		if err != nil {
+			return nil, err
+		}
+		if !all && !bulletinPublished(data, now) {
+			continue
 		}
+		data["id"] = b
+		results = append(results, data)
 	}
 
 	return results, nil
 }
+
+// bulletins written by hand have no schedule, and are always published
+func bulletinPublished(data map[string]interface{}, now time.Time) bool {
+	if s, ok := data["publish_at"].(string); ok && s != "" {
+		if t, err := time.Parse(time.RFC3339, s); err == nil && now.Before(t) {
+			return false
+		}
+	}
+	if s, ok := data["expire_at"].(string); ok && s != "" {
+		if t, err := time.Parse(time.RFC3339, s); err == nil && !now.Before(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// creates or replaces a bulletin in the runtime config's bulletin path
+func (auth AuthInfo) WriteBulletin(id string, b Bulletin) error {
+	if err := CheckBulletinID(id); err != nil {
+		return err
+	}
+	if err := b.Validate(); err != nil {
+		return err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Write(GetConfig().BulletinPath+id, map[string]interface{}{
+		"title":      b.Title,
+		"message":    b.Message,
+		"severity":   b.Severity,
+		"type":       "is-" + b.Severity,
+		"publish_at": b.PublishAt,
+		"expire_at":  b.ExpireAt,
+	})
+	return err
+}
+
+// returns nil if the bulletin does not exist
+func (auth AuthInfo) ReadBulletin(id string) (map[string]interface{}, error) {
+	if err := CheckBulletinID(id); err != nil {
+		return nil, err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Logical().Read(GetConfig().BulletinPath + id)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+func (auth AuthInfo) DeleteBulletin(id string) error {
+	if err := CheckBulletinID(id); err != nil {
+		return err
+	}
+	_, err := auth.DeleteSecret(GetConfig().BulletinPath + id)
+	return err
+}
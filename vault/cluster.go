@@ -0,0 +1,42 @@
+package vault
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/caiyeon/goldfish/config"
+)
+
+var (
+	clusters     = make(map[string]config.ClusterConfig)
+	clustersLock = new(sync.RWMutex)
+)
+
+// may be called again at runtime, e.g. when the config file is reloaded
+func SetClusters(c map[string]*config.ClusterConfig) {
+	clustersLock.Lock()
+	defer clustersLock.Unlock()
+	clusters = make(map[string]config.ClusterConfig, len(c))
+	for name, cluster := range c {
+		clusters[name] = *cluster
+	}
+}
+
+func getCluster(name string) (config.ClusterConfig, bool) {
+	clustersLock.RLock()
+	defer clustersLock.RUnlock()
+	c, ok := clusters[name]
+	return c, ok
+}
+
+// returns the sorted names of clusters users may log in to, besides goldfish's own
+func ClusterNames() []string {
+	clustersLock.RLock()
+	defer clustersLock.RUnlock()
+	names := make([]string, 0, len(clusters))
+	for name := range clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
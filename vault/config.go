@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/caiyeon/goldfish/config"
 	"github.com/fatih/structs"
 	"github.com/mitchellh/hashstructure"
 )
@@ -28,11 +29,17 @@ type RuntimeConfig struct {
 	GithubPoliciesPath string
 	GithubTargetBranch string
 
+	// goldfish roles as a json document, replacing config file roles of the same name
+	Roles string
+
 	// fields that goldfish will write
 	LastUpdated         string `hash:"ignore"`
 	GithubCurrentCommit string
 }
 
+// slack.com can't be reached in air-gapped mode
+var airGapped bool
+
 var (
 	conf                       = RuntimeConfig{}
 	configLock                 = new(sync.RWMutex)
@@ -43,7 +50,26 @@ var (
 func GetConfig() RuntimeConfig {
 	configLock.RLock()
 	defer configLock.RUnlock()
-	return conf
+	c := conf
+	// the webhook is left in vault, for if the deployment ever leaves air-gapped mode
+	if airGapped {
+		c.SlackWebhook = ""
+		c.SlackChannel = ""
+	}
+	return c
+}
+
+// set once at startup, from the config file
+func SetAirGapped(on bool) {
+	configLock.Lock()
+	defer configLock.Unlock()
+	airGapped = on
+}
+
+func AirGapped() bool {
+	configLock.RLock()
+	defer configLock.RUnlock()
+	return airGapped
 }
 
 func loadConfigFromVault(path string) error {
@@ -72,6 +98,13 @@ func loadConfigFromVault(path string) error {
 	// the local copy of current commit is the source of truth
 	temp.GithubCurrentCommit = GithubCurrentCommit
 
+	// roles are only taken as a whole, so a typo can't silently grant more than intended
+	if temp.Roles != "" {
+		if _, err := config.ParseRolesJSON(temp.Roles); err != nil {
+			return errors.New("Invalid Roles in runtime config: " + err.Error())
+		}
+	}
+
 	// improperly formed slack webhooks are not allowed
 	if !strings.HasPrefix(temp.SlackWebhook, "https://hooks.slack.com/services") {
 		temp.SlackWebhook = ""
@@ -0,0 +1,120 @@
+package vault
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/config"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+var (
+	consulNodes     []*url.URL
+	consulNodesLock = new(sync.RWMutex)
+	consulWatchOnce sync.Once
+)
+
+// returns healthy vault nodes registered in consul, kept up to date by a background watch
+func lookupConsul(c config.VaultConfig) ([]*url.URL, error) {
+	consulWatchOnce.Do(func() {
+		go watchConsul()
+	})
+
+	consulNodesLock.RLock()
+	nodes := consulNodes
+	consulNodesLock.RUnlock()
+	if len(nodes) > 0 {
+		return nodes, nil
+	}
+
+	// the watch hasn't resolved anything yet
+	nodes, _, err := queryConsul(c, 0)
+	if err != nil {
+		return nil, err
+	}
+	setConsulNodes(nodes)
+	return nodes, nil
+}
+
+func setConsulNodes(nodes []*url.URL) {
+	consulNodesLock.Lock()
+	defer consulNodesLock.Unlock()
+	if len(consulNodes) > 0 && len(nodes) > 0 && consulNodes[0].String() != nodes[0].String() {
+		log.Println("[INFO ]: Consul reports a new vault node:", nodes[0].String())
+	}
+	consulNodes = nodes
+}
+
+// address_consul is a url such as https://vault, where the host is the consul service name
+func queryConsul(c config.VaultConfig, waitIndex uint64) ([]*url.URL, uint64, error) {
+	u, err := url.Parse(c.Address_consul)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	conf := consulapi.DefaultConfig()
+	if c.Consul_address != "" {
+		conf.Address = c.Consul_address
+	}
+	client, err := consulapi.NewClient(conf)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries, meta, err := client.Health().Service(u.Host, c.Consul_tag, true, &consulapi.QueryOptions{
+		WaitIndex: waitIndex,
+		WaitTime:  5 * time.Minute,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	nodes := make([]*url.URL, 0, len(entries))
+	for _, entry := range entries {
+		host := entry.Service.Address
+		if host == "" {
+			host = entry.Node.Address
+		}
+		nodes = append(nodes, &url.URL{
+			Scheme: u.Scheme,
+			Host:   net.JoinHostPort(host, strconv.Itoa(entry.Service.Port)),
+		})
+	}
+	if len(nodes) == 0 {
+		return nil, meta.LastIndex, errors.New("No healthy instances of " + u.Host + " found in consul")
+	}
+	return nodes, meta.LastIndex, nil
+}
+
+// blocks on consul until the service's healthy nodes change, e.g. when vault's leader changes
+func watchConsul() {
+	var index uint64
+	for {
+		c := getVaultConfig()
+		if c.Address_consul == "" {
+			time.Sleep(time.Minute)
+			continue
+		}
+
+		nodes, newIndex, err := queryConsul(c, index)
+		if err != nil {
+			log.Println("[ERROR]: Consul lookup of vault failed:", err.Error())
+			index = 0
+			time.Sleep(10 * time.Second)
+			continue
+		}
+
+		// consul's index may go backwards, e.g. after a snapshot restore
+		if newIndex < index {
+			index = 0
+		} else {
+			index = newIndex
+		}
+		setConsulNodes(nodes)
+	}
+}
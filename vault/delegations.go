@@ -0,0 +1,190 @@
+package vault
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/mitchellh/mapstructure"
+)
+
+// lets an approver's change request approvals be given by someone else for a while, e.g. when out of office
+// users are identified by their vault identity entity, since display names can be shared across auth mounts
+// the delegate still approves with an unseal key of their own, so a delegation adds nothing to a request's
+// quorum. It only records whose approval the delegate gave, once per request
+type Delegation struct {
+	ID         string    `json:"id" mapstructure:"id"`
+	From       string    `json:"from" mapstructure:"from"`
+	To         string    `json:"to" mapstructure:"to"`
+	FromEntity string    `json:"from_entity" mapstructure:"from_entity"`
+	ToEntity   string    `json:"to_entity" mapstructure:"to_entity"`
+	Start      time.Time `json:"start" mapstructure:"-"`
+	End        time.Time `json:"end" mapstructure:"-"`
+	Created    time.Time `json:"created" mapstructure:"-"`
+}
+
+// a vault identity entity, with the name shown to users
+type Identity struct {
+	EntityID string
+	Name     string
+}
+
+// the identity entity of the token, named by its display name
+func (auth AuthInfo) SelfIdentity() (Identity, error) {
+	resp, err := auth.LookupSelf()
+	if err != nil {
+		return Identity{}, err
+	}
+	if resp == nil {
+		return Identity{}, errors.New("Could not confirm your identity")
+	}
+	id, _ := resp.Data["entity_id"].(string)
+	name, _ := resp.Data["display_name"].(string)
+	if id == "" {
+		return Identity{}, errors.New("Your token has no vault identity entity, which delegations need")
+	}
+	return Identity{EntityID: id, Name: name}, nil
+}
+
+// finds an identity entity by its name or id, with goldfish's own token
+func LookupIdentity(nameOrID string) (Identity, error) {
+	if nameOrID == "" || strings.Contains(nameOrID, "/") {
+		return Identity{}, errors.New("A vault identity entity name or id is required")
+	}
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return Identity{}, err
+	}
+	for _, path := range []string{"identity/entity/name/", "identity/entity/id/"} {
+		resp, err := client.Logical().Read(path + nameOrID)
+		if err != nil {
+			return Identity{}, err
+		}
+		if resp == nil {
+			continue
+		}
+		id, _ := resp.Data["id"].(string)
+		name, _ := resp.Data["name"].(string)
+		return Identity{EntityID: id, Name: name}, nil
+	}
+	return Identity{}, errors.New("No vault identity entity is named " + nameOrID)
+}
+
+// delegations are kept under goldfish's state path, one entry each, so they outlive its token
+func delegationKey(id string) string {
+	return "delegations/" + id
+}
+
+// every delegation, ended or not, ordered by start
+func ListDelegations() ([]Delegation, error) {
+	ids, err := ListState("delegations/")
+	if err != nil {
+		return nil, err
+	}
+
+	delegations := make([]Delegation, 0, len(ids))
+	for _, id := range ids {
+		d, err := getDelegation(id)
+		if err != nil {
+			return nil, err
+		}
+		if d != nil {
+			delegations = append(delegations, *d)
+		}
+	}
+	sort.Slice(delegations, func(i, j int) bool { return delegations[i].Start.Before(delegations[j].Start) })
+	return delegations, nil
+}
+
+func getDelegation(id string) (*Delegation, error) {
+	resp, err := ReadState(delegationKey(id))
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	var d Delegation
+	if err := mapstructure.WeakDecode(resp.Data, &d); err != nil {
+		return nil, err
+	}
+	for field, t := range map[string]*time.Time{"start": &d.Start, "end": &d.End, "created": &d.Created} {
+		raw, _ := resp.Data[field].(string)
+		if *t, err = time.Parse(time.RFC3339, raw); err != nil {
+			return nil, errors.New("Delegation " + id + " has an invalid " + field)
+		}
+	}
+	return &d, nil
+}
+
+func AddDelegation(from, to Identity, start, end time.Time) (*Delegation, error) {
+	if from.EntityID == "" || to.EntityID == "" {
+		return nil, errors.New("A delegation needs both a delegator and a delegate")
+	}
+	if from.EntityID == to.EntityID {
+		return nil, errors.New("Approvals can not be delegated to oneself")
+	}
+	if !end.After(start) {
+		return nil, errors.New("A delegation must end after it starts")
+	}
+	if !end.After(time.Now()) {
+		return nil, errors.New("A delegation must end in the future")
+	}
+
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	d := &Delegation{
+		ID:         id,
+		From:       from.Name,
+		To:         to.Name,
+		FromEntity: from.EntityID,
+		ToEntity:   to.EntityID,
+		Start:      start.UTC(),
+		End:        end.UTC(),
+		Created:    time.Now().UTC(),
+	}
+	err = WriteState(delegationKey(id), map[string]interface{}{
+		"id":          d.ID,
+		"from":        d.From,
+		"to":          d.To,
+		"from_entity": d.FromEntity,
+		"to_entity":   d.ToEntity,
+		"start":       d.Start.Format(time.RFC3339),
+		"end":         d.End.Format(time.RFC3339),
+		"created":     d.Created.Format(time.RFC3339),
+	})
+	return d, err
+}
+
+// only the delegator or the delegate may end a delegation
+func DeleteDelegation(id, byEntity string) error {
+	d, err := getDelegation(id)
+	if err != nil {
+		return err
+	}
+	if d == nil {
+		return errors.New("Delegation not found")
+	}
+	if d.FromEntity != byEntity && d.ToEntity != byEntity {
+		return errors.New("Only the delegator or the delegate may remove a delegation")
+	}
+	err = DeleteState(delegationKey(id))
+	return err
+}
+
+// reports whether the approvals of the from entity are delegated to the to entity at the given time
+// delegations from before entities were recorded have none, and are never active
+func DelegationActive(fromEntity, toEntity string, at time.Time) (bool, error) {
+	delegations, err := ListDelegations()
+	if err != nil {
+		return false, err
+	}
+	for _, d := range delegations {
+		if d.FromEntity != "" && d.FromEntity == fromEntity && d.ToEntity == toEntity &&
+			!at.Before(d.Start) && at.Before(d.End) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
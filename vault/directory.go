@@ -0,0 +1,114 @@
+package vault
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// a user or group that approvers, delegations and policy templates can name
+// ldap principals are those vault's ldap auth mounts map, since goldfish only reaches ldap through vault
+type Principal struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Source string `json:"source"`
+	Mount  string `json:"mount,omitempty"`
+}
+
+// searches ldap auth mounts and the identity store for users and groups whose name contains query
+// kind may be "user" or "group", and source "ldap" or "identity", to narrow the search. Sources the
+// token can't list are skipped, unless none could be listed
+func (auth AuthInfo) SearchDirectory(query, kind, source string, limit int) ([]Principal, error) {
+	if kind != "" && kind != "user" && kind != "group" {
+		return nil, errors.New("Kind must be user or group")
+	}
+	if source != "" && source != "ldap" && source != "identity" {
+		return nil, errors.New("Source must be ldap or identity")
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	type listing struct {
+		path, kind, source, mount string
+	}
+	var listings []listing
+	if source == "" || source == "ldap" {
+		mounts := []string{"ldap/"}
+		if auths, err := client.Sys().ListAuth(); err == nil {
+			mounts = ldapMounts(auths)
+		}
+		for _, m := range mounts {
+			listings = append(listings,
+				listing{"auth/" + m + "users", "user", "ldap", m},
+				listing{"auth/" + m + "groups", "group", "ldap", m})
+		}
+	}
+	if source == "" || source == "identity" {
+		listings = append(listings,
+			listing{"identity/entity/name", "user", "identity", ""},
+			listing{"identity/group/name", "group", "identity", ""})
+	}
+
+	query = strings.ToLower(query)
+	results := make([]Principal, 0)
+	var firstErr error
+	listed := false
+	for _, l := range listings {
+		if kind != "" && l.kind != kind {
+			continue
+		}
+		resp, err := client.Logical().List(l.path)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		listed = true
+		if resp == nil {
+			continue
+		}
+		keys, _ := resp.Data["keys"].([]interface{})
+		for _, k := range keys {
+			name, ok := k.(string)
+			if !ok || !strings.Contains(strings.ToLower(name), query) {
+				continue
+			}
+			results = append(results, Principal{
+				Name:   name,
+				Kind:   l.kind,
+				Source: l.source,
+				Mount:  l.mount,
+			})
+		}
+	}
+	if !listed && firstErr != nil {
+		return nil, firstErr
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Name != results[j].Name {
+			return results[i].Name < results[j].Name
+		}
+		return results[i].Source < results[j].Source
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func ldapMounts(auths map[string]*api.AuthMount) []string {
+	var mounts []string
+	for path, m := range auths {
+		if m != nil && m.Type == "ldap" {
+			mounts = append(mounts, path)
+		}
+	}
+	sort.Strings(mounts)
+	return mounts
+}
@@ -0,0 +1,217 @@
+package vault
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caiyeon/goldfish/config"
+)
+
+var (
+	currentNode     string
+	currentNodeLock = new(sync.RWMutex)
+
+	srvCache     srvResult
+	srvCacheLock = new(sync.Mutex)
+)
+
+type srvResult struct {
+	name    string
+	nodes   []*url.URL
+	expires time.Time
+}
+
+// how long resolved srv records are used before looking them up again
+const srvCacheTTL = 30 * time.Second
+
+// returns the address of the vault node that last worked, which failover tries first
+// this is shared by every request, so it says nothing of which node served any one of them
+func CurrentNode() string {
+	currentNodeLock.RLock()
+	defer currentNodeLock.RUnlock()
+	if currentNode == "" {
+		return getVaultConfig().Address
+	}
+	return currentNode
+}
+
+func setCurrentNode(node string) {
+	currentNodeLock.Lock()
+	defer currentNodeLock.Unlock()
+	if currentNode != "" && currentNode != node {
+		log.Println("[WARN ]: Vault requests are now served by", node)
+	}
+	currentNode = node
+}
+
+// the vault node that served one goldfish request's vault calls,
+// recorded by the failover transport of each client made for the request
+type ServedNode struct {
+	lock sync.Mutex
+	node string
+}
+
+// returns the node that served the latest vault call, or "" if none was made
+func (n *ServedNode) String() string {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.node
+}
+
+func (n *ServedNode) set(node string) {
+	if n == nil {
+		return
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.node = node
+}
+
+// failover is only needed if vault may be reached at more than one address
+func failoverEnabled(c config.VaultConfig) bool {
+	return len(c.Addresses) > 1 || c.Address_srv != "" || c.Address_consul != ""
+}
+
+// tries each of vault's nodes in turn on connection errors or a 503 (e.g. sealed),
+// starting with the node that last worked
+// served, if set, is where the node that answered is recorded
+type failoverTransport struct {
+	base   http.RoundTripper
+	served *ServedNode
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	nodes, err := vaultNodes(getVaultConfig())
+	if err != nil {
+		return nil, err
+	}
+	nodes = startingAt(nodes, CurrentNode())
+
+	var lastErr error
+	for i, node := range nodes {
+		r := new(http.Request)
+		*r = *req
+		u := *req.URL
+		u.Scheme, u.Host = node.Scheme, node.Host
+		r.URL, r.Host = &u, ""
+
+		// a request body can only be replayed if it can be recreated
+		if i > 0 && req.Body != nil {
+			if req.GetBody == nil {
+				break
+			}
+			if r.Body, err = req.GetBody(); err != nil {
+				break
+			}
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		if err == nil && (resp.StatusCode != http.StatusServiceUnavailable || i == len(nodes)-1) {
+			setCurrentNode(node.String())
+			t.served.set(node.String())
+			return resp, nil
+		}
+		if err == nil {
+			resp.Body.Close()
+			err = errors.New(node.String() + " is unavailable")
+		}
+		lastErr = err
+		log.Println("[WARN ]: Vault node failed, trying next:", err.Error())
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("No vault nodes available")
+	}
+	return nil, lastErr
+}
+
+// returns nodes discovered with consul first, then srv records, then any static addresses
+func vaultNodes(c config.VaultConfig) ([]*url.URL, error) {
+	// without static addresses, there is nothing to fall back to if discovery fails
+	hasStatic := c.Address != c.Address_srv && c.Address != c.Address_consul
+
+	var nodes []*url.URL
+	if c.Address_consul != "" {
+		resolved, err := lookupConsul(c)
+		if err != nil {
+			if !hasStatic && c.Address_srv == "" {
+				return nil, err
+			}
+			log.Println("[WARN ]: Failed to discover vault with consul:", err.Error())
+		}
+		nodes = append(nodes, resolved...)
+	}
+	if c.Address_srv != "" {
+		resolved, err := lookupSRV(c.Address_srv)
+		if err != nil {
+			if !hasStatic && len(nodes) == 0 {
+				return nil, err
+			}
+			log.Println("[WARN ]: Failed to resolve address_srv:", err.Error())
+		}
+		nodes = append(nodes, resolved...)
+	}
+
+	static := c.Addresses
+	if len(static) == 0 && hasStatic {
+		static = []string{c.Address}
+	}
+	for _, address := range static {
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, u)
+	}
+	return nodes, nil
+}
+
+// rotates nodes so that the current one is tried first
+func startingAt(nodes []*url.URL, current string) []*url.URL {
+	for i, node := range nodes {
+		if node.String() == current {
+			return append(nodes[i:], nodes[:i]...)
+		}
+	}
+	return nodes
+}
+
+// address_srv is a url such as https://_vault._tcp.example.com, where the host is the srv name
+func lookupSRV(address string) ([]*url.URL, error) {
+	srvCacheLock.Lock()
+	defer srvCacheLock.Unlock()
+	if srvCache.name == address && time.Now().Before(srvCache.expires) {
+		return srvCache.nodes, nil
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	_, records, err := net.LookupSRV("", "", u.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	// records are already sorted by priority, and randomized by weight
+	nodes := make([]*url.URL, 0, len(records))
+	for _, record := range records {
+		nodes = append(nodes, &url.URL{
+			Scheme: u.Scheme,
+			Host:   net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port))),
+		})
+	}
+	if len(nodes) == 0 {
+		return nil, errors.New("No srv records found for " + u.Host)
+	}
+
+	srvCache = srvResult{name: address, nodes: nodes, expires: time.Now().Add(srvCacheTTL)}
+	return nodes, nil
+}
@@ -0,0 +1,58 @@
+package vault
+
+// what decides whether a user may use one of goldfish's features
+type Feature struct {
+	// the goldfish endpoint, as "<method> <route>", which goldfish's roles must allow
+	Endpoint string
+
+	// the vault request the feature makes with the user's token, which vault's policies must allow
+	// features without one are checked by goldfish itself, e.g. requests, or depend on the path used, e.g. secrets
+	Method string
+	Path   string
+
+	// features that change goldfish itself only work with a session on goldfish's own cluster
+	OwnCluster bool
+
+	// features that are not configured, e.g. transit without a user transit key, are never allowed
+	Disabled bool
+}
+
+// the main features of the ui, keyed by the name the frontend knows them by
+// paths come from the runtime config, so this is built on each call
+func Features() map[string]Feature {
+	c := GetConfig()
+	return map[string]Feature{
+		"tokens.list":    {Endpoint: "GET /v1/token/accessors", Method: "LIST", Path: "auth/token/accessors"},
+		"tokens.create":  {Endpoint: "POST /v1/token/create", Method: "POST", Path: "auth/token/create"},
+		"users.userpass": {Endpoint: "GET /v1/userpass/users", Method: "LIST", Path: userpassPath() + "/users"},
+		"users.approle":  {Endpoint: "GET /v1/approle/roles", Method: "LIST", Path: "auth/approle/role"},
+		"policies.read":  {Endpoint: "GET /v1/policy", Method: "GET", Path: "sys/policy"},
+		"mounts.read":    {Endpoint: "GET /v1/mount", Method: "GET", Path: "sys/mounts"},
+		"transit": {
+			Endpoint: "POST /v1/transit/encrypt",
+			Method:   "POST",
+			Path:     c.TransitBackend + "/encrypt/" + c.UserTransitKey,
+			Disabled: c.UserTransitKey == "",
+		},
+		"secrets":  {Endpoint: "GET /v1/secrets"},
+		"requests": {Endpoint: "POST /v1/request/add"},
+		// bulletins are written one path each, so any path below the bulletin path stands in for them
+		"bulletins.manage":   {Endpoint: "POST /v1/bulletins", Method: "POST", Path: c.BulletinPath + "new", Disabled: c.BulletinPath == ""},
+		"settings.update":    {Endpoint: "PUT /v1/settings", Method: "PUT", Path: settingsPath(), OwnCluster: true},
+		"sessions.admin":     {Endpoint: "POST /v1/sessions/revoke-all", Method: "PUT", Path: sessionAdministrationPath(), OwnCluster: true},
+		"goldfish.bootstrap": {Endpoint: "POST /v1/rebootstrap", Method: "PUT", Path: getVaultConfig().Runtime_config, OwnCluster: true},
+		"raw":                {Endpoint: "POST /v1/raw"},
+	}
+}
+
+// reports whether vault's policies let the user use the feature
+// goldfish's own roles are checked by the handlers, which know the user's identity
+func (auth *AuthInfo) CanUse(f Feature) bool {
+	if f.Disabled || (f.OwnCluster && auth.Cluster != "") {
+		return false
+	}
+	if f.Method == "" {
+		return true
+	}
+	return auth.RawPreflight(f.Method, f.Path) == nil
+}
@@ -0,0 +1,236 @@
+package vault
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// states of goldfish's vault clusters, as seen by the health watcher
+const (
+	StateUnknown       = "unknown"
+	StateOK            = "ok"
+	StateSealed        = "sealed"
+	StateUninitialized = "uninitialized"
+	StateUnreachable   = "unreachable"
+)
+
+type VaultState struct {
+	State     string    `json:"state"`
+	Message   string    `json:"message,omitempty"`
+	Since     time.Time `json:"since"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// until the first check completes, vault is assumed to be fine
+func (s VaultState) Healthy() bool {
+	return s.State == StateOK || s.State == StateUnknown
+}
+
+// each cluster has its own state, keyed by name, with "" for goldfish's own
+// so one cluster being down never fails requests to another
+var (
+	vaultStates    = map[string]VaultState{"": {State: StateUnknown, Since: time.Now()}}
+	vaultStateLock = new(sync.RWMutex)
+)
+
+// the state of goldfish's own cluster
+func GetVaultState() VaultState {
+	return GetClusterState("")
+}
+
+// clusters that have not been checked yet are in the unknown state
+func GetClusterState(cluster string) VaultState {
+	vaultStateLock.RLock()
+	defer vaultStateLock.RUnlock()
+	if state, ok := vaultStates[cluster]; ok {
+		return state
+	}
+	return VaultState{State: StateUnknown}
+}
+
+func setVaultState(state, message string) {
+	setClusterState("", state, message)
+}
+
+func setClusterState(cluster, state, message string) {
+	vaultStateLock.Lock()
+	defer vaultStateLock.Unlock()
+
+	name := "Vault"
+	if cluster != "" {
+		name = "Vault cluster " + cluster
+	}
+	now := time.Now()
+	current, ok := vaultStates[cluster]
+	if !ok {
+		current = VaultState{State: StateUnknown, Since: now}
+	}
+	if state != current.State {
+		if state == StateOK {
+			log.Println("[INFO ]: " + name + " is available again")
+		} else {
+			log.Println("[WARN ]: " + name + " is " + state + ", failing requests fast until it recovers")
+		}
+		current.Since = now
+	}
+	current.State = state
+	current.Message = message
+	current.CheckedAt = now
+	vaultStates[cluster] = current
+}
+
+// clusters removed from the config are no longer checked, and requests to them fail on their own
+func forgetRemovedClusters(names []string) {
+	keep := map[string]bool{"": true}
+	for _, name := range names {
+		keep[name] = true
+	}
+	vaultStateLock.Lock()
+	defer vaultStateLock.Unlock()
+	for cluster := range vaultStates {
+		if !keep[cluster] {
+			delete(vaultStates, cluster)
+		}
+	}
+}
+
+// polls the health endpoint of goldfish's cluster and every named one,
+// so requests can be short-circuited while their cluster is down
+// every check of goldfish's own cluster is kept in the health history. The interval is read before each check, so reloads apply
+func WatchVaultHealth() {
+	for {
+		names := ClusterNames()
+		forgetRemovedClusters(names)
+
+		var wg sync.WaitGroup
+		for _, cluster := range names {
+			wg.Add(1)
+			go func(cluster string) {
+				defer wg.Done()
+				state, message := vaultHealthState(clusterHealthRequest(cluster, "?standbyok=true"))
+				setClusterState(cluster, state, message)
+			}(cluster)
+		}
+
+		// standbys can serve goldfish by forwarding to the active node
+		start := time.Now()
+		served := new(ServedNode)
+		code, body, err := vaultHealthRequest("?standbyok=true", served)
+		state, message := vaultHealthState(code, body, err)
+		setVaultState(state, message)
+		recordHealth(start, time.Since(start), served.String(), state, message, body)
+		wg.Wait()
+
+		interval := getVaultConfig().Health_check_interval
+		if interval <= 0 {
+			interval = 5 * time.Second
+		}
+		time.Sleep(interval)
+	}
+}
+
+func checkVaultHealth() (string, string) {
+	return vaultHealthState(vaultHealthRequest("?standbyok=true", nil))
+}
+
+func vaultHealthState(code int, _ []byte, err error) (string, string) {
+	if err != nil {
+		return StateUnreachable, err.Error()
+	}
+	switch code {
+	case http.StatusOK, http.StatusTooManyRequests:
+		return StateOK, ""
+	case http.StatusNotImplemented:
+		return StateUninitialized, "Vault is not initialized"
+	case http.StatusServiceUnavailable:
+		return StateSealed, "Vault is sealed"
+	default:
+		return StateUnreachable, "Unexpected status from vault health check: " + http.StatusText(code)
+	}
+}
+
+// health check clients, kept per cluster for as long as their pooled transport is,
+// so checks reuse connections instead of opening new ones each time
+var (
+	healthClients     = make(map[string]*http.Client)
+	healthClientsLock = new(sync.Mutex)
+)
+
+// failover, if true, wraps the client to try each of vault's nodes, recording the one that answered in served
+func healthClient(cluster, address string, settings tlsSettings, failover bool, served *ServedNode) (*http.Client, error) {
+	transport, err := sharedTransport(cluster, address, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	healthClientsLock.Lock()
+	client, ok := healthClients[cluster]
+	if !ok || client.Transport != transport {
+		client = &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: transport,
+		}
+		healthClients[cluster] = client
+	}
+	healthClientsLock.Unlock()
+
+	if !failover {
+		served.set(address)
+		return client, nil
+	}
+	return &http.Client{
+		Timeout:   client.Timeout,
+		Transport: &failoverTransport{base: client.Transport, served: served},
+	}, nil
+}
+
+// makes a request to vault's health endpoint, returning the status code and body
+// served, if set, records the node that answered
+func vaultHealthRequest(query string, served *ServedNode) (int, []byte, error) {
+	vaultConfig := getVaultConfig()
+	client, err := healthClient("", vaultConfig.Address, vaultTLSSettings(vaultConfig), failoverEnabled(vaultConfig), served)
+	if err != nil {
+		return 0, nil, err
+	}
+	address := vaultConfig.Address
+	if failoverEnabled(vaultConfig) {
+		address = CurrentNode()
+	}
+	return healthRequest(client, address+"/v1/sys/health"+query)
+}
+
+// the same request, to a named cluster
+func clusterHealthRequest(cluster, query string) (int, []byte, error) {
+	c, ok := getCluster(cluster)
+	if !ok {
+		return 0, nil, errors.New("Unknown cluster: " + cluster)
+	}
+	client, err := healthClient(cluster, c.Address, tlsSettings{caCert: c.Ca_cert, insecure: c.Tls_skip_verify}, false, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	return healthRequest(client, c.Address+"/v1/sys/health"+query)
+}
+
+func healthRequest(client *http.Client, url string) (int, []byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
+// if true, vault being sealed or down doesn't fail goldfish's readiness check
+func ReadyWhileSealed() bool {
+	return getVaultConfig().Ready_while_sealed
+}
@@ -0,0 +1,200 @@
+package vault
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// bounds on the health history, however long health_history is
+const (
+	maxHealthSamples = 100000
+	maxHealthEvents  = 1000
+	maxHealthPoints  = 500
+)
+
+// one health check of goldfish's own vault cluster
+type healthSample struct {
+	Time    time.Time
+	State   string
+	Standby bool
+	Node    string
+	Latency time.Duration
+}
+
+// a change seen between two health checks: state (e.g. ok to sealed), standby (active to standby or back),
+// or node (failover moved goldfish to another vault node)
+type HealthEvent struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Message string    `json:"message,omitempty"`
+}
+
+// health checks over a span of time, combined so a day of them can be charted
+// State is the worst seen, so a brief outage still shows
+type HealthPoint struct {
+	Time       time.Time `json:"time"`
+	State      string    `json:"state"`
+	Standby    bool      `json:"standby"`
+	Checks     int       `json:"checks"`
+	LatencyAvg float64   `json:"latency_avg_ms"`
+	LatencyMax float64   `json:"latency_max_ms"`
+}
+
+type HealthHistory struct {
+	Since  time.Time     `json:"since"`
+	Checks int           `json:"checks"`
+	Uptime float64       `json:"uptime"`
+	Events []HealthEvent `json:"events"`
+	Points []HealthPoint `json:"points"`
+}
+
+// the history without node addresses or vault's error messages, for callers without a session
+func (h HealthHistory) Public() HealthHistory {
+	events := make([]HealthEvent, 0, len(h.Events))
+	for _, e := range h.Events {
+		if e.Type == "node" {
+			continue
+		}
+		e.Message = ""
+		events = append(events, e)
+	}
+	h.Events = events
+	return h
+}
+
+var (
+	healthSamples     = make([]healthSample, 0)
+	healthEvents      = make([]HealthEvent, 0)
+	healthHistoryLock = new(sync.RWMutex)
+)
+
+// records a health check, noting any transition from the one before it
+// samples older than health_history are dropped as new ones arrive
+func recordHealth(at time.Time, latency time.Duration, node, state, message string, body []byte) {
+	var status struct {
+		Standby            bool `json:"standby"`
+		PerformanceStandby bool `json:"performance_standby"`
+	}
+	if state == StateOK {
+		json.Unmarshal(body, &status)
+	}
+	sample := healthSample{
+		Time:    at,
+		State:   state,
+		Standby: status.Standby || status.PerformanceStandby,
+		Node:    node,
+		Latency: latency,
+	}
+	retention := getVaultConfig().Health_history
+
+	healthHistoryLock.Lock()
+	defer healthHistoryLock.Unlock()
+
+	if n := len(healthSamples); n > 0 {
+		last := healthSamples[n-1]
+		if last.State != sample.State {
+			addHealthEvent(HealthEvent{Time: at, Type: "state", From: last.State, To: sample.State, Message: message})
+		}
+		// a sealed or unreachable node says nothing about standby, so only healthy checks are compared
+		if last.State == StateOK && sample.State == StateOK && last.Standby != sample.Standby {
+			addHealthEvent(HealthEvent{Time: at, Type: "standby", From: standbyName(last.Standby), To: standbyName(sample.Standby)})
+		}
+		if last.Node != sample.Node {
+			addHealthEvent(HealthEvent{Time: at, Type: "node", From: last.Node, To: sample.Node})
+		}
+	}
+	healthSamples = append(healthSamples, sample)
+
+	drop := 0
+	for drop < len(healthSamples) && (at.Sub(healthSamples[drop].Time) > retention || len(healthSamples)-drop > maxHealthSamples) {
+		drop++
+	}
+	if drop > 0 {
+		healthSamples = append(healthSamples[:0:0], healthSamples[drop:]...)
+	}
+	for len(healthEvents) > 0 && at.Sub(healthEvents[0].Time) > retention {
+		healthEvents = healthEvents[1:]
+	}
+}
+
+func addHealthEvent(e HealthEvent) {
+	if len(healthEvents) >= maxHealthEvents {
+		healthEvents = healthEvents[1:]
+	}
+	healthEvents = append(healthEvents, e)
+}
+
+func standbyName(standby bool) string {
+	if standby {
+		return "standby"
+	}
+	return "active"
+}
+
+// the health checks of the last window, or all that are kept if window is 0
+// uptime is the fraction of checks that found vault ok, standbys included
+func GetHealthHistory(window time.Duration) HealthHistory {
+	healthHistoryLock.RLock()
+	defer healthHistoryLock.RUnlock()
+
+	since := time.Time{}
+	if window > 0 {
+		since = time.Now().Add(-window)
+	}
+	h := HealthHistory{
+		Events: make([]HealthEvent, 0),
+		Points: make([]HealthPoint, 0),
+	}
+	for _, e := range healthEvents {
+		if !e.Time.Before(since) {
+			h.Events = append(h.Events, e)
+		}
+	}
+
+	samples := make([]healthSample, 0)
+	for _, s := range healthSamples {
+		if !s.Time.Before(since) {
+			samples = append(samples, s)
+		}
+	}
+	if len(samples) == 0 {
+		return h
+	}
+	h.Since = samples[0].Time
+	h.Checks = len(samples)
+
+	ok := 0
+	per := (len(samples) + maxHealthPoints - 1) / maxHealthPoints
+	for i := 0; i < len(samples); i += per {
+		end := i + per
+		if end > len(samples) {
+			end = len(samples)
+		}
+		p := HealthPoint{Time: samples[i].Time, State: StateOK}
+		total := time.Duration(0)
+		for _, s := range samples[i:end] {
+			if s.State == StateOK {
+				ok++
+			} else if p.State == StateOK {
+				p.State = s.State
+			}
+			p.Standby = s.Standby
+			p.Checks++
+			total += s.Latency
+			if ms := milliseconds(s.Latency); ms > p.LatencyMax {
+				p.LatencyMax = ms
+			}
+		}
+		p.LatencyAvg = milliseconds(total / time.Duration(p.Checks))
+		h.Points = append(h.Points, p)
+	}
+	h.Uptime = float64(ok) / float64(len(samples))
+	return h
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
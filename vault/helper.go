@@ -1,35 +1,18 @@
 package vault
 
 import (
-	"crypto/tls"
+	"encoding/json"
 	"errors"
-	"io/ioutil"
 	"log"
-	"net/http"
 
 	"github.com/hashicorp/vault/api"
 )
 
 func VaultHealth() (string, error) {
-	client := &http.Client{
-		Transport: &http.Transport{
-        	TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: vaultConfig.Tls_skip_verify,
-			},
-    	},
-	}
-
-	resp, err := client.Get(vaultConfig.Address + "/v1/sys/health")
-	if err != nil {
-		return "", err
-	}
-
-	body, err := ioutil.ReadAll(resp.Body)
-	resp.Body.Close()
+	_, body, err := vaultHealthRequest("", nil)
 	if err != nil {
 		return "", err
 	}
-
 	return string(body), nil
 }
 
@@ -82,6 +65,25 @@ func ReadFromCubbyhole(name string) (*api.Secret, error) {
 	return client.Logical().Read("cubbyhole/" + name)
 }
 
+func ListCubbyhole(name string) ([]string, error) {
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Logical().List("cubbyhole/" + name)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	keys, _ := resp.Data["keys"].([]interface{})
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if key, ok := k.(string); ok {
+			names = append(names, key)
+		}
+	}
+	return names, nil
+}
+
 func DeleteFromCubbyhole(name string) (*api.Secret, error) {
 	client, err := NewGoldfishVaultClient()
 	if err != nil {
@@ -137,6 +139,22 @@ func UnwrapData(wrappingToken string) (map[string]interface{}, error) {
 	return resp.Data, nil
 }
 
+// returns the remaining ttl of goldfish's server token in seconds
+func ServerTokenTTL() (int64, error) {
+	if !Bootstrapped() {
+		return 0, errors.New("Goldfish is not bootstrapped")
+	}
+	data, err := LookupSelf()
+	if err != nil {
+		return 0, err
+	}
+	ttl, ok := data["ttl"].(json.Number)
+	if !ok {
+		return 0, errors.New("Could not parse server token ttl")
+	}
+	return ttl.Int64()
+}
+
 func LookupSelf() (map[string]interface{}, error) {
 	client, err := NewGoldfishVaultClient()
 	if err != nil {
@@ -0,0 +1,71 @@
+package vault
+
+import (
+	"errors"
+)
+
+// returns the token's policies, including those granted through its identity
+// with groups, also returns the names of the identity groups it belongs to
+func (auth AuthInfo) Identity(groups bool) ([]string, []string, error) {
+	resp, err := auth.LookupSelf()
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp == nil {
+		return nil, nil, errors.New("Failed to lookup token")
+	}
+
+	var policies []string
+	for _, key := range []string{"policies", "identity_policies"} {
+		list, _ := resp.Data[key].([]interface{})
+		for _, p := range list {
+			if s, ok := p.(string); ok {
+				policies = append(policies, s)
+			}
+		}
+	}
+
+	// other clusters have their own identities, which goldfish's token can't read
+	entityID, _ := resp.Data["entity_id"].(string)
+	if !groups || entityID == "" || auth.Cluster != "" {
+		return policies, nil, nil
+	}
+	names, err := entityGroups(entityID)
+	return policies, names, err
+}
+
+// group names are looked up with goldfish's own token, since users can rarely read their own entity
+func entityGroups(entityID string) ([]string, error) {
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Logical().Read("identity/entity/id/" + entityID)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, nil
+	}
+
+	var names []string
+	ids, _ := resp.Data["group_ids"].([]interface{})
+	for _, id := range ids {
+		groupID, ok := id.(string)
+		if !ok {
+			continue
+		}
+		group, err := client.Logical().Read("identity/group/id/" + groupID)
+		if err != nil {
+			return nil, err
+		}
+		if group == nil {
+			continue
+		}
+		if name, ok := group.Data["name"].(string); ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,37 @@
+package vault
+
+import (
+	"errors"
+)
+
+// logs in with a jwt auth method's role, e.g. with an id token from goldfish's own single sign on
+// like Login, the auth becomes the resulting token, and the token's lookup-self data is returned
+func (auth *AuthInfo) LoginJWT(mount, role, jwt string) (map[string]interface{}, error) {
+	client, err := newClusterClient(auth.Cluster, auth.Namespace, auth.Trace, auth.Audit)
+	if err != nil {
+		return nil, err
+	}
+	client.SetToken("")
+
+	resp, err := client.Logical().Write("auth/"+mount+"/login", map[string]interface{}{
+		"role": role,
+		"jwt":  jwt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return nil, errors.New("Unable to parse vault response")
+	}
+	client.SetToken(resp.Auth.ClientToken)
+
+	lookupResp, err := client.Auth().Token().LookupSelf()
+	if err != nil {
+		return nil, err
+	}
+
+	auth.Type = "token"
+	auth.ID = client.Token()
+	auth.Pass = ""
+	return lookupResp.Data, nil
+}
@@ -0,0 +1,44 @@
+package vault
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// bootstraps goldfish by logging in with its kubernetes service account token
+func StartGoldfishWithKubernetes() error {
+	client, err := NewVaultClient()
+	if err != nil {
+		return err
+	}
+	accessor, err := loginWithKubernetes(client)
+	if err != nil {
+		return errors.New("Failed to login with kubernetes service account: " + err.Error())
+	}
+	log.Println("[INFO ]: Server token accessor:", accessor)
+
+	return LoadRuntimeConfig(getVaultConfig().Runtime_config)
+}
+
+func loginWithKubernetes(client *api.Client) (string, error) {
+	vaultConfig := getVaultConfig()
+
+	// the jwt is re-read on every login, since kubernetes may rotate projected tokens
+	b, err := ioutil.ReadFile(vaultConfig.Kubernetes_jwt_file)
+	if err != nil {
+		return "", err
+	}
+	jwt := strings.TrimSpace(string(b))
+	if jwt == "" {
+		return "", errors.New("Service account token file " + vaultConfig.Kubernetes_jwt_file + " is empty")
+	}
+
+	return loginAs(client, vaultConfig.Kubernetes_login, map[string]interface{}{
+		"role": vaultConfig.Kubernetes_role,
+		"jwt":  jwt,
+	})
+}
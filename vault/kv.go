@@ -0,0 +1,256 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// a kv version 2 secret's latest write. Path is as users see it, without the data/ or metadata/ segment
+type KVSecret struct {
+	Path    string    `json:"path"`
+	Mount   string    `json:"mount"`
+	Version int64     `json:"version"`
+	Updated time.Time `json:"updated"`
+}
+
+// the path vault's audit log records reads of the secret under
+func (s KVSecret) DataPath() string {
+	return s.Mount + "data/" + strings.TrimPrefix(s.Path, s.Mount)
+}
+
+// the kv mount a path is under, and its version, "1" or "2"
+// read from the endpoint vault's own ui uses, which any token with access to the path may read
+func (auth AuthInfo) kvMount(path string) (string, string, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return "", "", err
+	}
+	resp, err := client.Logical().Read("sys/internal/ui/mounts/" + path)
+	if err != nil {
+		return "", "", err
+	}
+	if resp == nil {
+		return "", "", errors.New("Could not find the mount of " + path + ". Kv version 2 needs vault 0.10 or newer")
+	}
+	mount, _ := resp.Data["path"].(string)
+	options, _ := resp.Data["options"].(map[string]interface{})
+	if t, _ := resp.Data["type"].(string); (t != "kv" && t != "generic") || mount == "" {
+		return "", "", errors.New(path + " is not in a kv mount")
+	}
+	version, _ := options["version"].(string)
+	if version != "2" {
+		version = "1"
+	}
+	return mount, version, nil
+}
+
+// the mount a path is under, if it is a kv version 2 mount
+func (auth AuthInfo) kvV2Mount(path string) (string, error) {
+	mount, version, err := auth.kvMount(path)
+	if err != nil {
+		return "", err
+	}
+	if version != "2" {
+		return "", errors.New(path + " is not in a kv version 2 mount")
+	}
+	return mount, nil
+}
+
+// when a kv version 2 secret was last written
+func (auth AuthInfo) KVSecretMetadata(path string) (*KVSecret, error) {
+	mount, err := auth.kvV2Mount(path)
+	if err != nil {
+		return nil, err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+	return readKVMetadata(client, mount, path)
+}
+
+func readKVMetadata(client *api.Client, mount, path string) (*KVSecret, error) {
+	meta, err := client.Logical().Read(mount + "metadata/" + strings.TrimPrefix(path, mount))
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, errors.New(path + " does not exist")
+	}
+	s := &KVSecret{Path: path, Mount: mount}
+	updated, _ := meta.Data["updated_time"].(string)
+	s.Updated, _ = time.Parse(time.RFC3339Nano, updated)
+	if version, ok := meta.Data["current_version"].(json.Number); ok {
+		s.Version, _ = version.Int64()
+	}
+	return s, nil
+}
+
+// every kv version 2 secret under a folder ending in '/', with when it was last written
+// listed with the token's own access to the mount's metadata, and folders it can't list are skipped
+// secrets whose metadata can't be read are returned as skipped, so a report can say it is incomplete
+func (auth AuthInfo) KVSecrets(prefix string) (secrets []KVSecret, skipped []string, err error) {
+	if !strings.HasSuffix(prefix, "/") {
+		return nil, nil, errors.New("Prefix must be a folder, ending in '/'")
+	}
+	mount, err := auth.kvV2Mount(prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paths, err := walkSecrets(prefix, maxSecretWalk, func(folder string) ([]interface{}, error) {
+		keys, err := listKeys(client, mount+"metadata/"+strings.TrimPrefix(prefix+folder, mount))
+		// the prefix itself must be listable, but not every folder under it
+		if folder != "" {
+			return keys, nil
+		}
+		if err == nil && keys == nil {
+			err = errors.New("No secrets under " + prefix)
+		}
+		return keys, err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	secrets = make([]KVSecret, 0, len(paths))
+	skipped = []string{}
+	for _, path := range paths {
+		if s, err := readKVMetadata(client, mount, prefix+path); err == nil {
+			secrets = append(secrets, *s)
+		} else {
+			skipped = append(skipped, prefix+path)
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Path < secrets[j].Path })
+	return secrets, skipped, nil
+}
+
+// the path a kv secret's data is read and written at, whichever kv version its mount is
+func (auth AuthInfo) KVDataPath(path string) (string, error) {
+	mount, version, err := auth.kvMount(path)
+	if err != nil {
+		return "", err
+	}
+	if version == "2" {
+		return mount + "data/" + strings.TrimPrefix(path, mount), nil
+	}
+	return path, nil
+}
+
+// a kv secret's data, or nil if it does not exist
+func (auth AuthInfo) ReadKV(path string) (map[string]interface{}, error) {
+	dataPath, err := auth.KVDataPath(path)
+	if err != nil {
+		return nil, err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Logical().Read(dataPath)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	if dataPath == path {
+		return resp.Data, nil
+	}
+	// a deleted version 2 secret reads as metadata with no data
+	data, _ := resp.Data["data"].(map[string]interface{})
+	return data, nil
+}
+
+func (auth AuthInfo) WriteKV(path string, data map[string]interface{}) error {
+	dataPath, err := auth.KVDataPath(path)
+	if err != nil {
+		return err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	if dataPath != path {
+		data = map[string]interface{}{"data": data}
+	}
+	_, err = client.Logical().Write(dataPath, data)
+	return err
+}
+
+// every secret under a kv folder ending in '/', relative to it and sorted, whichever kv version its mount is
+func (auth AuthInfo) ListKV(folder string) ([]string, error) {
+	if !strings.HasSuffix(folder, "/") {
+		return nil, errors.New("Path must be a folder, ending in '/'")
+	}
+	mount, version, err := auth.kvMount(folder)
+	if err != nil {
+		return nil, err
+	}
+	listPath := folder
+	if version == "2" {
+		listPath = mount + "metadata/" + strings.TrimPrefix(folder, mount)
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	return walkSecrets(folder, maxSecretWalk, func(sub string) ([]interface{}, error) {
+		return listKeys(client, listPath+sub)
+	})
+}
+
+// the keys listed at a path, or nil if there are none
+func listKeys(client *api.Client, path string) ([]interface{}, error) {
+	resp, err := client.Logical().List(path)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	keys, _ := resp.Data["keys"].([]interface{})
+	return keys, nil
+}
+
+// every secret below a folder, with paths relative to it, sorted
+// list is called with each folder, also relative, and an error from it stops the walk
+// more than limit keys, folders included, is an error, unless limit is 0
+func walkSecrets(folder string, limit int, list func(sub string) ([]interface{}, error)) ([]string, error) {
+	secrets := make([]string, 0)
+	walked := 0
+	var walk func(sub string) error
+	walk = func(sub string) error {
+		keys, err := list(sub)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			key, ok := k.(string)
+			if !ok {
+				continue
+			}
+			if walked++; limit > 0 && walked > limit {
+				return fmt.Errorf("More than %d secrets are under %s, narrow it down", limit, folder)
+			}
+			if strings.HasSuffix(key, "/") {
+				if err := walk(sub + key); err != nil {
+					return err
+				}
+			} else {
+				secrets = append(secrets, sub+key)
+			}
+		}
+		return nil
+	}
+	if err := walk(""); err != nil {
+		return nil, err
+	}
+	sort.Strings(secrets)
+	return secrets, nil
+}
@@ -0,0 +1,149 @@
+package vault
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// how a kv version 1 mount is migrated: its secrets copied into a kv version 2 mount,
+// or the mount itself upgraded, which vault does in the background while the mount is unavailable
+type KVMigration struct {
+	Mode        string `json:"mode"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Overwrite   bool   `json:"overwrite"`
+}
+
+// one secret of a migration. Status is copy or conflict in a plan, and copied, skipped, failed or "upgrade started" once run
+type KVMigrationStep struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// checks the mounts, and trims them to the form "secret/"
+func (auth AuthInfo) checkKVMigration(m *KVMigration) error {
+	m.Source = strings.Trim(m.Source, "/") + "/"
+	source, version, err := auth.kvMount(m.Source)
+	if err != nil {
+		return err
+	}
+	if source != m.Source {
+		return errors.New("Source must be a mount, e.g. \"secret/\"")
+	}
+	if version != "1" {
+		return errors.New(m.Source + " is already kv version 2")
+	}
+
+	switch m.Mode {
+	case "upgrade":
+		m.Destination = m.Source
+	case "copy":
+		m.Destination = strings.Trim(m.Destination, "/") + "/"
+		destination, err := auth.kvV2Mount(m.Destination)
+		if err != nil {
+			return err
+		}
+		if destination != m.Destination {
+			return errors.New("Destination must be a mount, e.g. \"secret-v2/\"")
+		}
+	default:
+		return errors.New("Mode must be copy or upgrade")
+	}
+	return nil
+}
+
+// what a migration would do to each secret, without changing anything
+// copied secrets that already exist in the destination conflict, and are only replaced with overwrite
+func (auth AuthInfo) PlanKVMigration(m *KVMigration) ([]KVMigrationStep, error) {
+	if err := auth.checkKVMigration(m); err != nil {
+		return nil, err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+	// unlike other walks there is no limit, as a migration must see every secret
+	secrets, err := walkSecrets(m.Source, 0, func(folder string) ([]interface{}, error) {
+		return listKeys(client, m.Source+folder)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make([]KVMigrationStep, 0, len(secrets))
+	for _, s := range secrets {
+		step := KVMigrationStep{Path: s, Status: "copy"}
+		if m.Mode == "copy" {
+			existing, err := client.Logical().Read(m.Destination + "metadata/" + s)
+			if err != nil {
+				return nil, err
+			}
+			if existing != nil {
+				step.Status = "conflict"
+			}
+		}
+		plan = append(plan, step)
+	}
+	return plan, nil
+}
+
+// runs a migration, calling progress after each secret. An error from progress stops the migration
+// upgrades tune the mount and report each secret's upgrade as started, since vault moves them itself in the background
+func (auth AuthInfo) RunKVMigration(m *KVMigration, progress func(KVMigrationStep) error) error {
+	plan, err := auth.PlanKVMigration(m)
+	if err != nil {
+		return err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+
+	if m.Mode == "upgrade" {
+		if _, err := client.Logical().Write("sys/mounts/"+m.Source+"tune", map[string]interface{}{
+			"options": map[string]interface{}{"version": "2"},
+		}); err != nil {
+			return err
+		}
+		for _, step := range plan {
+			step.Status = "upgrade started"
+			if err := progress(step); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, step := range plan {
+		if step.Status == "conflict" && !m.Overwrite {
+			step.Status = "skipped"
+		} else if err := copyKVSecret(client, m, step.Path); err != nil {
+			step.Status = "failed"
+			step.Error = err.Error()
+		} else {
+			step.Status = "copied"
+		}
+		if err := progress(step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copies one secret's data as a new version of it in the destination
+func copyKVSecret(client *api.Client, m *KVMigration, path string) error {
+	secret, err := client.Logical().Read(m.Source + path)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		return errors.New("Secret was deleted during the migration")
+	}
+	_, err = client.Logical().Write(m.Destination+"data/"+path, map[string]interface{}{
+		"data": secret.Data,
+	})
+	return err
+}
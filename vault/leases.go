@@ -0,0 +1,145 @@
+package vault
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// each lease's expiry is a separate lookup, so only this many are looked up per summary
+const maxLeaseLookups = 500
+
+// secrets engines that issue leased credentials. Pki only does if a role has generate_lease set
+var dynamicEngines = map[string]bool{
+	"aws":        true,
+	"azure":      true,
+	"cassandra":  true,
+	"consul":     true,
+	"database":   true,
+	"gcp":        true,
+	"mongodb":    true,
+	"mssql":      true,
+	"mysql":      true,
+	"nomad":      true,
+	"pki":        true,
+	"postgresql": true,
+	"rabbitmq":   true,
+}
+
+// the outstanding leases of one role of a mount, e.g. role "creds/readonly" of mount "database/"
+// if more leases are outstanding than could be looked up, the soonest expiry is of those looked up
+type LeaseGroup struct {
+	Mount         string     `json:"mount"`
+	Type          string     `json:"type"`
+	Role          string     `json:"role"`
+	Count         int        `json:"count"`
+	LookedUp      int        `json:"looked_up"`
+	SoonestExpiry *time.Time `json:"soonest_expiry"`
+}
+
+// counts the outstanding leases of every dynamic secrets engine, by mount and role
+// vault only lists leases for tokens with sudo on sys/leases/lookup. Mounts that can't be listed are
+// returned with their error, rather than failing the whole summary
+func (auth AuthInfo) LeaseSummary() ([]LeaseGroup, map[string]string, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, nil, err
+	}
+	mounts, err := client.Sys().ListMounts()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paths := make([]string, 0, len(mounts))
+	for path, m := range mounts {
+		if m != nil && dynamicEngines[m.Type] {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	groups := make([]LeaseGroup, 0)
+	errs := make(map[string]string)
+	lookups := 0
+	for _, mount := range paths {
+		leases := make(map[string][]string)
+		if err := listLeases(client, mount, "", leases); err != nil {
+			errs[mount] = err.Error()
+			continue
+		}
+
+		roles := make([]string, 0, len(leases))
+		for role := range leases {
+			roles = append(roles, role)
+		}
+		sort.Strings(roles)
+		for _, role := range roles {
+			g := LeaseGroup{
+				Mount: mount,
+				Type:  mounts[mount].Type,
+				Role:  role,
+				Count: len(leases[role]),
+			}
+			for _, id := range leases[role] {
+				if lookups >= maxLeaseLookups {
+					break
+				}
+				lookups++
+				expiry, err := leaseExpiry(client, id)
+				if err != nil {
+					continue
+				}
+				g.LookedUp++
+				if expiry != nil && (g.SoonestExpiry == nil || expiry.Before(*g.SoonestExpiry)) {
+					g.SoonestExpiry = expiry
+				}
+			}
+			groups = append(groups, g)
+		}
+	}
+	return groups, errs, nil
+}
+
+// walks the lease prefixes under a mount, collecting lease ids by the prefix they were issued under
+func listLeases(client *api.Client, mount, prefix string, leases map[string][]string) error {
+	resp, err := client.Logical().List("sys/leases/lookup/" + mount + prefix)
+	if err != nil || resp == nil {
+		return err
+	}
+	keys, _ := resp.Data["keys"].([]interface{})
+	for _, k := range keys {
+		key, ok := k.(string)
+		if !ok {
+			continue
+		}
+		if strings.HasSuffix(key, "/") {
+			if err := listLeases(client, mount, prefix+key, leases); err != nil {
+				return err
+			}
+			continue
+		}
+		role := strings.TrimSuffix(prefix, "/")
+		leases[role] = append(leases[role], mount+prefix+key)
+	}
+	return nil
+}
+
+func leaseExpiry(client *api.Client, id string) (*time.Time, error) {
+	resp, err := client.Logical().Write("sys/leases/lookup", map[string]interface{}{
+		"lease_id": id,
+	})
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	raw, _ := resp.Data["expire_time"].(string)
+	if raw == "" {
+		return nil, nil
+	}
+	expiry, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &expiry, nil
+}
@@ -0,0 +1,126 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// a listing, cut short at list_max_items
+type KeyList struct {
+	Keys      []interface{}
+	Truncated bool
+}
+
+// lists a path, calling each with every key as it is decoded from vault's response,
+// so the response is never held in memory. Stops after list_max_items keys, returning true if there were more
+func (auth AuthInfo) StreamList(path string, each func(key string) error) (bool, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return false, err
+	}
+
+	// the same request as the api's own List
+	r := client.NewRequest("GET", "/v1/"+path)
+	r.Params.Set("list", "true")
+	resp, err := client.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if resp != nil && resp.StatusCode == 404 {
+		return false, errors.New("Invalid path")
+	}
+	if err != nil {
+		return false, err
+	}
+
+	max := getVaultConfig().List_max_items
+	dec := json.NewDecoder(resp.Body)
+	if err := expectDelim(dec, '{'); err != nil {
+		return false, err
+	}
+	for dec.More() {
+		if field, err := dec.Token(); err != nil {
+			return false, err
+		} else if field != "data" {
+			if err := skipValue(dec); err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		if err := expectDelim(dec, '{'); err != nil {
+			return false, err
+		}
+		for dec.More() {
+			if field, err := dec.Token(); err != nil {
+				return false, err
+			} else if field != "keys" {
+				if err := skipValue(dec); err != nil {
+					return false, err
+				}
+				continue
+			}
+
+			if err := expectDelim(dec, '['); err != nil {
+				return false, err
+			}
+			for n := 0; dec.More(); n++ {
+				if max > 0 && n >= max {
+					return true, nil
+				}
+				var key string
+				if err := dec.Decode(&key); err != nil {
+					return false, err
+				}
+				if err := each(key); err != nil {
+					return false, err
+				}
+			}
+			return false, nil
+		}
+	}
+	return false, errors.New("Vault's response to listing " + path + " has no keys")
+}
+
+// a listing collected in memory, for responses that aren't streamed
+func (auth AuthInfo) ListKeys(path string) (KeyList, error) {
+	list := KeyList{Keys: make([]interface{}, 0)}
+	truncated, err := auth.StreamList(path, func(key string) error {
+		list.Keys = append(list.Keys, key)
+		return nil
+	})
+	list.Truncated = truncated
+	return list, err
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t != delim {
+		return fmt.Errorf("Unexpected %v in vault's response, expected %v", t, delim)
+	}
+	return nil
+}
+
+// skips a value without decoding it, in case it is large too
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
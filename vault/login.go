@@ -2,14 +2,17 @@ package vault
 
 import (
 	"errors"
+	"sort"
 	"strings"
+	"sync"
 
+	"github.com/caiyeon/goldfish/config"
 	"github.com/hashicorp/vault/api"
 )
 
-// constructs a client with server's vault address and client access token
+// constructs a client with the session's cluster address and client access token
 func (auth AuthInfo) Client() (client *api.Client, err error) {
-	if client, err = NewVaultClient(); err == nil {
+	if client, err = newClusterClient(auth.Cluster, auth.Namespace, auth.Trace, auth.Audit); err == nil {
 		client.SetToken(auth.ID)
 	}
 	return client, err
@@ -18,18 +21,18 @@ func (auth AuthInfo) Client() (client *api.Client, err error) {
 // verifies whether auth ID and password are valid
 // if valid, creates a client access token and returns the metadata
 func (auth *AuthInfo) Login() (map[string]interface{}, error) {
-	client, err := NewVaultClient()
+	client, err := newClusterClient(auth.Cluster, auth.Namespace, auth.Trace, auth.Audit)
 	if err != nil {
 		return nil, err
 	}
 	client.SetToken("")
 
 	// supported means there's a mapping to how the login should be performed
-	t := strings.ToLower(auth.Type)
-	key, exists := LoginMap[t]
-	if !exists {
-		return nil, errors.New("Unsupported authentication type: " + t)
+	t, path, err := loginMethod(auth.Type)
+	if err != nil {
+		return nil, err
 	}
+	key := LoginMap[t]
 
 	// token logins don't require any writes to vault
 	if t == "token" {
@@ -38,8 +41,8 @@ func (auth *AuthInfo) Login() (map[string]interface{}, error) {
 
 	// if logging in for the first time with these auth backends
 	if t == "userpass" || t == "ldap" || t == "github" || t == "okta" {
-		// fetch a client token by logging. Auth backend is hardcoded for now
-		resp, err := client.Logical().Write("auth/" + t + "/login/" + auth.ID,
+		// fetch a client token by logging in at the login's mount
+		resp, err := client.Logical().Write(path + "/login/" + auth.ID,
 			map[string]interface{}{
 				key: auth.Pass,
 			})
@@ -69,11 +72,16 @@ func (auth *AuthInfo) Login() (map[string]interface{}, error) {
 }
 
 func (auth AuthInfo) RenewSelf() (*api.Secret, error) {
+	return auth.RenewSelfIncrement(0)
+}
+
+// increment is in seconds. Vault caps it at the token's max ttl, and zero leaves the choice to vault
+func (auth AuthInfo) RenewSelfIncrement(increment int) (*api.Secret, error) {
 	client, err := auth.Client()
 	if err != nil {
 		return nil, err
 	}
-	return client.Auth().Token().RenewSelf(0)
+	return client.Auth().Token().RenewSelf(increment)
 }
 
 func (auth AuthInfo) LookupSelf() (*api.Secret, error) {
@@ -92,3 +100,69 @@ var LoginMap = map[string]string{
 	"ldap": "password",
 	"okta": "password",
 }
+
+var (
+	logins     map[string]config.LoginConfig
+	loginsLock = new(sync.RWMutex)
+)
+
+// may be called again at runtime, e.g. when the config file is reloaded
+func SetLogins(c map[string]*config.LoginConfig) {
+	loginsLock.Lock()
+	defer loginsLock.Unlock()
+	logins = make(map[string]config.LoginConfig, len(c))
+	for name, l := range c {
+		logins[name] = *l
+	}
+}
+
+// a login option the login page offers
+type LoginOption struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Label string `json:"label"`
+}
+
+// the mount userpass users are managed at: the first configured userpass login by name,
+// or vault's default mount if none is
+func userpassPath() string {
+	loginsLock.RLock()
+	defer loginsLock.RUnlock()
+	path, name := "auth/userpass", ""
+	for _, l := range logins {
+		if l.Type == "userpass" && (name == "" || l.Name < name) {
+			path, name = l.Path, l.Name
+		}
+	}
+	return path
+}
+
+// the configured logins, sorted by name. Empty if none are, in which case each type is offered at its default mount
+func LoginOptions() []LoginOption {
+	loginsLock.RLock()
+	defer loginsLock.RUnlock()
+	options := make([]LoginOption, 0, len(logins))
+	for _, l := range logins {
+		options = append(options, LoginOption{Name: l.Name, Type: l.Type, Label: l.Label})
+	}
+	sort.Slice(options, func(i, j int) bool { return options[i].Name < options[j].Name })
+	return options
+}
+
+// the type and mount of a login, by name: a configured login, or one of LoginMap's types at vault's default mount
+// once any logins are configured, only those and tokens may be used
+func loginMethod(name string) (string, string, error) {
+	loginsLock.RLock()
+	l, ok := logins[name]
+	configured := len(logins) > 0
+	loginsLock.RUnlock()
+	if ok {
+		return l.Type, l.Path, nil
+	}
+
+	t := strings.ToLower(name)
+	if _, exists := LoginMap[t]; !exists || (configured && t != "token") {
+		return "", "", errors.New("Unsupported authentication type: " + name)
+	}
+	return t, "auth/" + t, nil
+}
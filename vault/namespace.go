@@ -0,0 +1,99 @@
+package vault
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// vault enterprise runs each call in the namespace this header names, relative to the token's own
+// older vaults and open source vault ignore it, so an empty namespace is always the root
+const NamespaceHeader = "X-Vault-Namespace"
+
+var namespaceSegment = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// trims a namespace to the form "parent/child", with no leading or trailing slash. Empty is the root
+func NormalizeNamespace(namespace string) (string, error) {
+	namespace = strings.Trim(strings.TrimSpace(namespace), "/")
+	if namespace == "" {
+		return "", nil
+	}
+	for _, segment := range strings.Split(namespace, "/") {
+		if !namespaceSegment.MatchString(segment) || segment == "." || segment == ".." {
+			return "", errors.New("Invalid namespace: " + namespace)
+		}
+	}
+	return namespace, nil
+}
+
+// sends the namespace with every call, the same way audit info is sent
+func newNamespaceTransport(base http.RoundTripper, namespace string) http.RoundTripper {
+	if namespace == "" {
+		return base
+	}
+	headers := make(http.Header)
+	headers.Set(NamespaceHeader, namespace)
+	return &auditTransport{base: base, headers: headers}
+}
+
+// the namespaces directly under the auth's namespace, as full paths, that the token may list
+// a token without list on sys/namespaces, or a vault without namespaces, has none
+func (auth AuthInfo) ChildNamespaces() ([]string, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Logical().List("sys/namespaces")
+	if err != nil {
+		if strings.Contains(err.Error(), "Code: 403") {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	children := make([]string, 0)
+	if resp == nil {
+		return children, nil
+	}
+	keys, _ := resp.Data["keys"].([]interface{})
+	for _, k := range keys {
+		key, ok := k.(string)
+		if !ok {
+			continue
+		}
+		child := strings.Trim(key, "/")
+		if auth.Namespace != "" {
+			child = auth.Namespace + "/" + child
+		}
+		children = append(children, child)
+	}
+	sort.Strings(children)
+	return children, nil
+}
+
+// whether the token can be used in another namespace, which it can in its own and those under it
+func (auth AuthInfo) CanEnterNamespace(namespace string) error {
+	auth.Namespace = namespace
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	if _, err := client.Auth().Token().LookupSelf(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// goldfish's sessions, credentials and settings are only administered from its own cluster's root namespace,
+// since an admin of another cluster or of a child namespace may hold any policy there
+func (auth AuthInfo) requireGoldfishScope(action string) error {
+	if auth.Cluster != "" {
+		return errors.New(action + " requires a session on goldfish's own cluster")
+	}
+	if auth.Namespace != "" {
+		return errors.New(action + " requires a session in the root namespace")
+	}
+	return nil
+}
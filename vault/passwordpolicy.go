@@ -0,0 +1,114 @@
+package vault
+
+import (
+	"errors"
+	"strings"
+)
+
+func passwordPolicyPath(name string) (string, error) {
+	if name == "" || strings.Contains(name, "/") {
+		return "", errors.New("Invalid password policy name")
+	}
+	return "sys/policies/password/" + name, nil
+}
+
+// the names of vault's password policies, which describe how generated passwords are made
+func (auth AuthInfo) ListPasswordPolicies() ([]string, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Logical().List("sys/policies/password")
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	if resp == nil {
+		return names, nil
+	}
+	keys, _ := resp.Data["keys"].([]interface{})
+	for _, k := range keys {
+		if name, ok := k.(string); ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// the password policy's hcl, e.g. its length and charset rules
+func (auth AuthInfo) GetPasswordPolicy(name string) (string, error) {
+	path, err := passwordPolicyPath(name)
+	if err != nil {
+		return "", err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Logical().Read(path)
+	if err != nil {
+		return "", err
+	}
+	if resp == nil {
+		return "", errors.New("Password policy " + name + " does not exist")
+	}
+	policy, _ := resp.Data["policy"].(string)
+	return policy, nil
+}
+
+// vault checks that the policy can generate a password before storing it
+func (auth AuthInfo) PutPasswordPolicy(name, policy string) error {
+	path, err := passwordPolicyPath(name)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(policy) == "" {
+		return errors.New("Empty password policy")
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Write(path, map[string]interface{}{
+		"policy": policy,
+	})
+	return err
+}
+
+func (auth AuthInfo) DeletePasswordPolicy(name string) error {
+	path, err := passwordPolicyPath(name)
+	if err != nil {
+		return err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Delete(path)
+	return err
+}
+
+// a random password that complies with the policy, generated by vault
+func (auth AuthInfo) GeneratePassword(name string) (string, error) {
+	path, err := passwordPolicyPath(name)
+	if err != nil {
+		return "", err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Logical().Read(path + "/generate")
+	if err != nil {
+		return "", err
+	}
+	if resp == nil {
+		return "", errors.New("Password policy " + name + " does not exist")
+	}
+	password, _ := resp.Data["password"].(string)
+	if password == "" {
+		return "", errors.New("Vault generated no password")
+	}
+	return password, nil
+}
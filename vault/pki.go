@@ -0,0 +1,59 @@
+package vault
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"strings"
+)
+
+// reads each certificate issued by a pki mount, skipping revoked ones
+// an error from fn stops the reads
+func (auth AuthInfo) EachCertificate(mount string, fn func(serial string, cert *x509.Certificate) error) error {
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	logical := client.Logical()
+	mount = strings.Trim(mount, "/")
+
+	resp, err := logical.List(mount + "/certs")
+	if err != nil {
+		return err
+	}
+	if resp == nil {
+		return nil
+	}
+	serials, ok := resp.Data["keys"].([]interface{})
+	if !ok {
+		return errors.New("Failed to list certificates of " + mount)
+	}
+
+	for _, s := range serials {
+		serial, ok := s.(string)
+		if !ok {
+			continue
+		}
+		resp, err := logical.Read(mount + "/cert/" + serial)
+		if err != nil || resp == nil {
+			continue
+		}
+		if revoked, _ := resp.Data["revocation_time"].(json.Number); revoked != "" && revoked != "0" {
+			continue
+		}
+		raw, _ := resp.Data["certificate"].(string)
+		block, _ := pem.Decode([]byte(raw))
+		if block == nil {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		if err := fn(serial, cert); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package vault
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"golang.org/x/net/http2"
+)
+
+// idle connections kept open to each vault node
+const maxIdleVaultConns = 32
+
+// what a cluster's transport was built from. Any change, including its tls files changing on disk, builds a new one
+type transportKey struct {
+	address string
+	tls     tlsSettings
+	stamp   string
+	proxy   string
+	noProxy string
+}
+
+type pooledTransport struct {
+	key       transportKey
+	transport *http.Transport
+}
+
+// one transport per cluster, by name, so its connections are kept alive and shared by every request
+// api clients are still made per request, since each carries a user's token, namespace and trace
+var (
+	transports     = make(map[string]pooledTransport)
+	transportsLock = new(sync.Mutex)
+)
+
+// the cluster's shared transport, built on first use or whenever its config changed
+func sharedTransport(cluster, address string, settings tlsSettings) (*http.Transport, error) {
+	c := getVaultConfig()
+	key := transportKey{
+		address: address,
+		tls:     settings,
+		stamp:   fileStamp(settings.caCert, settings.caPath, settings.clientCert, settings.clientKey),
+		proxy:   c.Proxy_address,
+		noProxy: c.No_proxy,
+	}
+
+	transportsLock.Lock()
+	defer transportsLock.Unlock()
+	pooled, ok := transports[cluster]
+	if ok && pooled.key == key {
+		return pooled.transport, nil
+	}
+
+	tlsConfig, err := vaultTLSConfig(settings)
+	if err != nil {
+		return nil, err
+	}
+	t := cleanhttp.DefaultPooledTransport()
+	t.TLSClientConfig = tlsConfig
+	t.Proxy = vaultProxy(c)
+	t.MaxIdleConnsPerHost = maxIdleVaultConns
+	// as api.NewClient would, which can't be given a transport that is already configured
+	if err := http2.ConfigureTransport(t); err != nil {
+		return nil, err
+	}
+
+	// requests already using the old transport finish on their connections
+	if ok {
+		pooled.transport.CloseIdleConnections()
+	}
+	transports[cluster] = pooledTransport{key: key, transport: t}
+	return t, nil
+}
@@ -0,0 +1,48 @@
+package vault
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/caiyeon/goldfish/config"
+)
+
+// returns the proxy for requests to vault: proxy_address if set, otherwise the standard
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY env variables. Hosts in no_proxy always connect directly
+func vaultProxy(c config.VaultConfig) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(req.URL.Hostname(), c.No_proxy) {
+			return nil, nil
+		}
+		if c.Proxy_address == "" {
+			return http.ProxyFromEnvironment(req)
+		}
+		return url.Parse(c.Proxy_address)
+	}
+}
+
+// no_proxy is a comma separated list of hosts, domain suffixes (e.g. ".internal"), and CIDRs
+func bypassProxy(host, noProxy string) bool {
+	for _, entry := range strings.Split(noProxy, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip := net.ParseIP(host); ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		entry = strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// the kinds of quota vault has. Lease count quotas need vault enterprise
+var quotaTypes = []string{"rate-limit", "lease-count"}
+
+// a rate limit or lease count quota on a path, e.g. a mount "secret/", a namespace "team-a/", or everything ""
+// Rate, Interval and Block_interval apply to rate limits, Max_leases to lease counts
+type Quota struct {
+	Name           string  `json:"name"`
+	Type           string  `json:"type"`
+	Path           string  `json:"path"`
+	Rate           float64 `json:"rate,omitempty"`
+	Interval       string  `json:"interval,omitempty"`
+	Block_interval string  `json:"block_interval,omitempty"`
+	Max_leases     int64   `json:"max_leases,omitempty"`
+}
+
+func (q *Quota) Validate() error {
+	if q.Name == "" || strings.Contains(q.Name, "/") {
+		return errors.New("A quota needs a name, without '/'")
+	}
+	switch q.Type {
+	case "rate-limit":
+		if q.Rate <= 0 {
+			return errors.New("A rate limit quota needs a rate above zero")
+		}
+	case "lease-count":
+		if q.Max_leases <= 0 {
+			return errors.New("A lease count quota needs max_leases above zero")
+		}
+	default:
+		return errors.New("Quota type must be rate-limit or lease-count")
+	}
+	return nil
+}
+
+// every quota in the auth's namespace, sorted by type then path
+// a quota type the vault doesn't have, e.g. lease counts on open source vault, is left out
+func (auth AuthInfo) ListQuotas() ([]Quota, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	quotas := make([]Quota, 0)
+	for _, typ := range quotaTypes {
+		resp, err := client.Logical().List("sys/quotas/" + typ)
+		if err != nil {
+			if typ == "lease-count" && !strings.Contains(err.Error(), "Code: 403") {
+				continue
+			}
+			return nil, err
+		}
+		if resp == nil {
+			continue
+		}
+		keys, _ := resp.Data["keys"].([]interface{})
+		for _, k := range keys {
+			name, ok := k.(string)
+			if !ok {
+				continue
+			}
+			q, err := auth.GetQuota(typ, name)
+			if err != nil {
+				return nil, err
+			}
+			if q != nil {
+				quotas = append(quotas, *q)
+			}
+		}
+	}
+	sort.Slice(quotas, func(i, j int) bool {
+		if quotas[i].Type != quotas[j].Type {
+			return quotas[i].Type > quotas[j].Type
+		}
+		return quotas[i].Path < quotas[j].Path
+	})
+	return quotas, nil
+}
+
+// returns nil if the quota does not exist
+func (auth AuthInfo) GetQuota(typ, name string) (*Quota, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Logical().Read("sys/quotas/" + typ + "/" + name)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+
+	q := &Quota{Name: name, Type: typ}
+	q.Path, _ = resp.Data["path"].(string)
+	if rate, ok := resp.Data["rate"].(json.Number); ok {
+		q.Rate, _ = rate.Float64()
+	}
+	q.Interval = quotaSeconds(resp.Data["interval"])
+	q.Block_interval = quotaSeconds(resp.Data["block_interval"])
+	if max, ok := resp.Data["max_leases"].(json.Number); ok {
+		q.Max_leases, _ = max.Int64()
+	}
+	return q, nil
+}
+
+// vault reports intervals in seconds, and takes them back as durations
+func quotaSeconds(v interface{}) string {
+	n, ok := v.(json.Number)
+	if !ok || n.String() == "0" {
+		return ""
+	}
+	return n.String() + "s"
+}
+
+// creates or replaces a quota
+func (auth AuthInfo) WriteQuota(q Quota) error {
+	if err := q.Validate(); err != nil {
+		return err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{
+		"path": q.Path,
+	}
+	if q.Type == "rate-limit" {
+		data["rate"] = q.Rate
+		if q.Interval != "" {
+			data["interval"] = q.Interval
+		}
+		if q.Block_interval != "" {
+			data["block_interval"] = q.Block_interval
+		}
+	} else {
+		data["max_leases"] = q.Max_leases
+	}
+	_, err = client.Logical().Write("sys/quotas/"+q.Type+"/"+q.Name, data)
+	return err
+}
+
+func (auth AuthInfo) DeleteQuota(typ, name string) error {
+	if name == "" || strings.Contains(name, "/") {
+		return errors.New("Invalid quota name")
+	}
+	if typ != "rate-limit" && typ != "lease-count" {
+		return errors.New("Quota type must be rate-limit or lease-count")
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Delete("sys/quotas/" + typ + "/" + name)
+	return err
+}
+
+// vault's quota settings: whether rejections are audit logged, whether rate limit headers are sent,
+// and paths exempt from rate limits
+func (auth AuthInfo) GetQuotaConfig() (map[string]interface{}, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Logical().Read("sys/quotas/config")
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errors.New("This vault has no quotas. They need vault 1.5 or newer")
+	}
+	return resp.Data, nil
+}
+
+func (auth AuthInfo) WriteQuotaConfig(data map[string]interface{}) error {
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Write("sys/quotas/config", data)
+	return err
+}
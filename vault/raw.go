@@ -0,0 +1,88 @@
+package vault
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+// maps an http method to the vault capabilities that would allow it
+var rawMethodCapabilities = map[string][]string{
+	"GET":    []string{"read"},
+	"LIST":   []string{"list"},
+	"POST":   []string{"create", "update"},
+	"PUT":    []string{"create", "update"},
+	"DELETE": []string{"delete"},
+}
+
+type RawResponse struct {
+	StatusCode int
+	Body       map[string]interface{}
+}
+
+// checks if the current auth has the capability to perform method on path
+func (auth *AuthInfo) RawPreflight(method, path string) error {
+	allowed, ok := rawMethodCapabilities[method]
+	if !ok {
+		return errors.New("Unsupported method: " + method)
+	}
+
+	capabilities, err := auth.CapabilitiesSelf(path)
+	if err != nil {
+		return err
+	}
+
+	if capabilitiesAllow(capabilities, allowed) {
+		return nil
+	}
+	return errors.New("Permission denied: token lacks '" +
+		strings.Join(allowed, "' or '") + "' capability on " + path)
+}
+
+// forwards an arbitrary request to vault with the user's token
+func (auth *AuthInfo) RawRequest(method, path string, body map[string]interface{}) (*RawResponse, error) {
+	method = strings.ToUpper(method)
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "/"), "v1/")
+	if path == "" {
+		return nil, errors.New("Empty path")
+	}
+
+	if err := auth.RawPreflight(method, path); err != nil {
+		return nil, err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	// LIST is sent as a GET with a list parameter for compatibility, same as the api package
+	r := client.NewRequest(method, "/v1/"+path)
+	if method == "LIST" {
+		r.Method = "GET"
+		r.Params.Set("list", "true")
+	}
+	if body != nil && method != "GET" && method != "LIST" {
+		if err := r.SetJSONBody(body); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := client.RawRequest(r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RawResponse{
+		StatusCode: resp.StatusCode,
+	}
+
+	// 204 and similar responses will not have a body
+	if err := resp.DecodeJSON(&result.Body); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return result, nil
+}
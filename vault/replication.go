@@ -0,0 +1,168 @@
+package vault
+
+import (
+	"errors"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// replication is a vault enterprise feature. Open source vaults answer every replication path with an error
+var replicationModes = map[string]bool{
+	"dr":          true,
+	"performance": true,
+}
+
+// the actions guided by goldfish, and the role a cluster must have in a mode to take them
+var replicationActions = map[string]string{
+	"promote": "secondary",
+	"demote":  "primary",
+	"disable": "",
+}
+
+const drOperationTokenPath = "sys/replication/dr/secondary/generate-operation-token/"
+
+// a client without a token, for what vault authorizes otherwise. A dr secondary accepts no tokens at all,
+// only dr operation tokens, which are generated with unseal keys
+func replicationClient(cluster string) (*api.Client, error) {
+	client, err := NewClusterClient(cluster)
+	if err == nil {
+		client.ClearToken()
+	}
+	return client, err
+}
+
+// the replication status of a cluster, by mode. Vault serves it without a token
+func ReplicationStatus(cluster string) (map[string]interface{}, error) {
+	client, err := replicationClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Logical().Read("sys/replication/status")
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errors.New("Vault returned no replication status")
+	}
+	return resp.Data, nil
+}
+
+// the cluster's role in a replication mode: primary, secondary, or disabled
+func ReplicationRole(cluster, mode string) (string, error) {
+	if !replicationModes[mode] {
+		return "", errors.New("Replication mode must be dr or performance")
+	}
+	status, err := ReplicationStatus(cluster)
+	if err != nil {
+		return "", err
+	}
+	m, _ := status[mode].(map[string]interface{})
+	role, _ := m["mode"].(string)
+	if role == "" {
+		return "", errors.New("Vault did not report a " + mode + " replication status. Replication needs vault enterprise")
+	}
+	return role, nil
+}
+
+// the progress of generating a dr operation token, like generate-root's status
+func DROperationTokenStatus(cluster string) (map[string]interface{}, error) {
+	return drOperationToken(cluster, "GET", "attempt", nil)
+}
+
+// starts generating a dr operation token. Without an otp or pgp key, newer vaults generate an otp
+func StartDROperationToken(cluster, otp, pgpKey string) (map[string]interface{}, error) {
+	body := map[string]interface{}{}
+	if otp != "" {
+		body["otp"] = otp
+	}
+	if pgpKey != "" {
+		body["pgp_key"] = pgpKey
+	}
+	return drOperationToken(cluster, "PUT", "attempt", body)
+}
+
+// provides one unseal key. The response holds the encoded token once enough keys were given
+func UpdateDROperationToken(cluster, key, nonce string) (map[string]interface{}, error) {
+	if key == "" || nonce == "" {
+		return nil, errors.New("Both an unseal key and the attempt's nonce are required")
+	}
+	return drOperationToken(cluster, "PUT", "update", map[string]interface{}{
+		"key":   key,
+		"nonce": nonce,
+	})
+}
+
+func CancelDROperationToken(cluster string) error {
+	_, err := drOperationToken(cluster, "DELETE", "attempt", nil)
+	return err
+}
+
+func drOperationToken(cluster, method, endpoint string, body map[string]interface{}) (map[string]interface{}, error) {
+	client, err := replicationClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	var resp *api.Secret
+	switch method {
+	case "GET":
+		resp, err = client.Logical().Read(drOperationTokenPath + endpoint)
+	case "PUT":
+		resp, err = client.Logical().Write(drOperationTokenPath+endpoint, body)
+	case "DELETE":
+		resp, err = client.Logical().Delete(drOperationTokenPath + endpoint)
+	}
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// checks that the cluster's role in the mode allows the action, and returns the path that takes it
+// e.g. only a secondary can be promoted, and disabling is done as whatever role the cluster has
+func ReplicationActionPath(cluster, mode, action string) (string, string, error) {
+	required, ok := replicationActions[action]
+	if !ok {
+		return "", "", errors.New("Replication action must be promote, demote or disable")
+	}
+	role, err := ReplicationRole(cluster, mode)
+	if err != nil {
+		return "", "", err
+	}
+	if role == "disabled" || role == "bootstrapping" {
+		return "", "", errors.New("This cluster has no " + mode + " replication role to " + action)
+	}
+	if required != "" && role != required {
+		return "", "", errors.New("Only a " + mode + " " + required + " can be " + action + "d, this cluster is a " + role)
+	}
+	return "sys/replication/" + mode + "/" + role + "/" + action, role, nil
+}
+
+// takes a replication action on a dr secondary, which is authorized by a dr operation token instead of a vault token
+func DRSecondaryAction(cluster, path, operationToken string, body map[string]interface{}) (map[string]interface{}, error) {
+	if operationToken == "" {
+		return nil, errors.New("A dr secondary needs a dr operation token, generate one first")
+	}
+	client, err := replicationClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	body["dr_operation_token"] = operationToken
+	resp, err := client.Logical().Write(path, body)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
+
+// takes a replication action with the user's token, which needs sudo on the path
+func (auth AuthInfo) ReplicationAction(path string, body map[string]interface{}) (map[string]interface{}, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Logical().Write(path, body)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	return resp.Data, nil
+}
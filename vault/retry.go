@@ -0,0 +1,109 @@
+package vault
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/caiyeon/goldfish/config"
+)
+
+// retries failed vault calls with exponential backoff, and bounds each attempt with a timeout
+type retryTransport struct {
+	base http.RoundTripper
+	conf config.VaultConfig
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	timeout := t.conf.Timeout
+	if isList(req) {
+		timeout = t.conf.List_timeout
+	}
+
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = new(http.Request)
+			*r = *req
+			r.Body = body
+		}
+
+		ctx, cancel := context.WithCancel(req.Context())
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(req.Context(), timeout)
+		}
+		resp, err := t.base.RoundTrip(r.WithContext(ctx))
+		if attempt >= t.conf.Max_retries || !shouldRetry(req, resp, err) {
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			// the deadline must outlive the response body
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		cancel()
+
+		select {
+		case <-time.After(backoff(attempt, t.conf.Retry_wait_min, t.conf.Retry_wait_max)):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// connection failures are always safe to retry, server errors only if the request is idempotent
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	// a request body can only be replayed if it can be recreated
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		if req.Context().Err() != nil {
+			return false
+		}
+		if opErr, ok := err.(*net.OpError); ok && opErr.Op == "dial" {
+			return true
+		}
+		return isIdempotent(req)
+	}
+	return resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented && isIdempotent(req)
+}
+
+func isIdempotent(req *http.Request) bool {
+	return req.Method == "GET" || req.Method == "HEAD" || req.Method == "LIST"
+}
+
+func isList(req *http.Request) bool {
+	return req.Method == "LIST" || req.URL.Query().Get("list") == "true"
+}
+
+// doubles the wait each attempt up to max, with jitter so retries from many requests spread out
+func backoff(attempt int, min, max time.Duration) time.Duration {
+	wait := min << uint(attempt)
+	if wait > max || wait <= 0 {
+		wait = max
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
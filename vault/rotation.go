@@ -0,0 +1,66 @@
+package vault
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hashicorp/vault/helper/parseutil"
+)
+
+// a secret with a rotation period, and when it is due. Age is known only for kv version 2 secrets,
+// from when they were last written. Error says why it couldn't be found otherwise
+type Rotation struct {
+	Path           string     `json:"path"`
+	Owner          string     `json:"owner"`
+	RotationPeriod string     `json:"rotation_period"`
+	Updated        *time.Time `json:"updated,omitempty"`
+	Due            *time.Time `json:"due,omitempty"`
+	Overdue        bool       `json:"overdue"`
+	Error          string     `json:"error,omitempty"`
+}
+
+// the secrets under prefix that were tagged with a rotation period, soonest due first
+// secrets whose age couldn't be found are last. Metadata is read with the token's own access
+func (auth AuthInfo) Rotations(prefix string) ([]Rotation, error) {
+	tags, err := ListSecretTags(prefix, SecretTags{})
+	if err != nil {
+		return nil, err
+	}
+
+	rotations := make([]Rotation, 0)
+	for _, t := range tags {
+		if t.RotationPeriod == "" {
+			continue
+		}
+		r := Rotation{
+			Path:           t.Path,
+			Owner:          t.Owner,
+			RotationPeriod: t.RotationPeriod,
+		}
+		period, err := parseutil.ParseDurationSecond(t.RotationPeriod)
+		if err != nil {
+			r.Error = "invalid rotation period"
+			rotations = append(rotations, r)
+			continue
+		}
+		s, err := auth.KVSecretMetadata(t.Path)
+		if err != nil {
+			r.Error = err.Error()
+			rotations = append(rotations, r)
+			continue
+		}
+		due := s.Updated.Add(period)
+		r.Updated, r.Due = &s.Updated, &due
+		r.Overdue = due.Before(time.Now())
+		rotations = append(rotations, r)
+	}
+
+	sort.SliceStable(rotations, func(i, j int) bool {
+		a, b := rotations[i].Due, rotations[j].Due
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return a.Before(*b)
+	})
+	return rotations, nil
+}
@@ -0,0 +1,139 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/caiyeon/goldfish/config"
+)
+
+var (
+	// "<backend>/<key>" of the dedicated session key, empty to fall back to the runtime config
+	sessionKey       string
+	sessionAdminPath string
+	sessionKeyLock   = new(sync.RWMutex)
+)
+
+func SetSessionConfig(c *config.SessionConfig) {
+	sessionKeyLock.Lock()
+	defer sessionKeyLock.Unlock()
+	sessionKey = ""
+	if c.Transit_key != "" {
+		sessionKey = c.Transit_backend + "/" + c.Transit_key
+	}
+	sessionAdminPath = c.Admin_path
+}
+
+// only users that can update the session admin path may revoke everyone's sessions
+func (auth *AuthInfo) CanAdministerSessions() error {
+	if err := auth.requireGoldfishScope("Session administration"); err != nil {
+		return err
+	}
+	return auth.RawPreflight("PUT", sessionAdministrationPath())
+}
+
+func sessionAdministrationPath() string {
+	sessionKeyLock.RLock()
+	path := sessionAdminPath
+	sessionKeyLock.RUnlock()
+	if path == "" {
+		path = getVaultConfig().Runtime_config
+	}
+	return path
+}
+
+// rotates the server transit key and retires every older version of it
+// this invalidates every stateless cipher handed out before server-side sessions existed
+func RetireServerTransitKey() error {
+	c := GetConfig()
+	if c.ServerTransitKey == "" {
+		return errors.New("No server transit key is configured")
+	}
+
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return err
+	}
+
+	path := c.TransitBackend + "/keys/" + c.ServerTransitKey
+	if _, err := client.Logical().Write(path+"/rotate", nil); err != nil {
+		return err
+	}
+
+	resp, err := client.Logical().Read(path)
+	if err != nil {
+		return err
+	}
+	if resp == nil {
+		return errors.New("Failed to read server transit key")
+	}
+	latest, ok := resp.Data["latest_version"].(json.Number)
+	if !ok {
+		return errors.New("Failed type assertion of response to json.Number")
+	}
+
+	_, err = client.Logical().Write(path+"/config", map[string]interface{}{
+		"min_decryption_version": latest,
+	})
+	return err
+}
+
+// returns the transit key new sessions should be encrypted with, as "<backend>/<key>"
+// an empty string means sessions are stored unencrypted
+func SessionTransitKey() string {
+	sessionKeyLock.RLock()
+	key := sessionKey
+	sessionKeyLock.RUnlock()
+	if key != "" {
+		return key
+	}
+
+	if c := GetConfig(); c.ServerTransitKey != "" {
+		return c.TransitBackend + "/" + c.ServerTransitKey
+	}
+	return ""
+}
+
+// key names can't contain slashes, but mount paths can
+func splitTransitKey(key string) (string, string) {
+	i := strings.LastIndex(key, "/")
+	return key[:i], key[i+1:]
+}
+
+// encrypts auth.ID (and auth.Cluster) with the given session key
+func (auth *AuthInfo) EncryptSession(key string) error {
+	backend, name := splitTransitKey(key)
+	return auth.encryptAuth(backend, name)
+}
+
+// decrypts auth.ID, which must be a cipher from EncryptSession with the same key
+func (auth *AuthInfo) DecryptSession(key string) error {
+	backend, name := splitTransitKey(key)
+	return auth.decryptAuth(backend, name)
+}
+
+// re-encrypts a session cipher with the latest version of its key, so old versions can be retired
+func RewrapSession(key, cipher string) (string, error) {
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return "", err
+	}
+
+	backend, name := splitTransitKey(key)
+	resp, err := client.Logical().Write(
+		backend+"/rewrap/"+name,
+		map[string]interface{}{
+			"ciphertext": cipher,
+		})
+	if err != nil {
+		return "", err
+	}
+
+	rewrapped, ok := resp.Data["ciphertext"].(string)
+	if !ok {
+		return "", errors.New("Failed type assertion of response to string")
+	}
+	return rewrapped, nil
+}
@@ -0,0 +1,169 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// options admins may change at runtime, kept in vault at the settings path
+// unlike the runtime config, goldfish only reads the settings, and a missing settings secret just means defaults
+type Settings struct {
+	// used by POST /v1/wrapping/wrap when no wrapttl is given, e.g. "1h"
+	WrapTTL string `json:"wrap_ttl"`
+	// unseal key approvals a change request needs, if more than vault's own threshold
+	ApprovalQuorum int `json:"approval_quorum"`
+	// switches for the ui, by name
+	Features map[string]bool `json:"features"`
+	// shown by the ui at the top of every page
+	Banner string `json:"banner"`
+}
+
+const maxBannerLength = 1024
+
+var featureName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+var (
+	settings         = Settings{}
+	settingsLock     = new(sync.RWMutex)
+	settingsLoadErr  string
+	settingsLoadLock = new(sync.Mutex)
+)
+
+func GetSettings() Settings {
+	settingsLock.RLock()
+	defer settingsLock.RUnlock()
+	return settings
+}
+
+func SetSettings(s Settings) {
+	settingsLock.Lock()
+	defer settingsLock.Unlock()
+	if !reflect.DeepEqual(s, settings) {
+		log.Println("[INFO ]: Settings updated")
+	}
+	settings = s
+}
+
+func settingsPath() string {
+	c := getVaultConfig()
+	if c.Settings_path != "" {
+		return c.Settings_path
+	}
+	return c.Runtime_config + "/settings"
+}
+
+func (s *Settings) Validate() error {
+	if s.WrapTTL != "" {
+		if _, err := parseTTL(s.WrapTTL); err != nil {
+			return errors.New("Setting wrap_ttl must look like 30m, or a number of seconds")
+		}
+	}
+	if s.ApprovalQuorum < 0 {
+		return errors.New("Setting approval_quorum must not be negative")
+	}
+	for name := range s.Features {
+		if !featureName.MatchString(name) {
+			return errors.New("Feature names may only contain lowercase letters, numbers, '_' and '-'")
+		}
+	}
+	if len(s.Banner) > maxBannerLength {
+		return errors.New("Setting banner must be at most " + strconv.Itoa(maxBannerLength) + " characters")
+	}
+	return nil
+}
+
+func parseTTL(ttl string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(ttl); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	d, err := time.ParseDuration(ttl)
+	if err == nil && d <= 0 {
+		err = errors.New("ttl must be positive")
+	}
+	return d, err
+}
+
+// reads the settings with goldfish's own token, keeping the current settings if they can't be read
+// the same error is only logged once, so a token that can't read the settings path doesn't flood the log
+func loadSettings() {
+	s, err := readSettings()
+	settingsLoadLock.Lock()
+	defer settingsLoadLock.Unlock()
+	if err != nil {
+		if err.Error() != settingsLoadErr {
+			log.Println("[ERROR]: Could not load settings from "+settingsPath()+":", err.Error())
+		}
+		settingsLoadErr = err.Error()
+		return
+	}
+	settingsLoadErr = ""
+	SetSettings(s)
+}
+
+func readSettings() (Settings, error) {
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return Settings{}, err
+	}
+	resp, err := client.Logical().Read(settingsPath())
+	if err != nil || resp == nil {
+		return Settings{}, err
+	}
+	return decodeSettings(resp.Data)
+}
+
+func decodeSettings(data map[string]interface{}) (Settings, error) {
+	s := Settings{}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return s, err
+	}
+	return s, s.Validate()
+}
+
+// writes the settings with the user's own token, so vault's policies on the settings path decide who may change them
+// the new settings apply here straight away, and on other goldfish instances when they next reload
+func (auth AuthInfo) WriteSettings(s Settings) error {
+	if err := auth.requireGoldfishScope("Changing settings"); err != nil {
+		return err
+	}
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	if _, err := client.Logical().Write(settingsPath(), data); err != nil {
+		return err
+	}
+	SetSettings(s)
+	return nil
+}
+
+// change requests need at least as many approvals as vault's threshold, since each approval is an unseal key
+func ApprovalsNeeded(threshold int) int {
+	if q := GetSettings().ApprovalQuorum; q > threshold {
+		return q
+	}
+	return threshold
+}
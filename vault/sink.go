@@ -0,0 +1,62 @@
+package vault
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+// bootstraps goldfish with a token written to a file by vault agent's auto-auth sink
+// the file is watched, and the server token is swapped whenever the agent rotates it
+func StartGoldfishWithTokenFile(path string) error {
+	if err := loadTokenFile(path); err != nil {
+		return err
+	}
+
+	changed := make(chan struct{}, 1)
+	if err := watchFile(path, changed); err != nil {
+		return err
+	}
+	go func() {
+		for range changed {
+			if err := loadTokenFile(path); err != nil {
+				errorChannel <- errors.New("Failed to reload token file, keeping current token: " + err.Error())
+			}
+		}
+	}()
+
+	return LoadRuntimeConfig(getVaultConfig().Runtime_config)
+}
+
+// reads and verifies the token in path, replacing the server token only if it is valid
+func loadTokenFile(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return errors.New("Token file " + path + " is empty")
+	}
+	if token == getVaultToken() {
+		return nil
+	}
+
+	client, err := NewVaultClient()
+	if err != nil {
+		return err
+	}
+	client.SetToken(token)
+	resp, err := client.Auth().Token().LookupSelf()
+	if err != nil {
+		return err
+	}
+	if resp == nil {
+		return errors.New("Vault response was nil while looking up token from file")
+	}
+
+	setVaultToken(token)
+	log.Println("[INFO ]: Server token loaded from", path, "accessor:", resp.Data["accessor"])
+	return nil
+}
@@ -0,0 +1,56 @@
+package vault
+
+import (
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// signals changed whenever path is written or replaced
+// the parent directory is watched, since vault agent renames a temp file over the sink
+func watchFile(path string, changed chan<- struct{}) error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return err
+	}
+	dir, name := filepath.Split(filepath.Clean(path))
+	if dir == "" {
+		dir = "."
+	}
+	if _, err := unix.InotifyAddWatch(fd, dir, unix.IN_CLOSE_WRITE|unix.IN_MOVED_TO|unix.IN_CREATE); err != nil {
+		unix.Close(fd)
+		return err
+	}
+
+	go func() {
+		defer unix.Close(fd)
+		buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+		for {
+			n, err := unix.Read(fd, buf)
+			if err == unix.EINTR {
+				continue
+			}
+			if err != nil {
+				errorChannel <- err
+				return
+			}
+
+			for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+				event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				start := offset + unix.SizeofInotifyEvent
+				offset = start + int(event.Len)
+				if strings.TrimRight(string(buf[start:offset]), "\x00") != name {
+					continue
+				}
+				// a pending signal is enough, the file is re-read in full anyway
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,33 @@
+// +build !linux
+
+package vault
+
+import (
+	"os"
+	"time"
+)
+
+// without inotify, the file's modification time is polled instead
+func watchFile(path string, changed chan<- struct{}) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		last := info.ModTime()
+		for {
+			time.Sleep(10 * time.Second)
+			info, err := os.Stat(path)
+			if err != nil || !info.ModTime().After(last) {
+				continue
+			}
+			last = info.ModTime()
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return nil
+}
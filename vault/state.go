@@ -0,0 +1,126 @@
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/mapstructure"
+)
+
+// goldfish's own records, kept under the state path so they outlive goldfish's token and every instance shares them
+// unlike the cubbyhole, goldfish's token needs create, read, update, delete and list on the state path
+func statePath() string {
+	c := getVaultConfig()
+	if c.State_path != "" {
+		return c.State_path
+	}
+	return c.Runtime_config + "/state"
+}
+
+func WriteState(name string, data map[string]interface{}) error {
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Write(statePath()+"/"+name, data)
+	return err
+}
+
+func ReadState(name string) (*api.Secret, error) {
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return nil, err
+	}
+	return client.Logical().Read(statePath() + "/" + name)
+}
+
+func ListState(name string) ([]string, error) {
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Logical().List(statePath() + "/" + name)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	keys, _ := resp.Data["keys"].([]interface{})
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if key, ok := k.(string); ok {
+			names = append(names, key)
+		}
+	}
+	return names, nil
+}
+
+func DeleteState(name string) error {
+	client, err := NewGoldfishVaultClient()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Delete(statePath() + "/" + name)
+	return err
+}
+
+// this process, in job leases. Random, so replicas on one host and restarts differ
+var instanceID = func() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	host, _ := os.Hostname()
+	return host + "-" + hex.EncodeToString(b)
+}()
+
+// how long to wait before reading back a lease just taken
+const leaseSettle = 2 * time.Second
+
+type jobLease struct {
+	Holder  string
+	Expires int64
+}
+
+// runs a scheduled job on one goldfish instance only, however many share the state path
+// the instance that runs it keeps a lease at locks/<name> for the given time, renewed on each run,
+// and other instances skip the job until the lease runs out
+// kv has no check-and-set before version 2, so an instance taking the lease reads it back after a moment,
+// and only runs the job if its own write was the last
+func RunExclusive(name string, lease time.Duration, run func() error) error {
+	path := "locks/" + name
+	current, err := readLease(path)
+	if err != nil {
+		return err
+	}
+	held := current.Holder == instanceID
+	if !held && current.Expires > time.Now().Unix() {
+		return nil
+	}
+
+	if err := WriteState(path, map[string]interface{}{
+		"holder":  instanceID,
+		"expires": time.Now().Add(lease).Unix(),
+	}); err != nil {
+		return err
+	}
+	if !held {
+		time.Sleep(leaseSettle)
+		if current, err = readLease(path); err != nil {
+			return err
+		}
+		if current.Holder != instanceID {
+			return nil
+		}
+	}
+	return run()
+}
+
+func readLease(path string) (jobLease, error) {
+	var l jobLease
+	resp, err := ReadState(path)
+	if err != nil || resp == nil {
+		return l, err
+	}
+	err = mapstructure.WeakDecode(resp.Data, &l)
+	return l, err
+}
@@ -0,0 +1,158 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/structs"
+	"github.com/hashicorp/vault/helper/parseutil"
+	"github.com/mitchellh/mapstructure"
+)
+
+// secrets listed per walk for the untagged secrets report, so a large mount can't stall it
+const maxSecretWalk = 5000
+
+// data classifications, from least to most sensitive
+var secretClassifications = map[string]bool{
+	"public":       true,
+	"internal":     true,
+	"confidential": true,
+	"restricted":   true,
+}
+
+// who owns a secret, how sensitive it is, and how often it must be rotated, e.g. "2160h"
+// tags are goldfish's own index, kept under its state path, so they work with any secrets engine. Updated is a unix time
+type SecretTags struct {
+	Path           string `json:"path"`
+	Owner          string `json:"owner"`
+	Description    string `json:"description"`
+	Classification string `json:"classification"`
+	RotationPeriod string `json:"rotation_period"`
+	UpdatedBy      string `json:"updated_by"`
+	Updated        int64  `json:"updated"`
+}
+
+// paths are hashed for their key, as they may hold any character
+func secretTagsKey(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return "secret_tags/" + hex.EncodeToString(sum[:])
+}
+
+// a secret is tagged once it has both an owner and a classification
+func (t *SecretTags) Tagged() bool {
+	return t != nil && t.Owner != "" && t.Classification != ""
+}
+
+func (t *SecretTags) Validate() error {
+	t.Path = strings.TrimPrefix(t.Path, "/")
+	if t.Path == "" || strings.HasSuffix(t.Path, "/") {
+		return errors.New("Path must be a secret, not a folder")
+	}
+	if t.Classification != "" && !secretClassifications[t.Classification] {
+		return errors.New("Classification must be one of public, internal, confidential, restricted")
+	}
+	if t.RotationPeriod != "" {
+		if d, err := parseutil.ParseDurationSecond(t.RotationPeriod); err != nil || d <= 0 {
+			return errors.New("Rotation period must be a duration, e.g. \"2160h\"")
+		}
+	}
+	return nil
+}
+
+// returns nil if the secret has no tags
+func GetSecretTags(path string) (*SecretTags, error) {
+	resp, err := ReadState(secretTagsKey(strings.TrimPrefix(path, "/")))
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	t := &SecretTags{}
+	if err := mapstructure.Decode(resp.Data, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// replaces a secret's tags. Tags with no owner, description, classification or rotation period are removed
+func SetSecretTags(t SecretTags, by string) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if t.Owner == "" && t.Description == "" && t.Classification == "" && t.RotationPeriod == "" {
+		err := DeleteState(secretTagsKey(t.Path))
+		return err
+	}
+	t.UpdatedBy = by
+	t.Updated = time.Now().Unix()
+	err := WriteState(secretTagsKey(t.Path), structs.Map(t))
+	return err
+}
+
+// every tagged secret under prefix, sorted by path. Empty fields of filter must match anything
+func ListSecretTags(prefix string, filter SecretTags) ([]SecretTags, error) {
+	keys, err := ListState("secret_tags/")
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]SecretTags, 0)
+	for _, key := range keys {
+		resp, err := ReadState("secret_tags/" + key)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil {
+			continue
+		}
+		var t SecretTags
+		if err := mapstructure.Decode(resp.Data, &t); err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(t.Path, prefix) ||
+			(filter.Owner != "" && t.Owner != filter.Owner) ||
+			(filter.Classification != "" && t.Classification != filter.Classification) {
+			continue
+		}
+		tags = append(tags, t)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Path < tags[j].Path })
+	return tags, nil
+}
+
+// secrets under the prefix, a folder ending in '/', that have no owner or classification
+// the secrets are listed with the token's own access, and folders it can't list are skipped
+func (auth AuthInfo) UntaggedSecrets(prefix string) ([]string, error) {
+	if !strings.HasSuffix(prefix, "/") {
+		return nil, errors.New("Prefix must be a folder, ending in '/'")
+	}
+	tagged, err := ListSecretTags(prefix, SecretTags{})
+	if err != nil {
+		return nil, err
+	}
+	isTagged := make(map[string]bool)
+	for i := range tagged {
+		isTagged[tagged[i].Path] = tagged[i].Tagged()
+	}
+
+	secrets, err := walkSecrets(prefix, maxSecretWalk, func(folder string) ([]interface{}, error) {
+		keys, err := auth.ListSecret(prefix + folder)
+		// the prefix itself must be listable, but not every folder under it
+		if err != nil && folder != "" {
+			return nil, nil
+		}
+		return keys, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	untagged := make([]string, 0)
+	for _, s := range secrets {
+		if !isTagged[prefix+s] {
+			untagged = append(untagged, prefix+s)
+		}
+	}
+	return untagged, nil
+}
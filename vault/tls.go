@@ -0,0 +1,102 @@
+package vault
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/caiyeon/goldfish/config"
+	"github.com/hashicorp/vault/api"
+)
+
+// tls options of a connection to vault
+type tlsSettings struct {
+	caCert     string
+	caPath     string
+	clientCert string
+	clientKey  string
+	serverName string
+	insecure   bool
+}
+
+func vaultTLSSettings(c config.VaultConfig) tlsSettings {
+	return tlsSettings{
+		caCert:     c.Ca_cert,
+		caPath:     c.Ca_path,
+		clientCert: c.Client_cert,
+		clientKey:  c.Client_key,
+		serverName: c.Tls_server_name,
+		insecure:   c.Tls_skip_verify,
+	}
+}
+
+type cachedTLSConfig struct {
+	stamp  string
+	config *tls.Config
+}
+
+var (
+	tlsConfigs     = make(map[tlsSettings]*cachedTLSConfig)
+	tlsConfigsLock = new(sync.Mutex)
+)
+
+// returns a tls config for s, which is rebuilt whenever one of its files changes on disk
+// so a rotated CA or client certificate is picked up without a restart
+func vaultTLSConfig(s tlsSettings) (*tls.Config, error) {
+	stamp := fileStamp(s.caCert, s.caPath, s.clientCert, s.clientKey)
+
+	tlsConfigsLock.Lock()
+	defer tlsConfigsLock.Unlock()
+	cached, ok := tlsConfigs[s]
+	if ok && cached.stamp == stamp {
+		return cached.config.Clone(), nil
+	}
+
+	// let the vault api build it, so options behave the same as with the vault cli
+	conf := api.DefaultConfig()
+	err := conf.ConfigureTLS(&api.TLSConfig{
+		CACert:        s.caCert,
+		CAPath:        s.caPath,
+		ClientCert:    s.clientCert,
+		ClientKey:     s.clientKey,
+		TLSServerName: s.serverName,
+		Insecure:      s.insecure,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		log.Println("[INFO ]: Vault TLS files changed, reloaded them")
+	}
+
+	tlsConfig := conf.HttpClient.Transport.(*http.Transport).TLSClientConfig
+	tlsConfigs[s] = &cachedTLSConfig{stamp: stamp, config: tlsConfig}
+	return tlsConfig.Clone(), nil
+}
+
+// summarizes the modification times of the given files, and of files in any given directories
+func fileStamp(paths ...string) string {
+	stamp := ""
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			stamp += path + ":missing;"
+			continue
+		}
+		stamp += fmt.Sprintf("%s:%d;", path, info.ModTime().UnixNano())
+		if info.IsDir() {
+			files, _ := ioutil.ReadDir(path)
+			for _, f := range files {
+				stamp += fmt.Sprintf("%s:%d;", f.Name(), f.ModTime().UnixNano())
+			}
+		}
+	}
+	return stamp
+}
@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"encoding/json"
 	"errors"
 	"strings"
 
@@ -59,6 +60,116 @@ func (auth AuthInfo) LookupTokenByAccessor(accs string) ([]interface{}, error) {
 	return tokens, nil
 }
 
+// looks up each accessor in turn, for listings too long to hold every token's details in memory
+// accessors that are invalid or expired are skipped. Any other lookup error, or one from fn, stops the lookups
+func (auth AuthInfo) EachTokenByAccessor(accessors []interface{}, fn func(data map[string]interface{}) error) error {
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	logical := client.Logical()
+
+	for _, a := range accessors {
+		accessor, ok := a.(string)
+		if !ok {
+			continue
+		}
+		resp, err := logical.Write("auth/token/lookup-accessor",
+			map[string]interface{}{
+				"accessor": accessor,
+			})
+		// tokens revoked since the accessors were listed are skipped, but anything else fails the walk,
+		// rather than leaving out tokens that are still there
+		if err != nil && strings.Contains(err.Error(), "Code: 400") {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if resp == nil {
+			continue
+		}
+		if err := fn(resp.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// a token's lookup data, with its lease and the identity it belongs to, for deciding whether to revoke it
+// the entity and its groups are read with the user's own token, so they are left out if its policies don't allow it
+// vault has no api to list a token's children, so what else a revocation takes down can't be shown
+func (auth AuthInfo) AccessorDetail(accessor string) (map[string]interface{}, error) {
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+	logical := client.Logical()
+
+	resp, err := logical.Write("auth/token/lookup-accessor",
+		map[string]interface{}{
+			"accessor": accessor,
+		})
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errors.New("Failed to lookup accessor")
+	}
+	data := resp.Data
+
+	lease := make(map[string]interface{})
+	for _, key := range []string{"creation_time", "creation_ttl", "expire_time", "issue_time",
+		"ttl", "explicit_max_ttl", "period", "renewable", "num_uses"} {
+		if v, ok := data[key]; ok {
+			lease[key] = v
+		}
+	}
+	detail := map[string]interface{}{
+		"token": data,
+		"lease": lease,
+	}
+
+	entityID, _ := data["entity_id"].(string)
+	if entityID == "" {
+		return detail, nil
+	}
+	entity, err := logical.Read("identity/entity/id/" + entityID)
+	if err != nil || entity == nil {
+		detail["entity_error"] = "The entity could not be read, your policies may not allow it"
+		return detail, nil
+	}
+	detail["entity"] = map[string]interface{}{
+		"id":       entityID,
+		"name":     entity.Data["name"],
+		"aliases":  entity.Data["aliases"],
+		"policies": entity.Data["policies"],
+		"disabled": entity.Data["disabled"],
+		"metadata": entity.Data["metadata"],
+	}
+
+	groups := make([]map[string]interface{}, 0)
+	ids, _ := entity.Data["group_ids"].([]interface{})
+	for _, id := range ids {
+		groupID, ok := id.(string)
+		if !ok {
+			continue
+		}
+		group, err := logical.Read("identity/group/id/" + groupID)
+		if err != nil || group == nil {
+			groups = append(groups, map[string]interface{}{"id": groupID})
+			continue
+		}
+		groups = append(groups, map[string]interface{}{
+			"id":       groupID,
+			"name":     group.Data["name"],
+			"policies": group.Data["policies"],
+		})
+	}
+	detail["groups"] = groups
+	return detail, nil
+}
+
 func (auth AuthInfo) RevokeTokenByAccessor(acc string) error {
 	client, err := auth.Client()
 	if err != nil {
@@ -70,12 +181,28 @@ func (auth AuthInfo) RevokeTokenByAccessor(acc string) error {
 	return err
 }
 
+// vault's token create request, with the fields the vendored api lacks
+type TokenRequest struct {
+	api.TokenCreateRequest
+
+	// only allowed with a role, whose allowed_entity_aliases must include it
+	EntityAlias string `json:"entity_alias,omitempty"`
+}
+
 func (auth AuthInfo) CreateToken(opts *api.TokenCreateRequest, orphan bool,
 	rolename string, wrapttl string) (*api.Secret, error) {
+	return auth.CreateTokenRequest(&TokenRequest{TokenCreateRequest: *opts}, orphan, rolename, wrapttl)
+}
+
+func (auth AuthInfo) CreateTokenRequest(opts *TokenRequest, orphan bool,
+	rolename string, wrapttl string) (*api.Secret, error) {
 
 	if orphan && rolename != "" {
 		return nil, errors.New("Orphan and role are mutually exclusive parameters")
 	}
+	if opts.EntityAlias != "" && rolename == "" {
+		return nil, errors.New("An entity alias can only be set when creating a token against a role")
+	}
 
 	client, err := auth.Client()
 	if err != nil {
@@ -89,12 +216,25 @@ func (auth AuthInfo) CreateToken(opts *api.TokenCreateRequest, orphan bool,
 		})
 	}
 
-	if orphan {
-		return client.Auth().Token().CreateOrphan(opts)
-	} else if rolename != "" {
-		return client.Auth().Token().CreateWithRole(opts, rolename)
+	if opts.EntityAlias == "" {
+		if orphan {
+			return client.Auth().Token().CreateOrphan(&opts.TokenCreateRequest)
+		} else if rolename != "" {
+			return client.Auth().Token().CreateWithRole(&opts.TokenCreateRequest, rolename)
+		}
+		return client.Auth().Token().Create(&opts.TokenCreateRequest)
+	}
+
+	// the api's requests can't carry an entity alias, so the role's create endpoint is written to directly
+	b, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
 	}
-	return client.Auth().Token().Create(opts)
+	return client.Logical().Write("auth/token/create/"+rolename, data)
 }
 
 func (auth AuthInfo) ListRoles() (interface{}, error) {
@@ -0,0 +1,84 @@
+package vault
+
+import (
+	"encoding/base64"
+	"errors"
+)
+
+// the hash algorithms sys/tools/hash takes
+var hashAlgorithms = map[string]bool{
+	"sha2-224": true,
+	"sha2-256": true,
+	"sha2-384": true,
+	"sha2-512": true,
+}
+
+func validToolFormat(format string) error {
+	if format != "" && format != "hex" && format != "base64" {
+		return errors.New("Format must be hex or base64")
+	}
+	return nil
+}
+
+// random bytes from vault's own source of randomness, encoded as hex or base64 (the default)
+func (auth AuthInfo) RandomBytes(bytes int, format string) (string, error) {
+	if bytes <= 0 {
+		return "", errors.New("Number of bytes must be above zero")
+	}
+	if err := validToolFormat(format); err != nil {
+		return "", err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return "", err
+	}
+
+	data := map[string]interface{}{
+		"bytes": bytes,
+	}
+	if format != "" {
+		data["format"] = format
+	}
+	resp, err := client.Logical().Write("sys/tools/random", data)
+	if err != nil {
+		return "", err
+	}
+	if resp == nil {
+		return "", errors.New("This vault has no sys/tools. It needs vault 1.1 or newer")
+	}
+	random, _ := resp.Data["random_bytes"].(string)
+	return random, nil
+}
+
+// hashes input with an algorithm, sha2-256 by default, encoded as hex (the default) or base64
+func (auth AuthInfo) Hash(input []byte, algorithm, format string) (string, error) {
+	if algorithm == "" {
+		algorithm = "sha2-256"
+	}
+	if !hashAlgorithms[algorithm] {
+		return "", errors.New("Algorithm must be one of sha2-224, sha2-256, sha2-384, sha2-512")
+	}
+	if err := validToolFormat(format); err != nil {
+		return "", err
+	}
+	client, err := auth.Client()
+	if err != nil {
+		return "", err
+	}
+
+	data := map[string]interface{}{
+		"input": base64.StdEncoding.EncodeToString(input),
+	}
+	if format != "" {
+		data["format"] = format
+	}
+	resp, err := client.Logical().Write("sys/tools/hash/"+algorithm, data)
+	if err != nil {
+		return "", err
+	}
+	if resp == nil {
+		return "", errors.New("This vault has no sys/tools. It needs vault 1.1 or newer")
+	}
+	sum, _ := resp.Data["sum"].(string)
+	return sum, nil
+}
@@ -3,6 +3,9 @@ package vault
 import (
 	"encoding/base64"
 	"errors"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
 )
 
 // encrypt given string with userTransitKey
@@ -77,3 +80,80 @@ func (auth AuthInfo) DecryptTransit(key string, cipher string) (string, error) {
 
 	return string(rawbytes), nil
 }
+
+// backups hold the key's material, so goldfish asks for sudo on top of what vault's policy requires
+func (auth *AuthInfo) requireSudo(path string) error {
+	capabilities, err := auth.CapabilitiesSelf(path)
+	if err != nil {
+		return err
+	}
+	for _, capability := range capabilities {
+		if capability == "sudo" || capability == "root" {
+			return nil
+		}
+	}
+	return errors.New("Permission denied: token lacks 'sudo' capability on " + path)
+}
+
+// backs up a transit key, which vault allows only if the key is exportable and allows plaintext backups
+// the backup is only ever returned response-wrapped
+func (auth *AuthInfo) BackupTransitKey(mount, key, wrapttl string) (*api.SecretWrapInfo, error) {
+	if key == "" {
+		return nil, errors.New("No transit key specified")
+	}
+	if wrapttl == "" {
+		return nil, errors.New("Transit key backups must be response-wrapped")
+	}
+	if mount == "" {
+		mount = GetConfig().TransitBackend
+	}
+	path := strings.Trim(mount, "/") + "/backup/" + key
+	if err := auth.requireSudo(path); err != nil {
+		return nil, err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return nil, err
+	}
+	client.SetWrappingLookupFunc(func(operation, path string) string {
+		return wrapttl
+	})
+
+	resp, err := client.Logical().Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.WrapInfo == nil {
+		return nil, errors.New("Transit key " + key + " was not found in " + mount)
+	}
+	return resp.WrapInfo, nil
+}
+
+// restores a backup made by BackupTransitKey, under its original name unless key is given
+// force is needed to overwrite an existing key
+func (auth *AuthInfo) RestoreTransitKey(mount, key, backup string, force bool) error {
+	if backup == "" {
+		return errors.New("No backup provided")
+	}
+	if mount == "" {
+		mount = GetConfig().TransitBackend
+	}
+	path := strings.Trim(mount, "/") + "/restore"
+	if key != "" {
+		path += "/" + key
+	}
+	if err := auth.requireSudo(path); err != nil {
+		return err
+	}
+
+	client, err := auth.Client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Logical().Write(path, map[string]interface{}{
+		"backup": backup,
+		"force":  force,
+	})
+	return err
+}
@@ -0,0 +1,128 @@
+package vault
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// days of goldfish's own request counts that are kept
+const usageDays = 30
+
+// vault's usage counters, each read independently since older vaults have only some of them
+var usageCounters = map[string]string{
+	"requests": "sys/internal/counters/requests",
+	"entities": "sys/internal/counters/entities",
+	"tokens":   "sys/internal/counters/tokens",
+	"activity": "sys/internal/counters/activity",
+}
+
+// the requests goldfish has made to vault, by utc day and mount
+// a sample of vault's traffic, for vaults without usage counters
+var (
+	usage     = make(map[string]map[string]int)
+	usageLock = new(sync.Mutex)
+	usageFrom = time.Now().UTC()
+)
+
+type UsageDay struct {
+	Date   string         `json:"date"`
+	Mounts map[string]int `json:"mounts"`
+}
+
+type usageTransport struct {
+	base http.RoundTripper
+}
+
+func (t *usageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recordUsage(usageMount(req.URL.Path), time.Now().UTC())
+	return t.base.RoundTrip(req)
+}
+
+func recordUsage(mount string, now time.Time) {
+	if mount == "" {
+		return
+	}
+	day := now.Format("2006-01-02")
+
+	usageLock.Lock()
+	defer usageLock.Unlock()
+	if usage[day] == nil {
+		usage[day] = make(map[string]int)
+		// dates sort as strings, so the oldest days are dropped first
+		if len(usage) > usageDays {
+			days := make([]string, 0, len(usage))
+			for d := range usage {
+				days = append(days, d)
+			}
+			sort.Strings(days)
+			for _, d := range days[:len(days)-usageDays] {
+				delete(usage, d)
+			}
+		}
+	}
+	usage[day][mount]++
+}
+
+// the mount a vault api path is under, without reading the mount table
+// mounts nested deeper than their first segment, e.g. "team/kv/", are counted under that segment
+func usageMount(path string) string {
+	if !strings.HasPrefix(path, "/v1/") {
+		return ""
+	}
+	segments := strings.SplitN(strings.TrimPrefix(path, "/v1/"), "/", 3)
+	if segments[0] == "" {
+		return ""
+	}
+	// auth backends are mounted one level down
+	if segments[0] == "auth" && len(segments) > 1 && segments[1] != "" {
+		return "auth/" + segments[1] + "/"
+	}
+	return segments[0] + "/"
+}
+
+// goldfish's own request counts, oldest day first, and when counting began
+func GoldfishUsage() ([]UsageDay, time.Time) {
+	usageLock.Lock()
+	defer usageLock.Unlock()
+	days := make([]UsageDay, 0, len(usage))
+	for d, mounts := range usage {
+		copied := make(map[string]int, len(mounts))
+		for m, n := range mounts {
+			copied[m] = n
+		}
+		days = append(days, UsageDay{Date: d, Mounts: copied})
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	return days, usageFrom
+}
+
+// reads each of vault's usage counters that the token may read
+// counters the vault doesn't have, or that can't be read, are left out and their error returned instead
+func (auth AuthInfo) UsageCounters() (map[string]interface{}, map[string]string) {
+	counters := make(map[string]interface{})
+	errs := make(map[string]string)
+
+	client, err := auth.Client()
+	if err != nil {
+		for name := range usageCounters {
+			errs[name] = err.Error()
+		}
+		return counters, errs
+	}
+
+	for name, path := range usageCounters {
+		resp, err := client.Logical().Read(path)
+		switch {
+		case err != nil:
+			errs[name] = err.Error()
+		case resp == nil || resp.Data == nil:
+			errs[name] = "not supported by this version of vault"
+		default:
+			counters[name] = resp.Data
+		}
+	}
+	return counters, errs
+}
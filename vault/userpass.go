@@ -12,6 +12,7 @@ type UserpassUser struct {
 	Policies string
 }
 
+// users of the userpass mount goldfish manages, the first configured userpass login or auth/userpass
 func (auth AuthInfo) ListUserpassUsers() ([]UserpassUser, error) {
 	client, err := auth.Client()
 	if err != nil {
@@ -20,7 +21,8 @@ func (auth AuthInfo) ListUserpassUsers() ([]UserpassUser, error) {
 	logical := client.Logical()
 
 	// get a list of usernames
-	resp, err := logical.List("auth/userpass/users")
+	path := userpassPath() + "/users/"
+	resp, err := logical.List(path)
 	if err != nil {
 		return nil, err
 	}
@@ -33,7 +35,7 @@ func (auth AuthInfo) ListUserpassUsers() ([]UserpassUser, error) {
 	users := make([]UserpassUser, len(usernames))
 	for i, username := range usernames {
 		users[i].Name = username.(string)
-		resp, err := logical.Read("auth/userpass/users/" + users[i].Name)
+		resp, err := logical.Read(path + users[i].Name)
 		if err == nil {
 			if b, err := json.Marshal(resp.Data); err == nil {
 				json.Unmarshal(b, &users[i])
@@ -42,3 +44,8 @@ func (auth AuthInfo) ListUserpassUsers() ([]UserpassUser, error) {
 	}
 	return users, nil
 }
+
+// the path a userpass user is at, in the mount goldfish manages
+func UserpassUserPath(username string) string {
+	return userpassPath() + "/users/" + username
+}
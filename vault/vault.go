@@ -2,59 +2,146 @@ package vault
 
 import (
 	"errors"
+	"io/ioutil"
 	"log"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/caiyeon/goldfish/config"
+	"github.com/caiyeon/goldfish/metrics"
+	"github.com/caiyeon/goldfish/tracing"
 	"github.com/hashicorp/vault/api"
 )
 
 type AuthInfo struct {
-	Type string `json:"Type" form:"Type" query:"Type"`
-	ID   string `json:"ID" form:"ID" query:"ID"`
-	Pass string `json:"password" form:"Password" query:"Password"`
+	Type    string `json:"Type" form:"Type" query:"Type"`
+	ID      string `json:"ID" form:"ID" query:"ID"`
+	Pass    string `json:"password" form:"Password" query:"Password"`
+	Cluster string `json:"Cluster" form:"Cluster" query:"Cluster"`
+
+	// vault enterprise namespace the token is used in, empty for the root
+	Namespace string `json:"Namespace" form:"Namespace" query:"Namespace"`
+
+	// the request's span, so vault calls made on its behalf are traced under it
+	Trace *tracing.Span `json:"-" form:"-" query:"-"`
+
+	// sent along with vault calls made on the user's behalf, for vault's audit log
+	Audit *AuditInfo `json:"-" form:"-" query:"-"`
 }
 
 var (
-	vaultConfig  config.VaultConfig
-	vaultToken   string
-	errorChannel = make(chan error, 1)
+	vaultConfig     config.VaultConfig
+	vaultConfigLock = new(sync.RWMutex)
+	vaultToken      string
+	vaultTokenLock  = new(sync.RWMutex)
+	errorChannel    = make(chan error, 1)
+	backgroundOnce  sync.Once
 )
 
 func Bootstrapped() bool {
-	return vaultToken != ""
+	return getVaultToken() != ""
+}
+
+func getVaultToken() string {
+	vaultTokenLock.RLock()
+	defer vaultTokenLock.RUnlock()
+	return vaultToken
 }
 
+func setVaultToken(token string) {
+	vaultTokenLock.Lock()
+	defer vaultTokenLock.Unlock()
+	vaultToken = token
+}
+
+// may be called again at runtime, e.g. when the config file is reloaded
 func SetConfig(c *config.VaultConfig) {
+	vaultConfigLock.Lock()
+	defer vaultConfigLock.Unlock()
 	vaultConfig = *c
 }
 
+func getVaultConfig() config.VaultConfig {
+	vaultConfigLock.RLock()
+	defer vaultConfigLock.RUnlock()
+	return vaultConfig
+}
+
 func NewVaultClient() (*api.Client, error) {
-	config := api.DefaultConfig()
-	err := config.ConfigureTLS(
-		&api.TLSConfig{
-			Insecure: vaultConfig.Tls_skip_verify,
-		},
-	)
+	return newClusterClient("", "", nil, nil)
+}
+
+// constructs a client for a named cluster, or for goldfish's own cluster if name is empty
+func NewClusterClient(name string) (*api.Client, error) {
+	return newClusterClient(name, "", nil, nil)
+}
+
+// calls made with the client are traced as children of trace, and carry audit's headers, if they are not nil
+func newClusterClient(name, namespace string, trace *tracing.Span, audit *AuditInfo) (*api.Client, error) {
+	if name == "" {
+		vaultConfig := getVaultConfig()
+		return newClient(name, vaultConfig.Address, vaultTLSSettings(vaultConfig), failoverEnabled(vaultConfig), namespace, trace, audit)
+	}
+	c, ok := getCluster(name)
+	if !ok {
+		return nil, errors.New("Unknown cluster: " + name)
+	}
+	return newClient(name, c.Address, tlsSettings{caCert: c.Ca_cert, insecure: c.Tls_skip_verify}, false, namespace, trace, audit)
+}
+
+func newClient(cluster, address string, settings tlsSettings, failover bool, namespace string, trace *tracing.Span, audit *AuditInfo) (*api.Client, error) {
+	shared, err := sharedTransport(cluster, address, settings)
 	if err != nil {
 		return nil, err
 	}
+	// api.NewClient requires an *http.Transport of its own, so the shared one is swapped in after construction
+	config := &api.Config{Address: address, HttpClient: &http.Client{Transport: &http.Transport{}}}
 	client, err := api.NewClient(config)
 	if err != nil {
 		return nil, err
 	}
-	client.SetAddress(vaultConfig.Address)
+	// traced below failover and retries, so each attempt is its own span against the node it reached
+	config.HttpClient.Transport = tracing.Transport(metrics.InstrumentTransport(shared), trace)
+	config.HttpClient.Transport = newAuditTransport(config.HttpClient.Transport, audit)
+	config.HttpClient.Transport = newNamespaceTransport(config.HttpClient.Transport, namespace)
+	var served *ServedNode
+	if audit != nil {
+		served = audit.Node
+	}
+	if failover {
+		config.HttpClient.Transport = &failoverTransport{base: config.HttpClient.Transport, served: served}
+		address = CurrentNode()
+	} else {
+		// with a single address, that is the node
+		served.set(address)
+	}
+
+	// timeouts are enforced per attempt by the retrying transport instead
+	config.HttpClient.Transport = &retryTransport{base: config.HttpClient.Transport, conf: getVaultConfig()}
+	// counted once per call, however many attempts it took
+	config.HttpClient.Transport = &usageTransport{base: config.HttpClient.Transport}
+	config.HttpClient.Timeout = 0
+	client.SetAddress(address)
 	client.SetToken("")
 	return client, nil
 }
 
 func NewGoldfishVaultClient() (client *api.Client, err error) {
 	if client, err = NewVaultClient(); err == nil {
-		client.SetToken(vaultToken)
+		client.SetToken(getVaultToken())
 	}
 	return client, err
 }
 
+// goldfish's own credentials, for background jobs such as scheduled reports that have no user
+func GoldfishAuth() AuthInfo {
+	return AuthInfo{Type: "token", ID: getVaultToken()}
+}
+
+// bootstraps goldfish with a wrapped secret_id
+// may be called again at runtime to replace goldfish's credentials, e.g. if its token was revoked
 func StartGoldfishWrapper(wrappingToken string) error {
 	if wrappingToken == "" {
 		return errors.New("Token must be provided in non-dev mode")
@@ -64,6 +151,7 @@ func StartGoldfishWrapper(wrappingToken string) error {
 	if err != nil {
 		return err
 	}
+	vaultConfig := getVaultConfig()
 
 	// make a raw unwrap call. This will use the token as a header
 	client.SetToken(wrappingToken)
@@ -89,37 +177,17 @@ func StartGoldfishWrapper(wrappingToken string) error {
 	}
 
 	// fetch vault token with secret_id
-	resp, err = client.Logical().Write(vaultConfig.Approle_login,
-		map[string]interface{}{
-			"role_id":   vaultConfig.Approle_id,
-			"secret_id": secretID,
-		})
+	accessor, err := loginWithSecretID(client, secretID)
 	if err != nil {
 		return err
 	}
 
-	// verify that the secret_id is valid
-	vaultToken = resp.Auth.ClientToken
-	client.SetToken(resp.Auth.ClientToken)
-	if _, err := client.Auth().Token().LookupSelf(); err != nil {
-		return err
-	}
-
 	// verify that the client token is renewable
 	if err := renewServerToken(); err != nil {
 		return err
 	}
 
-	// errors that are not catastrophic can be logged here
-	go func() {
-		for err := range errorChannel {
-			if err != nil {
-				log.Println("[ERROR]: ", err.Error())
-			}
-		}
-	}()
-
-	log.Println("[INFO ]: Server token accessor:", resp.Auth.Accessor)
+	log.Println("[INFO ]: Server token accessor:", accessor)
 
 	// start goroutines for loading config and renewing token
 	if err := LoadRuntimeConfig(vaultConfig.Runtime_config); err != nil {
@@ -134,21 +202,151 @@ func LoadRuntimeConfig(configPath string) error {
 	if err := loadConfigFromVault(configPath); err != nil {
 		return err
 	}
-	go loadConfigEvery(time.Minute, configPath)
-	go renewServerTokenEvery(time.Hour)
+	loadSettings()
+
+	// background goroutines survive re-bootstrapping, since they always use the current token
+	backgroundOnce.Do(func() {
+		// errors that are not catastrophic can be logged here
+		go func() {
+			for err := range errorChannel {
+				if err != nil {
+					log.Println("[ERROR]: ", err.Error())
+				}
+			}
+		}()
+		go loadConfigEvery(time.Minute, configPath)
+		go maintainServerToken()
+	})
 	return nil
 }
 
+// only users that can write goldfish's runtime config may replace goldfish's credentials
+func (auth *AuthInfo) CanRebootstrap() error {
+	if err := auth.requireGoldfishScope("Re-bootstrapping"); err != nil {
+		return err
+	}
+	return auth.RawPreflight("PUT", getVaultConfig().Runtime_config)
+}
+
 func loadConfigEvery(interval time.Duration, configPath string) {
 	for {
 		time.Sleep(interval)
 		errorChannel <- loadConfigFromVault(configPath)
+		loadSettings()
+	}
+}
+
+// logs in with goldfish's approle, replacing the server token only once the new one is verified
+func loginWithSecretID(client *api.Client, secretID string) (string, error) {
+	vaultConfig := getVaultConfig()
+	return loginAs(client, vaultConfig.Approle_login, map[string]interface{}{
+		"role_id":   vaultConfig.Approle_id,
+		"secret_id": secretID,
+	})
+}
+
+// writes to an auth backend's login path, replacing the server token only once the new one is verified
+func loginAs(client *api.Client, path string, data map[string]interface{}) (string, error) {
+	resp, err := client.Logical().Write(path, data)
+	if err != nil {
+		return "", err
+	}
+	if resp == nil || resp.Auth == nil {
+		return "", errors.New("Login response from vault was nil")
+	}
+
+	// verify that the new token is valid, before replacing any existing token
+	client.SetToken(resp.Auth.ClientToken)
+	if _, err := client.Auth().Token().LookupSelf(); err != nil {
+		return "", err
 	}
+	setVaultToken(resp.Auth.ClientToken)
+	return resp.Auth.Accessor, nil
 }
 
-func renewServerTokenEvery(interval time.Duration) {
+// renews the server token at half its remaining ttl
+// if renewal fails, or the token is about to hit its max ttl, logs in again with configured credentials
+func maintainServerToken() {
+	// vault agent renews and rotates the token itself
+	if getVaultConfig().Token_file != "" {
+		return
+	}
+
 	for {
-		time.Sleep(interval)
-		errorChannel <- renewServerToken()
+		time.Sleep(nextRenewal())
+
+		err := renewServerToken()
+		if err == nil {
+			ttl, lookupErr := ServerTokenTTL()
+			if lookupErr != nil || ttl > int64(serverTokenMinTTL/time.Second) {
+				continue
+			}
+			err = errors.New("Server token is about to reach its max ttl")
+		}
+		errorChannel <- err
+
+		errorChannel <- reauthenticate()
+	}
+}
+
+// a server token with less ttl than this is replaced instead of renewed
+const serverTokenMinTTL = 5 * time.Minute
+
+// returns how long to wait before the next renewal, between a minute and an hour
+func nextRenewal() time.Duration {
+	ttl, err := ServerTokenTTL()
+	if err != nil {
+		return time.Minute
+	}
+	next := time.Duration(ttl) * time.Second / 2
+	if next < time.Minute {
+		return time.Minute
+	}
+	if next > time.Hour {
+		return time.Hour
+	}
+	return next
+}
+
+// logs in with kubernetes if configured, otherwise with a configured secret_id
+func reauthenticate() error {
+	client, err := NewVaultClient()
+	if err != nil {
+		return err
+	}
+
+	var accessor string
+	if getVaultConfig().Kubernetes_role != "" {
+		if accessor, err = loginWithKubernetes(client); err != nil {
+			return errors.New("Failed to re-authenticate with kubernetes: " + err.Error())
+		}
+	} else {
+		secretID, err := configuredSecretID()
+		if err != nil {
+			return err
+		}
+		if secretID == "" {
+			log.Println("[WARN ]: No approle_secret_id configured, goldfish must be re-bootstrapped manually")
+			return nil
+		}
+		if accessor, err = loginWithSecretID(client, secretID); err != nil {
+			return errors.New("Failed to re-authenticate with configured secret_id: " + err.Error())
+		}
+	}
+
+	log.Println("[INFO ]: Server token re-authenticated, new accessor:", accessor)
+	return nil
+}
+
+// returns the secret_id from config, or from the configured file (read fresh, in case it was rotated)
+func configuredSecretID() (string, error) {
+	vaultConfig := getVaultConfig()
+	if vaultConfig.Approle_secret_id_file == "" {
+		return vaultConfig.Approle_secret_id, nil
+	}
+	b, err := ioutil.ReadFile(vaultConfig.Approle_secret_id_file)
+	if err != nil {
+		return "", err
 	}
+	return strings.TrimSpace(string(b)), nil
 }
@@ -3,6 +3,7 @@ package vault
 import (
 	"encoding/base64"
 	"testing"
+	"time"
 
 	"github.com/caiyeon/goldfish/config"
 	"github.com/hashicorp/go-uuid"
@@ -36,6 +37,12 @@ func TestGoldfishWrapper(t *testing.T) {
 			So(client.Token(), ShouldEqual, "")
 		})
 
+		Convey("Health watcher should see vault as available", func() {
+			So(GetVaultState().Healthy(), ShouldBeTrue)
+			setVaultState(checkVaultHealth())
+			So(GetVaultState().State, ShouldEqual, StateOK)
+		})
+
 		// run-time config
 		Convey("Config should be loaded", func() {
 			c := GetConfig()
@@ -49,6 +56,34 @@ func TestGoldfishWrapper(t *testing.T) {
 			})
 		})
 
+		Convey("Settings should apply once written", func() {
+			So(GetSettings(), ShouldResemble, Settings{})
+			So(rootAuth.WriteSettings(Settings{WrapTTL: "1h", ApprovalQuorum: 5}), ShouldBeNil)
+			So(ApprovalsNeeded(3), ShouldEqual, 5)
+
+			s, err := readSettings()
+			So(err, ShouldBeNil)
+			So(s.WrapTTL, ShouldEqual, "1h")
+
+			So(rootAuth.WriteSettings(Settings{WrapTTL: "soon"}), ShouldNotBeNil)
+			_, err = rootAuth.DeleteSecret(settingsPath())
+			So(err, ShouldBeNil)
+			loadSettings()
+			So(GetSettings(), ShouldResemble, Settings{})
+		})
+
+		Convey("Goldfish should only be administered from its own cluster's root namespace", func() {
+			namespaced := &AuthInfo{ID: "goldfish", Type: "token", Namespace: "team"}
+			So(namespaced.CanAdministerSessions(), ShouldNotBeNil)
+			So(namespaced.CanRebootstrap(), ShouldNotBeNil)
+			So(namespaced.WriteSettings(Settings{WrapTTL: "1h"}), ShouldNotBeNil)
+			So(GetSettings(), ShouldResemble, Settings{})
+
+			clustered := &AuthInfo{ID: "goldfish", Type: "token", Cluster: "dr"}
+			So(clustered.CanAdministerSessions(), ShouldNotBeNil)
+			So(clustered.CanRebootstrap(), ShouldNotBeNil)
+		})
+
 		// credentials
 		Convey("Encrypting and decrypting credentials should work", func() {
 			root := rootAuth.ID
@@ -83,6 +118,7 @@ func TestGoldfishWrapper(t *testing.T) {
 					"title":   "Message title",
 					"message": "Message body",
 					"type":    "is-success",
+					"id":      "testbulletin",
 				})
 			})
 
@@ -93,6 +129,24 @@ func TestGoldfishWrapper(t *testing.T) {
 				So(secrets, ShouldContain, "testbulletin")
 			})
 
+			Convey("Scheduled bulletins should only be listed with all", func() {
+				So(rootAuth.WriteBulletin("scheduled", Bulletin{
+					Title:     "Maintenance",
+					Severity:  "warning",
+					PublishAt: time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+				}), ShouldBeNil)
+
+				bulletins, err := rootAuth.GetBulletins()
+				So(err, ShouldBeNil)
+				So(len(bulletins), ShouldEqual, 4)
+
+				bulletins, err = rootAuth.GetAllBulletins()
+				So(err, ShouldBeNil)
+				So(len(bulletins), ShouldEqual, 5)
+
+				So(rootAuth.DeleteBulletin("scheduled"), ShouldBeNil)
+			})
+
 			Convey("Deleting secrets should work", func() {
 				_, err := rootAuth.DeleteSecret("secret/bulletins/testbulletin")
 				So(err, ShouldBeNil)
@@ -123,7 +177,48 @@ func TestGoldfishWrapper(t *testing.T) {
 			})
 		})
 
+		// raw requests
+		Convey("Raw requests should be forwarded to vault", func() {
+			resp, err := rootAuth.RawRequest("get", "/v1/secret/goldfish", nil)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 200)
+			So(resp.Body["data"], ShouldContainKey, "TransitBackend")
+
+			resp, err = rootAuth.RawRequest("LIST", "secret/bulletins", nil)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode, ShouldEqual, 200)
+
+			_, err = rootAuth.RawRequest("PATCH", "secret/goldfish", nil)
+			So(err, ShouldNotBeNil)
+
+			emptyAuth := &AuthInfo{ID: "not_a_token", Type: "token"}
+			_, err = emptyAuth.RawRequest("GET", "secret/goldfish", nil)
+			So(err, ShouldNotBeNil)
+		})
+
 		// tokens
+		Convey("Goldfish should be re-bootstrappable", func() {
+			So(rootAuth.CanRebootstrap(), ShouldBeNil)
+
+			client, err := rootAuth.Client()
+			So(err, ShouldBeNil)
+			client.SetWrappingLookupFunc(func(operation, path string) string {
+				return "5m"
+			})
+			resp, err := client.Logical().Write("auth/approle/role/goldfish/secret-id", map[string]interface{}{})
+			So(err, ShouldBeNil)
+			So(resp.WrapInfo, ShouldNotBeNil)
+
+			oldToken := getVaultToken()
+			So(StartGoldfishWrapper(resp.WrapInfo.Token), ShouldBeNil)
+			So(getVaultToken(), ShouldNotEqual, oldToken)
+			So(Bootstrapped(), ShouldBeTrue)
+
+			// a used wrapping token should not replace the current token
+			So(StartGoldfishWrapper(resp.WrapInfo.Token), ShouldNotBeNil)
+			So(Bootstrapped(), ShouldBeTrue)
+		})
+
 		Convey("Creating a token", func() {
 			resp, err := rootAuth.CreateToken(&api.TokenCreateRequest{}, false, "", "")
 			So(err, ShouldBeNil)